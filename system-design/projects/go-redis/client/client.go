@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Client 是 go-redis 服务器的客户端，内部维护连接池，可以被多个 goroutine 同时使用
+type Client struct {
+	cmdable
+	opt  *Options
+	pool *Pool
+}
+
+// NewClient 创建客户端，连接在第一次使用时建立
+func NewClient(opt *Options) *Client {
+	o := *opt
+	o.init()
+
+	c := &Client{
+		opt:  &o,
+		pool: newPool(&o),
+	}
+	c.cmdable = c.Process
+	return c
+}
+
+// Options 返回客户端使用的配置（已填充默认值）
+func (c *Client) Options() *Options {
+	return c.opt
+}
+
+// PoolStats 返回连接池的统计信息
+func (c *Client) PoolStats() *PoolStats {
+	return c.pool.Stats()
+}
+
+// Close 关闭客户端和连接池
+func (c *Client) Close() error {
+	return c.pool.Close()
+}
+
+// Process 执行一条命令，网络错误时按 MaxRetries 重试
+// 错误同时记录在 cmd 上
+func (c *Client) Process(ctx context.Context, cmd Cmder) error {
+	err := c.withRetry(ctx, func(cn *Conn) error {
+		if err := cn.writeCommands(ctx, c.opt.WriteTimeout, []Cmder{cmd}); err != nil {
+			return err
+		}
+		v, err := cn.readReply(ctx, c.cmdTimeout(cmd))
+		if err != nil {
+			return err
+		}
+		return cmd.readReply(v)
+	})
+	cmd.SetErr(err)
+	return err
+}
+
+// cmdTimeout 返回读取命令回复的超时
+// 阻塞命令在阻塞时间之外再加上 ReadTimeout，阻塞时间为 0（一直等待）时不设超时
+func (c *Client) cmdTimeout(cmd Cmder) time.Duration {
+	if t := cmd.readTimeout(); t != nil {
+		if *t == 0 {
+			return 0
+		}
+		return *t + c.opt.ReadTimeout
+	}
+	return c.opt.ReadTimeout
+}
+
+// withRetry 取一个连接执行 fn，连接层面的错误会换一个连接重试
+func (c *Client) withRetry(ctx context.Context, fn func(cn *Conn) error) error {
+	var lastErr error
+	for attempt := 0; attempt <= c.opt.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.retryBackoff(attempt)); err != nil {
+				return err
+			}
+		}
+
+		lastErr = c.withConn(ctx, fn)
+		if !shouldRetry(lastErr) {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// withConn 从连接池取出一个连接执行 fn，出现网络或协议错误时关闭该连接
+func (c *Client) withConn(ctx context.Context, fn func(cn *Conn) error) error {
+	cn, err := c.pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(cn)
+	if isBadConn(err) {
+		c.pool.Remove(cn)
+	} else {
+		c.pool.Put(cn)
+	}
+	return err
+}
+
+// retryBackoff 返回第 attempt 次重试前的等待时间，指数增长并限制在 MaxRetryBackoff 以内
+func (c *Client) retryBackoff(attempt int) time.Duration {
+	d := c.opt.MinRetryBackoff << uint(attempt-1)
+	if d <= 0 || d > c.opt.MaxRetryBackoff {
+		d = c.opt.MaxRetryBackoff
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,666 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"go-redis/logger"
+	"go-redis/server"
+	"go-redis/store"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testAddr 是 TestMain 在进程内启动的服务器地址
+var testAddr string
+
+func TestMain(m *testing.M) {
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.ErrorLevel)
+
+	srv := server.NewServer("127.0.0.1:0", store.NewStore())
+	if err := srv.Listen(); err != nil {
+		panic(err)
+	}
+	go srv.Serve()
+	testAddr = srv.Addr()
+
+	code := m.Run()
+	srv.Stop()
+	os.Exit(code)
+}
+
+func newTestClient(t *testing.T, opt *Options) *Client {
+	t.Helper()
+	if opt == nil {
+		opt = &Options{}
+	}
+	opt.Addr = testAddr
+
+	c := NewClient(opt)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestBasicCommands(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	if err := c.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if err := c.Set(ctx, "basic:k", "v").Err(); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if v, err := c.Get(ctx, "basic:k").Result(); err != nil || v != "v" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if _, err := c.Get(ctx, "basic:missing").Result(); err != Nil {
+		t.Errorf("Expected Nil, got %v", err)
+	}
+	if n := c.Strlen(ctx, "basic:k").Val(); n != 1 {
+		t.Errorf("Strlen = %d, expected 1", n)
+	}
+
+	if err := c.IncrBy(ctx, "basic:n", 5).Err(); err != nil {
+		t.Errorf("IncrBy failed: %v", err)
+	}
+	if n, _ := c.Get(ctx, "basic:n").Int64(); n != 5 {
+		t.Errorf("Expected 5, got %d", n)
+	}
+	if !c.Exists(ctx, "basic:k").Val() || c.Exists(ctx, "basic:missing").Val() {
+		t.Error("Unexpected Exists result")
+	}
+
+	if ok := c.Expire(ctx, "basic:k", time.Minute).Val(); !ok {
+		t.Error("Expected Expire to succeed")
+	}
+	if ttl := c.TTL(ctx, "basic:k").Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Unexpected TTL %v", ttl)
+	}
+	if ttl := c.PTTL(ctx, "basic:missing").Val(); ttl != -2 {
+		t.Errorf("Expected -2 for missing key, got %v", ttl)
+	}
+
+	if n := c.Del(ctx, "basic:k", "basic:n").Val(); n != 2 {
+		t.Errorf("Del = %d, expected 2", n)
+	}
+}
+
+func TestKeyspaceCommands(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	c.Set(ctx, "ks:a", "12345")
+	if typ := c.Type(ctx, "ks:a").Val(); typ != "string" {
+		t.Errorf("Type = %q, expected string", typ)
+	}
+	if enc := c.ObjectEncoding(ctx, "ks:a").Val(); enc != "int" {
+		t.Errorf("ObjectEncoding = %q, expected int", enc)
+	}
+	if idle, err := c.ObjectIdleTime(ctx, "ks:a").Result(); err != nil || idle < 0 {
+		t.Errorf("ObjectIdleTime = %v, %v", idle, err)
+	}
+	if n := c.ObjectRefCount(ctx, "ks:a").Val(); n != 1 {
+		t.Errorf("ObjectRefCount = %d, expected 1", n)
+	}
+	if _, err := c.ObjectFreq(ctx, "ks:a").Result(); err != nil {
+		t.Errorf("ObjectFreq failed: %v", err)
+	}
+
+	if n := c.Copy(ctx, "ks:a", "ks:b", 0, false).Val(); n != 1 {
+		t.Errorf("Copy = %d, expected 1", n)
+	}
+	if ok := c.RenameNX(ctx, "ks:a", "ks:b").Val(); ok {
+		t.Error("RenameNX should not overwrite an existing key")
+	}
+	if err := c.Rename(ctx, "ks:a", "ks:c").Err(); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	dump, err := c.Dump(ctx, "ks:c").Result()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	if err := c.Restore(ctx, "ks:c", 0, dump).Err(); err == nil {
+		t.Error("Restore should refuse to overwrite an existing key")
+	}
+	if err := c.RestoreReplace(ctx, "ks:c", time.Minute, dump).Err(); err != nil {
+		t.Fatalf("RestoreReplace failed: %v", err)
+	}
+	if v := c.Get(ctx, "ks:c").Val(); v != "12345" {
+		t.Errorf("Get after RestoreReplace = %q", v)
+	}
+	if ttl := c.TTL(ctx, "ks:c").Val(); ttl <= 0 {
+		t.Errorf("TTL after RestoreReplace = %v", ttl)
+	}
+	if _, err := c.Dump(ctx, "ks:missing").Result(); err != Nil {
+		t.Errorf("Dump of a missing key: %v", err)
+	}
+
+	if key, err := c.RandomKey(ctx).Result(); err != nil || key == "" {
+		t.Errorf("RandomKey = %q, %v", key, err)
+	}
+	if n := c.Touch(ctx, "ks:b", "ks:c", "ks:missing").Val(); n != 2 {
+		t.Errorf("Touch = %d, expected 2", n)
+	}
+	if n := c.Unlink(ctx, "ks:b", "ks:c").Val(); n != 2 {
+		t.Errorf("Unlink = %d, expected 2", n)
+	}
+}
+
+func TestScanAndMemory(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		c.Set(ctx, "scan:"+strconv.Itoa(i), "v")
+	}
+	c.XAdd(ctx, &XAddArgs{Stream: "scan:stream", Values: []string{"f", "v"}})
+
+	keys, cursor, err := c.Scan(ctx, 0, "scan:*", 5).Result()
+	if err != nil || cursor == 0 || len(keys) == 0 {
+		t.Fatalf("Scan = %v, %d, %v", keys, cursor, err)
+	}
+
+	// 迭代器跟随游标发起多次 SCAN，直到遍历结束
+	seen := make(map[string]bool)
+	it := c.Scan(ctx, 0, "scan:*", 5).Iterator()
+	for it.Next(ctx) {
+		seen[it.Val()] = true
+	}
+	if err := it.Err(); err != nil || len(seen) != n+1 {
+		t.Errorf("iterator returned %d keys, expected %d: %v", len(seen), n+1, err)
+	}
+
+	it = c.ScanType(ctx, 0, "scan:*", 100, "stream").Iterator()
+	var streams []string
+	for it.Next(ctx) {
+		streams = append(streams, it.Val())
+	}
+	if len(streams) != 1 || streams[0] != "scan:stream" {
+		t.Errorf("ScanType stream = %v", streams)
+	}
+
+	if size := c.DBSize(ctx).Val(); size < n+1 {
+		t.Errorf("DBSize = %d, expected at least %d", size, n+1)
+	}
+	if size, err := c.MemoryUsage(ctx, "scan:stream", 0).Result(); err != nil || size <= 0 {
+		t.Errorf("MemoryUsage = %d, %v", size, err)
+	}
+	if _, err := c.MemoryUsage(ctx, "scan:missing").Result(); err != Nil {
+		t.Errorf("MemoryUsage of a missing key: %v", err)
+	}
+	stats, err := c.MemoryStats(ctx).Result()
+	if fields, ok := stats.([]interface{}); err != nil || !ok || len(fields) == 0 || fields[0] != "peak.allocated" {
+		t.Errorf("MemoryStats = %v, %v", stats, err)
+	}
+	if doctor, err := c.MemoryDoctor(ctx).Result(); err != nil || !strings.Contains(doctor, "Sam") {
+		t.Errorf("MemoryDoctor = %q, %v", doctor, err)
+	}
+	if err := c.MemoryPurge(ctx).Err(); err != nil {
+		t.Errorf("MemoryPurge failed: %v", err)
+	}
+}
+
+func TestRedisErrorKeepsConnection(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	c.Set(ctx, "err:str", "abc")
+	err := c.Incr(ctx, "err:str").Err()
+
+	var re RedisError
+	if !errors.As(err, &re) {
+		t.Fatalf("Expected RedisError, got %v", err)
+	}
+	if err := c.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if stats := c.PoolStats(); stats.Misses != 1 || stats.TotalConns != 1 {
+		t.Errorf("Expected the connection to be reused, got %+v", stats)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	var get, incr *StringCmd
+	cmds, err := c.Pipelined(ctx, func(p *Pipeline) error {
+		p.Set(ctx, "pipe:k", "v")
+		get = p.Get(ctx, "pipe:k")
+		p.Incr(ctx, "pipe:n")
+		incr = p.Get(ctx, "pipe:n")
+		p.Get(ctx, "pipe:missing")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if len(cmds) != 5 {
+		t.Fatalf("Expected 5 commands, got %d", len(cmds))
+	}
+	if get.Val() != "v" || incr.Val() != "1" {
+		t.Errorf("Unexpected results: %q %q", get.Val(), incr.Val())
+	}
+	if cmds[4].Err() != Nil {
+		t.Errorf("Expected Nil for missing key, got %v", cmds[4].Err())
+	}
+}
+
+func TestTxPipeline(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	var get1, get2 *StringCmd
+	_, err := c.TxPipelined(ctx, func(p *Pipeline) error {
+		p.Incr(ctx, "tx:n")
+		get1 = p.Get(ctx, "tx:n")
+		p.IncrBy(ctx, "tx:n", 10)
+		get2 = p.Get(ctx, "tx:n")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if get1.Val() != "1" || get2.Val() != "11" {
+		t.Errorf("Unexpected results: %q %q", get1.Val(), get2.Val())
+	}
+
+	// 排队时出错的事务整体被放弃
+	p := c.TxPipeline()
+	p.Incr(ctx, "tx:n")
+	p.Do(ctx, "nosuchcommand")
+	cmds, err := p.Exec(ctx)
+	if err == nil {
+		t.Fatal("Expected transaction to fail")
+	}
+	if re, ok := cmds[0].Err().(RedisError); !ok || re.Prefix() != "EXECABORT" {
+		t.Errorf("Expected EXECABORT, got %v", cmds[0].Err())
+	}
+	if v, _ := c.Get(ctx, "tx:n").Int64(); v != 11 {
+		t.Errorf("Expected aborted transaction to leave 11, got %d", v)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	id, err := c.XAdd(ctx, &XAddArgs{Stream: "s:events", Values: []string{"type", "login"}}).Result()
+	if err != nil {
+		t.Fatalf("XAdd failed: %v", err)
+	}
+	if n := c.XLen(ctx, "s:events").Val(); n != 1 {
+		t.Errorf("XLen = %d, expected 1", n)
+	}
+
+	msgs := c.XRange(ctx, "s:events", "-", "+").Val()
+	if len(msgs) != 1 || msgs[0].ID != id || msgs[0].Values["type"] != "login" {
+		t.Errorf("Unexpected XRange result: %+v", msgs)
+	}
+
+	if err := c.XGroupCreate(ctx, "s:events", "g", "0").Err(); err != nil {
+		t.Fatalf("XGroupCreate failed: %v", err)
+	}
+	streams, err := c.XReadGroup(ctx, &XReadGroupArgs{
+		Group: "g", Consumer: "alice", Streams: []string{"s:events", ">"}, Block: -1,
+	}).Result()
+	if err != nil || len(streams) != 1 || len(streams[0].Messages) != 1 {
+		t.Fatalf("Unexpected XReadGroup result: %+v, %v", streams, err)
+	}
+
+	pending := c.XPending(ctx, "s:events", "g").Val()
+	if pending == nil || pending.Count != 1 || pending.Consumers["alice"] != 1 {
+		t.Errorf("Unexpected XPending result: %+v", pending)
+	}
+	if n := c.XAck(ctx, "s:events", "g", id).Val(); n != 1 {
+		t.Errorf("XAck = %d, expected 1", n)
+	}
+
+	// 阻塞读取超时返回 Nil
+	_, err = c.XRead(ctx, &XReadArgs{Streams: []string{"s:events", "$"}, Block: 50 * time.Millisecond}).Result()
+	if err != Nil {
+		t.Errorf("Expected Nil after block timeout, got %v", err)
+	}
+}
+
+func TestBitmap(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	if old := c.SetBit(ctx, "bit:a", 7, 1).Val(); old != 0 {
+		t.Errorf("SetBit = %d, expected 0", old)
+	}
+	c.SetBit(ctx, "bit:a", 9, 1)
+	if v := c.GetBit(ctx, "bit:a", 7).Val(); v != 1 {
+		t.Errorf("GetBit = %d, expected 1", v)
+	}
+	if n := c.BitCount(ctx, "bit:a", nil).Val(); n != 2 {
+		t.Errorf("BitCount = %d, expected 2", n)
+	}
+	if n := c.BitCount(ctx, "bit:a", &BitCount{Start: 8, End: 15, Unit: "bit"}).Val(); n != 1 {
+		t.Errorf("BitCount BIT = %d, expected 1", n)
+	}
+	if pos := c.BitPos(ctx, "bit:a", 1, 1).Val(); pos != 9 {
+		t.Errorf("BitPos = %d, expected 9", pos)
+	}
+	if pos := c.BitPosSpan(ctx, "bit:a", 1, 8, 15, "bit").Val(); pos != 9 {
+		t.Errorf("BitPosSpan = %d, expected 9", pos)
+	}
+
+	c.SetBit(ctx, "bit:b", 7, 1)
+	if n := c.BitOpAnd(ctx, "bit:and", "bit:a", "bit:b").Val(); n != 2 {
+		t.Errorf("BitOpAnd = %d, expected 2", n)
+	}
+	if n := c.BitCount(ctx, "bit:and", nil).Val(); n != 1 {
+		t.Errorf("BitCount after BitOpAnd = %d, expected 1", n)
+	}
+
+	vals, err := c.BitField(ctx, "bit:f", "set", "u8", 0, 200, "overflow", "fail", "incrby", "u8", 0, 100, "get", "u8", 0).Result()
+	if err != nil || len(vals) != 3 || vals[0] != 0 || vals[1] != 0 || vals[2] != 200 {
+		t.Errorf("BitField = %v, %v", vals, err)
+	}
+	if vals := c.BitFieldRO(ctx, "bit:f", "get", "u4", 0).Val(); len(vals) != 1 || vals[0] != 12 {
+		t.Errorf("BitFieldRO = %v", vals)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	if n := c.PFAdd(ctx, "hll:a", "x", "y", "z").Val(); n != 1 {
+		t.Errorf("PFAdd = %d, expected 1", n)
+	}
+	if n := c.PFAdd(ctx, "hll:a", "x").Val(); n != 0 {
+		t.Errorf("PFAdd of an existing element = %d, expected 0", n)
+	}
+	c.PFAdd(ctx, "hll:b", "z", "w")
+	if n := c.PFCount(ctx, "hll:a", "hll:b").Val(); n != 4 {
+		t.Errorf("PFCount of the union = %d, expected 4", n)
+	}
+	if err := c.PFMerge(ctx, "hll:dest", "hll:a", "hll:b").Err(); err != nil {
+		t.Fatalf("PFMerge failed: %v", err)
+	}
+	if n := c.PFCount(ctx, "hll:dest").Val(); n != 4 {
+		t.Errorf("PFCount after PFMerge = %d, expected 4", n)
+	}
+}
+
+func TestBlockingReadWakesUp(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, nil)
+
+	done := make(chan []XStream, 1)
+	go func() {
+		streams, _ := c.XRead(ctx, &XReadArgs{Streams: []string{"s:wake", "$"}, Block: 0}).Result()
+		done <- streams
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	c.XAdd(ctx, &XAddArgs{Stream: "s:wake", Values: map[string]interface{}{"n": 1}})
+
+	select {
+	case streams := <-done:
+		if len(streams) != 1 || streams[0].Messages[0].Values["n"] != "1" {
+			t.Errorf("Unexpected XRead result: %+v", streams)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Blocking XRead was not woken up")
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := c.XRead(ctx, &XReadArgs{Streams: []string{"s:never", "$"}, Block: 0}).Err()
+	if err == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Context deadline not respected, took %v", elapsed)
+	}
+}
+
+func TestPoolSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, &Options{PoolSize: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Incr(ctx, "pool:n").Err(); err != nil {
+				t.Errorf("Incr failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := c.Get(ctx, "pool:n").Int64(); v != 20 {
+		t.Errorf("Expected 20, got %d", v)
+	}
+	if stats := c.PoolStats(); stats.TotalConns > 2 {
+		t.Errorf("Pool exceeded its size: %+v", stats)
+	}
+}
+
+func TestRetryOnBrokenConnection(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	c := newTestClient(t, &Options{
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			if err == nil {
+				mu.Lock()
+				conns = append(conns, conn)
+				mu.Unlock()
+			}
+			return conn, err
+		},
+	})
+
+	if err := c.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	// 连接在空闲时断开，下一条命令应该换一个新连接重试成功
+	mu.Lock()
+	conns[0].Close()
+	mu.Unlock()
+
+	if err := c.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if stats := c.PoolStats(); stats.Misses != 2 || stats.TotalConns != 1 {
+		t.Errorf("Unexpected pool stats: %+v", stats)
+	}
+}
+
+func TestIdleConnectionsExpire(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, &Options{ConnMaxIdleTime: 20 * time.Millisecond})
+
+	c.Ping(ctx)
+	time.Sleep(40 * time.Millisecond)
+	c.Ping(ctx)
+
+	if stats := c.PoolStats(); stats.Stale != 1 || stats.Misses != 2 {
+		t.Errorf("Expected the idle connection to be replaced, got %+v", stats)
+	}
+}
+
+func TestClientName(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, &Options{ClientName: "worker-1"})
+
+	if name, err := c.ClientGetName(ctx).Result(); err != nil || name != "worker-1" {
+		t.Errorf("ClientGetName = %q, %v", name, err)
+	}
+}
+
+// 服务器只有 0 号数据库，选择其他数据库时建立连接失败
+func TestClientDB(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, &Options{DB: 1, MaxRetries: -1})
+
+	var redisErr RedisError
+	if err := c.Ping(ctx).Err(); !errors.As(err, &redisErr) || !strings.Contains(err.Error(), "DB index is out of range") {
+		t.Errorf("Ping with DB 1: %v", err)
+	}
+	if stats := c.PoolStats(); stats.TotalConns != 0 {
+		t.Errorf("failed connection should not stay in the pool: %+v", stats)
+	}
+}
+
+func TestClientSetName(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, &Options{PoolSize: 1})
+
+	if err := c.ClientSetName(ctx, "renamed").Err(); err != nil {
+		t.Fatalf("ClientSetName failed: %v", err)
+	}
+	if name := c.ClientGetName(ctx).Val(); name != "renamed" {
+		t.Errorf("Expected renamed, got %q", name)
+	}
+	if err := c.ClientSetName(ctx, "has space").Err(); err == nil {
+		t.Error("Expected an error for a name with spaces")
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := newTestClient(t, nil)
+
+	ps := c.Subscribe(ctx, "ps:news")
+	defer ps.Close()
+	if err := ps.PSubscribe(ctx, "ps:log.*"); err != nil {
+		t.Fatalf("PSubscribe failed: %v", err)
+	}
+	for _, want := range []Subscription{{"subscribe", "ps:news", 1}, {"psubscribe", "ps:log.*", 2}} {
+		msg, err := ps.Receive(ctx)
+		if sub, ok := msg.(*Subscription); err != nil || !ok || *sub != want {
+			t.Fatalf("Receive = %v, %v, expected %v", msg, err, want)
+		}
+	}
+
+	if n := c.Publish(ctx, "ps:news", "hello").Val(); n != 1 {
+		t.Errorf("Expected 1 receiver, got %d", n)
+	}
+	c.Publish(ctx, "ps:log.error", "boom")
+
+	if m, err := ps.ReceiveMessage(ctx); err != nil || m.Channel != "ps:news" || m.Payload != "hello" || m.Pattern != "" {
+		t.Errorf("ReceiveMessage = %+v, %v", m, err)
+	}
+	if m, err := ps.ReceiveMessage(ctx); err != nil || m.Channel != "ps:log.error" || m.Payload != "boom" || m.Pattern != "ps:log.*" {
+		t.Errorf("ReceiveMessage = %+v, %v", m, err)
+	}
+
+	if err := ps.Ping(ctx, "hi"); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if msg, err := ps.Receive(ctx); err != nil || *msg.(*Pong) != (Pong{"hi"}) {
+		t.Errorf("Expected pong, got %v, %v", msg, err)
+	}
+
+	if err := ps.Unsubscribe(ctx, "ps:news"); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+	if msg, err := ps.Receive(ctx); err != nil || *msg.(*Subscription) != (Subscription{"unsubscribe", "ps:news", 1}) {
+		t.Errorf("Unexpected unsubscribe reply %v, %v", msg, err)
+	}
+	if n := c.Publish(ctx, "ps:news", "again").Val(); n != 0 {
+		t.Errorf("Expected no receivers, got %d", n)
+	}
+
+	ps.Close()
+	if _, err := ps.Receive(ctx); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPubSubResubscribes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := newTestClient(t, nil)
+
+	ps := c.Subscribe(ctx, "resub:ch")
+	defer ps.Close()
+	if _, err := ps.Receive(ctx); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+
+	// 断开连接后，下一次 Receive 重新连接并恢复订阅
+	ps.mu.Lock()
+	ps.cn.netConn.Close()
+	ps.mu.Unlock()
+	if _, err := ps.Receive(ctx); err == nil {
+		t.Fatal("Expected an error from the closed connection")
+	}
+	if msg, err := ps.Receive(ctx); err != nil || *msg.(*Subscription) != (Subscription{"subscribe", "resub:ch", 1}) {
+		t.Fatalf("Expected resubscribe, got %v, %v", msg, err)
+	}
+
+	c.Publish(ctx, "resub:ch", "back")
+	if m, err := ps.ReceiveMessage(ctx); err != nil || m.Payload != "back" {
+		t.Errorf("ReceiveMessage = %+v, %v", m, err)
+	}
+}
+
+func TestClientTracking(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := newTestClient(t, &Options{PoolSize: 1})
+
+	ps := c.Subscribe(ctx, "__redis__:invalidate")
+	defer ps.Close()
+	if _, err := ps.Receive(ctx); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	id, err := ps.ID(ctx)
+	if err != nil {
+		t.Fatalf("ID failed: %v", err)
+	}
+
+	opt := &TrackingOptions{Redirect: id, BCast: true, Prefixes: []string{"trk:"}}
+	if err := c.ClientTracking(ctx, true, opt).Err(); err != nil {
+		t.Fatalf("ClientTracking failed: %v", err)
+	}
+	if err := c.ClientTracking(ctx, true, &TrackingOptions{Prefixes: []string{"trk:"}}).Err(); err == nil {
+		t.Error("Expected PREFIX without BCAST to fail")
+	}
+
+	c.Set(ctx, "other:k", "v")
+	c.Set(ctx, "trk:k", "v")
+	m, err := ps.ReceiveMessage(ctx)
+	if err != nil || m.Channel != "__redis__:invalidate" || len(m.PayloadSlice) != 1 || m.PayloadSlice[0] != "trk:k" {
+		t.Fatalf("Unexpected invalidation %+v, %v", m, err)
+	}
+
+	if err := c.ClientTracking(ctx, false, nil).Err(); err != nil {
+		t.Fatalf("ClientTracking off failed: %v", err)
+	}
+	c.Set(ctx, "trk:k", "v2")
+	c.Publish(ctx, "__redis__:invalidate", "marker")
+	if m, err := ps.ReceiveMessage(ctx); err != nil || m.Payload != "marker" {
+		t.Errorf("Expected no invalidation after tracking off, got %+v, %v", m, err)
+	}
+}
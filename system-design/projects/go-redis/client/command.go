@@ -0,0 +1,656 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"go-redis/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cmder 是一条命令及其回复，由各个带类型的 XxxCmd 实现
+type Cmder interface {
+	Name() string
+	Args() []interface{}
+	Err() error
+	SetErr(err error)
+
+	// readTimeout 返回读取回复的超时，nil 表示使用 Options.ReadTimeout
+	readTimeout() *time.Duration
+	// readReply 解析服务器的回复
+	readReply(v *protocol.Value) error
+}
+
+type baseCmd struct {
+	args    []interface{}
+	err     error
+	timeout *time.Duration
+}
+
+// Name 返回小写的命令名
+func (c *baseCmd) Name() string {
+	if len(c.args) == 0 {
+		return ""
+	}
+	return strings.ToLower(argString(c.args[0]))
+}
+
+func (c *baseCmd) Args() []interface{} {
+	return c.args
+}
+
+func (c *baseCmd) Err() error {
+	return c.err
+}
+
+func (c *baseCmd) SetErr(err error) {
+	c.err = err
+}
+
+func (c *baseCmd) readTimeout() *time.Duration {
+	return c.timeout
+}
+
+// setReadTimeout 为阻塞命令设置读取超时，0 表示一直等待
+func (c *baseCmd) setReadTimeout(d time.Duration) {
+	c.timeout = &d
+}
+
+func (c *baseCmd) String() string {
+	parts := make([]string, len(c.args))
+	for i, arg := range c.args {
+		parts[i] = argString(arg)
+	}
+	s := strings.Join(parts, " ")
+	if c.err != nil {
+		return s + ": " + c.err.Error()
+	}
+	return s
+}
+
+// replyError 把错误回复转换为 RedisError，把空回复转换为 Nil
+func replyError(v *protocol.Value) error {
+	if v.Type == protocol.ErrorType {
+		return RedisError(v.Str)
+	}
+	if v.IsNull {
+		return Nil
+	}
+	return nil
+}
+
+func valueString(v *protocol.Value) (string, error) {
+	switch v.Type {
+	case protocol.StringType, protocol.BulkStringType:
+		return v.Str, nil
+	case protocol.IntType:
+		return strconv.FormatInt(v.Int, 10), nil
+	}
+	return "", fmt.Errorf("redis: unexpected reply type %s, expected string", v.Type)
+}
+
+func valueInt(v *protocol.Value) (int64, error) {
+	switch v.Type {
+	case protocol.IntType:
+		return v.Int, nil
+	case protocol.StringType, protocol.BulkStringType:
+		return strconv.ParseInt(v.Str, 10, 64)
+	}
+	return 0, fmt.Errorf("redis: unexpected reply type %s, expected integer", v.Type)
+}
+
+func valueArray(v *protocol.Value) ([]protocol.Value, error) {
+	if v.Type != protocol.ArrayType {
+		return nil, fmt.Errorf("redis: unexpected reply type %s, expected array", v.Type)
+	}
+	return v.Array, nil
+}
+
+// *****
+// Cmd 是没有专门类型的命令，回复转换为 string、int64、[]interface{} 或 nil
+type Cmd struct {
+	baseCmd
+	val interface{}
+}
+
+func NewCmd(args ...interface{}) *Cmd {
+	return &Cmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *Cmd) Val() interface{} {
+	return c.val
+}
+
+func (c *Cmd) Result() (interface{}, error) {
+	return c.val, c.err
+}
+
+// Text 以字符串形式返回回复
+func (c *Cmd) Text() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	s, ok := c.val.(string)
+	if !ok {
+		return "", fmt.Errorf("redis: unexpected type %T for Text", c.val)
+	}
+	return s, nil
+}
+
+// Int64 以整数形式返回回复
+func (c *Cmd) Int64() (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	switch v := c.val.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("redis: unexpected type %T for Int64", c.val)
+}
+
+func (c *Cmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	c.val = genericValue(v)
+	return nil
+}
+
+func genericValue(v *protocol.Value) interface{} {
+	switch {
+	case v.IsNull:
+		return nil
+	case v.Type == protocol.IntType:
+		return v.Int
+	case v.Type == protocol.ErrorType:
+		return RedisError(v.Str)
+	case v.Type == protocol.ArrayType:
+		res := make([]interface{}, len(v.Array))
+		for i := range v.Array {
+			res[i] = genericValue(&v.Array[i])
+		}
+		return res
+	}
+	return v.Str
+}
+
+// *****
+type StatusCmd struct {
+	baseCmd
+	val string
+}
+
+func NewStatusCmd(args ...interface{}) *StatusCmd {
+	return &StatusCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *StatusCmd) Val() string {
+	return c.val
+}
+
+func (c *StatusCmd) Result() (string, error) {
+	return c.val, c.err
+}
+
+func (c *StatusCmd) readReply(v *protocol.Value) (err error) {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	c.val, err = valueString(v)
+	return err
+}
+
+// *****
+type StringCmd struct {
+	baseCmd
+	val string
+}
+
+func NewStringCmd(args ...interface{}) *StringCmd {
+	return &StringCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *StringCmd) Val() string {
+	return c.val
+}
+
+func (c *StringCmd) Result() (string, error) {
+	return c.val, c.err
+}
+
+func (c *StringCmd) Bytes() ([]byte, error) {
+	return []byte(c.val), c.err
+}
+
+func (c *StringCmd) Int64() (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return strconv.ParseInt(c.val, 10, 64)
+}
+
+func (c *StringCmd) readReply(v *protocol.Value) (err error) {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	c.val, err = valueString(v)
+	return err
+}
+
+// *****
+type IntCmd struct {
+	baseCmd
+	val int64
+}
+
+func NewIntCmd(args ...interface{}) *IntCmd {
+	return &IntCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *IntCmd) Val() int64 {
+	return c.val
+}
+
+func (c *IntCmd) Result() (int64, error) {
+	return c.val, c.err
+}
+
+func (c *IntCmd) readReply(v *protocol.Value) (err error) {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	c.val, err = valueInt(v)
+	return err
+}
+
+// *****
+// BoolCmd 把整数回复 1/0 或状态回复 OK 转换为布尔值
+type BoolCmd struct {
+	baseCmd
+	val bool
+}
+
+func NewBoolCmd(args ...interface{}) *BoolCmd {
+	return &BoolCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *BoolCmd) Val() bool {
+	return c.val
+}
+
+func (c *BoolCmd) Result() (bool, error) {
+	return c.val, c.err
+}
+
+func (c *BoolCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	switch v.Type {
+	case protocol.IntType:
+		c.val = v.Int != 0
+	case protocol.StringType:
+		c.val = v.Str == "OK"
+	default:
+		return fmt.Errorf("redis: unexpected reply type %s, expected bool", v.Type)
+	}
+	return nil
+}
+
+// *****
+// DurationCmd 用于 TTL/PTTL，键不存在时为 -2，没有过期时间时为 -1（不乘以单位）
+type DurationCmd struct {
+	baseCmd
+	val       time.Duration
+	precision time.Duration
+}
+
+func NewDurationCmd(precision time.Duration, args ...interface{}) *DurationCmd {
+	return &DurationCmd{baseCmd: baseCmd{args: args}, precision: precision}
+}
+
+func (c *DurationCmd) Val() time.Duration {
+	return c.val
+}
+
+func (c *DurationCmd) Result() (time.Duration, error) {
+	return c.val, c.err
+}
+
+func (c *DurationCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	n, err := valueInt(v)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		c.val = time.Duration(n)
+	} else {
+		c.val = time.Duration(n) * c.precision
+	}
+	return nil
+}
+
+// *****
+type StringSliceCmd struct {
+	baseCmd
+	val []string
+}
+
+func NewStringSliceCmd(args ...interface{}) *StringSliceCmd {
+	return &StringSliceCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *StringSliceCmd) Val() []string {
+	return c.val
+}
+
+func (c *StringSliceCmd) Result() ([]string, error) {
+	return c.val, c.err
+}
+
+func (c *StringSliceCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+	c.val = make([]string, len(arr))
+	for i := range arr {
+		if c.val[i], err = valueString(&arr[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *****
+// IntSliceCmd 的回复是整数数组，nil 元素（例如 BITFIELD 中 OVERFLOW FAIL 的操作）转换为 0
+type IntSliceCmd struct {
+	baseCmd
+	val []int64
+}
+
+func NewIntSliceCmd(args ...interface{}) *IntSliceCmd {
+	return &IntSliceCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *IntSliceCmd) Val() []int64 {
+	return c.val
+}
+
+func (c *IntSliceCmd) Result() ([]int64, error) {
+	return c.val, c.err
+}
+
+func (c *IntSliceCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+	c.val = make([]int64, len(arr))
+	for i := range arr {
+		if arr[i].IsNull {
+			continue
+		}
+		if c.val[i], err = valueInt(&arr[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *****
+// MapStringStringCmd 把 [k1, v1, k2, v2, ...] 形式的回复转换为 map
+type MapStringStringCmd struct {
+	baseCmd
+	val map[string]string
+}
+
+func NewMapStringStringCmd(args ...interface{}) *MapStringStringCmd {
+	return &MapStringStringCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *MapStringStringCmd) Val() map[string]string {
+	return c.val
+}
+
+func (c *MapStringStringCmd) Result() (map[string]string, error) {
+	return c.val, c.err
+}
+
+func (c *MapStringStringCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+	c.val = make(map[string]string, len(arr)/2)
+	for i := 0; i+1 < len(arr); i += 2 {
+		k, err := valueString(&arr[i])
+		if err != nil {
+			return err
+		}
+		val, err := valueString(&arr[i+1])
+		if err != nil {
+			return err
+		}
+		c.val[k] = val
+	}
+	return nil
+}
+
+// *****
+// MapStringIntCmd 把 [k1, n1, k2, n2, ...] 形式的回复转换为 map
+type MapStringIntCmd struct {
+	baseCmd
+	val map[string]int64
+}
+
+func NewMapStringIntCmd(args ...interface{}) *MapStringIntCmd {
+	return &MapStringIntCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *MapStringIntCmd) Val() map[string]int64 {
+	return c.val
+}
+
+func (c *MapStringIntCmd) Result() (map[string]int64, error) {
+	return c.val, c.err
+}
+
+func (c *MapStringIntCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+	c.val = make(map[string]int64, len(arr)/2)
+	for i := 0; i+1 < len(arr); i += 2 {
+		k, err := valueString(&arr[i])
+		if err != nil {
+			return err
+		}
+		n, err := valueInt(&arr[i+1])
+		if err != nil {
+			return err
+		}
+		c.val[k] = n
+	}
+	return nil
+}
+
+// *****
+// SlowLog 是 SLOWLOG GET 返回的一条记录
+type SlowLog struct {
+	ID         int64
+	Time       time.Time
+	Duration   time.Duration
+	Args       []string
+	ClientAddr string
+	ClientName string
+}
+
+type SlowLogCmd struct {
+	baseCmd
+	val []SlowLog
+}
+
+func NewSlowLogCmd(args ...interface{}) *SlowLogCmd {
+	return &SlowLogCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *SlowLogCmd) Val() []SlowLog {
+	return c.val
+}
+
+func (c *SlowLogCmd) Result() ([]SlowLog, error) {
+	return c.val, c.err
+}
+
+func (c *SlowLogCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+
+	c.val = make([]SlowLog, len(arr))
+	for i := range arr {
+		fields := arr[i].Array
+		if len(fields) < 4 {
+			return fmt.Errorf("redis: unexpected slowlog entry with %d fields", len(fields))
+		}
+		entry := &c.val[i]
+		entry.ID = fields[0].Int
+		entry.Time = time.Unix(fields[1].Int, 0)
+		entry.Duration = time.Duration(fields[2].Int) * time.Microsecond
+		entry.Args = make([]string, len(fields[3].Array))
+		for j := range fields[3].Array {
+			entry.Args[j] = fields[3].Array[j].Str
+		}
+		if len(fields) >= 6 {
+			entry.ClientAddr = fields[4].Str
+			entry.ClientName = fields[5].Str
+		}
+	}
+	return nil
+}
+
+// *****
+// ScanCmd 是 SCAN 的一次调用，回复是这一批键和下一次的游标，游标为 0 时遍历结束
+type ScanCmd struct {
+	baseCmd
+	page   []string
+	cursor uint64
+
+	process cmdable // Iterator 用它发起后续的 SCAN
+}
+
+func NewScanCmd(process cmdable, args ...interface{}) *ScanCmd {
+	return &ScanCmd{baseCmd: baseCmd{args: args}, process: process}
+}
+
+func (c *ScanCmd) Val() (keys []string, cursor uint64) {
+	return c.page, c.cursor
+}
+
+func (c *ScanCmd) Result() (keys []string, cursor uint64, err error) {
+	return c.page, c.cursor, c.err
+}
+
+// Iterator 返回从这一批开始、自动跟随游标遍历所有键的迭代器，只能用于 Client 执行的 SCAN
+func (c *ScanCmd) Iterator() *ScanIterator {
+	return &ScanIterator{cmd: c}
+}
+
+func (c *ScanCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+	if len(arr) != 2 {
+		return fmt.Errorf("redis: unexpected SCAN reply with %d elements", len(arr))
+	}
+	cursor, err := valueString(&arr[0])
+	if err != nil {
+		return err
+	}
+	if c.cursor, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+		return err
+	}
+	keys, err := valueArray(&arr[1])
+	if err != nil {
+		return err
+	}
+	c.page = make([]string, len(keys))
+	for i := range keys {
+		if c.page[i], err = valueString(&keys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanIterator 依次返回 SCAN 遍历到的键，需要时用上一次的游标发起下一次 SCAN
+// 与 SCAN 本身一样，遍历期间一直存在的键至少返回一次，但可能重复
+type ScanIterator struct {
+	cmd *ScanCmd
+	pos int
+}
+
+// Next 移动到下一个键，没有更多的键或出错时返回 false
+func (it *ScanIterator) Next(ctx context.Context) bool {
+	for {
+		if it.cmd.err != nil {
+			return false
+		}
+		if it.pos < len(it.cmd.page) {
+			it.pos++
+			return true
+		}
+		if it.cmd.cursor == 0 {
+			return false
+		}
+
+		// 游标是第二个参数，其余参数不变
+		args := append([]interface{}(nil), it.cmd.args...)
+		args[1] = it.cmd.cursor
+		next := NewScanCmd(it.cmd.process, args...)
+		_ = it.cmd.process(ctx, next)
+		it.cmd, it.pos = next, 0
+	}
+}
+
+// Val 返回当前的键
+func (it *ScanIterator) Val() string {
+	if it.pos == 0 {
+		return ""
+	}
+	return it.cmd.page[it.pos-1]
+}
+
+// Err 返回遍历中遇到的错误
+func (it *ScanIterator) Err() error {
+	return it.cmd.err
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"errors"
+	"go-redis/protocol"
+	"time"
+)
+
+// XMessage 是 stream 中的一条消息
+// 消费组历史中已被删除的消息 Values 为 nil
+type XMessage struct {
+	ID     string
+	Values map[string]interface{}
+}
+
+// XStream 是 XREAD/XREADGROUP 从一个 stream 中读到的消息
+type XStream struct {
+	Stream   string
+	Messages []XMessage
+}
+
+func parseXMessage(v *protocol.Value) (XMessage, error) {
+	arr, err := valueArray(v)
+	if err != nil {
+		return XMessage{}, err
+	}
+	if len(arr) != 2 {
+		return XMessage{}, errors.New("redis: unexpected stream entry")
+	}
+
+	msg := XMessage{ID: arr[0].Str}
+	if arr[1].IsNull {
+		return msg, nil
+	}
+
+	fields := arr[1].Array
+	msg.Values = make(map[string]interface{}, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		msg.Values[fields[i].Str] = fields[i+1].Str
+	}
+	return msg, nil
+}
+
+func parseXMessages(v *protocol.Value) ([]XMessage, error) {
+	arr, err := valueArray(v)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]XMessage, len(arr))
+	for i := range arr {
+		if msgs[i], err = parseXMessage(&arr[i]); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+// *****
+type XMessageSliceCmd struct {
+	baseCmd
+	val []XMessage
+}
+
+func NewXMessageSliceCmd(args ...interface{}) *XMessageSliceCmd {
+	return &XMessageSliceCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *XMessageSliceCmd) Val() []XMessage {
+	return c.val
+}
+
+func (c *XMessageSliceCmd) Result() ([]XMessage, error) {
+	return c.val, c.err
+}
+
+func (c *XMessageSliceCmd) readReply(v *protocol.Value) (err error) {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	c.val, err = parseXMessages(v)
+	return err
+}
+
+// *****
+type XStreamSliceCmd struct {
+	baseCmd
+	val []XStream
+}
+
+func NewXStreamSliceCmd(args ...interface{}) *XStreamSliceCmd {
+	return &XStreamSliceCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *XStreamSliceCmd) Val() []XStream {
+	return c.val
+}
+
+func (c *XStreamSliceCmd) Result() ([]XStream, error) {
+	return c.val, c.err
+}
+
+func (c *XStreamSliceCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+
+	c.val = make([]XStream, len(arr))
+	for i := range arr {
+		pair := arr[i].Array
+		if len(pair) != 2 {
+			return errors.New("redis: unexpected XREAD reply")
+		}
+		c.val[i].Stream = pair[0].Str
+		if c.val[i].Messages, err = parseXMessages(&pair[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *****
+// XPending 是 XPENDING key group 的汇总信息
+type XPending struct {
+	Count     int64
+	Lower     string
+	Higher    string
+	Consumers map[string]int64
+}
+
+type XPendingCmd struct {
+	baseCmd
+	val *XPending
+}
+
+func NewXPendingCmd(args ...interface{}) *XPendingCmd {
+	return &XPendingCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *XPendingCmd) Val() *XPending {
+	return c.val
+}
+
+func (c *XPendingCmd) Result() (*XPending, error) {
+	return c.val, c.err
+}
+
+func (c *XPendingCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+	if len(arr) != 4 {
+		return errors.New("redis: unexpected XPENDING reply")
+	}
+
+	p := &XPending{
+		Count:     arr[0].Int,
+		Lower:     arr[1].Str,
+		Higher:    arr[2].Str,
+		Consumers: make(map[string]int64, len(arr[3].Array)),
+	}
+	for _, consumer := range arr[3].Array {
+		if len(consumer.Array) != 2 {
+			return errors.New("redis: unexpected XPENDING consumer")
+		}
+		n, err := valueInt(&consumer.Array[1])
+		if err != nil {
+			return err
+		}
+		p.Consumers[consumer.Array[0].Str] = n
+	}
+	c.val = p
+	return nil
+}
+
+// *****
+// XPendingExt 是 XPENDING 明细中的一条待确认消息
+type XPendingExt struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration
+	RetryCount int64
+}
+
+type XPendingExtCmd struct {
+	baseCmd
+	val []XPendingExt
+}
+
+func NewXPendingExtCmd(args ...interface{}) *XPendingExtCmd {
+	return &XPendingExtCmd{baseCmd: baseCmd{args: args}}
+}
+
+func (c *XPendingExtCmd) Val() []XPendingExt {
+	return c.val
+}
+
+func (c *XPendingExtCmd) Result() ([]XPendingExt, error) {
+	return c.val, c.err
+}
+
+func (c *XPendingExtCmd) readReply(v *protocol.Value) error {
+	if err := replyError(v); err != nil {
+		return err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return err
+	}
+
+	c.val = make([]XPendingExt, len(arr))
+	for i := range arr {
+		fields := arr[i].Array
+		if len(fields) != 4 {
+			return errors.New("redis: unexpected XPENDING entry")
+		}
+		c.val[i] = XPendingExt{
+			ID:         fields[0].Str,
+			Consumer:   fields[1].Str,
+			Idle:       time.Duration(fields[2].Int) * time.Millisecond,
+			RetryCount: fields[3].Int,
+		}
+	}
+	return nil
+}
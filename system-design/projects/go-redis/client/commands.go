@@ -0,0 +1,465 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// cmdable 为 Client 和 Pipeline 提供同一套带类型的命令方法
+// Client 立即执行命令，Pipeline 只把命令加入队列
+type cmdable func(ctx context.Context, cmd Cmder) error
+
+// Do 执行任意命令
+func (c cmdable) Do(ctx context.Context, args ...interface{}) *Cmd {
+	cmd := NewCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Ping(ctx context.Context) *StatusCmd {
+	cmd := NewStatusCmd("ping")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Set(ctx context.Context, key string, value interface{}) *StatusCmd {
+	cmd := NewStatusCmd("set", key, value)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Get 获取键的值，键不存在时返回 Nil 错误
+func (c cmdable) Get(ctx context.Context, key string) *StringCmd {
+	cmd := NewStringCmd("get", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Strlen 返回字符串值的长度，键不存在时为 0
+func (c cmdable) Strlen(ctx context.Context, key string) *IntCmd {
+	cmd := NewIntCmd("strlen", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Del(ctx context.Context, keys ...string) *IntCmd {
+	args := make([]interface{}, 1+len(keys))
+	args[0] = "del"
+	for i, key := range keys {
+		args[1+i] = key
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Exists 判断键是否存在，服务器的 EXISTS 只接受一个键
+func (c cmdable) Exists(ctx context.Context, key string) *BoolCmd {
+	cmd := NewBoolCmd("exists", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Keys(ctx context.Context, pattern string) *StringSliceCmd {
+	cmd := NewStringSliceCmd("keys", pattern)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Incr 把键的值加一
+// 服务器对 INCR/INCRBY 回复 OK 而不是新值，需要新值时再执行 Get
+func (c cmdable) Incr(ctx context.Context, key string) *StatusCmd {
+	cmd := NewStatusCmd("incr", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) IncrBy(ctx context.Context, key string, value int64) *StatusCmd {
+	cmd := NewStatusCmd("incrby", key, value)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Expire(ctx context.Context, key string, expiration time.Duration) *BoolCmd {
+	cmd := NewBoolCmd("expire", key, int64(expiration/time.Second))
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) PExpire(ctx context.Context, key string, expiration time.Duration) *BoolCmd {
+	cmd := NewBoolCmd("pexpire", key, int64(expiration/time.Millisecond))
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) TTL(ctx context.Context, key string) *DurationCmd {
+	cmd := NewDurationCmd(time.Second, "ttl", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) PTTL(ctx context.Context, key string) *DurationCmd {
+	cmd := NewDurationCmd(time.Millisecond, "pttl", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Persist(ctx context.Context, key string) *BoolCmd {
+	cmd := NewBoolCmd("persist", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Type 返回值的类型，键不存在时为 none
+func (c cmdable) Type(ctx context.Context, key string) *StatusCmd {
+	cmd := NewStatusCmd("type", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Rename(ctx context.Context, key, newkey string) *StatusCmd {
+	cmd := NewStatusCmd("rename", key, newkey)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// RenameNX 只在 newkey 不存在时改名
+func (c cmdable) RenameNX(ctx context.Context, key, newkey string) *BoolCmd {
+	cmd := NewBoolCmd("renamenx", key, newkey)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Copy 把 source 的值复制到 destination，服务器只有 0 号数据库，db 只能为 0
+func (c cmdable) Copy(ctx context.Context, source, destination string, db int, replace bool) *IntCmd {
+	args := []interface{}{"copy", source, destination, "db", db}
+	if replace {
+		args = append(args, "replace")
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Dump 返回值的序列化结果，键不存在时返回 Nil 错误
+func (c cmdable) Dump(ctx context.Context, key string) *StringCmd {
+	cmd := NewStringCmd("dump", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Restore 用 Dump 的结果创建键，ttl 为 0 表示不过期
+func (c cmdable) Restore(ctx context.Context, key string, ttl time.Duration, value string) *StatusCmd {
+	cmd := NewStatusCmd("restore", key, int64(ttl/time.Millisecond), value)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// RestoreReplace 与 Restore 相同，但覆盖已经存在的键
+func (c cmdable) RestoreReplace(ctx context.Context, key string, ttl time.Duration, value string) *StatusCmd {
+	cmd := NewStatusCmd("restore", key, int64(ttl/time.Millisecond), value, "replace")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) ObjectEncoding(ctx context.Context, key string) *StringCmd {
+	cmd := NewStringCmd("object", "encoding", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) ObjectIdleTime(ctx context.Context, key string) *DurationCmd {
+	cmd := NewDurationCmd(time.Second, "object", "idletime", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) ObjectFreq(ctx context.Context, key string) *IntCmd {
+	cmd := NewIntCmd("object", "freq", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) ObjectRefCount(ctx context.Context, key string) *IntCmd {
+	cmd := NewIntCmd("object", "refcount", key)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// RandomKey 返回一个随机的键，数据库为空时返回 Nil 错误
+func (c cmdable) RandomKey(ctx context.Context) *StringCmd {
+	cmd := NewStringCmd("randomkey")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Touch(ctx context.Context, keys ...string) *IntCmd {
+	args := make([]interface{}, 1+len(keys))
+	args[0] = "touch"
+	for i, key := range keys {
+		args[1+i] = key
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Unlink 与 Del 相同，但大对象的内存在服务器后台释放
+func (c cmdable) Unlink(ctx context.Context, keys ...string) *IntCmd {
+	args := make([]interface{}, 1+len(keys))
+	args[0] = "unlink"
+	for i, key := range keys {
+		args[1+i] = key
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// Scan 从 cursor 开始遍历一批键，match 为空时不过滤，count 不大于 0 时使用服务器的默认值
+func (c cmdable) Scan(ctx context.Context, cursor uint64, match string, count int64) *ScanCmd {
+	return c.scan(ctx, cursor, match, count, "")
+}
+
+// ScanType 与 Scan 相同，只返回值的类型（TYPE 的结果）为 keyType 的键
+func (c cmdable) ScanType(ctx context.Context, cursor uint64, match string, count int64, keyType string) *ScanCmd {
+	return c.scan(ctx, cursor, match, count, keyType)
+}
+
+func (c cmdable) scan(ctx context.Context, cursor uint64, match string, count int64, keyType string) *ScanCmd {
+	args := []interface{}{"scan", cursor}
+	if match != "" {
+		args = append(args, "match", match)
+	}
+	if count > 0 {
+		args = append(args, "count", count)
+	}
+	if keyType != "" {
+		args = append(args, "type", keyType)
+	}
+	cmd := NewScanCmd(c, args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) DBSize(ctx context.Context) *IntCmd {
+	cmd := NewIntCmd("dbsize")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// *****
+// 服务器管理
+
+func (c cmdable) ConfigGet(ctx context.Context, pattern string) *MapStringStringCmd {
+	cmd := NewMapStringStringCmd("config", "get", pattern)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) ConfigSet(ctx context.Context, parameter, value string) *StatusCmd {
+	cmd := NewStatusCmd("config", "set", parameter, value)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) ConfigResetStat(ctx context.Context) *StatusCmd {
+	cmd := NewStatusCmd("config", "resetstat")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) Info(ctx context.Context, sections ...string) *StringCmd {
+	args := make([]interface{}, 1+len(sections))
+	args[0] = "info"
+	for i, s := range sections {
+		args[1+i] = s
+	}
+	cmd := NewStringCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) SlowLogGet(ctx context.Context, num int64) *SlowLogCmd {
+	cmd := NewSlowLogCmd("slowlog", "get", num)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) SlowLogLen(ctx context.Context) *IntCmd {
+	cmd := NewIntCmd("slowlog", "len")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) SlowLogReset(ctx context.Context) *StatusCmd {
+	cmd := NewStatusCmd("slowlog", "reset")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// ClientID 返回执行该命令的连接的 ID，连接来自连接池，每次调用可能不同
+func (c cmdable) ClientID(ctx context.Context) *IntCmd {
+	cmd := NewIntCmd("client", "id")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// ClientGetName 返回连接的名字，通过 Options.ClientName 设置
+func (c cmdable) ClientGetName(ctx context.Context) *StringCmd {
+	cmd := NewStringCmd("client", "getname")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// ClientSetName 设置执行该命令的连接的名字，连接来自连接池，需要所有连接都带名字时使用 Options.ClientName
+func (c cmdable) ClientSetName(ctx context.Context, name string) *StatusCmd {
+	cmd := NewStatusCmd("client", "setname", name)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// TrackingOptions 是 CLIENT TRACKING ON 的选项
+type TrackingOptions struct {
+	// Redirect 是接收失效消息的连接 ID，通常是订阅了 __redis__:invalidate 的 PubSub（见 PubSub.ID）
+	Redirect int64
+	BCast    bool
+	Prefixes []string // 只在 BCast 模式下使用
+	OptIn    bool
+	OptOut   bool
+	NoLoop   bool
+}
+
+// ClientTracking 开启或关闭执行该命令的连接的客户端缓存跟踪，opt 为 nil 时不带选项
+// 默认模式只跟踪该连接读过的键，连接来自连接池时应使用 BCast 模式，或者把 PoolSize 设为 1
+func (c cmdable) ClientTracking(ctx context.Context, on bool, opt *TrackingOptions) *StatusCmd {
+	args := []interface{}{"client", "tracking", "off"}
+	if on {
+		args[2] = "on"
+	}
+	if on && opt != nil {
+		if opt.Redirect != 0 {
+			args = append(args, "redirect", opt.Redirect)
+		}
+		if opt.BCast {
+			args = append(args, "bcast")
+		}
+		for _, p := range opt.Prefixes {
+			args = append(args, "prefix", p)
+		}
+		if opt.OptIn {
+			args = append(args, "optin")
+		}
+		if opt.OptOut {
+			args = append(args, "optout")
+		}
+		if opt.NoLoop {
+			args = append(args, "noloop")
+		}
+	}
+	cmd := NewStatusCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// MemoryUsage 返回键和值占用的字节数，samples 是嵌套值的采样个数（0 表示全部），键不存在时返回 Nil 错误
+func (c cmdable) MemoryUsage(ctx context.Context, key string, samples ...int) *IntCmd {
+	args := []interface{}{"memory", "usage", key}
+	if len(samples) > 0 {
+		args = append(args, "samples", samples[0])
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// MemoryStats 返回 MEMORY STATS 的回复：字段和值交替的数组，db.0 的值是嵌套的同样格式的数组
+func (c cmdable) MemoryStats(ctx context.Context) *Cmd {
+	cmd := NewCmd("memory", "stats")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) MemoryDoctor(ctx context.Context) *StringCmd {
+	cmd := NewStringCmd("memory", "doctor")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) MemoryPurge(ctx context.Context) *StatusCmd {
+	cmd := NewStatusCmd("memory", "purge")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// *****
+// 发布订阅
+// SUBSCRIBE 会独占连接，不适合放在连接池中执行，订阅使用 Client.Subscribe 返回的 PubSub，这里只提供发布和查询
+
+func (c cmdable) Publish(ctx context.Context, channel string, message interface{}) *IntCmd {
+	cmd := NewIntCmd("publish", channel, message)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) PubSubChannels(ctx context.Context, pattern string) *StringSliceCmd {
+	args := []interface{}{"pubsub", "channels"}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	cmd := NewStringSliceCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) PubSubNumSub(ctx context.Context, channels ...string) *MapStringIntCmd {
+	args := make([]interface{}, 2+len(channels))
+	args[0], args[1] = "pubsub", "numsub"
+	for i, ch := range channels {
+		args[2+i] = ch
+	}
+	cmd := NewMapStringIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) PubSubNumPat(ctx context.Context) *IntCmd {
+	cmd := NewIntCmd("pubsub", "numpat")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// *****
+// HyperLogLog
+
+func (c cmdable) PFAdd(ctx context.Context, key string, els ...interface{}) *IntCmd {
+	args := make([]interface{}, 2+len(els))
+	args[0], args[1] = "pfadd", key
+	copy(args[2:], els)
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// PFCount 返回基数的估计值，多个键时估计它们并集的基数
+func (c cmdable) PFCount(ctx context.Context, keys ...string) *IntCmd {
+	args := make([]interface{}, 1+len(keys))
+	args[0] = "pfcount"
+	for i, key := range keys {
+		args[1+i] = key
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) PFMerge(ctx context.Context, dest string, keys ...string) *StatusCmd {
+	args := make([]interface{}, 2+len(keys))
+	args[0], args[1] = "pfmerge", dest
+	for i, key := range keys {
+		args[2+i] = key
+	}
+	cmd := NewStatusCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
@@ -0,0 +1,101 @@
+package client
+
+import "context"
+
+// BitCount 是 BITCOUNT 和 BITPOS 的范围，Unit 为 "byte"（默认）或 "bit"
+type BitCount struct {
+	Start, End int64
+	Unit       string
+}
+
+func (c cmdable) SetBit(ctx context.Context, key string, offset int64, value int) *IntCmd {
+	cmd := NewIntCmd("setbit", key, offset, value)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) GetBit(ctx context.Context, key string, offset int64) *IntCmd {
+	cmd := NewIntCmd("getbit", key, offset)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// BitCount 统计值为 1 的位数，bitCount 为 nil 时统计整个字符串
+func (c cmdable) BitCount(ctx context.Context, key string, bitCount *BitCount) *IntCmd {
+	args := []interface{}{"bitcount", key}
+	if bitCount != nil {
+		args = append(args, bitCount.Start, bitCount.End)
+		if bitCount.Unit != "" {
+			args = append(args, bitCount.Unit)
+		}
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// BitPos 返回第一个值为 bit 的位置，pos 依次是起始和结束字节（最多两个），找不到时为 -1
+func (c cmdable) BitPos(ctx context.Context, key string, bit int64, pos ...int64) *IntCmd {
+	args := []interface{}{"bitpos", key, bit}
+	for _, p := range pos {
+		args = append(args, p)
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// BitPosSpan 与 BitPos 相同，span 为 "byte" 或 "bit"，指定 start 和 end 的单位
+func (c cmdable) BitPosSpan(ctx context.Context, key string, bit int8, start, end int64, span string) *IntCmd {
+	cmd := NewIntCmd("bitpos", key, bit, start, end, span)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) bitOp(ctx context.Context, op, destKey string, keys ...string) *IntCmd {
+	args := make([]interface{}, 3+len(keys))
+	args[0], args[1], args[2] = "bitop", op, destKey
+	for i, key := range keys {
+		args[3+i] = key
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) BitOpAnd(ctx context.Context, destKey string, keys ...string) *IntCmd {
+	return c.bitOp(ctx, "and", destKey, keys...)
+}
+
+func (c cmdable) BitOpOr(ctx context.Context, destKey string, keys ...string) *IntCmd {
+	return c.bitOp(ctx, "or", destKey, keys...)
+}
+
+func (c cmdable) BitOpXor(ctx context.Context, destKey string, keys ...string) *IntCmd {
+	return c.bitOp(ctx, "xor", destKey, keys...)
+}
+
+func (c cmdable) BitOpNot(ctx context.Context, destKey string, key string) *IntCmd {
+	return c.bitOp(ctx, "not", destKey, key)
+}
+
+// BitField 依次执行 GET/SET/INCRBY/OVERFLOW 子命令，values 是子命令及其参数，例如
+// BitField(ctx, "key", "incrby", "u8", 0, 10, "get", "u4", 0)
+func (c cmdable) BitField(ctx context.Context, key string, values ...interface{}) *IntSliceCmd {
+	args := make([]interface{}, 2+len(values))
+	args[0], args[1] = "bitfield", key
+	copy(args[2:], values)
+	cmd := NewIntSliceCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// BitFieldRO 是只读的 BitField，只接受 GET 子命令，例如 BitFieldRO(ctx, "key", "get", "u8", 0)
+func (c cmdable) BitFieldRO(ctx context.Context, key string, values ...interface{}) *IntSliceCmd {
+	args := make([]interface{}, 2+len(values))
+	args[0], args[1] = "bitfield_ro", key
+	copy(args[2:], values)
+	cmd := NewIntSliceCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
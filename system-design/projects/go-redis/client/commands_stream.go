@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// XAddArgs 是 XADD 的参数
+// MaxLen 和 MinID 二选一用于裁剪，Approx 对应 "~"，Limit 只在 Approx 时有效
+// Values 可以是 map[string]interface{}、[]string 或 []interface{}（field value 交替）
+type XAddArgs struct {
+	Stream     string
+	NoMkStream bool
+	MaxLen     int64
+	MinID      string
+	Approx     bool
+	Limit      int64
+	ID         string
+	Values     interface{}
+}
+
+func (c cmdable) XAdd(ctx context.Context, a *XAddArgs) *StringCmd {
+	args := []interface{}{"xadd", a.Stream}
+	if a.NoMkStream {
+		args = append(args, "nomkstream")
+	}
+	args = appendTrimArgs(args, a.MaxLen, a.MinID, a.Approx, a.Limit)
+
+	id := a.ID
+	if id == "" {
+		id = "*"
+	}
+	args = append(args, id)
+	args = appendValues(args, a.Values)
+
+	cmd := NewStringCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func appendTrimArgs(args []interface{}, maxLen int64, minID string, approx bool, limit int64) []interface{} {
+	switch {
+	case maxLen > 0:
+		args = append(args, "maxlen")
+	case minID != "":
+		args = append(args, "minid")
+	default:
+		return args
+	}
+
+	if approx {
+		args = append(args, "~")
+	}
+	if maxLen > 0 {
+		args = append(args, maxLen)
+	} else {
+		args = append(args, minID)
+	}
+	if approx && limit > 0 {
+		args = append(args, "limit", limit)
+	}
+	return args
+}
+
+func appendValues(args []interface{}, values interface{}) []interface{} {
+	switch v := values.(type) {
+	case map[string]interface{}:
+		for field, value := range v {
+			args = append(args, field, value)
+		}
+	case map[string]string:
+		for field, value := range v {
+			args = append(args, field, value)
+		}
+	case []string:
+		for _, s := range v {
+			args = append(args, s)
+		}
+	case []interface{}:
+		args = append(args, v...)
+	}
+	return args
+}
+
+func (c cmdable) XLen(ctx context.Context, stream string) *IntCmd {
+	cmd := NewIntCmd("xlen", stream)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XRange(ctx context.Context, stream, start, stop string) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrange", stream, start, stop)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XRangeN(ctx context.Context, stream, start, stop string, count int64) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrange", stream, start, stop, "count", count)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XRevRange(ctx context.Context, stream, start, stop string) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrevrange", stream, start, stop)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XRevRangeN(ctx context.Context, stream, start, stop string, count int64) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrevrange", stream, start, stop, "count", count)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XDel(ctx context.Context, stream string, ids ...string) *IntCmd {
+	args := []interface{}{"xdel", stream}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XTrimMaxLen(ctx context.Context, key string, maxLen int64) *IntCmd {
+	return c.xTrim(ctx, key, maxLen, "", false, 0)
+}
+
+func (c cmdable) XTrimMaxLenApprox(ctx context.Context, key string, maxLen, limit int64) *IntCmd {
+	return c.xTrim(ctx, key, maxLen, "", true, limit)
+}
+
+func (c cmdable) XTrimMinID(ctx context.Context, key string, minID string) *IntCmd {
+	return c.xTrim(ctx, key, 0, minID, false, 0)
+}
+
+func (c cmdable) XTrimMinIDApprox(ctx context.Context, key string, minID string, limit int64) *IntCmd {
+	return c.xTrim(ctx, key, 0, minID, true, limit)
+}
+
+func (c cmdable) xTrim(ctx context.Context, key string, maxLen int64, minID string, approx bool, limit int64) *IntCmd {
+	args := appendTrimArgs([]interface{}{"xtrim", key}, maxLen, minID, approx, limit)
+	if maxLen == 0 && minID == "" {
+		// MAXLEN 0 表示清空，appendTrimArgs 会把它当作不裁剪
+		args = append(args, "maxlen", 0)
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// XReadArgs 是 XREAD 的参数
+// Streams 先列出所有键，再列出对应的 ID；与 go-redis 相同，Block 为 0 时一直阻塞，小于 0 时不阻塞
+type XReadArgs struct {
+	Streams []string
+	Count   int64
+	Block   time.Duration
+}
+
+// XRead 读取消息，没有消息（阻塞超时）时返回 Nil 错误
+func (c cmdable) XRead(ctx context.Context, a *XReadArgs) *XStreamSliceCmd {
+	args := []interface{}{"xread"}
+	if a.Count > 0 {
+		args = append(args, "count", a.Count)
+	}
+	if a.Block >= 0 {
+		args = append(args, "block", int64(a.Block/time.Millisecond))
+	}
+	args = append(args, "streams")
+	for _, s := range a.Streams {
+		args = append(args, s)
+	}
+
+	cmd := NewXStreamSliceCmd(args...)
+	if a.Block >= 0 {
+		cmd.setReadTimeout(a.Block)
+	}
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// XReadStreams 以不阻塞的方式读取消息
+func (c cmdable) XReadStreams(ctx context.Context, streams ...string) *XStreamSliceCmd {
+	return c.XRead(ctx, &XReadArgs{Streams: streams, Block: -1})
+}
+
+func (c cmdable) XGroupCreate(ctx context.Context, stream, group, start string) *StatusCmd {
+	cmd := NewStatusCmd("xgroup", "create", stream, group, start)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XGroupCreateMkStream(ctx context.Context, stream, group, start string) *StatusCmd {
+	cmd := NewStatusCmd("xgroup", "create", stream, group, start, "mkstream")
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XGroupSetID(ctx context.Context, stream, group, start string) *StatusCmd {
+	cmd := NewStatusCmd("xgroup", "setid", stream, group, start)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XGroupDestroy(ctx context.Context, stream, group string) *IntCmd {
+	cmd := NewIntCmd("xgroup", "destroy", stream, group)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XGroupCreateConsumer(ctx context.Context, stream, group, consumer string) *IntCmd {
+	cmd := NewIntCmd("xgroup", "createconsumer", stream, group, consumer)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XGroupDelConsumer(ctx context.Context, stream, group, consumer string) *IntCmd {
+	cmd := NewIntCmd("xgroup", "delconsumer", stream, group, consumer)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// XReadGroupArgs 是 XREADGROUP 的参数，Block 的含义与 XReadArgs 相同
+type XReadGroupArgs struct {
+	Group    string
+	Consumer string
+	Streams  []string
+	Count    int64
+	Block    time.Duration
+	NoAck    bool
+}
+
+func (c cmdable) XReadGroup(ctx context.Context, a *XReadGroupArgs) *XStreamSliceCmd {
+	args := []interface{}{"xreadgroup", "group", a.Group, a.Consumer}
+	if a.Count > 0 {
+		args = append(args, "count", a.Count)
+	}
+	if a.Block >= 0 {
+		args = append(args, "block", int64(a.Block/time.Millisecond))
+	}
+	if a.NoAck {
+		args = append(args, "noack")
+	}
+	args = append(args, "streams")
+	for _, s := range a.Streams {
+		args = append(args, s)
+	}
+
+	cmd := NewXStreamSliceCmd(args...)
+	if a.Block >= 0 {
+		cmd.setReadTimeout(a.Block)
+	}
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XAck(ctx context.Context, stream, group string, ids ...string) *IntCmd {
+	args := []interface{}{"xack", stream, group}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	cmd := NewIntCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XPending(ctx context.Context, stream, group string) *XPendingCmd {
+	cmd := NewXPendingCmd("xpending", stream, group)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// XPendingExtArgs 是 XPENDING 明细查询的参数
+type XPendingExtArgs struct {
+	Stream   string
+	Group    string
+	Idle     time.Duration
+	Start    string
+	End      string
+	Count    int64
+	Consumer string
+}
+
+func (c cmdable) XPendingExt(ctx context.Context, a *XPendingExtArgs) *XPendingExtCmd {
+	args := []interface{}{"xpending", a.Stream, a.Group}
+	if a.Idle > 0 {
+		args = append(args, "idle", int64(a.Idle/time.Millisecond))
+	}
+	args = append(args, a.Start, a.End, a.Count)
+	if a.Consumer != "" {
+		args = append(args, a.Consumer)
+	}
+	cmd := NewXPendingExtCmd(args...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+// XClaimArgs 是 XCLAIM 的参数
+type XClaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+	MinIdle  time.Duration
+	Messages []string
+}
+
+func xClaimArgs(a *XClaimArgs) []interface{} {
+	args := []interface{}{"xclaim", a.Stream, a.Group, a.Consumer, int64(a.MinIdle / time.Millisecond)}
+	for _, id := range a.Messages {
+		args = append(args, id)
+	}
+	return args
+}
+
+func (c cmdable) XClaim(ctx context.Context, a *XClaimArgs) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd(xClaimArgs(a)...)
+	_ = c(ctx, cmd)
+	return cmd
+}
+
+func (c cmdable) XClaimJustID(ctx context.Context, a *XClaimArgs) *StringSliceCmd {
+	cmd := NewStringSliceCmd(append(xClaimArgs(a), "justid")...)
+	_ = c(ctx, cmd)
+	return cmd
+}
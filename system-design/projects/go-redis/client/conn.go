@@ -0,0 +1,124 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go-redis/protocol"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Conn 是连接池中的一个连接
+type Conn struct {
+	netConn net.Conn
+	parser  *protocol.Parser
+	wr      *protocol.Writer
+
+	createdAt time.Time
+	usedAt    time.Time
+}
+
+func newConn(netConn net.Conn) *Conn {
+	now := time.Now()
+	return &Conn{
+		netConn:   netConn,
+		parser:    protocol.NewParser(bufio.NewReader(netConn)),
+		wr:        protocol.NewWriter(netConn),
+		createdAt: now,
+		usedAt:    now,
+	}
+}
+
+// deadline 返回读写的截止时间：timeout 和 context 截止时间中较早的一个，都没有时返回零值
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	var t time.Time
+	if timeout > 0 {
+		t = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (t.IsZero() || d.Before(t)) {
+		t = d
+	}
+	return t
+}
+
+// writeCommands 把一组命令写到连接上，一次 Flush 发出（流水线）
+func (cn *Conn) writeCommands(ctx context.Context, timeout time.Duration, cmds []Cmder) error {
+	if err := cn.netConn.SetWriteDeadline(deadline(ctx, timeout)); err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		if err := cn.wr.WriteValue(commandValue(cmd.Args())); err != nil {
+			return err
+		}
+	}
+
+	cn.usedAt = time.Now()
+	return cn.wr.Flush()
+}
+
+// readReply 读取一条回复
+func (cn *Conn) readReply(ctx context.Context, timeout time.Duration) (*protocol.Value, error) {
+	if err := cn.netConn.SetReadDeadline(deadline(ctx, timeout)); err != nil {
+		return nil, err
+	}
+
+	v, err := cn.parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	cn.usedAt = time.Now()
+	return v, nil
+}
+
+// Close 关闭底层网络连接
+func (cn *Conn) Close() error {
+	return cn.netConn.Close()
+}
+
+// commandValue 把命令参数转换为 RESP 数组
+func commandValue(args []interface{}) *protocol.Value {
+	values := make([]protocol.Value, len(args))
+	for i, arg := range args {
+		values[i] = *protocol.BulkString(argString(arg))
+	}
+	return protocol.Array(values)
+}
+
+// argString 把参数转换为字符串，与服务器端的解析方式对应
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Duration:
+		return strconv.FormatInt(v.Milliseconds(), 10)
+	case nil:
+		return ""
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+)
+
+// Nil 表示服务器返回了空回复（如 GET 不存在的键）
+const Nil = RedisError("redis: nil")
+
+var (
+	// ErrClosed 表示客户端已经关闭
+	ErrClosed = errors.New("redis: client is closed")
+	// ErrPoolTimeout 表示在 PoolTimeout 内没有拿到连接
+	ErrPoolTimeout = errors.New("redis: connection pool timeout")
+)
+
+// RedisError 是服务器返回的错误回复，如 "WRONGTYPE Operation against a key ..."
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// Prefix 返回错误的类型前缀，如 "ERR"、"WRONGTYPE"
+func (e RedisError) Prefix() string {
+	s := string(e)
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// isRedisError 判断 err 是否为服务器返回的错误，这类错误不影响连接的复用
+func isRedisError(err error) bool {
+	var re RedisError
+	return errors.As(err, &re)
+}
+
+// isBadConn 判断出错后连接能否放回连接池
+// 网络错误或协议错误之后连接上可能残留未读的数据，只能关闭
+func isBadConn(err error) bool {
+	return err != nil && !isRedisError(err)
+}
+
+// shouldRetry 判断命令失败后是否应该重试
+// 只重试连接层面的错误；超时和 context 取消不重试，因为命令可能已经执行
+func shouldRetry(err error) bool {
+	if err == nil || isRedisError(err) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrPoolTimeout) || errors.Is(err, ErrClosed) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return !netErr.Timeout()
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Options 是客户端的配置，零值字段使用默认值
+type Options struct {
+	// Addr 是服务器地址，默认 "localhost:16379"
+	Addr string
+	// Dialer 创建网络连接，默认使用 net.Dialer
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+	// ClientName 非空时，每个新连接都会执行 CLIENT SETNAME
+	ClientName string
+	// DB 是连接使用的数据库编号，不为 0 时每个新连接都会执行 SELECT
+	// 选择数据库是连接的状态，连接池中的连接必须一致，因此没有单独的 Select 方法
+	DB int
+
+	// DialTimeout 是建立连接的超时，默认 5 秒
+	DialTimeout time.Duration
+	// ReadTimeout 和 WriteTimeout 是单条命令读写的超时，默认 3 秒，-1 表示不设超时
+	// context 的截止时间更早时以 context 为准
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// MaxRetries 是网络错误时的最大重试次数，默认 3，-1 表示不重试
+	// 服务器返回的错误（如 WRONGTYPE）不会重试
+	MaxRetries int
+	// MinRetryBackoff 和 MaxRetryBackoff 是重试间隔的范围，默认 8ms 和 512ms
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+
+	// PoolSize 是连接池的最大连接数，默认 10
+	PoolSize int
+	// PoolTimeout 是连接全部被占用时等待空闲连接的时间，默认 ReadTimeout + 1 秒
+	PoolTimeout time.Duration
+	// ConnMaxIdleTime 是连接的最长空闲时间，超过后关闭，默认 30 分钟，-1 表示不限制
+	ConnMaxIdleTime time.Duration
+	// HealthCheckInterval 是空闲连接在复用前需要 PING 检查的空闲时长，默认 1 分钟，-1 表示不检查
+	HealthCheckInterval time.Duration
+}
+
+func (opt *Options) init() {
+	if opt.Addr == "" {
+		opt.Addr = "localhost:16379"
+	}
+	if opt.DialTimeout == 0 {
+		opt.DialTimeout = 5 * time.Second
+	}
+	if opt.Dialer == nil {
+		timeout := opt.DialTimeout
+		opt.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			d := &net.Dialer{Timeout: timeout, KeepAlive: 5 * time.Minute}
+			return d.DialContext(ctx, network, addr)
+		}
+	}
+
+	switch opt.ReadTimeout {
+	case -1:
+		opt.ReadTimeout = 0
+	case 0:
+		opt.ReadTimeout = 3 * time.Second
+	}
+	switch opt.WriteTimeout {
+	case -1:
+		opt.WriteTimeout = 0
+	case 0:
+		opt.WriteTimeout = opt.ReadTimeout
+	}
+
+	switch opt.MaxRetries {
+	case -1:
+		opt.MaxRetries = 0
+	case 0:
+		opt.MaxRetries = 3
+	}
+	if opt.MinRetryBackoff == 0 {
+		opt.MinRetryBackoff = 8 * time.Millisecond
+	}
+	if opt.MaxRetryBackoff == 0 {
+		opt.MaxRetryBackoff = 512 * time.Millisecond
+	}
+
+	if opt.PoolSize <= 0 {
+		opt.PoolSize = 10
+	}
+	if opt.PoolTimeout == 0 {
+		opt.PoolTimeout = opt.ReadTimeout + time.Second
+	}
+	switch opt.ConnMaxIdleTime {
+	case -1:
+		opt.ConnMaxIdleTime = 0
+	case 0:
+		opt.ConnMaxIdleTime = 30 * time.Minute
+	}
+	switch opt.HealthCheckInterval {
+	case -1:
+		opt.HealthCheckInterval = 0
+	case 0:
+		opt.HealthCheckInterval = time.Minute
+	}
+}
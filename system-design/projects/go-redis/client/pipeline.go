@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-redis/protocol"
+)
+
+// Pipeline 把多条命令一次性发给服务器，再依次读取回复，减少网络往返
+// 命令方法只把命令加入队列，调用 Exec 后才真正执行；Pipeline 不能被多个 goroutine 同时使用
+type Pipeline struct {
+	cmdable
+	client *Client
+	tx     bool // 用 MULTI/EXEC 包裹，作为事务执行
+	cmds   []Cmder
+}
+
+// Pipeline 创建流水线
+func (c *Client) Pipeline() *Pipeline {
+	p := &Pipeline{client: c}
+	p.cmdable = p.queue
+	return p
+}
+
+// TxPipeline 创建事务流水线，命令在服务器端用 MULTI/EXEC 原子地执行
+func (c *Client) TxPipeline() *Pipeline {
+	p := c.Pipeline()
+	p.tx = true
+	return p
+}
+
+// Pipelined 在 fn 中向流水线添加命令并执行
+func (c *Client) Pipelined(ctx context.Context, fn func(p *Pipeline) error) ([]Cmder, error) {
+	return c.Pipeline().pipelined(ctx, fn)
+}
+
+// TxPipelined 在 fn 中向事务流水线添加命令并执行
+func (c *Client) TxPipelined(ctx context.Context, fn func(p *Pipeline) error) ([]Cmder, error) {
+	return c.TxPipeline().pipelined(ctx, fn)
+}
+
+func (p *Pipeline) pipelined(ctx context.Context, fn func(p *Pipeline) error) ([]Cmder, error) {
+	if err := fn(p); err != nil {
+		return nil, err
+	}
+	return p.Exec(ctx)
+}
+
+func (p *Pipeline) queue(ctx context.Context, cmd Cmder) error {
+	p.cmds = append(p.cmds, cmd)
+	return nil
+}
+
+// Len 返回队列中的命令数
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Discard 清空队列
+func (p *Pipeline) Discard() {
+	p.cmds = nil
+}
+
+// Exec 执行队列中的所有命令并清空队列
+// 返回全部命令，以及第一条失败命令的错误；每条命令的结果和错误记录在各自的 Cmder 上
+func (p *Pipeline) Exec(ctx context.Context) ([]Cmder, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	exec := p.execPipeline
+	if p.tx {
+		exec = p.execTx
+	}
+
+	err := p.client.withRetry(ctx, func(cn *Conn) error {
+		// 重试时清掉上一次尝试留下的结果
+		setCmdsErr(cmds, nil)
+		return exec(ctx, cn, cmds)
+	})
+	if err != nil && !isRedisError(err) {
+		// 连接层面的错误，所有命令都没有可靠的结果
+		setCmdsErr(cmds, err)
+		return cmds, err
+	}
+
+	return cmds, firstCmdErr(cmds)
+}
+
+// execPipeline 写出所有命令后依次读取回复
+// 返回的错误只表示连接层面的问题，命令自身的错误记录在 cmd 上
+func (p *Pipeline) execPipeline(ctx context.Context, cn *Conn, cmds []Cmder) error {
+	if err := cn.writeCommands(ctx, p.client.opt.WriteTimeout, cmds); err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		v, err := cn.readReply(ctx, p.client.cmdTimeout(cmd))
+		if err != nil {
+			return err
+		}
+		cmd.SetErr(cmd.readReply(v))
+	}
+	return nil
+}
+
+// execTx 用 MULTI/EXEC 包裹命令：先读取 MULTI 和每条命令的 QUEUED，再从 EXEC 的数组回复中取出结果
+func (p *Pipeline) execTx(ctx context.Context, cn *Conn, cmds []Cmder) error {
+	all := make([]Cmder, 0, len(cmds)+2)
+	all = append(all, NewStatusCmd("multi"))
+	all = append(all, cmds...)
+	all = append(all, NewCmd("exec"))
+
+	if err := cn.writeCommands(ctx, p.client.opt.WriteTimeout, all); err != nil {
+		return err
+	}
+
+	// MULTI 的 OK 和每条命令的 QUEUED，排队失败的命令会直接得到错误
+	for i := 0; i < len(cmds)+1; i++ {
+		v, err := cn.readReply(ctx, p.client.opt.ReadTimeout)
+		if err != nil {
+			return err
+		}
+		if v.Type == protocol.ErrorType && i > 0 {
+			cmds[i-1].SetErr(RedisError(v.Str))
+		}
+	}
+
+	v, err := cn.readReply(ctx, p.client.opt.ReadTimeout)
+	if err != nil {
+		return err
+	}
+	if v.Type == protocol.ErrorType {
+		// EXECABORT：排队时出现错误，整个事务被放弃
+		txErr := RedisError(v.Str)
+		for _, cmd := range cmds {
+			if cmd.Err() == nil {
+				cmd.SetErr(txErr)
+			}
+		}
+		return nil
+	}
+	if v.Type != protocol.ArrayType || len(v.Array) != len(cmds) {
+		return fmt.Errorf("redis: unexpected EXEC reply with %d elements, expected %d", len(v.Array), len(cmds))
+	}
+
+	for i, cmd := range cmds {
+		cmd.SetErr(cmd.readReply(&v.Array[i]))
+	}
+	return nil
+}
+
+func setCmdsErr(cmds []Cmder, err error) {
+	for _, cmd := range cmds {
+		cmd.SetErr(err)
+	}
+}
+
+// firstCmdErr 返回第一条失败命令的错误，Nil 不算失败
+func firstCmdErr(cmds []Cmder) error {
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && !errors.Is(err, Nil) {
+			return err
+		}
+	}
+	return nil
+}
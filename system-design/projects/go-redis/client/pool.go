@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats 是连接池的统计信息
+type PoolStats struct {
+	Hits     uint64 // 复用了空闲连接的次数
+	Misses   uint64 // 需要新建连接的次数
+	Timeouts uint64 // 等待连接超时的次数
+	Stale    uint64 // 因空闲过久或健康检查失败被关闭的连接数
+
+	TotalConns uint32 // 当前连接总数
+	IdleConns  uint32 // 当前空闲连接数
+}
+
+// Pool 是固定上限的连接池
+// queue 是容量为 PoolSize 的令牌桶，持有令牌才能使用连接，保证同时使用的连接数不超过上限
+type Pool struct {
+	opt *Options
+
+	queue chan struct{}
+
+	mu     sync.Mutex
+	idle   []*Conn
+	total  int
+	closed bool
+
+	hits, misses, timeouts, stale atomic.Uint64
+}
+
+func newPool(opt *Options) *Pool {
+	return &Pool{
+		opt:   opt,
+		queue: make(chan struct{}, opt.PoolSize),
+	}
+}
+
+// Get 取出一个可用的连接，用完后必须调用 Put 或 Remove
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	if p.isClosed() {
+		return nil, ErrClosed
+	}
+
+	if err := p.waitTurn(ctx); err != nil {
+		return nil, err
+	}
+
+	for {
+		cn := p.popIdle()
+		if cn == nil {
+			break
+		}
+		if !p.isHealthy(ctx, cn) {
+			p.stale.Add(1)
+			p.closeConn(cn)
+			continue
+		}
+		p.hits.Add(1)
+		return cn, nil
+	}
+
+	p.misses.Add(1)
+	cn, err := p.dial(ctx)
+	if err != nil {
+		<-p.queue
+		return nil, err
+	}
+	return cn, nil
+}
+
+// waitTurn 获取令牌，连接全部被占用时最多等待 PoolTimeout
+func (p *Pool) waitTurn(ctx context.Context) error {
+	select {
+	case p.queue <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(p.opt.PoolTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.queue <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		p.timeouts.Add(1)
+		return ErrPoolTimeout
+	}
+}
+
+func (p *Pool) popIdle() *Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.idle)
+	if n == 0 {
+		return nil
+	}
+	// 后进先出，让不常用的连接自然空闲过期
+	cn := p.idle[n-1]
+	p.idle = p.idle[:n-1]
+	return cn
+}
+
+// isHealthy 检查空闲连接能否复用：空闲过久的直接关闭，空闲较久的先 PING 一次
+func (p *Pool) isHealthy(ctx context.Context, cn *Conn) bool {
+	idle := time.Since(cn.usedAt)
+	if p.opt.ConnMaxIdleTime > 0 && idle >= p.opt.ConnMaxIdleTime {
+		return false
+	}
+	if p.opt.HealthCheckInterval > 0 && idle >= p.opt.HealthCheckInterval {
+		return p.do(ctx, cn, NewStatusCmd("ping")) == nil
+	}
+	return true
+}
+
+// do 在连接上执行一条命令，用于健康检查和连接初始化
+func (p *Pool) do(ctx context.Context, cn *Conn, cmd Cmder) error {
+	if err := cn.writeCommands(ctx, p.opt.WriteTimeout, []Cmder{cmd}); err != nil {
+		return err
+	}
+	v, err := cn.readReply(ctx, p.opt.ReadTimeout)
+	if err != nil {
+		return err
+	}
+	return cmd.readReply(v)
+}
+
+// dial 新建连接，并执行连接初始化命令
+func (p *Pool) dial(ctx context.Context) (*Conn, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, p.opt.DialTimeout)
+	defer cancel()
+
+	netConn, err := p.opt.Dialer(dialCtx, "tcp", p.opt.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := newConn(netConn)
+
+	if p.opt.ClientName != "" {
+		if err := p.do(ctx, cn, NewStatusCmd("client", "setname", p.opt.ClientName)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if p.opt.DB != 0 {
+		if err := p.do(ctx, cn, NewStatusCmd("select", p.opt.DB)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	p.total++
+	p.mu.Unlock()
+
+	return cn, nil
+}
+
+// Put 把连接放回连接池
+func (p *Pool) Put(cn *Conn) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.closeConn(cn)
+		<-p.queue
+		return
+	}
+	p.idle = append(p.idle, cn)
+	p.mu.Unlock()
+
+	<-p.queue
+}
+
+// Remove 关闭出错的连接，不再放回连接池
+func (p *Pool) Remove(cn *Conn) {
+	p.closeConn(cn)
+	<-p.queue
+}
+
+func (p *Pool) closeConn(cn *Conn) {
+	p.mu.Lock()
+	p.total--
+	p.mu.Unlock()
+	cn.Close()
+}
+
+func (p *Pool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Stats 返回连接池的统计信息
+func (p *Pool) Stats() *PoolStats {
+	p.mu.Lock()
+	total, idle := p.total, len(p.idle)
+	p.mu.Unlock()
+
+	return &PoolStats{
+		Hits:       p.hits.Load(),
+		Misses:     p.misses.Load(),
+		Timeouts:   p.timeouts.Load(),
+		Stale:      p.stale.Load(),
+		TotalConns: uint32(total),
+		IdleConns:  uint32(idle),
+	}
+}
+
+// Close 关闭连接池和所有空闲连接，正在使用的连接在放回时关闭
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, cn := range idle {
+		p.closeConn(cn)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"go-redis/protocol"
+	"strings"
+	"sync"
+)
+
+// 发布订阅
+//
+// 订阅后连接只能执行订阅命令和 PING，因此 PubSub 不使用连接池，而是独占一个连接。
+// Subscribe 等方法只发送命令，服务器的确认和消息都由 Receive 按到达顺序返回；
+// 连接断开后下一次操作会重新连接并恢复之前的订阅。
+
+// Subscription 是 (P)SUBSCRIBE / (P)UNSUBSCRIBE 的确认
+type Subscription struct {
+	Kind    string // subscribe、unsubscribe、psubscribe 或 punsubscribe
+	Channel string // 频道或模式
+	Count   int    // 连接当前订阅的频道和模式总数
+}
+
+func (s *Subscription) String() string {
+	return fmt.Sprintf("%s: %s", s.Kind, s.Channel)
+}
+
+// Message 是收到的消息，通过模式订阅收到时 Pattern 非空
+// CLIENT TRACKING 的失效消息的内容是键的数组，保存在 PayloadSlice 中
+type Message struct {
+	Channel      string
+	Pattern      string
+	Payload      string
+	PayloadSlice []string
+}
+
+func (m *Message) String() string {
+	return fmt.Sprintf("Message<%s: %s>", m.Channel, m.Payload)
+}
+
+// Pong 是订阅模式下 PING 的回复
+type Pong struct {
+	Payload string
+}
+
+// PubSub 是独占一个连接的订阅，可以在一个 goroutine 中 Receive 的同时在其他 goroutine 中修改订阅
+type PubSub struct {
+	opt *Options
+
+	mu       sync.Mutex
+	cn       *Conn
+	id       int64
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	closed   bool
+}
+
+// Subscribe 创建 PubSub 并订阅频道，确认通过 Receive 返回
+func (c *Client) Subscribe(ctx context.Context, channels ...string) *PubSub {
+	ps := c.newPubSub()
+	if len(channels) > 0 {
+		_ = ps.Subscribe(ctx, channels...)
+	}
+	return ps
+}
+
+// PSubscribe 创建 PubSub 并订阅模式，确认通过 Receive 返回
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) *PubSub {
+	ps := c.newPubSub()
+	if len(patterns) > 0 {
+		_ = ps.PSubscribe(ctx, patterns...)
+	}
+	return ps
+}
+
+func (c *Client) newPubSub() *PubSub {
+	return &PubSub{
+		opt:      c.opt,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+}
+
+// conn 返回当前连接，没有时新建连接，执行 CLIENT ID 并恢复订阅
+func (ps *PubSub) conn(ctx context.Context) (*Conn, error) {
+	if ps.closed {
+		return nil, ErrClosed
+	}
+	if ps.cn != nil {
+		return ps.cn, nil
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, ps.opt.DialTimeout)
+	defer cancel()
+	netConn, err := ps.opt.Dialer(dialCtx, "tcp", ps.opt.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := newConn(netConn)
+
+	// CLIENT ID 必须在订阅之前执行，订阅之后连接不再接受普通命令
+	cmds := []Cmder{NewIntCmd("client", "id")}
+	if ps.opt.ClientName != "" {
+		cmds = append(cmds, NewStatusCmd("client", "setname", ps.opt.ClientName))
+	}
+	if err := ps.initConn(ctx, cn, cmds); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	ps.id = cmds[0].(*IntCmd).Val()
+
+	var resub []Cmder
+	if len(ps.channels) > 0 {
+		resub = append(resub, subscribeCmd("subscribe", keysOf(ps.channels)))
+	}
+	if len(ps.patterns) > 0 {
+		resub = append(resub, subscribeCmd("psubscribe", keysOf(ps.patterns)))
+	}
+	if len(resub) > 0 {
+		if err := cn.writeCommands(ctx, ps.opt.WriteTimeout, resub); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	ps.cn = cn
+	return cn, nil
+}
+
+func (ps *PubSub) initConn(ctx context.Context, cn *Conn, cmds []Cmder) error {
+	if err := cn.writeCommands(ctx, ps.opt.WriteTimeout, cmds); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		v, err := cn.readReply(ctx, ps.opt.ReadTimeout)
+		if err != nil {
+			return err
+		}
+		if err := cmd.readReply(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseConn 在网络或协议错误后关闭连接，下一次操作时重新连接
+func (ps *PubSub) releaseConn(cn *Conn, err error) {
+	if !isBadConn(err) {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.cn == cn {
+		ps.cn.Close()
+		ps.cn = nil
+	}
+}
+
+func subscribeCmd(name string, names []string) Cmder {
+	args := make([]interface{}, 1+len(names))
+	args[0] = name
+	for i, n := range names {
+		args[1+i] = n
+	}
+	return NewCmd(args...)
+}
+
+func keysOf(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// write 发送一条命令，回复由 Receive 读取
+// update 在取得连接之后修改订阅记录，新建连接时恢复的只是之前的订阅，避免同一个频道订阅两次
+func (ps *PubSub) write(ctx context.Context, cmd Cmder, update func()) error {
+	ps.mu.Lock()
+	cn, err := ps.conn(ctx)
+	if update != nil {
+		update()
+	}
+	if err == nil {
+		err = cn.writeCommands(ctx, ps.opt.WriteTimeout, []Cmder{cmd})
+	}
+	ps.mu.Unlock()
+
+	if cn != nil {
+		ps.releaseConn(cn, err)
+	}
+	return err
+}
+
+// ID 返回订阅连接的 ID，可以作为 CLIENT TRACKING 的 REDIRECT 目标
+// 还没有连接时先建立连接；重新连接后 ID 会改变
+func (ps *PubSub) ID(ctx context.Context) (int64, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, err := ps.conn(ctx); err != nil {
+		return 0, err
+	}
+	return ps.id, nil
+}
+
+func (ps *PubSub) Subscribe(ctx context.Context, channels ...string) error {
+	return ps.write(ctx, subscribeCmd("subscribe", channels), func() {
+		for _, ch := range channels {
+			ps.channels[ch] = struct{}{}
+		}
+	})
+}
+
+func (ps *PubSub) PSubscribe(ctx context.Context, patterns ...string) error {
+	return ps.write(ctx, subscribeCmd("psubscribe", patterns), func() {
+		for _, p := range patterns {
+			ps.patterns[p] = struct{}{}
+		}
+	})
+}
+
+// Unsubscribe 取消订阅频道，不指定频道时取消全部频道
+func (ps *PubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	return ps.write(ctx, subscribeCmd("unsubscribe", channels), func() {
+		if len(channels) == 0 {
+			clear(ps.channels)
+		}
+		for _, ch := range channels {
+			delete(ps.channels, ch)
+		}
+	})
+}
+
+// PUnsubscribe 取消订阅模式，不指定模式时取消全部模式
+func (ps *PubSub) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	return ps.write(ctx, subscribeCmd("punsubscribe", patterns), func() {
+		if len(patterns) == 0 {
+			clear(ps.patterns)
+		}
+		for _, p := range patterns {
+			delete(ps.patterns, p)
+		}
+	})
+}
+
+// Ping 发送 PING，回复以 *Pong 的形式由 Receive 返回
+func (ps *PubSub) Ping(ctx context.Context, payload ...string) error {
+	args := []interface{}{"ping"}
+	if len(payload) > 0 {
+		args = append(args, payload[0])
+	}
+	return ps.write(ctx, NewCmd(args...), nil)
+}
+
+// Receive 读取下一条回复，返回 *Subscription、*Message 或 *Pong
+// 没有超时，需要时通过 ctx 设置截止时间
+func (ps *PubSub) Receive(ctx context.Context) (interface{}, error) {
+	ps.mu.Lock()
+	cn, err := ps.conn(ctx)
+	ps.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := cn.readReply(ctx, 0)
+	if err != nil {
+		ps.releaseConn(cn, err)
+		return nil, err
+	}
+	return newPubSubReply(v)
+}
+
+// ReceiveMessage 读取下一条消息，跳过订阅确认和 PING 的回复
+func (ps *PubSub) ReceiveMessage(ctx context.Context) (*Message, error) {
+	for {
+		msg, err := ps.Receive(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := msg.(*Message); ok {
+			return m, nil
+		}
+	}
+}
+
+// Close 关闭订阅连接，正在执行的 Receive 会返回错误
+func (ps *PubSub) Close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		return ErrClosed
+	}
+	ps.closed = true
+	if ps.cn != nil {
+		err := ps.cn.Close()
+		ps.cn = nil
+		return err
+	}
+	return nil
+}
+
+// newPubSubReply 把订阅模式下的回复转换为对应的类型
+func newPubSubReply(v *protocol.Value) (interface{}, error) {
+	if err := replyError(v); err != nil {
+		return nil, err
+	}
+	arr, err := valueArray(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(arr) < 2 {
+		return nil, fmt.Errorf("redis: unexpected pubsub reply of %d elements", len(arr))
+	}
+
+	switch kind := strings.ToLower(arr[0].Str); kind {
+	case "subscribe", "unsubscribe", "psubscribe", "punsubscribe":
+		if len(arr) != 3 {
+			break
+		}
+		return &Subscription{Kind: kind, Channel: arr[1].Str, Count: int(arr[2].Int)}, nil
+	case "message":
+		if len(arr) != 3 {
+			break
+		}
+		return newMessage(arr[1].Str, "", &arr[2]), nil
+	case "pmessage":
+		if len(arr) != 4 {
+			break
+		}
+		return newMessage(arr[2].Str, arr[1].Str, &arr[3]), nil
+	case "pong":
+		return &Pong{Payload: arr[1].Str}, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected pubsub reply %q", arr[0].Str)
+}
+
+func newMessage(channel, pattern string, payload *protocol.Value) *Message {
+	m := &Message{Channel: channel, Pattern: pattern}
+	if payload.Type != protocol.ArrayType {
+		m.Payload = payload.Str
+		return m
+	}
+	for _, p := range payload.Array {
+		m.PayloadSlice = append(m.PayloadSlice, p.Str)
+	}
+	return m
+}
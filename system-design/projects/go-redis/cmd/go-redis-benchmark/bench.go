@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go-redis/protocol"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type config struct {
+	addr     string
+	clients  int
+	requests int
+	dataSize int
+	pipeline int
+	keyspace int
+}
+
+// result 是一个测试项的结果
+type result struct {
+	name     string
+	requests int64
+	errors   int64
+	firstErr string // 第一条错误回复，便于发现服务器不支持的命令
+	elapsed  time.Duration
+	hist     *histogram
+}
+
+// rps 返回每秒完成的请求数
+func (r *result) rps() float64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return float64(r.requests) / r.elapsed.Seconds()
+}
+
+// worker 是一个客户端连接及其统计
+type worker struct {
+	conn     net.Conn
+	parser   *protocol.Parser
+	load     *workload
+	buf      []byte
+	hist     *histogram
+	errors   int64
+	firstErr string
+}
+
+// runTest 用 cfg.clients 个连接共同完成 cfg.requests 个请求
+// 每个连接每次取 cfg.pipeline 个请求一起发送，批次的往返时间记为其中每个请求的延迟
+func runTest(cfg config, t test) (*result, error) {
+	workers := make([]*worker, cfg.clients)
+	defer func() {
+		for _, w := range workers {
+			if w != nil {
+				w.conn.Close()
+			}
+		}
+	}()
+
+	for i := range workers {
+		conn, err := net.DialTimeout("tcp", cfg.addr, 5*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("Could not connect to Redis at %s: %v", cfg.addr, err)
+		}
+		workers[i] = &worker{
+			conn:   conn,
+			parser: protocol.NewParser(bufio.NewReaderSize(conn, 64*1024)),
+			load:   newWorkload(time.Now().UnixNano()+int64(i), cfg.keyspace, cfg.dataSize),
+			hist:   newHistogram(),
+		}
+	}
+
+	var remaining atomic.Int64
+	remaining.Store(int64(cfg.requests))
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(workers))
+	start := time.Now()
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = w.run(t, &remaining, cfg.pipeline)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	r := &result{name: t.name, elapsed: elapsed, hist: newHistogram()}
+	for i, w := range workers {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("%s: %v", t.name, errs[i])
+		}
+		r.hist.merge(w.hist)
+		r.errors += w.errors
+		if r.firstErr == "" {
+			r.firstErr = w.firstErr
+		}
+	}
+	r.requests = r.hist.count()
+	return r, nil
+}
+
+func (w *worker) run(t test, remaining *atomic.Int64, pipeline int) error {
+	for {
+		left := remaining.Add(-int64(pipeline)) + int64(pipeline)
+		if left <= 0 {
+			return nil
+		}
+		n := int(min(left, int64(pipeline)))
+
+		w.buf = w.buf[:0]
+		for range n {
+			w.buf = t.build(w.load, w.buf)
+		}
+
+		start := time.Now()
+		if _, err := w.conn.Write(w.buf); err != nil {
+			return err
+		}
+		for range n {
+			v, err := w.parser.Parse()
+			if err != nil {
+				return err
+			}
+			if v.Type == protocol.ErrorType {
+				w.errors++
+				if w.firstErr == "" {
+					w.firstErr = v.Str
+				}
+			}
+		}
+
+		latency := time.Since(start).Microseconds()
+		for range n {
+			w.hist.record(latency)
+		}
+	}
+}
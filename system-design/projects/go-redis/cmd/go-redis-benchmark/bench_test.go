@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"go-redis/logger"
+	"go-redis/server"
+	"go-redis/store"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func startTestServer(t *testing.T) string {
+	t.Helper()
+	logger.SetLevel(logrus.PanicLevel)
+
+	srv := server.NewServer("127.0.0.1:0", store.NewStore())
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Stop() })
+	return srv.Addr()
+}
+
+func TestRunTest(t *testing.T) {
+	srv := startTestServer(t)
+
+	get, _ := lookupTest("get")
+	nope := test{name: "NOPE", build: func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "NOPE")
+	}}
+
+	cfg := config{addr: srv, clients: 4, requests: 1001, dataSize: 3, pipeline: 8, keyspace: 100}
+	r, err := runTest(cfg, get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.requests != 1001 || r.errors != 0 || r.rps() <= 0 {
+		t.Errorf("GET result: requests=%d errors=%d rps=%v", r.requests, r.errors, r.rps())
+	}
+
+	r, err = runTest(cfg, nope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.errors != 1001 || !strings.HasPrefix(r.firstErr, "ERR unknown command") {
+		t.Errorf("NOPE result: errors=%d firstErr=%q", r.errors, r.firstErr)
+	}
+
+	var out bytes.Buffer
+	printCSVHeader(&out)
+	printCSV(&out, r)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || strings.Count(lines[0], ",") != strings.Count(lines[1], ",") {
+		t.Errorf("csv = %q", out.String())
+	}
+}
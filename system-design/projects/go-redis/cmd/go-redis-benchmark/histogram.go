@@ -0,0 +1,145 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+// histogram 是对数线性分桶的延迟直方图，思路与 HdrHistogram 相同：
+// 小于 128µs 的值精确记录，更大的值每个 2 的幂区间分成 64 个桶，相对误差不超过 1/64。
+// 内存固定，记录是 O(1) 的，每个客户端各持有一个，结束后合并
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+	bucketCount    = (64 - subBucketBits + 2) * subBucketHalf
+)
+
+type histogram struct {
+	counts [bucketCount]int64
+	total  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{min: math.MaxInt64}
+}
+
+func bucketIndex(v uint64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBucketBits
+	return shift*subBucketHalf + int(v>>shift)
+}
+
+// bucketHigh 返回桶能表示的最大值
+func bucketHigh(idx int) int64 {
+	if idx < subBucketCount {
+		return int64(idx)
+	}
+	shift := idx/subBucketHalf - 1
+	m := idx - shift*subBucketHalf
+	return int64(m+1)<<shift - 1
+}
+
+// record 记录一个以微秒为单位的值
+func (h *histogram) record(us int64) {
+	if us < 0 {
+		us = 0
+	}
+	h.counts[bucketIndex(uint64(us))]++
+	h.total++
+	h.sum += us
+	h.min = min(h.min, us)
+	h.max = max(h.max, us)
+}
+
+func (h *histogram) merge(o *histogram) {
+	for i, n := range o.counts {
+		h.counts[i] += n
+	}
+	h.total += o.total
+	h.sum += o.sum
+	h.min = min(h.min, o.min)
+	h.max = max(h.max, o.max)
+}
+
+func (h *histogram) count() int64 {
+	return h.total
+}
+
+func (h *histogram) mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return float64(h.sum) / float64(h.total)
+}
+
+func (h *histogram) minValue() int64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.min
+}
+
+func (h *histogram) maxValue() int64 {
+	return h.max
+}
+
+// percentile 返回不小于 p% 的样本所在桶的上界，p 取值 0~100
+func (h *histogram) percentile(p float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+
+	target := int64(math.Ceil(p / 100 * float64(h.total)))
+	target = max(target, 1)
+
+	var seen int64
+	for i, n := range h.counts {
+		if seen += n; seen >= target {
+			return min(bucketHigh(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// bucket 是累计分布中的一行
+type bucket struct {
+	value      int64   // 延迟上界（微秒）
+	percentile float64 // 不超过 value 的样本占比
+	cumulative int64   // 不超过 value 的样本数
+}
+
+// distribution 按 redis-benchmark 的方式生成累计分布：
+// 从 50% 开始，每一行把与 100% 的距离减半，直到覆盖所有样本
+func (h *histogram) distribution() []bucket {
+	if h.total == 0 {
+		return nil
+	}
+
+	var rows []bucket
+	var seen int64
+	next := 0.0
+	step := 50.0
+	for i, n := range h.counts {
+		if n == 0 {
+			continue
+		}
+		seen += n
+		pct := float64(seen) * 100 / float64(h.total)
+		if pct < next && seen < h.total {
+			continue
+		}
+
+		rows = append(rows, bucket{value: min(bucketHigh(i), h.max), percentile: pct, cumulative: seen})
+		for next <= pct && step > 1e-6 {
+			next += step
+			step /= 2
+		}
+	}
+	return rows
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	prev := -1
+	for _, v := range []uint64{0, 1, 127, 128, 129, 255, 256, 1000, 1 << 20, math.MaxInt64} {
+		idx := bucketIndex(v)
+		if idx < prev || idx >= bucketCount {
+			t.Fatalf("bucketIndex(%d) = %d, out of order or range", v, idx)
+		}
+		prev = idx
+
+		high := bucketHigh(idx)
+		if uint64(high) < v {
+			t.Errorf("bucketHigh(bucketIndex(%d)) = %d, below value", v, high)
+		}
+		if v >= subBucketCount && float64(uint64(high)-v) > float64(v)/subBucketHalf {
+			t.Errorf("bucket for %d too wide: high %d", v, high)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := newHistogram()
+	for v := int64(1); v <= 10000; v++ {
+		h.record(v)
+	}
+
+	if h.count() != 10000 || h.minValue() != 1 || h.maxValue() != 10000 {
+		t.Fatalf("count=%d min=%d max=%d", h.count(), h.minValue(), h.maxValue())
+	}
+	if mean := h.mean(); mean != 5000.5 {
+		t.Errorf("mean = %v, want 5000.5", mean)
+	}
+
+	for _, tt := range []struct {
+		p    float64
+		want int64
+	}{{50, 5000}, {95, 9500}, {99, 9900}, {99.9, 9990}, {100, 10000}} {
+		got := h.percentile(tt.p)
+		if got < tt.want || float64(got-tt.want) > float64(tt.want)/subBucketHalf {
+			t.Errorf("p%v = %d, want about %d", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramMergeAndDistribution(t *testing.T) {
+	a, b := newHistogram(), newHistogram()
+	for i := 0; i < 900; i++ {
+		a.record(100)
+	}
+	for i := 0; i < 100; i++ {
+		b.record(5000)
+	}
+	a.merge(b)
+
+	if a.count() != 1000 || a.maxValue() != 5000 || a.minValue() != 100 {
+		t.Fatalf("merged count=%d min=%d max=%d", a.count(), a.minValue(), a.maxValue())
+	}
+	if p := a.percentile(90); p != 100 {
+		t.Errorf("p90 = %d, want 100", p)
+	}
+	if p := a.percentile(91); p != 5000 {
+		t.Errorf("p91 = %d, want 5000", p)
+	}
+
+	rows := a.distribution()
+	last := rows[len(rows)-1]
+	if last.percentile != 100 || last.cumulative != 1000 || last.value != 5000 {
+		t.Errorf("last row = %+v", last)
+	}
+
+	if rows := newHistogram().distribution(); rows != nil {
+		t.Errorf("empty distribution = %+v", rows)
+	}
+}
@@ -0,0 +1,88 @@
+// go-redis-benchmark 是 redis-benchmark 风格的压测工具，可以同时用于 go-redis 和 Redis：
+//
+//	go-redis-benchmark [-h host] [-p port] [-c clients] [-n requests] [-d size] [-P pipeline] [-r keyspace] [-t tests] [--mix spec] [-q] [--csv]
+//
+// 每个测试项输出吞吐量和延迟分布（p50/p95/p99/p999），--csv 输出便于在不同提交之间对比的 CSV。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-redis/logger"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	var (
+		cfg   config
+		host  string
+		port  int
+		tests string
+		mix   string
+		quiet bool
+		csv   bool
+	)
+
+	flag.StringVar(&host, "h", "127.0.0.1", "服务器地址")
+	flag.IntVar(&port, "p", 16379, "服务器端口")
+	flag.IntVar(&cfg.clients, "c", 50, "并发连接数")
+	flag.IntVar(&cfg.requests, "n", 100000, "每个测试项的请求总数")
+	flag.IntVar(&cfg.dataSize, "d", 3, "SET/GET 等命令的值大小（字节）")
+	flag.IntVar(&cfg.pipeline, "P", 1, "每个连接每次发送的请求数")
+	flag.IntVar(&cfg.keyspace, "r", 0, "随机键的范围，0 表示所有请求使用同一个键")
+	flag.StringVar(&tests, "t", "", "逗号分隔的测试项，默认全部: ping,set,get,incr,lpush,rpush,lpop,rpop,sadd,hset,spop,zadd,xadd,mset")
+	flag.StringVar(&mix, "mix", "", "按权重混合的命令，例如 set=20,get=80，作为一个测试项执行")
+	flag.BoolVar(&quiet, "q", false, "每个测试项只输出一行")
+	flag.BoolVar(&csv, "csv", false, "输出 CSV")
+	flag.Parse()
+
+	// 协议解析器在 Debug 级别会输出日志
+	logger.SetLevel(logrus.WarnLevel)
+
+	if cfg.clients <= 0 || cfg.requests <= 0 || cfg.pipeline <= 0 || cfg.dataSize < 0 {
+		fatal(fmt.Errorf("-c, -n and -P must be positive, -d must not be negative"))
+	}
+	cfg.addr = fmt.Sprintf("%s:%d", host, port)
+
+	var list []test
+	if mix != "" {
+		t, err := parseMix(mix)
+		if err != nil {
+			fatal(err)
+		}
+		list = []test{t}
+	}
+	if tests != "" || mix == "" {
+		selected, err := parseTests(tests)
+		if err != nil {
+			fatal(err)
+		}
+		list = append(selected, list...)
+	}
+
+	if csv {
+		printCSVHeader(os.Stdout)
+	}
+	for _, t := range list {
+		r, err := runTest(cfg, t)
+		if err != nil {
+			fatal(err)
+		}
+
+		switch {
+		case csv:
+			printCSV(os.Stdout, r)
+		case quiet:
+			printQuiet(os.Stdout, r)
+		default:
+			printText(os.Stdout, cfg, r)
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 输出格式与 redis-benchmark 相同：默认输出完整报告，-q 每个测试项一行，--csv 输出 CSV。
+// 延迟以毫秒为单位，保留三位小数
+
+func msec(us int64) string {
+	return fmt.Sprintf("%.3f", float64(us)/1000)
+}
+
+// percentiles 是摘要中输出的分位数
+var percentiles = []struct {
+	name string
+	p    float64
+}{
+	{"p50", 50},
+	{"p95", 95},
+	{"p99", 99},
+	{"p999", 99.9},
+}
+
+func printText(w io.Writer, cfg config, r *result) {
+	fmt.Fprintf(w, "====== %s ======\n", r.name)
+	fmt.Fprintf(w, "  %d requests completed in %.2f seconds\n", r.requests, r.elapsed.Seconds())
+	fmt.Fprintf(w, "  %d parallel clients\n", cfg.clients)
+	fmt.Fprintf(w, "  %d bytes payload\n", cfg.dataSize)
+	fmt.Fprintf(w, "  pipeline %d, keyspace %d\n", cfg.pipeline, cfg.keyspace)
+	if r.errors > 0 {
+		fmt.Fprintf(w, "  %d errors, first error: %s\n", r.errors, r.firstErr)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Latency by percentile distribution:")
+	for _, b := range r.hist.distribution() {
+		fmt.Fprintf(w, "%.3f%% <= %s milliseconds (cumulative count %d)\n", b.percentile, msec(b.value), b.cumulative)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Summary:")
+	fmt.Fprintf(w, "  throughput summary: %.2f requests per second\n", r.rps())
+	fmt.Fprintln(w, "  latency summary (msec):")
+
+	header := []string{"avg", "min"}
+	values := []string{fmt.Sprintf("%.3f", r.hist.mean()/1000), msec(r.hist.minValue())}
+	for _, p := range percentiles {
+		header = append(header, p.name)
+		values = append(values, msec(r.hist.percentile(p.p)))
+	}
+	header = append(header, "max")
+	values = append(values, msec(r.hist.maxValue()))
+
+	fmt.Fprintf(w, "    %s\n", padColumns(header))
+	fmt.Fprintf(w, "    %s\n", padColumns(values))
+	fmt.Fprintln(w)
+}
+
+func padColumns(cols []string) string {
+	var b strings.Builder
+	for _, c := range cols {
+		fmt.Fprintf(&b, "%10s", c)
+	}
+	return b.String()
+}
+
+func printQuiet(w io.Writer, r *result) {
+	fmt.Fprintf(w, "%s: %.2f requests per second, p50=%s msec", r.name, r.rps(), msec(r.hist.percentile(50)))
+	if r.errors > 0 {
+		fmt.Fprintf(w, " (%d errors: %s)", r.errors, r.firstErr)
+	}
+	fmt.Fprintln(w)
+}
+
+func printCSVHeader(w io.Writer) {
+	cols := []string{"test", "rps", "avg_latency_ms", "min_latency_ms"}
+	for _, p := range percentiles {
+		cols = append(cols, p.name+"_latency_ms")
+	}
+	cols = append(cols, "max_latency_ms", "errors")
+	fmt.Fprintln(w, csvRow(cols))
+}
+
+func printCSV(w io.Writer, r *result) {
+	cols := []string{
+		r.name,
+		fmt.Sprintf("%.2f", r.rps()),
+		fmt.Sprintf("%.3f", r.hist.mean()/1000),
+		msec(r.hist.minValue()),
+	}
+	for _, p := range percentiles {
+		cols = append(cols, msec(r.hist.percentile(p.p)))
+	}
+	cols = append(cols, msec(r.hist.maxValue()), fmt.Sprint(r.errors))
+	fmt.Fprintln(w, csvRow(cols))
+}
+
+// csvRow 与 redis-benchmark 一样给每一列加上双引号
+func csvRow(cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = `"` + strings.ReplaceAll(c, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, ",")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// 测试项与 redis-benchmark 使用相同的命令和键名，方便与真实的 Redis 对比。
+// 键名中的 __rand_int__ 在指定 -r 时替换为 [0, keyspace) 内的随机数，否则保持原样（所有请求访问同一个键）
+
+// workload 为一个客户端生成请求，不是并发安全的
+type workload struct {
+	rng      *rand.Rand
+	keyspace int
+	value    string
+}
+
+func newWorkload(seed int64, keyspace, dataSize int) *workload {
+	return &workload{
+		rng:      rand.New(rand.NewSource(seed)),
+		keyspace: keyspace,
+		value:    strings.Repeat("x", dataSize),
+	}
+}
+
+// key 返回带随机后缀的键名
+func (w *workload) key(prefix string) string {
+	if w.keyspace <= 0 {
+		return prefix + "__rand_int__"
+	}
+	return fmt.Sprintf("%s%012d", prefix, w.rng.Intn(w.keyspace))
+}
+
+// test 是一个测试项，build 把一条请求追加到 dst
+type test struct {
+	name  string
+	build func(w *workload, dst []byte) []byte
+}
+
+var allTests = []test{
+	{"PING", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "PING")
+	}},
+	{"SET", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "SET", w.key("key:"), w.value)
+	}},
+	{"GET", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "GET", w.key("key:"))
+	}},
+	{"INCR", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "INCR", w.key("counter:"))
+	}},
+	{"LPUSH", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "LPUSH", "mylist", w.value)
+	}},
+	{"RPUSH", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "RPUSH", "mylist", w.value)
+	}},
+	{"LPOP", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "LPOP", "mylist")
+	}},
+	{"RPOP", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "RPOP", "mylist")
+	}},
+	{"SADD", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "SADD", "myset", w.key("element:"))
+	}},
+	{"HSET", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "HSET", "myhash", w.key("element:"), w.value)
+	}},
+	{"SPOP", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "SPOP", "myset")
+	}},
+	{"ZADD", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "ZADD", "myzset", "0", w.key("element:"))
+	}},
+	{"XADD", func(w *workload, dst []byte) []byte {
+		return appendCommand(dst, "XADD", "mystream", "*", "myfield", w.value)
+	}},
+	{"MSET", func(w *workload, dst []byte) []byte {
+		args := make([]string, 0, 21)
+		args = append(args, "MSET")
+		for range 10 {
+			args = append(args, w.key("key:"), w.value)
+		}
+		return appendCommand(dst, args...)
+	}},
+}
+
+func lookupTest(name string) (test, bool) {
+	for _, t := range allTests {
+		if strings.EqualFold(t.name, name) {
+			return t, true
+		}
+	}
+	return test{}, false
+}
+
+// parseTests 解析 -t 指定的逗号分隔的测试项，为空时返回全部测试项
+func parseTests(s string) ([]test, error) {
+	if s == "" {
+		return allTests, nil
+	}
+
+	var tests []test
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		t, ok := lookupTest(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown test: %s", name)
+		}
+		tests = append(tests, t)
+	}
+	return tests, nil
+}
+
+// parseMix 解析 --mix 指定的命令配比，例如 "set=20,get=80"，
+// 返回的测试项按权重随机选择每条请求的命令
+func parseMix(s string) (test, error) {
+	var tests []test
+	var weights []int
+	total := 0
+
+	for _, part := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return test{}, fmt.Errorf("invalid mix entry %q, expected name=weight", part)
+		}
+		t, found := lookupTest(name)
+		if !found {
+			return test{}, fmt.Errorf("unknown test: %s", name)
+		}
+		n, err := strconv.Atoi(weight)
+		if err != nil || n <= 0 {
+			return test{}, fmt.Errorf("invalid weight for %s: %q", name, weight)
+		}
+
+		tests = append(tests, t)
+		weights = append(weights, n)
+		total += n
+	}
+
+	return test{
+		name: "MIX(" + strings.ToUpper(s) + ")",
+		build: func(w *workload, dst []byte) []byte {
+			r := w.rng.Intn(total)
+			for i, n := range weights {
+				if r < n {
+					return tests[i].build(w, dst)
+				}
+				r -= n
+			}
+			return dst
+		},
+	}, nil
+}
+
+// appendCommand 把命令按 RESP 数组编码追加到 dst
+func appendCommand(dst []byte, args ...string) []byte {
+	dst = append(dst, '*')
+	dst = strconv.AppendInt(dst, int64(len(args)), 10)
+	dst = append(dst, '\r', '\n')
+	for _, arg := range args {
+		dst = append(dst, '$')
+		dst = strconv.AppendInt(dst, int64(len(arg)), 10)
+		dst = append(dst, '\r', '\n')
+		dst = append(dst, arg...)
+		dst = append(dst, '\r', '\n')
+	}
+	return dst
+}
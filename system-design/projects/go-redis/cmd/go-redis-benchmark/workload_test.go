@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"go-redis/protocol"
+	"testing"
+)
+
+func TestAppendCommand(t *testing.T) {
+	buf := appendCommand(nil, "SET", "key", "a\r\nb")
+	buf = appendCommand(buf, "PING")
+
+	p := protocol.NewParser(bytes.NewReader(buf))
+	v, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Array) != 3 || v.Array[2].Str != "a\r\nb" {
+		t.Errorf("first command = %+v", v)
+	}
+	if v, err = p.Parse(); err != nil || v.Array[0].Str != "PING" {
+		t.Errorf("second command = %+v, %v", v, err)
+	}
+}
+
+func TestWorkloadKeys(t *testing.T) {
+	w := newWorkload(1, 0, 3)
+	if key := w.key("key:"); key != "key:__rand_int__" {
+		t.Errorf("key without keyspace = %q", key)
+	}
+
+	w = newWorkload(1, 10, 3)
+	for i := 0; i < 100; i++ {
+		key := w.key("key:")
+		if len(key) != len("key:")+12 || key < "key:000000000000" || key > "key:000000000009" {
+			t.Fatalf("key = %q", key)
+		}
+	}
+}
+
+func TestParseTests(t *testing.T) {
+	tests, err := parseTests("ping, Set,GET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != 3 || tests[1].name != "SET" {
+		t.Errorf("parseTests = %+v", tests)
+	}
+
+	if all, _ := parseTests(""); len(all) != len(allTests) {
+		t.Errorf("default tests = %d, want %d", len(all), len(allTests))
+	}
+	if _, err := parseTests("set,nope"); err == nil {
+		t.Error("expected error for unknown test")
+	}
+}
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("set=1,get=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := newWorkload(1, 0, 3)
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		v, err := protocol.NewParser(bytes.NewReader(mix.build(w, nil))).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[v.Array[0].Str]++
+	}
+	if counts["SET"] < 800 || counts["SET"] > 1200 || counts["SET"]+counts["GET"] != 4000 {
+		t.Errorf("mix counts = %v", counts)
+	}
+
+	for _, spec := range []string{"set", "set=0", "set=x", "nope=1"} {
+		if _, err := parseMix(spec); err == nil {
+			t.Errorf("parseMix(%q): expected error", spec)
+		}
+	}
+}
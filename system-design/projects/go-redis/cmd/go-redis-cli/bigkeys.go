@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-redis/protocol"
+	"strconv"
+	"time"
+)
+
+// --bigkeys / --memkeys：分批遍历整个键空间，找出每种类型中最大的键
+//
+// 与 redis-cli 相同，每批键先用 TYPE 取得类型，再按类型取得大小：--bigkeys 使用元素个数或字符串长度，
+// --memkeys 使用 MEMORY USAGE。命令以流水线方式发送，每批只需要两次往返
+
+// bigKeyType 是 --bigkeys 支持的类型，sizeCmd 返回该类型的键的大小
+type bigKeyType struct {
+	name    string
+	sizeCmd string
+	unit    string
+}
+
+var bigKeyTypes = []bigKeyType{
+	{name: "string", sizeCmd: "STRLEN", unit: "bytes"},
+	{name: "stream", sizeCmd: "XLEN", unit: "entries"},
+}
+
+// bigKeyStats 是一种类型的统计
+type bigKeyStats struct {
+	count       int64
+	totalSize   int64
+	biggest     string
+	biggestSize int64
+}
+
+// bigKeys 遍历键空间并输出每种类型最大的键和平均大小
+// memkeys 为 true 时按 MEMORY USAGE 统计，samples 大于 0 时作为它的 SAMPLES 参数；
+// interval 大于 0 时每 100 批暂停一次，减轻对服务器的影响
+func (c *cli) bigKeys(memkeys bool, samples int, interval time.Duration) error {
+	total, err := c.dbSize()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(c.out, "\n# Scanning the entire keyspace to find biggest keys as well as\n"+
+		"# average sizes per key type.  You can use -i 0.1 to sleep 0.1 sec\n"+
+		"# per 100 SCAN commands (not usually needed).\n\n")
+
+	stats := make(map[string]*bigKeyStats)
+	types := make(map[string]bigKeyType)
+	for _, t := range bigKeyTypes {
+		if memkeys {
+			t.unit = "bytes"
+		}
+		types[t.name] = t
+		stats[t.name] = &bigKeyStats{}
+	}
+
+	var sampled, keyLen int64
+	batches := 0
+	err = c.scanKeys("*", func(keys []string) error {
+		typeCmds := make([][]string, len(keys))
+		for i, key := range keys {
+			typeCmds[i] = []string{"TYPE", key}
+		}
+		typeReplies, err := c.pipeline(typeCmds)
+		if err != nil {
+			return err
+		}
+
+		// 只统计支持的类型，遍历期间被删除的键类型为 none
+		var found []string
+		var foundTypes []bigKeyType
+		var sizeCmds [][]string
+		for i, key := range keys {
+			t, ok := types[typeReplies[i].Str]
+			if !ok {
+				continue
+			}
+			found = append(found, key)
+			foundTypes = append(foundTypes, t)
+			if memkeys {
+				args := []string{"MEMORY", "USAGE", key}
+				if samples > 0 {
+					args = append(args, "SAMPLES", strconv.Itoa(samples))
+				}
+				sizeCmds = append(sizeCmds, args)
+			} else {
+				sizeCmds = append(sizeCmds, []string{t.sizeCmd, key})
+			}
+		}
+		sizeReplies, err := c.pipeline(sizeCmds)
+		if err != nil {
+			return err
+		}
+
+		for i, key := range found {
+			reply := sizeReplies[i]
+			if reply.Type == protocol.ErrorType {
+				return errors.New(reply.Str)
+			}
+			if reply.Type != protocol.IntType {
+				continue
+			}
+
+			t, st := foundTypes[i], stats[foundTypes[i].name]
+			sampled++
+			keyLen += int64(len(key))
+			st.count++
+			st.totalSize += reply.Int
+			if st.biggest == "" || reply.Int > st.biggestSize {
+				st.biggest, st.biggestSize = key, reply.Int
+				pct := 0.0
+				if total > 0 {
+					pct = 100 * float64(sampled) / float64(total)
+				}
+				fmt.Fprintf(c.out, "[%05.2f%%] Biggest %-6s found so far %s with %d %s\n",
+					pct, t.name, protocol.Quote(key), reply.Int, t.unit)
+			}
+		}
+
+		batches++
+		if interval > 0 && batches%100 == 0 {
+			time.Sleep(interval)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(c.out, "\n-------- summary -------\n\n")
+	fmt.Fprintf(c.out, "Sampled %d keys in the keyspace!\n", sampled)
+	avgLen := 0.0
+	if sampled > 0 {
+		avgLen = float64(keyLen) / float64(sampled)
+	}
+	fmt.Fprintf(c.out, "Total key length in bytes is %d (avg len %.2f)\n\n", keyLen, avgLen)
+
+	for _, t := range bigKeyTypes {
+		if st := stats[t.name]; st.biggest != "" {
+			fmt.Fprintf(c.out, "Biggest %6s found %s has %d %s\n", t.name, protocol.Quote(st.biggest), st.biggestSize, types[t.name].unit)
+		}
+	}
+	fmt.Fprintln(c.out)
+
+	for _, t := range bigKeyTypes {
+		st := stats[t.name]
+		var pct, avg float64
+		if sampled > 0 {
+			pct = 100 * float64(st.count) / float64(sampled)
+		}
+		if st.count > 0 {
+			avg = float64(st.totalSize) / float64(st.count)
+		}
+		fmt.Fprintf(c.out, "%d %ss with %d %s (%05.2f%% of keys, avg size %.2f)\n",
+			st.count, t.name, st.totalSize, types[t.name].unit, pct, avg)
+	}
+	return nil
+}
+
+// dbSize 返回键的总数，用于显示进度
+func (c *cli) dbSize() (int64, error) {
+	v, err := c.do([]string{"DBSIZE"})
+	if err != nil {
+		return 0, err
+	}
+	if v.Type == protocol.ErrorType {
+		return 0, errors.New(v.Str)
+	}
+	return v.Int, nil
+}
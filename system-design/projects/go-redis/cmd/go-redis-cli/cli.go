@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-redis/protocol"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// cli 持有到服务器的连接，连接断开后在下一条命令时重连
+type cli struct {
+	addr   string
+	raw    bool
+	out    io.Writer
+	conn   net.Conn
+	parser *protocol.Parser
+	w      *bufio.Writer
+}
+
+func (c *cli) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", c.addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("Could not connect to Redis at %s: %v", c.addr, err)
+	}
+	c.conn = conn
+	c.parser = protocol.NewParser(bufio.NewReader(conn))
+	c.w = bufio.NewWriter(conn)
+	return nil
+}
+
+func (c *cli) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// do 发送一条命令并读取回复，网络错误时关闭连接
+func (c *cli) do(args []string) (*protocol.Value, error) {
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	if err := c.send(args); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// errServerClosed 表示服务器关闭了连接
+var errServerClosed = errors.New("Server closed the connection")
+
+func (c *cli) send(args []string) error {
+	c.w.WriteString(protocol.Serialize(command(args)))
+	return c.w.Flush()
+}
+
+func (c *cli) readReply() (*protocol.Value, error) {
+	v, err := c.parser.Parse()
+	if err != nil {
+		c.close()
+		if err == io.EOF {
+			return nil, errServerClosed
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+func (c *cli) print(v *protocol.Value) {
+	fmt.Fprint(c.out, formatReply(v, c.raw))
+}
+
+// command 把参数列表编码为 RESP 数组
+func command(args []string) *protocol.Value {
+	values := make([]protocol.Value, len(args))
+	for i, arg := range args {
+		values[i] = *protocol.BulkString(arg)
+	}
+	return protocol.Array(values)
+}
+
+// isStreamingCommand 判断命令执行后服务器是否会持续推送消息
+func isStreamingCommand(name string) bool {
+	switch strings.ToUpper(name) {
+	case "SUBSCRIBE", "PSUBSCRIBE", "MONITOR":
+		return true
+	}
+	return false
+}
+
+// run 执行一条命令 repeat 次（-1 表示无限次），每次之间间隔 interval
+func (c *cli) run(args []string, repeat int, interval time.Duration) error {
+	for i := 0; repeat < 0 || i < repeat; i++ {
+		if i > 0 && interval > 0 {
+			time.Sleep(interval)
+		}
+
+		v, err := c.do(args)
+		if err == errServerClosed && strings.EqualFold(args[0], "SHUTDOWN") {
+			// 与 redis-cli 一样，SHUTDOWN 成功时服务器直接关闭连接，不算错误
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		c.print(v)
+
+		if isStreamingCommand(args[0]) {
+			return c.stream(args[0])
+		}
+	}
+	return nil
+}
+
+// stream 在 SUBSCRIBE / MONITOR 之后持续打印服务器推送的消息，直到连接断开或进程被中断
+func (c *cli) stream(name string) error {
+	if strings.ToUpper(name) != "MONITOR" && !c.raw {
+		fmt.Fprintln(c.out, "Reading messages... (press Ctrl-C to quit)")
+	}
+	for {
+		v, err := c.readReply()
+		if err != nil {
+			return err
+		}
+		c.print(v)
+	}
+}
+
+// scan 输出所有匹配 pattern 的键，每行一个
+func (c *cli) scan(pattern string) error {
+	return c.scanKeys(pattern, func(keys []string) error {
+		for _, key := range keys {
+			fmt.Fprintln(c.out, key)
+		}
+		return nil
+	})
+}
+
+// scanKeys 用 SCAN 分批遍历匹配 pattern 的键，每一批交给 fn 处理
+func (c *cli) scanKeys(pattern string, fn func(keys []string) error) error {
+	cursor := "0"
+	for {
+		v, err := c.do([]string{"SCAN", cursor, "MATCH", pattern})
+		if err != nil {
+			return err
+		}
+		if v.Type == protocol.ErrorType {
+			return errors.New(v.Str)
+		}
+		if v.Type != protocol.ArrayType || len(v.Array) != 2 || v.Array[1].Type != protocol.ArrayType {
+			return errors.New("unexpected SCAN reply")
+		}
+
+		if err := fn(replyStrings(v.Array[1].Array)); err != nil {
+			return err
+		}
+		if cursor = v.Array[0].Str; cursor == "0" {
+			return nil
+		}
+	}
+}
+
+func replyStrings(values []protocol.Value) []string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = v.Str
+	}
+	return strs
+}
+
+// pipeline 一次发送多条命令再依次读取回复
+func (c *cli) pipeline(cmds [][]string) ([]*protocol.Value, error) {
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	for _, args := range cmds {
+		c.w.WriteString(protocol.Serialize(command(args)))
+	}
+	if err := c.w.Flush(); err != nil {
+		c.close()
+		return nil, err
+	}
+
+	replies := make([]*protocol.Value, len(cmds))
+	for i := range cmds {
+		v, err := c.readReply()
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = v
+	}
+	return replies, nil
+}
+
+// pipe 实现 --pipe 批量导入：把 in 中的 RESP 命令原样发送，同时读取回复，
+// 发送完毕后追加一条带随机内容的 PING，收到它的回复即表示所有命令都已执行
+func (c *cli) pipe(in io.Reader) (replies, errs int, err error) {
+	if err := c.connect(); err != nil {
+		return 0, 0, err
+	}
+
+	tag := make([]byte, 20)
+	rand.Read(tag)
+	magic := hex.EncodeToString(tag)
+
+	writeErr := make(chan error, 1)
+	go func() {
+		p := protocol.NewParser(in)
+		for {
+			v, err := p.Parse()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				writeErr <- fmt.Errorf("invalid input: %v", err)
+				c.conn.Close()
+				return
+			}
+			if _, err := c.w.WriteString(protocol.Serialize(v)); err != nil {
+				writeErr <- err
+				return
+			}
+		}
+		c.w.WriteString(protocol.Serialize(command([]string{"PING", magic})))
+		err := c.w.Flush()
+		if err == nil {
+			fmt.Fprintln(os.Stderr, "All data transferred. Waiting for the last reply...")
+		}
+		writeErr <- err
+	}()
+
+	for {
+		v, err := c.parser.Parse()
+		if err != nil {
+			select {
+			case werr := <-writeErr:
+				if werr != nil {
+					return replies, errs, werr
+				}
+			default:
+			}
+			return replies, errs, err
+		}
+
+		if v.Type == protocol.BulkStringType && v.Str == magic {
+			break
+		}
+		replies++
+		if v.Type == protocol.ErrorType {
+			errs++
+			fmt.Fprintln(os.Stderr, v.Str)
+		}
+	}
+
+	if err := <-writeErr; err != nil {
+		return replies, errs, err
+	}
+	fmt.Fprintln(os.Stderr, "Last reply received from server.")
+	return replies, errs, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"go-redis/server"
+	"go-redis/store"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+var testAddr string
+
+func TestMain(m *testing.M) {
+	logger.SetLevel(logrus.PanicLevel)
+
+	srv := server.NewServer("127.0.0.1:0", store.NewStore())
+	if err := srv.Listen(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go srv.Serve()
+	testAddr = srv.Addr()
+
+	code := m.Run()
+	srv.Stop()
+	os.Exit(code)
+}
+
+func newTestCLI(out io.Writer) *cli {
+	return &cli{addr: testAddr, raw: true, out: out}
+}
+
+func TestCLIRun(t *testing.T) {
+	var out bytes.Buffer
+	c := newTestCLI(&out)
+	defer c.close()
+
+	c.raw = false
+	if err := c.run([]string{"SET", "cli:run", "v"}, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.run([]string{"GET", "cli:run"}, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.run([]string{"NOPE"}, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "OK\n\"v\"\n\"v\"\n(error) ERR unknown command: NOPE\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCLIPipe(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 100; i++ {
+		input.WriteString(protocol.Serialize(command([]string{"SET", fmt.Sprintf("cli:pipe:%d", i), "v"})))
+	}
+	input.WriteString(protocol.Serialize(command([]string{"NOPE"})))
+
+	c := newTestCLI(io.Discard)
+	defer c.close()
+
+	replies, errs, err := c.pipe(strings.NewReader(input.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replies != 101 || errs != 1 {
+		t.Errorf("replies=%d errs=%d, want 101 and 1", replies, errs)
+	}
+
+	c.close()
+	if _, _, err := c.pipe(strings.NewReader("garbage\r\n")); err == nil {
+		t.Error("expected error for invalid input")
+	}
+}
+
+func TestCLIScan(t *testing.T) {
+	var out bytes.Buffer
+	c := newTestCLI(&out)
+	defer c.close()
+
+	// 键数远多于 SCAN 默认的 COUNT，需要多次调用才能遍历完
+	var want []string
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("cli:scan:%03d", i)
+		want = append(want, key)
+		if _, err := c.do([]string{"SET", key, "v"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.do([]string{"SET", fmt.Sprintf("other:%d", i), "v"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.scan("cli:scan:*"); err != nil {
+		t.Fatal(err)
+	}
+	keys := strings.Fields(out.String())
+	sort.Strings(keys)
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Errorf("scan = %q", keys)
+	}
+
+	calls := 0
+	if err := c.scanKeys("*", func([]string) error { calls++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if calls < 2 {
+		t.Errorf("SCAN over %d keys finished in %d call", 2*len(want), calls)
+	}
+
+	if err := c.scanKeys("*", func([]string) error { return errors.New("stop") }); err == nil || err.Error() != "stop" {
+		t.Errorf("scanKeys should return the callback error, got %v", err)
+	}
+}
+
+func TestCLIBigKeys(t *testing.T) {
+	var out bytes.Buffer
+	c := newTestCLI(&out)
+	defer c.close()
+
+	for _, args := range [][]string{
+		{"SET", "cli:big:small", "v"},
+		{"SET", "cli:big:large", strings.Repeat("x", 4096)},
+		{"XADD", "cli:big:stream", "*", "f", "v"},
+		{"XADD", "cli:big:stream", "*", "f", "v"},
+	} {
+		if _, err := c.do(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.bigKeys(false, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Biggest string found "cli:big:large" has 4096 bytes`,
+		`Biggest stream found "cli:big:stream" has 2 entries`,
+		"1 streams with 2 entries",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("--bigkeys output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := c.bigKeys(true, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `Biggest string found "cli:big:large" has `) {
+		t.Errorf("--memkeys output:\n%s", out.String())
+	}
+}
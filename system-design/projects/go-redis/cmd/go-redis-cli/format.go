@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"go-redis/protocol"
+	"strconv"
+	"strings"
+)
+
+// 回复的输出格式与 redis-cli 保持一致：
+// 终端下使用 TTY 格式（带类型标注、编号的数组），--raw 或输出被重定向时使用原始格式
+
+// formatReply 格式化一条回复，结果以换行结尾
+func formatReply(v *protocol.Value, raw bool) string {
+	if raw {
+		return formatRaw(v) + "\n"
+	}
+	return formatTTY(v, "")
+}
+
+// formatTTY 对应 redis-cli 的 cliFormatReplyTTY，prefix 是嵌套数组的缩进
+func formatTTY(v *protocol.Value, prefix string) string {
+	switch v.Type {
+	case protocol.ErrorType:
+		return "(error) " + v.Str + "\n"
+	case protocol.StringType:
+		return v.Str + "\n"
+	case protocol.IntType:
+		return "(integer) " + strconv.FormatInt(v.Int, 10) + "\n"
+	case protocol.BulkStringType:
+		if v.IsNull {
+			return "(nil)\n"
+		}
+		return protocol.Quote(v.Str) + "\n"
+	case protocol.ArrayType:
+		if v.IsNull {
+			return "(nil)\n"
+		}
+		if len(v.Array) == 0 {
+			return "(empty array)\n"
+		}
+
+		// 编号按最大编号的位数右对齐，嵌套数组的缩进为编号宽度加上 ") "
+		idxLen := len(strconv.Itoa(len(v.Array)))
+		nested := prefix + strings.Repeat(" ", idxLen+2)
+
+		var b strings.Builder
+		for i := range v.Array {
+			// 第一个元素前的缩进已经由上一层的编号占据
+			p := prefix
+			if i == 0 {
+				p = ""
+			}
+			fmt.Fprintf(&b, "%s%*d) ", p, idxLen, i+1)
+			b.WriteString(formatTTY(&v.Array[i], nested))
+		}
+		return b.String()
+	default:
+		return fmt.Sprintf("(unknown reply type %q)\n", v.Type)
+	}
+}
+
+// formatRaw 对应 redis-cli 的 cliFormatReplyRaw，数组元素逐行输出
+func formatRaw(v *protocol.Value) string {
+	switch v.Type {
+	case protocol.ErrorType:
+		return v.Str + "\n"
+	case protocol.StringType, protocol.BulkStringType:
+		return v.Str
+	case protocol.IntType:
+		return strconv.FormatInt(v.Int, 10)
+	case protocol.ArrayType:
+		parts := make([]string, len(v.Array))
+		for i := range v.Array {
+			parts[i] = formatRaw(&v.Array[i])
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return ""
+	}
+}
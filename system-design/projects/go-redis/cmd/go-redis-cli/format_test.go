@@ -0,0 +1,74 @@
+package main
+
+import (
+	"go-redis/protocol"
+	"testing"
+)
+
+func TestFormatReplyTTY(t *testing.T) {
+	nested := protocol.Array([]protocol.Value{
+		*protocol.BulkString("1-1"),
+		*protocol.Array([]protocol.Value{
+			*protocol.BulkString("field"),
+			*protocol.BulkString("a\r\n"),
+		}),
+	})
+
+	tests := []struct {
+		name string
+		v    *protocol.Value
+		want string
+	}{
+		{"status", protocol.SimpleString("OK"), "OK\n"},
+		{"error", protocol.Error("ERR boom"), "(error) ERR boom\n"},
+		{"integer", protocol.Integer(42), "(integer) 42\n"},
+		{"bulk", protocol.BulkString("hello world"), "\"hello world\"\n"},
+		{"nil", protocol.NullBulkString(), "(nil)\n"},
+		{"nil array", protocol.NullArray(), "(nil)\n"},
+		{"empty array", protocol.EmptyArray(), "(empty array)\n"},
+		{"nested", protocol.Array([]protocol.Value{*nested, *protocol.Integer(7)}),
+			"1) 1) \"1-1\"\n" +
+				"   2) 1) \"field\"\n" +
+				"      2) \"a\\r\\n\"\n" +
+				"2) (integer) 7\n"},
+	}
+
+	for _, tt := range tests {
+		if got := formatReply(tt.v, false); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatReplyTTYAlignsIndexes(t *testing.T) {
+	values := make([]protocol.Value, 10)
+	for i := range values {
+		values[i] = *protocol.Integer(int64(i))
+	}
+
+	got := formatReply(protocol.Array(values), false)
+	want := " 1) (integer) 0\n 2) (integer) 1\n 3) (integer) 2\n 4) (integer) 3\n 5) (integer) 4\n" +
+		" 6) (integer) 5\n 7) (integer) 6\n 8) (integer) 7\n 9) (integer) 8\n10) (integer) 9\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatReplyRaw(t *testing.T) {
+	tests := []struct {
+		v    *protocol.Value
+		want string
+	}{
+		{protocol.SimpleString("OK"), "OK\n"},
+		{protocol.BulkString("a\r\n"), "a\r\n\n"},
+		{protocol.Integer(-1), "-1\n"},
+		{protocol.NullBulkString(), "\n"},
+		{protocol.Array([]protocol.Value{*protocol.BulkString("a"), *protocol.Integer(1)}), "a\n1\n"},
+	}
+
+	for _, tt := range tests {
+		if got := formatReply(tt.v, true); got != tt.want {
+			t.Errorf("formatReply(%+v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// 简单的行编辑器，按键与 linenoise（redis-cli 使用的行编辑库）一致：
+// 左右方向键 / Ctrl-B Ctrl-F 移动光标，上下方向键 / Ctrl-P Ctrl-N 翻历史，
+// Ctrl-A Ctrl-E 行首行尾，Ctrl-U 清空整行，Ctrl-K 删除到行尾，Ctrl-W 删除前一个单词，
+// Ctrl-L 清屏，Ctrl-C 退出，空行上的 Ctrl-D 退出
+
+// historyMaxLen 是保留的历史记录条数
+const historyMaxLen = 100
+
+var errInterrupted = errors.New("interrupted")
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEsc       = 27
+	keyBackspace = 127
+)
+
+type lineEditor struct {
+	fd       int
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	histFile string
+}
+
+func newLineEditor(in *os.File, out io.Writer, histFile string) *lineEditor {
+	e := &lineEditor{
+		fd:       int(in.Fd()),
+		in:       bufio.NewReader(in),
+		out:      out,
+		histFile: histFile,
+	}
+	e.loadHistory()
+	return e
+}
+
+// readLine 显示提示符并读取一行；终端无法切换模式时退化为逐行读取
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		fmt.Fprint(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	defer restore()
+
+	return e.edit(prompt)
+}
+
+// edit 在原始模式下逐个读取按键并编辑当前行
+func (e *lineEditor) edit(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	histIdx := len(e.history)
+	saved := "" // 翻历史前正在编辑的内容
+
+	setLine := func(s string) {
+		buf = []rune(s)
+		pos = len(buf)
+	}
+	showHistory := func(idx int) {
+		if idx < 0 || idx > len(e.history) {
+			return
+		}
+		if histIdx == len(e.history) {
+			saved = string(buf)
+		}
+		histIdx = idx
+		if idx == len(e.history) {
+			setLine(saved)
+		} else {
+			setLine(e.history[idx])
+		}
+	}
+
+	e.refresh(prompt, buf, pos)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyCR, keyLF:
+			fmt.Fprint(e.out, "\n")
+			return string(buf), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case keyBackspace, keyCtrlH:
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(buf)
+		case keyCtrlB:
+			if pos > 0 {
+				pos--
+			}
+		case keyCtrlF:
+			if pos < len(buf) {
+				pos++
+			}
+		case keyCtrlK:
+			buf = buf[:pos]
+		case keyCtrlU:
+			buf, pos = buf[:0], 0
+		case keyCtrlW:
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyCtrlP:
+			showHistory(histIdx - 1)
+		case keyCtrlN:
+			showHistory(histIdx + 1)
+		case keyTab:
+			// 没有命令补全，忽略
+		case keyEsc:
+			switch e.readEscape() {
+			case 'A':
+				showHistory(histIdx - 1)
+			case 'B':
+				showHistory(histIdx + 1)
+			case 'C':
+				if pos < len(buf) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(buf)
+			case '~': // Delete
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if r < ' ' {
+				continue
+			}
+			buf = append(buf, 0)
+			copy(buf[pos+1:], buf[pos:])
+			buf[pos] = r
+			pos++
+		}
+
+		e.refresh(prompt, buf, pos)
+	}
+}
+
+// readEscape 读取 ESC 之后的转义序列，返回归一化的按键：
+// 方向键 A/B/C/D，Home H，End F，Delete '~'，无法识别时返回 0
+func (e *lineEditor) readEscape() rune {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0
+	}
+
+	switch r {
+	case 'O':
+		r, _, _ = e.in.ReadRune()
+		return r
+	case '[':
+		r, _, _ = e.in.ReadRune()
+		if r < '0' || r > '9' {
+			return r
+		}
+		// ESC [ n ~
+		if t, _, _ := e.in.ReadRune(); t != '~' {
+			return 0
+		}
+		switch r {
+		case '1', '7':
+			return 'H'
+		case '4', '8':
+			return 'F'
+		case '3':
+			return '~'
+		}
+	}
+	return 0
+}
+
+// refresh 重绘当前行并把光标放到 pos 处
+func (e *lineEditor) refresh(prompt string, buf []rune, pos int) {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(prompt)
+	b.WriteString(string(buf))
+	b.WriteString("\x1b[0K\r")
+	if col := utf8.RuneCountInString(prompt) + pos; col > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", col)
+	}
+	fmt.Fprint(e.out, b.String())
+}
+
+// addHistory 追加一条历史记录并写回历史文件，与上一条相同时忽略
+func (e *lineEditor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > historyMaxLen {
+		e.history = e.history[len(e.history)-historyMaxLen:]
+	}
+	e.saveHistory()
+}
+
+func (e *lineEditor) loadHistory() {
+	if e.histFile == "" {
+		return
+	}
+	data, err := os.ReadFile(e.histFile)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > historyMaxLen {
+		e.history = e.history[len(e.history)-historyMaxLen:]
+	}
+}
+
+func (e *lineEditor) saveHistory() {
+	if e.histFile == "" {
+		return
+	}
+	data := strings.Join(e.history, "\n") + "\n"
+	os.WriteFile(e.histFile, []byte(data), 0600)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(keys string) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(strings.NewReader(keys)), out: io.Discard}
+}
+
+func TestLineEditorEditing(t *testing.T) {
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"get foo\r", "get foo"},
+		{"gt\x1b[De\r", "get"},             // 左移后插入
+		{"abc\x7f\x7fx\r", "ax"},           // 退格
+		{"foo bar\x17baz\r", "foo baz"},    // Ctrl-W
+		{"hello\x01J\x05!\r", "Jhello!"},   // Ctrl-A / Ctrl-E
+		{"hello\x1b[D\x1b[D\x0b\r", "hel"}, // Ctrl-K
+		{"hello\x01\x1b[3~\r", "ello"},     // Delete
+	}
+
+	for _, tt := range tests {
+		got, err := newTestEditor(tt.keys).edit("> ")
+		if err != nil {
+			t.Fatalf("edit(%q): %v", tt.keys, err)
+		}
+		if got != tt.want {
+			t.Errorf("edit(%q) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestLineEditorControlKeys(t *testing.T) {
+	if _, err := newTestEditor("\x04").edit("> "); err != io.EOF {
+		t.Errorf("Ctrl-D on empty line: got %v, want EOF", err)
+	}
+	if _, err := newTestEditor("abc\x03").edit("> "); err != errInterrupted {
+		t.Errorf("Ctrl-C: got %v, want errInterrupted", err)
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+
+	e := newTestEditor("")
+	e.histFile = histFile
+	e.addHistory("set a 1")
+	e.addHistory("get a")
+	e.addHistory("get a")
+
+	loaded := newTestEditor("\x1b[A\x1b[A\r")
+	loaded.histFile = histFile
+	loaded.loadHistory()
+	if len(loaded.history) != 2 {
+		t.Fatalf("history = %q, want 2 entries", loaded.history)
+	}
+
+	got, err := loaded.edit("> ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "set a 1" {
+		t.Errorf("two steps back = %q, want %q", got, "set a 1")
+	}
+
+	// 翻到历史再翻回来时恢复正在编辑的内容
+	loaded.in = bufio.NewReader(strings.NewReader("draft\x10\x0e\r"))
+	if got, _ := loaded.edit("> "); got != "draft" {
+		t.Errorf("back to draft = %q, want %q", got, "draft")
+	}
+}
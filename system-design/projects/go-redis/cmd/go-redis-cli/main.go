@@ -0,0 +1,198 @@
+// go-redis-cli 是 go-redis 的命令行客户端，用法与 redis-cli 相同：
+//
+//	go-redis-cli [options] [cmd [arg [arg ...]]]
+//
+// 不带命令时进入交互模式（REPL），支持行编辑与历史记录；
+// 输出到终端时按 redis-cli 的格式展示回复，否则（或指定 --raw）输出原始内容。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	var (
+		host     string
+		port     int
+		repeat   int
+		interval float64
+		raw      bool
+		noRaw    bool
+		pipe     bool
+		scan     bool
+		pattern  string
+		bigkeys  bool
+		memkeys  bool
+		samples  int
+	)
+
+	flag.StringVar(&host, "h", "127.0.0.1", "服务器地址")
+	flag.IntVar(&port, "p", 16379, "服务器端口")
+	flag.IntVar(&repeat, "r", 1, "命令执行次数，-1 表示一直执行")
+	flag.Float64Var(&interval, "i", 0, "配合 -r 使用，每次执行之间等待的秒数，可以是小数")
+	flag.BoolVar(&raw, "raw", false, "输出原始回复（输出不是终端时的默认行为）")
+	flag.BoolVar(&noRaw, "no-raw", false, "即使输出不是终端也按终端格式输出")
+	flag.BoolVar(&pipe, "pipe", false, "从标准输入读取 RESP 格式的命令批量导入")
+	flag.BoolVar(&scan, "scan", false, "列出所有键，可以配合 --pattern 使用")
+	flag.StringVar(&pattern, "pattern", "*", "配合 --scan 使用的键模式")
+	flag.BoolVar(&bigkeys, "bigkeys", false, "遍历键空间，找出每种类型中元素最多（字符串最长）的键")
+	flag.BoolVar(&memkeys, "memkeys", false, "遍历键空间，找出每种类型中占用内存最多的键")
+	flag.IntVar(&samples, "memkeys-samples", 0, "配合 --memkeys 使用，MEMORY USAGE 的 SAMPLES 参数，指定时即开启 --memkeys")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [cmd [arg [arg ...]]]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// 协议解析器在 Debug 级别会输出日志
+	logger.SetLevel(logrus.WarnLevel)
+
+	c := &cli{
+		addr: fmt.Sprintf("%s:%d", host, port),
+		raw:  (raw || !isatty(os.Stdout)) && !noRaw,
+		out:  os.Stdout,
+	}
+	defer c.close()
+
+	switch {
+	case pipe:
+		replies, errs, err := c.pipe(os.Stdin)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "errors: %d, replies: %d\n", errs, replies)
+		if errs > 0 {
+			os.Exit(1)
+		}
+	case bigkeys || memkeys || samples > 0:
+		if err := c.bigKeys(memkeys || samples > 0, samples, time.Duration(interval*float64(time.Second))); err != nil {
+			fatal(err)
+		}
+	case scan:
+		if err := c.scan(pattern); err != nil {
+			fatal(err)
+		}
+	case flag.NArg() > 0:
+		if err := c.run(flag.Args(), repeat, time.Duration(interval*float64(time.Second))); err != nil {
+			fatal(err)
+		}
+	case !isatty(os.Stdin):
+		// 标准输入不是终端时逐行执行其中的命令，不显示提示符
+		if err := c.runLines(os.Stdin); err != nil {
+			fatal(err)
+		}
+	default:
+		c.repl(historyFile())
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func isatty(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// historyFile 返回历史文件路径，可以通过 GOREDISCLI_HISTFILE 环境变量修改
+func historyFile() string {
+	if path, ok := os.LookupEnv("GOREDISCLI_HISTFILE"); ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".go-redis-cli_history")
+}
+
+// repl 是交互模式的主循环
+func (c *cli) repl(histFile string) {
+	if err := c.connect(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	editor := newLineEditor(os.Stdin, os.Stdout, histFile)
+	for {
+		prompt := c.addr + "> "
+		if c.conn == nil {
+			prompt = "not connected> "
+		}
+
+		line, err := editor.readLine(prompt)
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		editor.addHistory(line)
+
+		if quit := c.execLine(line); quit {
+			return
+		}
+	}
+}
+
+// runLines 逐行执行 in 中的命令
+func (c *cli) runLines(in io.Reader) error {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if quit := c.execLine(line); quit {
+			break
+		}
+	}
+	return nil
+}
+
+// execLine 执行一行输入，返回是否退出
+// 与 redis-cli 一样，以数字开头的行表示重复执行，例如 "3 INCR counter"
+func (c *cli) execLine(line string) bool {
+	args, err := protocol.SplitArgs(line)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid argument(s)")
+		return false
+	}
+	if len(args) == 0 {
+		return false
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "quit", "exit":
+		return true
+	case "clear":
+		fmt.Fprint(c.out, "\x1b[H\x1b[2J")
+		return false
+	}
+
+	repeat := 1
+	if n, err := strconv.Atoi(args[0]); err == nil && len(args) > 1 {
+		repeat, args = n, args[1:]
+	}
+
+	if err := c.run(args, repeat, 0); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package glob
+
+// Match 实现 Redis 风格的 glob 匹配（与 stringmatchlen 行为一致）
+// 支持的语法：
+// - "*" 匹配任意长度的字符串
+// - "?" 匹配单个字符
+// - "[abc]"、"[a-z]"、"[^a]" 字符集合、范围和取反
+// - "\x" 转义特殊字符
+func Match(pattern, str string) bool {
+	p, s := 0, 0
+
+	for p < len(pattern) {
+		switch pattern[p] {
+		case '*':
+			// 合并连续的 *
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true
+			}
+			for i := s; i <= len(str); i++ {
+				if Match(pattern[p+1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if s >= len(str) {
+				return false
+			}
+			s++
+
+		case '[':
+			if s >= len(str) {
+				return false
+			}
+			p++
+			not := p < len(pattern) && pattern[p] == '^'
+			if not {
+				p++
+			}
+
+			matched := false
+			for p < len(pattern) && pattern[p] != ']' {
+				switch {
+				case pattern[p] == '\\' && p+1 < len(pattern):
+					p++
+					if pattern[p] == str[s] {
+						matched = true
+					}
+				case p+2 < len(pattern) && pattern[p+1] == '-':
+					lo, hi := pattern[p], pattern[p+2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					if str[s] >= lo && str[s] <= hi {
+						matched = true
+					}
+					p += 2
+				default:
+					if pattern[p] == str[s] {
+						matched = true
+					}
+				}
+				p++
+			}
+			if not {
+				matched = !matched
+			}
+			if !matched {
+				return false
+			}
+			s++
+
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+			fallthrough
+
+		default:
+			if s >= len(str) || pattern[p] != str[s] {
+				return false
+			}
+			s++
+		}
+
+		p++
+	}
+
+	return s == len(str)
+}
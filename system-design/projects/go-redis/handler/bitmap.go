@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strconv"
+	"strings"
+)
+
+// parseBitOffset 解析位偏移量，范围是 [0, 2^32)
+func parseBitOffset(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n >= store.MaxBitOffset {
+		return 0, store.ErrBitOffset
+	}
+	return n, nil
+}
+
+// parseBitFieldOffset 解析 BITFIELD 的偏移量，"#N" 表示第 N 个 bits 位宽的整数
+func parseBitFieldOffset(s string, bits uint) (int64, error) {
+	multiply := strings.HasPrefix(s, "#")
+	n, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+	if err != nil || n < 0 {
+		return 0, store.ErrBitOffset
+	}
+	if multiply {
+		if n > (store.MaxBitOffset-1)/int64(bits) {
+			return 0, store.ErrBitOffset
+		}
+		n *= int64(bits)
+	}
+	if n >= store.MaxBitOffset {
+		return 0, store.ErrBitOffset
+	}
+	return n, nil
+}
+
+// parseBitRange 解析 BITCOUNT/BITPOS 的 start [end [BYTE|BIT]]
+func parseBitRange(args []protocol.Value) (*store.BitRange, *protocol.Value) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if len(args) > 3 {
+		return nil, protocol.Error("ERR syntax error")
+	}
+
+	r := &store.BitRange{}
+	var err error
+	if r.Start, err = strconv.ParseInt(args[0].Str, 10, 64); err != nil {
+		return nil, protocol.Error("ERR value is not an integer or out of range")
+	}
+	if len(args) >= 2 {
+		if r.End, err = strconv.ParseInt(args[1].Str, 10, 64); err != nil {
+			return nil, protocol.Error("ERR value is not an integer or out of range")
+		}
+		r.HasEnd = true
+	}
+	if len(args) == 3 {
+		switch strings.ToUpper(args[2].Str) {
+		case "BYTE":
+		case "BIT":
+			r.Bit = true
+		default:
+			return nil, protocol.Error("ERR syntax error")
+		}
+	}
+	return r, nil
+}
+
+// *****
+type SetBitHandler struct {
+	db *store.Store
+}
+
+func NewSetBitHandler(db *store.Store) *SetBitHandler {
+	return &SetBitHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 SETBIT 命令
+// SETBIT key offset value
+func (h *SetBitHandler) Handle(args []protocol.Value) *protocol.Value {
+	key := args[0].Str
+	offset, err := parseBitOffset(args[1].Str)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if args[2].Str != "0" && args[2].Str != "1" {
+		return protocol.Error("ERR bit is not an integer or out of range")
+	}
+
+	old, err := h.db.SetBit(key, offset, args[2].Str == "1")
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	h.db.Notify(store.NotifyString, "setbit", key)
+
+	return protocol.Integer(int64(old))
+}
+
+// *****
+type GetBitHandler struct {
+	db *store.Store
+}
+
+func NewGetBitHandler(db *store.Store) *GetBitHandler {
+	return &GetBitHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 GETBIT 命令
+// GETBIT key offset
+func (h *GetBitHandler) Handle(args []protocol.Value) *protocol.Value {
+	offset, err := parseBitOffset(args[1].Str)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	bit, err := h.db.GetBit(args[0].Str, offset)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	return protocol.Integer(int64(bit))
+}
+
+// *****
+type BitCountHandler struct {
+	db *store.Store
+}
+
+func NewBitCountHandler(db *store.Store) *BitCountHandler {
+	return &BitCountHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 BITCOUNT 命令
+// BITCOUNT key [start end [BYTE|BIT]]
+func (h *BitCountHandler) Handle(args []protocol.Value) *protocol.Value {
+	if len(args) == 2 {
+		// 只有 start 没有 end
+		return protocol.Error("ERR syntax error")
+	}
+
+	r, errResp := parseBitRange(args[1:])
+	if errResp != nil {
+		return errResp
+	}
+
+	n, err := h.db.BitCount(args[0].Str, r)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	return protocol.Integer(n)
+}
+
+// *****
+type BitPosHandler struct {
+	db *store.Store
+}
+
+func NewBitPosHandler(db *store.Store) *BitPosHandler {
+	return &BitPosHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 BITPOS 命令
+// BITPOS key bit [start [end [BYTE|BIT]]]
+func (h *BitPosHandler) Handle(args []protocol.Value) *protocol.Value {
+	if args[1].Str != "0" && args[1].Str != "1" {
+		return protocol.Error("ERR The bit argument must be 1 or 0.")
+	}
+	bit := 0
+	if args[1].Str == "1" {
+		bit = 1
+	}
+
+	r, errResp := parseBitRange(args[2:])
+	if errResp != nil {
+		return errResp
+	}
+
+	pos, err := h.db.BitPos(args[0].Str, bit, r)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	return protocol.Integer(pos)
+}
+
+// *****
+type BitOpHandler struct {
+	db *store.Store
+}
+
+func NewBitOpHandler(db *store.Store) *BitOpHandler {
+	return &BitOpHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 BITOP 命令
+// BITOP AND|OR|XOR|NOT destkey key [key ...]
+func (h *BitOpHandler) Handle(args []protocol.Value) *protocol.Value {
+	var op store.BitOperation
+	switch strings.ToUpper(args[0].Str) {
+	case "AND":
+		op = store.BitOpAnd
+	case "OR":
+		op = store.BitOpOr
+	case "XOR":
+		op = store.BitOpXor
+	case "NOT":
+		op = store.BitOpNot
+		if len(args) != 3 {
+			return protocol.Error("ERR BITOP NOT must be called with a single source key.")
+		}
+	default:
+		return protocol.Error("ERR syntax error")
+	}
+
+	dest := args[1].Str
+	keys := make([]string, len(args)-2)
+	for i, arg := range args[2:] {
+		keys[i] = arg.Str
+	}
+
+	n, deleted, err := h.db.BitOp(op, dest, keys)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if n > 0 {
+		h.db.Notify(store.NotifyString, "set", dest)
+	} else if deleted {
+		h.db.Notify(store.NotifyGeneric, "del", dest)
+	}
+
+	return protocol.Integer(n)
+}
+
+// *****
+type BitFieldHandler struct {
+	db       *store.Store
+	readOnly bool // BITFIELD_RO 只允许 GET
+}
+
+func NewBitFieldHandler(db *store.Store) *BitFieldHandler {
+	return &BitFieldHandler{
+		db: db,
+	}
+}
+
+func NewBitFieldROHandler(db *store.Store) *BitFieldHandler {
+	return &BitFieldHandler{
+		db:       db,
+		readOnly: true,
+	}
+}
+
+// Handle 处理 BITFIELD 和 BITFIELD_RO 命令
+// BITFIELD key [GET type offset] [SET type offset value] [INCRBY type offset increment] [OVERFLOW WRAP|SAT|FAIL] ...
+// BITFIELD_RO key [GET type offset ...]
+func (h *BitFieldHandler) Handle(args []protocol.Value) *protocol.Value {
+	key := args[0].Str
+	ops, errResp := h.parseOps(args[1:])
+	if errResp != nil {
+		return errResp
+	}
+
+	results, changed, err := h.db.BitField(key, ops)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if changed {
+		h.db.Notify(store.NotifyString, "setbit", key)
+	}
+
+	values := make([]protocol.Value, len(results))
+	for i, r := range results {
+		if r == nil {
+			values[i] = *protocol.NullBulkString()
+		} else {
+			values[i] = *protocol.Integer(*r)
+		}
+	}
+	return protocol.Array(values)
+}
+
+// parseOps 解析子命令，OVERFLOW 对之后的 SET 和 INCRBY 生效
+func (h *BitFieldHandler) parseOps(args []protocol.Value) ([]store.BitFieldOp, *protocol.Value) {
+	var ops []store.BitFieldOp
+	overflow := store.OverflowWrap
+
+	for i := 0; i < len(args); {
+		sub := strings.ToUpper(args[i].Str)
+
+		if sub == "OVERFLOW" {
+			if h.readOnly {
+				return nil, protocol.Error("ERR BITFIELD_RO only supports the GET subcommand")
+			}
+			if i+1 >= len(args) {
+				return nil, protocol.Error("ERR syntax error")
+			}
+			switch strings.ToUpper(args[i+1].Str) {
+			case "WRAP":
+				overflow = store.OverflowWrap
+			case "SAT":
+				overflow = store.OverflowSat
+			case "FAIL":
+				overflow = store.OverflowFail
+			default:
+				return nil, protocol.Error("ERR Invalid OVERFLOW type specified")
+			}
+			i += 2
+			continue
+		}
+
+		op := store.BitFieldOp{Overflow: overflow}
+		argc := 3
+		switch sub {
+		case "GET":
+			op.Kind = store.BitFieldGet
+		case "SET":
+			op.Kind = store.BitFieldSet
+			argc = 4
+		case "INCRBY":
+			op.Kind = store.BitFieldIncrBy
+			argc = 4
+		default:
+			return nil, protocol.Error("ERR syntax error")
+		}
+		if h.readOnly && op.Kind != store.BitFieldGet {
+			return nil, protocol.Error("ERR BITFIELD_RO only supports the GET subcommand")
+		}
+		if i+argc > len(args) {
+			return nil, protocol.Error("ERR syntax error")
+		}
+
+		var err error
+		if op.Type, err = store.ParseBitFieldType(args[i+1].Str); err != nil {
+			return nil, protocol.Error(err.Error())
+		}
+		if op.Offset, err = parseBitFieldOffset(args[i+2].Str, op.Type.Bits); err != nil {
+			return nil, protocol.Error(err.Error())
+		}
+		if argc == 4 {
+			if op.Value, err = strconv.ParseInt(args[i+3].Str, 10, 64); err != nil {
+				return nil, protocol.Error("ERR value is not an integer or out of range")
+			}
+		}
+
+		ops = append(ops, op)
+		i += argc
+	}
+	return ops, nil
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"testing"
+)
+
+func TestBitmapCommands(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	// 按天记录活跃用户：用户 ID 作为偏移量
+	for _, id := range []string{"1", "7", "100"} {
+		if resp := execCommand(r, "SETBIT", "dau:mon", id, "1"); resp.Int != 0 {
+			t.Fatalf("SETBIT %s: %+v", id, resp)
+		}
+	}
+	execCommand(r, "SETBIT", "dau:tue", "7", "1")
+	execCommand(r, "SETBIT", "dau:tue", "8", "1")
+
+	tests := []struct {
+		args []string
+		want int64
+	}{
+		{[]string{"GETBIT", "dau:mon", "7"}, 1},
+		{[]string{"GETBIT", "dau:mon", "8"}, 0},
+		{[]string{"SETBIT", "dau:mon", "7", "0"}, 1},
+		{[]string{"SETBIT", "dau:mon", "7", "1"}, 0},
+		{[]string{"BITCOUNT", "dau:mon"}, 3},
+		{[]string{"BITCOUNT", "dau:mon", "0", "0"}, 2},
+		{[]string{"BITCOUNT", "dau:mon", "0", "6", "BIT"}, 1},
+		{[]string{"BITPOS", "dau:mon", "1"}, 1},
+		{[]string{"BITPOS", "dau:mon", "1", "1"}, 100},
+		{[]string{"BITPOS", "dau:mon", "0", "0", "0"}, 0},
+		{[]string{"BITOP", "AND", "dau:both", "dau:mon", "dau:tue"}, 13},
+		{[]string{"BITCOUNT", "dau:both"}, 1},
+		{[]string{"BITOP", "OR", "dau:any", "dau:mon", "dau:tue"}, 13},
+		{[]string{"BITCOUNT", "dau:any"}, 4},
+	}
+	for _, tt := range tests {
+		resp := execCommand(r, tt.args...)
+		if resp.Type != protocol.IntType || resp.Int != tt.want {
+			t.Errorf("%v = %+v, want %d", tt.args, resp, tt.want)
+		}
+	}
+
+	// SET 写入的普通字符串同样可以按位操作
+	execCommand(r, "SET", "s", "a") // 0x61
+	if resp := execCommand(r, "BITCOUNT", "s"); resp.Int != 3 {
+		t.Errorf("BITCOUNT on SET value: %+v", resp)
+	}
+	execCommand(r, "SETBIT", "s", "6", "1")
+	if resp := execCommand(r, "GET", "s"); resp.Str != "c" {
+		t.Errorf("GET after SETBIT: %+v", resp)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"SETBIT", "k", "-1", "1"}, "ERR bit offset is not an integer or out of range"},
+		{[]string{"SETBIT", "k", "4294967296", "1"}, "ERR bit offset is not an integer or out of range"},
+		{[]string{"SETBIT", "k", "0", "2"}, "ERR bit is not an integer or out of range"},
+		{[]string{"BITCOUNT", "k", "0"}, "ERR syntax error"},
+		{[]string{"BITCOUNT", "k", "0", "1", "WORD"}, "ERR syntax error"},
+		{[]string{"BITPOS", "k", "2"}, "ERR The bit argument must be 1 or 0."},
+		{[]string{"BITOP", "NOT", "d", "a", "b"}, "ERR BITOP NOT must be called with a single source key."},
+		{[]string{"BITOP", "NAND", "d", "a"}, "ERR syntax error"},
+	} {
+		if resp := execCommand(r, tt.args...); resp.Str != tt.err {
+			t.Errorf("%v = %+v, want %q", tt.args, resp, tt.err)
+		}
+	}
+}
+
+func TestBitFieldCommand(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	resp := execCommand(r, "BITFIELD", "bf",
+		"SET", "u8", "#1", "255",
+		"GET", "u8", "8",
+		"INCRBY", "u8", "#1", "10",
+		"OVERFLOW", "SAT", "INCRBY", "u8", "#1", "1000",
+		"OVERFLOW", "FAIL", "INCRBY", "u8", "#1", "1",
+		"GET", "i8", "8")
+	want := []interface{}{int64(0), int64(255), int64(9), int64(255), nil, int64(-1)}
+	if len(resp.Array) != len(want) {
+		t.Fatalf("BITFIELD: %+v", resp)
+	}
+	for i, w := range want {
+		v := resp.Array[i]
+		if w == nil {
+			if !v.IsNull {
+				t.Errorf("result %d = %+v, want nil", i, v)
+			}
+		} else if v.Type != protocol.IntType || v.Int != w.(int64) {
+			t.Errorf("result %d = %+v, want %d", i, v, w)
+		}
+	}
+
+	if resp := execCommand(r, "BITFIELD_RO", "bf", "GET", "u16", "0"); len(resp.Array) != 1 || resp.Array[0].Int != 255 {
+		t.Errorf("BITFIELD_RO: %+v", resp)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"BITFIELD", "bf", "GET", "u64", "0"}, store.ErrBitFieldType.Error()},
+		{[]string{"BITFIELD", "bf", "GET", "u8"}, "ERR syntax error"},
+		{[]string{"BITFIELD", "bf", "OVERFLOW", "CLAMP"}, "ERR Invalid OVERFLOW type specified"},
+		{[]string{"BITFIELD", "bf", "SET", "i8", "0", "x"}, "ERR value is not an integer or out of range"},
+		{[]string{"BITFIELD", "bf", "GET", "i8", "#-1"}, "ERR bit offset is not an integer or out of range"},
+		{[]string{"BITFIELD_RO", "bf", "SET", "i8", "0", "1"}, "ERR BITFIELD_RO only supports the GET subcommand"},
+	} {
+		if resp := execCommand(r, tt.args...); resp.Str != tt.err {
+			t.Errorf("%v = %+v, want %q", tt.args, resp, tt.err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"sort"
+	"strings"
+)
+
+// *****
+type CommandHandler struct {
+	router *Router
+}
+
+func NewCommandHandler(r *Router) *CommandHandler {
+	return &CommandHandler{
+		router: r,
+	}
+}
+
+// Handle 处理 COMMAND 命令，回复的格式与 Redis 7 相同，集群客户端据此得到键的位置和只读命令
+// COMMAND | COMMAND COUNT | COMMAND INFO [name ...] | COMMAND DOCS [name ...] | COMMAND GETKEYS command [arg ...]
+func (h *CommandHandler) Handle(args []protocol.Value) *protocol.Value {
+	if len(args) == 0 {
+		return h.info(nil)
+	}
+
+	sub := strings.ToUpper(args[0].Str)
+	switch sub {
+	case "COUNT":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'command|count' command")
+		}
+		return protocol.Integer(int64(len(h.router.specs)))
+	case "INFO":
+		return h.info(args[1:])
+	case "DOCS":
+		return h.docs(args[1:])
+	case "GETKEYS":
+		if len(args) < 2 {
+			return protocol.Error("ERR wrong number of arguments for 'command|getkeys' command")
+		}
+		return h.getKeys(args[1:])
+	default:
+		return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try COMMAND HELP.")
+	}
+}
+
+// specs 返回 names 对应的命令，names 为空时返回所有命令（按名字排序），不存在的命令对应 nil
+func (h *CommandHandler) specs(names []protocol.Value) []*commandSpec {
+	if len(names) == 0 {
+		all := make([]*commandSpec, 0, len(h.router.specs))
+		for _, c := range h.router.specs {
+			all = append(all, c)
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+		return all
+	}
+
+	specs := make([]*commandSpec, len(names))
+	for i, name := range names {
+		specs[i] = h.router.specs[strings.ToUpper(name.Str)]
+	}
+	return specs
+}
+
+func (h *CommandHandler) info(names []protocol.Value) *protocol.Value {
+	specs := h.specs(names)
+	values := make([]protocol.Value, len(specs))
+	for i, c := range specs {
+		if c == nil {
+			values[i] = *protocol.NullArray()
+			continue
+		}
+		values[i] = *commandInfo(c)
+	}
+	return protocol.Array(values)
+}
+
+// commandInfo 返回一个命令的 COMMAND INFO：名字、arity、标志、第一个键、最后一个键、步长、ACL 分类、
+// 提示、键的说明和子命令，后三项是 Redis 7 增加的
+func commandInfo(c *commandSpec) *protocol.Value {
+	return protocol.Array([]protocol.Value{
+		*protocol.BulkString(c.name),
+		*protocol.Integer(int64(c.arity)),
+		*statusArray(c.flags),
+		*protocol.Integer(int64(c.firstKey)),
+		*protocol.Integer(int64(c.lastKey)),
+		*protocol.Integer(int64(c.keyStep)),
+		*statusArray(c.categories),
+		*protocol.Array([]protocol.Value{}),
+		*keySpecs(c),
+		*protocol.Array([]protocol.Value{}),
+	})
+}
+
+// keySpecs 按 Redis 7 的格式描述键的位置：从哪里开始找（begin_search），以及找到之后取哪些参数（find_keys）
+func keySpecs(c *commandSpec) *protocol.Value {
+	var beginSearch, findKeys *protocol.Value
+	switch {
+	case c.keyword != "":
+		beginSearch = fieldArray("type", protocol.BulkString("keyword"), "spec",
+			fieldArray("keyword", protocol.BulkString(c.keyword), "startfrom", protocol.Integer(int64(c.keywordFrom))))
+		// STREAMS 之后的参数前一半是键
+		findKeys = fieldArray("type", protocol.BulkString("range"), "spec",
+			fieldArray("lastkey", protocol.Integer(-1), "keystep", protocol.Integer(1), "limit", protocol.Integer(2)))
+	case c.firstKey > 0:
+		lastKey := c.lastKey
+		if lastKey > 0 {
+			lastKey -= c.firstKey
+		}
+		beginSearch = fieldArray("type", protocol.BulkString("index"), "spec",
+			fieldArray("index", protocol.Integer(int64(c.firstKey))))
+		findKeys = fieldArray("type", protocol.BulkString("range"), "spec",
+			fieldArray("lastkey", protocol.Integer(int64(lastKey)), "keystep", protocol.Integer(int64(c.keyStep)), "limit", protocol.Integer(0)))
+	default:
+		return protocol.Array([]protocol.Value{})
+	}
+
+	flags := []string{"RW"}
+	if c.hasFlag("readonly") {
+		flags = []string{"RO", "ACCESS"}
+	}
+	return protocol.Array([]protocol.Value{
+		*fieldArray("flags", statusArray(flags), "begin_search", beginSearch, "find_keys", findKeys),
+	})
+}
+
+func (h *CommandHandler) docs(names []protocol.Value) *protocol.Value {
+	var values []protocol.Value
+	for _, c := range h.specs(names) {
+		// 不存在的命令不出现在回复中
+		if c == nil {
+			continue
+		}
+		values = append(values, *protocol.BulkString(c.name), *fieldArray(
+			"summary", protocol.BulkString(c.summary),
+			"since", protocol.BulkString(c.since),
+			"group", protocol.BulkString(c.group)))
+	}
+	return protocol.Array(values)
+}
+
+func (h *CommandHandler) getKeys(argv []protocol.Value) *protocol.Value {
+	c := h.router.specs[strings.ToUpper(argv[0].Str)]
+	if c == nil {
+		return protocol.Error("ERR Invalid command specified")
+	}
+	if !c.checkArity(len(argv)) {
+		return protocol.Error("ERR Invalid number of arguments specified for command")
+	}
+
+	args := make([]string, len(argv)-1)
+	for i, arg := range argv[1:] {
+		args[i] = arg.Str
+	}
+	keys := c.keys(args)
+	if len(keys) == 0 {
+		return protocol.Error("ERR The command has no key arguments")
+	}
+	return stringArray(keys)
+}
+
+// statusArray 返回由状态字符串组成的数组，COMMAND 的标志和 ACL 分类使用这种格式
+func statusArray(strs []string) *protocol.Value {
+	values := make([]protocol.Value, len(strs))
+	for i, s := range strs {
+		values[i] = *protocol.SimpleString(s)
+	}
+	return protocol.Array(values)
+}
+
+// fieldArray 返回 field1, value1, field2, value2... 组成的数组，RESP2 用它表示映射
+func fieldArray(kv ...interface{}) *protocol.Value {
+	values := make([]protocol.Value, 0, len(kv))
+	for i := 0; i < len(kv); i += 2 {
+		values = append(values, *protocol.BulkString(kv[i].(string)), *kv[i+1].(*protocol.Value))
+	}
+	return protocol.Array(values)
+}
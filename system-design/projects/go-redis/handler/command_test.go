@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func strs(v *protocol.Value) []string {
+	res := make([]string, len(v.Array))
+	for i, e := range v.Array {
+		res[i] = e.Str
+	}
+	return res
+}
+
+func TestCommandInfo(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	all := execCommand(r, "COMMAND")
+	count := execCommand(r, "COMMAND", "COUNT")
+	if count.Type != protocol.IntType || int(count.Int) != len(all.Array) || count.Int == 0 {
+		t.Fatalf("COMMAND COUNT = %+v, COMMAND returned %d", count, len(all.Array))
+	}
+
+	resp := execCommand(r, "COMMAND", "INFO", "get", "NOSUCH", "RENAME")
+	if len(resp.Array) != 3 {
+		t.Fatalf("COMMAND INFO = %+v", resp)
+	}
+	get := resp.Array[0].Array
+	if len(get) != 10 || get[0].Str != "get" || get[1].Int != 2 {
+		t.Fatalf("COMMAND INFO get = %+v", get)
+	}
+	if flags := strs(&get[2]); !reflect.DeepEqual(flags, []string{"readonly", "fast"}) || get[2].Array[0].Type != protocol.StringType {
+		t.Errorf("get flags = %q", flags)
+	}
+	if get[3].Int != 1 || get[4].Int != 1 || get[5].Int != 1 {
+		t.Errorf("get key positions = %d %d %d", get[3].Int, get[4].Int, get[5].Int)
+	}
+	if cats := strs(&get[6]); !reflect.DeepEqual(cats, []string{"@read", "@string", "@fast"}) {
+		t.Errorf("get categories = %q", cats)
+	}
+	if !resp.Array[1].IsNull {
+		t.Errorf("unknown command should be null, got %+v", resp.Array[1])
+	}
+	if rename := resp.Array[2].Array; rename[3].Int != 1 || rename[4].Int != 2 || rename[5].Int != 1 {
+		t.Errorf("rename key positions = %+v", rename[3:6])
+	}
+
+	// 键的位置不固定的命令用关键字描述
+	xread := execCommand(r, "COMMAND", "INFO", "xread").Array[0].Array
+	if !strings.Contains(strings.Join(strs(&xread[2]), " "), "movablekeys") || xread[3].Int != 0 {
+		t.Errorf("xread = %+v", xread)
+	}
+	spec := xread[8].Array[0].Array
+	if spec[2].Str != "begin_search" || spec[3].Array[1].Str != "keyword" || spec[3].Array[3].Array[1].Str != "STREAMS" {
+		t.Errorf("xread key spec = %+v", spec)
+	}
+}
+
+func TestCommandGetKeys(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	tests := []struct {
+		args []string
+		keys []string
+		err  string
+	}{
+		{[]string{"SET", "a", "1"}, []string{"a"}, ""},
+		{[]string{"del", "a", "b", "c"}, []string{"a", "b", "c"}, ""},
+		{[]string{"BITOP", "AND", "dest", "s1", "s2"}, []string{"dest", "s1", "s2"}, ""},
+		{[]string{"OBJECT", "ENCODING", "k"}, []string{"k"}, ""},
+		{[]string{"XREAD", "COUNT", "1", "STREAMS", "s1", "s2", "0", "0"}, []string{"s1", "s2"}, ""},
+		{[]string{"XREADGROUP", "GROUP", "g", "c", "STREAMS", "s", ">"}, []string{"s"}, ""},
+		{[]string{"NOSUCH", "a"}, nil, "ERR Invalid command specified"},
+		{[]string{"GET"}, nil, "ERR Invalid number of arguments specified for command"},
+		{[]string{"PING"}, nil, "ERR The command has no key arguments"},
+	}
+	for _, tt := range tests {
+		resp := execCommand(r, append([]string{"COMMAND", "GETKEYS"}, tt.args...)...)
+		if tt.err != "" {
+			if resp.Type != protocol.ErrorType || resp.Str != tt.err {
+				t.Errorf("GETKEYS %v = %+v, want error %q", tt.args, resp, tt.err)
+			}
+			continue
+		}
+		if got := strs(resp); !reflect.DeepEqual(got, tt.keys) {
+			t.Errorf("GETKEYS %v = %q, want %q", tt.args, got, tt.keys)
+		}
+	}
+}
+
+func TestCommandDocs(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	resp := execCommand(r, "COMMAND", "DOCS", "get", "nosuch")
+	if len(resp.Array) != 2 || resp.Array[0].Str != "get" {
+		t.Fatalf("COMMAND DOCS = %+v", resp)
+	}
+	docs := strs(&resp.Array[1])
+	if docs[0] != "summary" || docs[3] != "1.0.0" || docs[5] != "string" {
+		t.Errorf("get docs = %q", docs)
+	}
+}
+
+func TestRouteChecksArity(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	// 处理器本身不再检查参数个数，参数不足的命令必须在 Route 中被拒绝
+	for _, args := range [][]string{
+		{"GET"}, {"GET", "a", "b"}, {"SET", "a"}, {"XADD", "s", "*"}, {"DEL"},
+		{"XLEN"}, {"RANDOMKEY", "x"}, {"SETBIT", "k", "1"}, {"BITFIELD_RO"}, {"RENAMENX", "a"},
+		{"XREADGROUP", "GROUP", "g", "c", "STREAMS", "s"}, {"PUBLISH", "ch"}, {"OBJECT"},
+	} {
+		want := "ERR wrong number of arguments for '" + strings.ToLower(args[0]) + "' command"
+		if resp := execCommand(r, args...); resp.Type != protocol.ErrorType || resp.Str != want {
+			t.Errorf("%v = %+v, want %q", args, resp, want)
+		}
+	}
+
+	// 被拒绝的调用单独统计
+	stats := execCommand(r, "INFO", "commandstats").Str
+	if !strings.Contains(stats, "cmdstat_get:calls=0,") || !strings.Contains(stats, "rejected_calls=2,failed_calls=0") {
+		t.Errorf("commandstats = %q", stats)
+	}
+
+	// 不在命令表中的命令不检查参数个数
+	r.Register("CUSTOM", NewPingHandler())
+	if resp := execCommand(r, "CUSTOM", "hello"); resp.Str != "hello" {
+		t.Errorf("CUSTOM = %+v", resp)
+	}
+}
@@ -0,0 +1,279 @@
+package handler
+
+import "strings"
+
+// 命令表
+//
+// 每个命令的元数据与 Redis 的 COMMAND INFO 相同：
+//   - arity 是包括命令名在内的参数个数，负数表示至少 -arity 个
+//   - firstKey/lastKey/keyStep 是键在参数中的位置（命令名是 0），lastKey 为负数时从末尾倒数，没有键时都是 0
+//   - 键的位置不固定的命令（XREAD 等）在 keyword 之后的参数中找键，并带有 movablekeys 标志
+//   - 带 no_multi 标志的命令不能放进事务，MULTI 之后入队时就拒绝
+//
+// arity 按本服务器实际支持的语法填写，例如 SET 还不支持 EX/NX 等选项，arity 是 3 而不是 Redis 的 -3
+
+type commandSpec struct {
+	name       string // 小写
+	arity      int
+	flags      []string
+	firstKey   int
+	lastKey    int
+	keyStep    int
+	categories []string
+
+	// keyword 不为空时键在 keyword 之后：从第 keywordFrom 个参数开始查找 keyword，
+	// 之后的参数前一半是键（XREAD 的 STREAMS key... id...）
+	keyword     string
+	keywordFrom int
+
+	group   string
+	since   string
+	summary string
+}
+
+// cmd 创建一条命令表的记录，flags 和 categories 以空格分隔
+func cmd(name string, arity int, flags string, firstKey, lastKey, keyStep int, categories, group, since, summary string) *commandSpec {
+	return &commandSpec{
+		name:       name,
+		arity:      arity,
+		flags:      strings.Fields(flags),
+		firstKey:   firstKey,
+		lastKey:    lastKey,
+		keyStep:    keyStep,
+		categories: strings.Fields(categories),
+		group:      group,
+		since:      since,
+		summary:    summary,
+	}
+}
+
+// movable 设置键位置不固定的命令查找键的关键字
+func (c *commandSpec) movable(keyword string, from int) *commandSpec {
+	c.keyword = keyword
+	c.keywordFrom = from
+	return c
+}
+
+var commandTable = make(map[string]*commandSpec)
+
+func init() {
+	for _, c := range []*commandSpec{
+		// connection
+		cmd("ping", -1, "fast", 0, 0, 0, "@fast @connection", "connection", "1.0.0",
+			"Returns the server's liveliness response."),
+		cmd("select", 2, "loading stale fast", 0, 0, 0, "@keyspace @fast", "connection", "1.0.0",
+			"Changes the selected database."),
+		cmd("client", -2, "noscript loading stale no_multi", 0, 0, 0, "@slow @connection", "connection", "2.4.0",
+			"A container for client connection commands."),
+
+		// string
+		cmd("set", 3, "write denyoom", 1, 1, 1, "@write @string @slow", "string", "1.0.0",
+			"Sets the string value of a key, ignoring its type. The key is created if it doesn't exist."),
+		cmd("get", 2, "readonly fast", 1, 1, 1, "@read @string @fast", "string", "1.0.0",
+			"Returns the string value of a key."),
+		cmd("strlen", 2, "readonly fast", 1, 1, 1, "@read @string @fast", "string", "2.2.0",
+			"Returns the length of a string value."),
+		cmd("incr", 2, "write denyoom fast", 1, 1, 1, "@write @string @fast", "string", "1.0.0",
+			"Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist."),
+		cmd("incrby", 3, "write denyoom fast", 1, 1, 1, "@write @string @fast", "string", "1.0.0",
+			"Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist."),
+
+		// generic
+		cmd("del", -2, "write", 1, -1, 1, "@keyspace @write @slow", "generic", "1.0.0",
+			"Deletes one or more keys."),
+		cmd("exists", 2, "readonly fast", 1, 1, 1, "@keyspace @read @fast", "generic", "1.0.0",
+			"Determines whether a key exists."),
+		cmd("keys", 2, "readonly", 0, 0, 0, "@keyspace @read @slow @dangerous", "generic", "1.0.0",
+			"Returns all key names that match a pattern."),
+		cmd("scan", -2, "readonly", 0, 0, 0, "@keyspace @read @slow", "generic", "2.8.0",
+			"Iterates over the key names in the database."),
+		cmd("expire", 3, "write fast", 1, 1, 1, "@keyspace @write @fast", "generic", "1.0.0",
+			"Sets the expiration time of a key in seconds."),
+		cmd("pexpire", 3, "write fast", 1, 1, 1, "@keyspace @write @fast", "generic", "2.6.0",
+			"Sets the expiration time of a key in milliseconds."),
+		cmd("ttl", 2, "readonly fast", 1, 1, 1, "@keyspace @read @fast", "generic", "1.0.0",
+			"Returns the expiration time in seconds of a key."),
+		cmd("pttl", 2, "readonly fast", 1, 1, 1, "@keyspace @read @fast", "generic", "2.6.0",
+			"Returns the expiration time in milliseconds of a key."),
+		cmd("persist", 2, "write fast", 1, 1, 1, "@keyspace @write @fast", "generic", "2.2.0",
+			"Removes the expiration time of a key."),
+		cmd("type", 2, "readonly fast", 1, 1, 1, "@keyspace @read @fast", "generic", "1.0.0",
+			"Determines the type of value stored at a key."),
+		cmd("rename", 3, "write", 1, 2, 1, "@keyspace @write @slow", "generic", "1.0.0",
+			"Renames a key and overwrites the destination."),
+		cmd("renamenx", 3, "write fast", 1, 2, 1, "@keyspace @write @fast", "generic", "1.0.0",
+			"Renames a key only when the target key name doesn't exist."),
+		cmd("copy", -3, "write denyoom", 1, 2, 1, "@keyspace @write @slow", "generic", "6.2.0",
+			"Copies the value of a key to a new key."),
+		cmd("dump", 2, "readonly", 1, 1, 1, "@keyspace @read @slow", "generic", "2.6.0",
+			"Returns a serialized representation of the value stored at a key."),
+		cmd("restore", -4, "write denyoom", 1, 1, 1, "@keyspace @write @slow @dangerous", "generic", "2.6.0",
+			"Creates a key from the serialized representation of a value."),
+		cmd("object", -2, "readonly", 2, 2, 1, "@keyspace @read @slow", "generic", "2.2.3",
+			"A container for object introspection commands."),
+		cmd("dbsize", 1, "readonly fast", 0, 0, 0, "@keyspace @read @fast", "server", "1.0.0",
+			"Returns the number of keys in the database."),
+		cmd("randomkey", 1, "readonly", 0, 0, 0, "@keyspace @read @slow", "generic", "1.0.0",
+			"Returns a random key name from the database."),
+		cmd("touch", -2, "readonly fast", 1, -1, 1, "@keyspace @read @fast", "generic", "3.2.1",
+			"Returns the number of existing keys out of those specified after updating the time they were last accessed."),
+		cmd("unlink", -2, "write fast", 1, -1, 1, "@keyspace @write @fast", "generic", "4.0.0",
+			"Asynchronously deletes one or more keys."),
+
+		// bitmap
+		cmd("setbit", 4, "write denyoom", 1, 1, 1, "@write @bitmap @slow", "bitmap", "2.2.0",
+			"Sets or clears the bit at offset of the string value. Creates the key if it doesn't exist."),
+		cmd("getbit", 3, "readonly fast", 1, 1, 1, "@read @bitmap @fast", "bitmap", "2.2.0",
+			"Returns a bit value by offset."),
+		cmd("bitcount", -2, "readonly", 1, 1, 1, "@read @bitmap @slow", "bitmap", "2.6.0",
+			"Counts the number of set bits (population counting) in a string."),
+		cmd("bitpos", -3, "readonly", 1, 1, 1, "@read @bitmap @slow", "bitmap", "2.8.7",
+			"Finds the first set (1) or clear (0) bit in a string."),
+		cmd("bitop", -4, "write denyoom", 2, -1, 1, "@write @bitmap @slow", "bitmap", "2.6.0",
+			"Performs bitwise operations on multiple strings, and stores the result."),
+		cmd("bitfield", -2, "write denyoom", 1, 1, 1, "@write @bitmap @slow", "bitmap", "3.2.0",
+			"Performs arbitrary bitfield integer operations on strings."),
+		cmd("bitfield_ro", -2, "readonly fast", 1, 1, 1, "@read @bitmap @fast", "bitmap", "6.0.0",
+			"Performs arbitrary read-only bitfield integer operations on strings."),
+
+		// hyperloglog
+		cmd("pfadd", -2, "write denyoom fast", 1, 1, 1, "@write @hyperloglog @fast", "hyperloglog", "2.8.9",
+			"Adds elements to a HyperLogLog key. Creates the key if it doesn't exist."),
+		cmd("pfcount", -2, "readonly", 1, -1, 1, "@read @hyperloglog @slow", "hyperloglog", "2.8.9",
+			"Returns the approximated cardinality of the set(s) observed by the HyperLogLog key(s)."),
+		cmd("pfmerge", -2, "write denyoom", 1, -1, 1, "@write @hyperloglog @slow", "hyperloglog", "2.8.9",
+			"Merges one or more HyperLogLog values into a single key."),
+
+		// stream
+		cmd("xadd", -5, "write denyoom fast", 1, 1, 1, "@write @stream @fast", "stream", "5.0.0",
+			"Appends a new message to a stream. Creates the key if it doesn't exist."),
+		cmd("xlen", 2, "readonly fast", 1, 1, 1, "@read @stream @fast", "stream", "5.0.0",
+			"Return the number of messages in a stream."),
+		cmd("xrange", -4, "readonly", 1, 1, 1, "@read @stream @slow", "stream", "5.0.0",
+			"Returns the messages from a stream within a range of IDs."),
+		cmd("xrevrange", -4, "readonly", 1, 1, 1, "@read @stream @slow", "stream", "5.0.0",
+			"Returns the messages from a stream within a range of IDs in reverse order."),
+		cmd("xdel", -3, "write fast", 1, 1, 1, "@write @stream @fast", "stream", "5.0.0",
+			"Returns the number of messages after removing them from a stream."),
+		cmd("xtrim", -4, "write", 1, 1, 1, "@write @stream @slow", "stream", "5.0.0",
+			"Deletes messages from the beginning of a stream."),
+		cmd("xread", -4, "readonly blocking movablekeys", 0, 0, 0, "@read @stream @slow @blocking", "stream", "5.0.0",
+			"Returns messages from multiple streams with IDs greater than the ones requested. Blocks until a message is available otherwise.").
+			movable("STREAMS", 1),
+		cmd("xgroup", -2, "write", 2, 2, 1, "@write @stream @slow", "stream", "5.0.0",
+			"A container for consumer groups commands."),
+		cmd("xreadgroup", -7, "write blocking movablekeys", 0, 0, 0, "@write @stream @slow @blocking", "stream", "5.0.0",
+			"Returns new or historical messages from a stream for a consumer in a group. Blocks until a message is available otherwise.").
+			movable("STREAMS", 4),
+		cmd("xack", -4, "write fast", 1, 1, 1, "@write @stream @fast", "stream", "5.0.0",
+			"Returns the number of messages that were successfully acknowledged by the consumer group member of a stream."),
+		cmd("xpending", -3, "readonly", 1, 1, 1, "@read @stream @slow", "stream", "5.0.0",
+			"Returns the information and entries from a stream consumer group's pending entries list."),
+		cmd("xclaim", -6, "write fast", 1, 1, 1, "@write @stream @fast", "stream", "5.0.0",
+			"Changes, or acquires, ownership of a message in a consumer group, as if the message was delivered a consumer group member."),
+
+		// pubsub
+		cmd("publish", 3, "pubsub loading stale fast", 0, 0, 0, "@pubsub @fast", "pubsub", "2.0.0",
+			"Posts a message to a channel."),
+		cmd("pubsub", -2, "pubsub loading stale", 0, 0, 0, "@pubsub @slow", "pubsub", "2.8.0",
+			"A container for Pub/Sub commands."),
+		cmd("subscribe", -2, "pubsub noscript loading stale no_multi", 0, 0, 0, "@pubsub @slow", "pubsub", "2.0.0",
+			"Listens for messages published to channels."),
+		cmd("unsubscribe", -1, "pubsub noscript loading stale no_multi", 0, 0, 0, "@pubsub @slow", "pubsub", "2.0.0",
+			"Stops listening to messages posted to channels."),
+		cmd("psubscribe", -2, "pubsub noscript loading stale no_multi", 0, 0, 0, "@pubsub @slow", "pubsub", "2.0.0",
+			"Listens for messages published to channels that match one or more patterns."),
+		cmd("punsubscribe", -1, "pubsub noscript loading stale no_multi", 0, 0, 0, "@pubsub @slow", "pubsub", "2.0.0",
+			"Stops listening to messages published to channels that match one or more patterns."),
+
+		// transactions
+		cmd("multi", 1, "noscript loading stale fast", 0, 0, 0, "@fast @transaction", "transactions", "1.2.0",
+			"Starts a transaction."),
+		cmd("exec", 1, "noscript loading stale", 0, 0, 0, "@slow @transaction", "transactions", "1.2.0",
+			"Executes all commands in a transaction."),
+		cmd("discard", 1, "noscript loading stale fast", 0, 0, 0, "@fast @transaction", "transactions", "2.0.0",
+			"Discards a transaction."),
+
+		// server
+		cmd("command", -1, "loading stale", 0, 0, 0, "@slow @connection", "server", "2.8.13",
+			"Returns detailed information about all commands."),
+		cmd("config", -2, "admin noscript loading stale", 0, 0, 0, "@admin @slow @dangerous", "server", "2.0.0",
+			"A container for server configuration commands."),
+		cmd("slowlog", -2, "admin loading stale", 0, 0, 0, "@admin @slow @dangerous", "server", "2.2.12",
+			"A container for slow log commands."),
+		cmd("latency", -2, "admin noscript loading stale", 0, 0, 0, "@admin @slow @dangerous", "server", "2.8.13",
+			"A container for latency diagnostics commands."),
+		cmd("memory", -2, "readonly", 2, 2, 1, "@read @slow", "server", "4.0.0",
+			"A container for memory diagnostics commands."),
+		cmd("info", -1, "loading stale", 0, 0, 0, "@slow @dangerous", "server", "1.0.0",
+			"Returns information and statistics about the server."),
+		cmd("monitor", 1, "admin noscript loading stale no_multi", 0, 0, 0, "@admin @slow @dangerous", "server", "1.0.0",
+			"Listens for all requests received by the server in real-time."),
+		cmd("shutdown", -1, "admin noscript loading stale no_multi", 0, 0, 0, "@admin @slow @dangerous", "server", "1.0.0",
+			"Synchronously saves the database(s) to disk and shuts down the Redis server."),
+
+		// sentinel
+		cmd("sentinel", -2, "admin noscript loading stale", 0, 0, 0, "@admin @slow @dangerous", "sentinel", "2.8.4",
+			"A container for Redis Sentinel commands."),
+	} {
+		commandTable[strings.ToUpper(c.name)] = c
+	}
+}
+
+// lookupSpec 返回命令表中的记录，不在表中的命令（例如测试注册的命令）不限制参数个数，也没有键
+func lookupSpec(name string) *commandSpec {
+	if c, ok := commandTable[name]; ok {
+		return c
+	}
+	return &commandSpec{name: strings.ToLower(name), arity: -1}
+}
+
+func (c *commandSpec) hasFlag(flag string) bool {
+	for _, f := range c.flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkArity 判断参数个数（包括命令名）是否符合 arity
+func (c *commandSpec) checkArity(argc int) bool {
+	if c.arity >= 0 {
+		return argc == c.arity
+	}
+	return argc >= -c.arity
+}
+
+// keys 返回命令参数（不含命令名）中的键，参数个数不足时返回 nil
+func (c *commandSpec) keys(args []string) []string {
+	if c.keyword != "" {
+		for i := c.keywordFrom - 1; i >= 0 && i < len(args); i++ {
+			if !strings.EqualFold(args[i], c.keyword) {
+				continue
+			}
+			rest := args[i+1:]
+			if len(rest) == 0 || len(rest)%2 != 0 {
+				return nil
+			}
+			return rest[:len(rest)/2]
+		}
+		return nil
+	}
+
+	if c.firstKey == 0 {
+		return nil
+	}
+	first, last := c.firstKey-1, c.lastKey-1
+	if c.lastKey < 0 {
+		last = len(args) + c.lastKey
+	}
+	if first >= len(args) || last >= len(args) || last < first {
+		return nil
+	}
+	keys := make([]string, 0, (last-first)/c.keyStep+1)
+	for i := first; i <= last; i += c.keyStep {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
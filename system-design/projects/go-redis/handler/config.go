@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"go-redis/glob"
+	"go-redis/protocol"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigParam 是配置表中的一个参数，可以在配置文件、命令行和 CONFIG GET/SET 中使用
+// 一般通过 IntParam、MemoryParam、BoolParam、EnumParam、StringParam 创建，由它们负责解析和校验
+type ConfigParam struct {
+	Name string
+	Get  func() string
+	Set  func(value string) error
+	// Immutable 的参数只能在启动时通过配置文件或命令行设置，CONFIG SET 会拒绝
+	Immutable bool
+	// Rewrite 返回 CONFIG REWRITE 写入配置文件时参数名之后的部分，为 nil 时写入 Get 的值（必要时加引号）
+	Rewrite func() string
+	// RewriteLines 用于可以出现多行的参数（例如哨兵的 sentinel），每项是一行中参数名之后的部分，优先于 Rewrite
+	RewriteLines func() []string
+
+	defaultValue string // 注册时的值，CONFIG REWRITE 不会追加等于默认值的参数
+}
+
+// IntParam 创建取值范围为 [min, max] 的整数参数
+func IntParam(name string, min, max int64, get func() int64, set func(int64)) *ConfigParam {
+	return &ConfigParam{
+		Name: name,
+		Get:  func() string { return strconv.FormatInt(get(), 10) },
+		Set: func(value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidConfigInt
+			}
+			if n < min || n > max {
+				return fmt.Errorf("argument must be between %d and %d inclusive", min, max)
+			}
+			set(n)
+			return nil
+		},
+	}
+}
+
+// MemoryParam 创建取值范围为 [min, max] 的内存大小参数，接受 1gb、100mb 等单位
+// CONFIG GET 返回字节数，CONFIG REWRITE 尽量写成带单位的形式
+func MemoryParam(name string, min, max int64, get func() int64, set func(int64)) *ConfigParam {
+	return &ConfigParam{
+		Name: name,
+		Get:  func() string { return strconv.FormatInt(get(), 10) },
+		Set: func(value string) error {
+			n, err := ParseMemory(value)
+			if err != nil {
+				return err
+			}
+			if n < min || n > max {
+				return fmt.Errorf("argument must be between %d and %d inclusive", min, max)
+			}
+			set(n)
+			return nil
+		},
+		Rewrite: func() string { return formatMemory(get()) },
+	}
+}
+
+// BoolParam 创建取值为 yes/no 的参数
+func BoolParam(name string, get func() bool, set func(bool)) *ConfigParam {
+	return &ConfigParam{
+		Name: name,
+		Get: func() string {
+			if get() {
+				return "yes"
+			}
+			return "no"
+		},
+		Set: func(value string) error {
+			switch strings.ToLower(value) {
+			case "yes":
+				set(true)
+			case "no":
+				set(false)
+			default:
+				return errors.New("argument must be 'yes' or 'no'")
+			}
+			return nil
+		},
+	}
+}
+
+// EnumParam 创建只能取 values 之一的参数，不区分大小写，set 收到的是 values 中的写法
+func EnumParam(name string, values []string, get func() string, set func(string)) *ConfigParam {
+	return &ConfigParam{
+		Name: name,
+		Get:  get,
+		Set: func(value string) error {
+			for _, v := range values {
+				if strings.EqualFold(v, value) {
+					set(v)
+					return nil
+				}
+			}
+			return errors.New("argument(s) must be one of the following: " + strings.Join(values, ", "))
+		},
+	}
+}
+
+// StringParam 创建由 set 自行解析和校验的参数
+func StringParam(name string, get func() string, set func(string) error) *ConfigParam {
+	return &ConfigParam{Name: name, Get: get, Set: set}
+}
+
+// ErrInvalidConfigInt 表示整数参数的值不合法，服务器层注册的参数也使用它
+var ErrInvalidConfigInt = errors.New("argument couldn't be parsed into an integer")
+
+// errInvalidConfigMemory 表示内存大小参数的值不合法
+var errInvalidConfigMemory = errors.New("argument must be a memory value")
+
+// memoryUnits 是内存大小支持的单位，与 Redis 的 memtoll 相同：k/m/g 以 1000 为底，kb/mb/gb 以 1024 为底
+var memoryUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1000,
+	"kb": 1024,
+	"m":  1000 * 1000,
+	"mb": 1024 * 1024,
+	"g":  1000 * 1000 * 1000,
+	"gb": 1024 * 1024 * 1024,
+}
+
+// ParseMemory 解析带单位的内存大小，例如 "512mb"，单位不区分大小写
+func ParseMemory(value string) (int64, error) {
+	lower := strings.ToLower(value)
+	i := len(lower)
+	for i > 0 && (lower[i-1] < '0' || lower[i-1] > '9') {
+		i--
+	}
+
+	unit, ok := memoryUnits[lower[i:]]
+	if !ok {
+		return 0, errInvalidConfigMemory
+	}
+	n, err := strconv.ParseInt(lower[:i], 10, 64)
+	if err != nil || n > math.MaxInt64/unit || n < math.MinInt64/unit {
+		return 0, errInvalidConfigMemory
+	}
+	return n * unit, nil
+}
+
+// formatMemory 把字节数写成配置文件中的形式，能整除时使用 gb/mb/kb 单位
+func formatMemory(n int64) string {
+	switch {
+	case n == 0:
+		return "0"
+	case n%(1024*1024*1024) == 0:
+		return strconv.FormatInt(n/(1024*1024*1024), 10) + "gb"
+	case n%(1024*1024) == 0:
+		return strconv.FormatInt(n/(1024*1024), 10) + "mb"
+	case n%1024 == 0:
+		return strconv.FormatInt(n/1024, 10) + "kb"
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
+
+// ConfigHandler 持有配置表，所有可调参数都注册在这里
+type ConfigHandler struct {
+	params    map[string]*ConfigParam
+	resetStat func()
+	file      string // 启动时加载的配置文件的绝对路径，CONFIG REWRITE 写回这里
+}
+
+func NewConfigHandler() *ConfigHandler {
+	return &ConfigHandler{
+		params: make(map[string]*ConfigParam),
+	}
+}
+
+// AddParam 注册一个参数，参数当前的值作为默认值
+func (h *ConfigHandler) AddParam(p *ConfigParam) {
+	p.defaultValue = p.Get()
+	h.params[p.Name] = p
+}
+
+// Handle 处理 CONFIG 命令
+// CONFIG GET pattern
+// CONFIG SET parameter value
+// CONFIG RESETSTAT
+// CONFIG REWRITE
+func (h *ConfigHandler) Handle(args []protocol.Value) *protocol.Value {
+	switch strings.ToUpper(args[0].Str) {
+	case "GET":
+		if len(args) != 2 {
+			return protocol.Error("ERR wrong number of arguments for 'config|get' command")
+		}
+		return h.get(strings.ToLower(args[1].Str))
+
+	case "SET":
+		if len(args) != 3 {
+			return protocol.Error("ERR wrong number of arguments for 'config|set' command")
+		}
+		return h.set(strings.ToLower(args[1].Str), args[2].Str)
+
+	case "REWRITE":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'config|rewrite' command")
+		}
+		if err := h.Rewrite(); err == errRewriteWithoutFile {
+			return protocol.Error("ERR " + err.Error())
+		} else if err != nil {
+			return protocol.Error("ERR Rewriting config file: " + err.Error())
+		}
+		return protocol.SimpleString("OK")
+
+	case "RESETSTAT":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'config|resetstat' command")
+		}
+		if h.resetStat != nil {
+			h.resetStat()
+		}
+		return protocol.SimpleString("OK")
+	}
+
+	return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try CONFIG HELP.")
+}
+
+func (h *ConfigHandler) get(pattern string) *protocol.Value {
+	names := make([]string, 0, len(h.params))
+	for name := range h.params {
+		if glob.Match(pattern, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	values := make([]protocol.Value, 0, 2*len(names))
+	for _, name := range names {
+		values = append(values,
+			*protocol.BulkString(name),
+			*protocol.BulkString(h.params[name].Get()),
+		)
+	}
+
+	return protocol.Array(values)
+}
+
+func (h *ConfigHandler) set(name, value string) *protocol.Value {
+	p, ok := h.params[name]
+	if !ok {
+		return protocol.Error("ERR Unknown option or number of arguments for CONFIG SET - '" + name + "'")
+	}
+
+	if p.Immutable {
+		return protocol.Error("ERR CONFIG SET failed (possibly related to argument '" + name + "') - can't set immutable config")
+	}
+
+	if err := p.Set(value); err != nil {
+		return protocol.Error("ERR CONFIG SET failed (possibly related to argument '" + name + "') - " + strings.TrimPrefix(err.Error(), "ERR "))
+	}
+
+	return protocol.SimpleString("OK")
+}
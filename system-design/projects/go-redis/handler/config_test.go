@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"go-redis/store"
+	"strings"
+	"testing"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"1024", 1024},
+		{"1b", 1},
+		{"1k", 1000},
+		{"1kb", 1024},
+		{"512MB", 512 * 1024 * 1024},
+		{"2g", 2000 * 1000 * 1000},
+		{"1gb", 1024 * 1024 * 1024},
+	}
+	for _, tt := range tests {
+		got, err := ParseMemory(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMemory(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "mb", "1tb", "1.5mb", "99999999999gb"} {
+		if _, err := ParseMemory(value); err == nil {
+			t.Errorf("ParseMemory(%q): expected error", value)
+		}
+	}
+}
+
+func TestConfigProtoLimits(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	resp := execCommand(r, "CONFIG", "GET", "proto-max-bulk-len")
+	if len(resp.Array) != 2 || resp.Array[1].Str != "536870912" {
+		t.Fatalf("Unexpected CONFIG GET response: %+v", resp)
+	}
+
+	if resp := execCommand(r, "CONFIG", "SET", "proto-max-bulk-len", "2mb"); resp.Str != "OK" {
+		t.Fatalf("Unexpected CONFIG SET response: %+v", resp)
+	}
+	if n := r.ProtocolLimits().MaxBulkLen(); n != 2*1024*1024 {
+		t.Errorf("MaxBulkLen = %d, want %d", n, 2*1024*1024)
+	}
+
+	if resp := execCommand(r, "CONFIG", "SET", "proto-max-multibulk-len", "100"); resp.Str != "OK" {
+		t.Fatalf("Unexpected CONFIG SET response: %+v", resp)
+	}
+	if n := r.ProtocolLimits().MaxMultiBulkLen(); n != 100 {
+		t.Errorf("MaxMultiBulkLen = %d, want 100", n)
+	}
+
+	for _, args := range [][]string{
+		{"proto-max-bulk-len", "1000"},
+		{"proto-max-bulk-len", "lots"},
+		{"proto-max-multibulk-len", "0"},
+		{"proto-max-multibulk-len", "4294967296"},
+	} {
+		resp := execCommand(r, "CONFIG", "SET", args[0], args[1])
+		if !strings.HasPrefix(resp.Str, "ERR CONFIG SET failed") {
+			t.Errorf("CONFIG SET %s %s: unexpected response %+v", args[0], args[1], resp)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go-redis/protocol"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 配置文件
+//
+// 格式与 redis.conf 相同：每行一个 "参数名 值"，# 开头的行是注释，
+// 值可以用单引号或双引号包围，多个值之间用空格分隔（例如 client-output-buffer-limit），
+// 内存大小支持 1gb、100mb 等单位，include 引入其他配置文件（相对路径相对于当前文件所在目录，支持通配符）。
+// 同一参数出现多次时以最后一次为准，命令行参数在配置文件之后加载，因此会覆盖文件中的值。
+//
+// CONFIG REWRITE 把当前配置写回启动时加载的文件：保留注释、include 和不认识的行，
+// 已有的参数行原地更新，重复的行删除，文件中没有且不等于默认值的参数追加到文件末尾
+
+// commandLineSource 是命令行参数在错误信息中显示的来源
+const commandLineSource = "(command line)"
+
+// rewriteSignature 标记 CONFIG REWRITE 追加的参数
+const rewriteSignature = "# Generated by CONFIG REWRITE"
+
+// ConfigError 是加载配置时的错误，包含出错的文件、行号和行内容
+type ConfigError struct {
+	File string
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("\n*** FATAL CONFIG FILE ERROR ***\nReading the configuration file %s, at line %d\n>>> '%s'\n%v",
+		e.File, e.Line, e.Text, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errBadDirective       = errors.New("Bad directive or wrong number of arguments")
+	errUnbalancedQuotes   = errors.New("Unbalanced quotes in configuration line")
+	errIncludeCycle       = errors.New("Configuration file includes itself")
+	errRewriteWithoutFile = errors.New("The server is running without a config file")
+)
+
+// Load 加载配置文件 path（为空表示没有配置文件），然后依次应用命令行参数 overrides，
+// overrides 的每一项是一行配置，格式与配置文件相同
+// 成功加载的文件会被记住，CONFIG REWRITE 写回这个文件
+func (h *ConfigHandler) Load(path string, overrides []string) error {
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if err := h.loadFile(abs, make(map[string]bool)); err != nil {
+			return err
+		}
+		h.file = abs
+	}
+
+	for i, line := range overrides {
+		if err := h.loadLine(line); err != nil {
+			return &ConfigError{File: commandLineSource, Line: i + 1, Text: line, Err: err}
+		}
+	}
+	return nil
+}
+
+// File 返回加载的配置文件的绝对路径，没有配置文件时返回空字符串
+func (h *ConfigHandler) File() string {
+	return h.file
+}
+
+// loadFile 加载一个配置文件，including 记录 include 链上的文件，用于发现循环引用
+func (h *ConfigHandler) loadFile(path string, including map[string]bool) error {
+	if including[path] {
+		return errIncludeCycle
+	}
+	including[path] = true
+	defer delete(including, path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Fatal error, can't open config file '%s': %v", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		args, err := protocol.SplitArgs(line)
+		if err != nil {
+			return &ConfigError{File: path, Line: lineNo, Text: line, Err: errUnbalancedQuotes}
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		if strings.EqualFold(args[0], "include") {
+			err = h.include(path, args[1:], including)
+		} else {
+			err = h.apply(args)
+		}
+		if err != nil {
+			var cfgErr *ConfigError
+			if errors.As(err, &cfgErr) {
+				// 被引入的文件中的错误已经带有位置
+				return err
+			}
+			return &ConfigError{File: path, Line: lineNo, Text: line, Err: err}
+		}
+	}
+	return scanner.Err()
+}
+
+// include 加载 include 指令引用的文件
+func (h *ConfigHandler) include(from string, args []string, including map[string]bool) error {
+	if len(args) != 1 {
+		return errBadDirective
+	}
+
+	pattern := args[0]
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		// 不是通配符时文件必须存在，由 loadFile 报告打开失败
+		matches = []string{pattern}
+	}
+
+	for _, match := range matches {
+		if err := h.loadFile(match, including); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *ConfigHandler) loadLine(line string) error {
+	args, err := protocol.SplitArgs(line)
+	if err != nil {
+		return errUnbalancedQuotes
+	}
+	if len(args) == 0 {
+		return errBadDirective
+	}
+	return h.apply(args)
+}
+
+// apply 设置一个参数，args[0] 是参数名，其余是值，启动时可以设置不可修改的参数
+func (h *ConfigHandler) apply(args []string) error {
+	p, ok := h.params[strings.ToLower(args[0])]
+	if !ok || len(args) < 2 {
+		return errBadDirective
+	}
+	return p.Set(strings.Join(args[1:], " "))
+}
+
+// Rewrite 把当前配置写回加载的配置文件
+func (h *ConfigHandler) Rewrite() error {
+	if h.file == "" {
+		return errRewriteWithoutFile
+	}
+
+	var lines []string
+	data, err := os.ReadFile(h.file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	written := make(map[string]bool)
+	hasSignature := false
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == rewriteSignature {
+			hasSignature = true
+		}
+
+		args, err := protocol.SplitArgs(trimmed)
+		if trimmed == "" || trimmed[0] == '#' || err != nil || len(args) == 0 {
+			out = append(out, line)
+			continue
+		}
+
+		name := strings.ToLower(args[0])
+		p, ok := h.params[name]
+		if !ok {
+			// include 和不认识的行原样保留
+			out = append(out, line)
+			continue
+		}
+		if !written[name] {
+			out = append(out, p.rewriteLines()...)
+			written[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(h.params))
+	for name, p := range h.params {
+		if !written[name] && p.Get() != p.defaultValue {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 0 && !hasSignature {
+		out = append(out, rewriteSignature)
+	}
+	for _, name := range names {
+		out = append(out, h.params[name].rewriteLines()...)
+	}
+
+	return writeFileAtomic(h.file, []byte(strings.Join(out, "\n")+"\n"))
+}
+
+// rewriteLines 返回参数在配置文件中的行，除 RewriteLines 之外每个参数只有一行
+func (p *ConfigParam) rewriteLines() []string {
+	switch {
+	case p.RewriteLines != nil:
+		lines := p.RewriteLines()
+		for i, line := range lines {
+			lines[i] = p.Name + " " + line
+		}
+		return lines
+	case p.Rewrite != nil:
+		return []string{p.Name + " " + p.Rewrite()}
+	default:
+		return []string{p.Name + " " + quoteConfigValue(p.Get())}
+	}
+}
+
+// quoteConfigValue 在值为空或包含空白、引号、不可打印字符时加引号，保证重新加载时得到相同的值
+func quoteConfigValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c <= ' ' || c > '~' || c == '"' || c == '\'' || c == '\\' {
+			return protocol.Quote(value)
+		}
+	}
+	return value
+}
+
+// writeFileAtomic 先写临时文件再重命名，写入中途失败不会损坏原文件
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
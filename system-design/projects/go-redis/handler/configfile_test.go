@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"go-redis/protocol"
+	"go-redis/sentinel"
+	"go-redis/store"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func configValue(t *testing.T, r *Router, name string) string {
+	t.Helper()
+	resp := execCommand(r, "CONFIG", "GET", name)
+	if len(resp.Array) != 2 {
+		t.Fatalf("CONFIG GET %s: %+v", name, resp)
+	}
+	return resp.Array[1].Str
+}
+
+func TestConfigLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "limits.conf", "proto-max-multibulk-len 1000\n")
+	path := writeConfig(t, dir, "redis.conf", `
+# 注释和空行被忽略
+   slowlog-max-len 10
+SLOWLOG-LOG-SLOWER-THAN -1
+proto-max-bulk-len 2mb
+notify-keyspace-events "KEA"
+include limits.conf
+slowlog-max-len 20
+`)
+
+	r := NewRouter(store.NewStore())
+	if err := r.Config().Load(path, []string{"slowlog-max-len 30", `notify-keyspace-events "Kx"`}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"slowlog-max-len":         "30", // 命令行覆盖文件，文件中后出现的覆盖先出现的
+		"slowlog-log-slower-than": "-1",
+		"proto-max-bulk-len":      "2097152",
+		"proto-max-multibulk-len": "1000",
+		"notify-keyspace-events":  "xK",
+	} {
+		if got := configValue(t, r, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if r.Config().File() != path {
+		t.Errorf("File() = %q, want %q", r.Config().File(), path)
+	}
+}
+
+func TestConfigLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "self.conf", "include self.conf\n")
+
+	tests := []struct {
+		content string
+		line    int
+		err     string
+	}{
+		{"slowlog-max-len 10\nno-such-option yes\n", 2, "Bad directive"},
+		{"\n\nslowlog-max-len\n", 3, "Bad directive"},
+		{"slowlog-max-len \"10\n", 1, "Unbalanced quotes"},
+		{"slowlog-max-len ten\n", 1, "couldn't be parsed into an integer"},
+		{"proto-max-bulk-len 1k\n", 1, "argument must be between 1048576"},
+		{"# ok\ninclude missing.conf\n", 2, "can't open config file"},
+	}
+	for _, tt := range tests {
+		path := writeConfig(t, dir, "redis.conf", tt.content)
+		err := NewRouter(store.NewStore()).Config().Load(path, nil)
+
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("%q: expected ConfigError, got %v", tt.content, err)
+			continue
+		}
+		if cfgErr.File != path || cfgErr.Line != tt.line || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got %s:%d %v, want line %d %q", tt.content, cfgErr.File, cfgErr.Line, cfgErr.Err, tt.line, tt.err)
+		}
+	}
+
+	err := NewRouter(store.NewStore()).Config().Load(filepath.Join(dir, "self.conf"), nil)
+	if !errors.Is(err, errIncludeCycle) {
+		t.Errorf("self include: %v", err)
+	}
+
+	err = NewRouter(store.NewStore()).Config().Load("", []string{"slowlog-max-len -5"})
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.File != "(command line)" || cfgErr.Line != 1 {
+		t.Errorf("command line error: %v", err)
+	}
+}
+
+func TestConfigRewrite(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "redis.conf", `# my server
+slowlog-max-len 10
+include extra/*.conf
+slowlog-max-len 20
+unknown-module-option 1
+`)
+
+	r := NewRouter(store.NewStore())
+	if resp := execCommand(r, "CONFIG", "REWRITE"); resp.Str != "ERR The server is running without a config file" {
+		t.Fatalf("CONFIG REWRITE without file: %+v", resp)
+	}
+
+	// 不认识的行在加载时会报错，这里先加载不含它的版本
+	content, _ := os.ReadFile(path)
+	loadable := writeConfig(t, dir, "loadable.conf", strings.Replace(string(content), "unknown-module-option 1\n", "", 1))
+	if err := r.Config().Load(loadable, nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Config().file = path
+
+	execCommand(r, "CONFIG", "SET", "slowlog-max-len", "64")
+	execCommand(r, "CONFIG", "SET", "proto-max-bulk-len", "8mb")
+	execCommand(r, "CONFIG", "SET", "notify-keyspace-events", "KA")
+	if resp := execCommand(r, "CONFIG", "REWRITE"); resp.Str != "OK" {
+		t.Fatalf("CONFIG REWRITE: %+v", resp)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# my server
+slowlog-max-len 64
+include extra/*.conf
+unknown-module-option 1
+# Generated by CONFIG REWRITE
+notify-keyspace-events AK
+proto-max-bulk-len 8mb
+`
+	if string(got) != want {
+		t.Fatalf("rewritten config:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 再次改写不会重复追加
+	execCommand(r, "CONFIG", "SET", "slowlog-max-len", "65")
+	execCommand(r, "CONFIG", "REWRITE")
+	got, _ = os.ReadFile(path)
+	if want = strings.Replace(want, "slowlog-max-len 64", "slowlog-max-len 65", 1); string(got) != want {
+		t.Fatalf("second rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConfigRewriteQuoting(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "redis.conf", "")
+
+	r := NewRouter(store.NewStore())
+	var value string
+	r.Config().AddParam(StringParam("test-string", func() string { return value },
+		func(v string) error { value = v; return nil }))
+	if err := r.Config().Load(path, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	value = "two  words \"quoted\"\n"
+	if err := r.Config().Rewrite(); err != nil {
+		t.Fatal(err)
+	}
+	value = ""
+
+	r2 := NewRouter(store.NewStore())
+	var reloaded string
+	r2.Config().AddParam(StringParam("test-string", func() string { return reloaded },
+		func(v string) error { reloaded = v; return nil }))
+	if err := r2.Config().Load(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded != "two  words \"quoted\"\n" {
+		t.Errorf("reloaded value %q", reloaded)
+	}
+}
+
+func TestConfigRewriteSentinel(t *testing.T) {
+	dir := t.TempDir()
+	id := strings.Repeat("a", 40)
+	path := writeConfig(t, dir, "sentinel.conf", `port 26379
+sentinel myid `+id+`
+sentinel monitor mymaster 127.0.0.1 6379 2
+# failover
+sentinel down-after-milliseconds mymaster 5000
+`)
+
+	r := NewRouter(store.NewStore())
+	st := sentinel.New()
+	t.Cleanup(st.Stop)
+	r.Config().AddParam(IntParam("port", 0, 65535, func() int64 { return 26379 }, func(int64) {}))
+	r.Config().AddParam(SentinelParam(st))
+	if err := r.Config().Load(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Configure(strings.Fields("known-replica mymaster 127.0.0.1 6380")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Config().Rewrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	// sentinel 的多行配置写在第一行的位置，其余的行删除
+	got, _ := os.ReadFile(path)
+	want := `port 26379
+sentinel myid ` + id + `
+sentinel monitor mymaster 127.0.0.1 6379 2
+sentinel down-after-milliseconds mymaster 5000
+sentinel known-replica mymaster 127.0.0.1 6380
+# failover
+`
+	if string(got) != want {
+		t.Fatalf("rewritten config:\n%s\nwant:\n%s", got, want)
+	}
+
+	// CONFIG SET 不能修改哨兵的配置
+	if resp := execCommand(r, "CONFIG", "SET", "sentinel", "monitor other 127.0.0.1 7000 1"); resp.Type != protocol.ErrorType {
+		t.Errorf("CONFIG SET sentinel: %+v", resp)
+	}
+}
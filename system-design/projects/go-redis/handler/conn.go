@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/types"
+)
+
+// connOf 返回 client 的连接上下文，client 没有实现 types.Conn（包括 nil）时返回不关联连接的默认上下文
+func connOf(client types.ClientInfo) types.Conn {
+	if conn, ok := client.(types.Conn); ok {
+		return conn
+	}
+	return &detachedConn{client: client}
+}
+
+// detachedConn 是不关联连接的上下文：内部调用（Route）或只提供 ClientInfo 的调用方使用它，
+// 数据库编号只在这次调用内有效，推送的消息被丢弃
+type detachedConn struct {
+	client types.ClientInfo
+	db     int
+}
+
+func (c *detachedConn) ID() int64 {
+	if c.client == nil {
+		return 0
+	}
+	return c.client.ID()
+}
+
+func (c *detachedConn) Addr() string {
+	if c.client == nil {
+		return ""
+	}
+	return c.client.Addr()
+}
+
+func (c *detachedConn) Name() string {
+	if c.client == nil {
+		return ""
+	}
+	return c.client.Name()
+}
+
+func (c *detachedConn) DB() int              { return c.db }
+func (c *detachedConn) SetDB(index int)      { c.db = index }
+func (c *detachedConn) User() string         { return "default" }
+func (c *detachedConn) ProtocolVersion() int { return 2 }
+func (c *detachedConn) InTransaction() bool  { return false }
+func (c *detachedConn) Push(*protocol.Value) {}
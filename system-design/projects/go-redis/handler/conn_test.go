@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"go-redis/types"
+	"strconv"
+	"testing"
+)
+
+// whoamiHandler 回复连接的编号、数据库和用户
+type whoamiHandler struct{}
+
+func (whoamiHandler) HandleContext(conn types.Conn, args []protocol.Value) *protocol.Value {
+	return stringArray([]string{strconv.FormatInt(conn.ID(), 10), strconv.Itoa(conn.DB()), conn.User()})
+}
+
+// bothHandler 同时实现 Handler 和 ContextHandler，Router 应该使用 HandleContext
+type bothHandler struct{}
+
+func (bothHandler) Handle(args []protocol.Value) *protocol.Value {
+	return protocol.SimpleString("plain")
+}
+
+func (bothHandler) HandleContext(conn types.Conn, args []protocol.Value) *protocol.Value {
+	return protocol.SimpleString("context")
+}
+
+func TestContextHandler(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	r.RegisterContext("WHOAMI", whoamiHandler{})
+	r.Register("BOTH", bothHandler{})
+
+	cmd := protocol.Array([]protocol.Value{*protocol.BulkString("WHOAMI")})
+	resp := r.RouteClient(fakeClient{}, cmd)
+	if len(resp.Array) != 3 || resp.Array[0].Str != "7" || resp.Array[1].Str != "0" || resp.Array[2].Str != "default" {
+		t.Errorf("WHOAMI with client = %+v", resp)
+	}
+	// 没有连接时使用默认上下文
+	if resp := execCommand(r, "WHOAMI"); len(resp.Array) != 3 || resp.Array[0].Str != "0" {
+		t.Errorf("WHOAMI without client = %+v", resp)
+	}
+
+	if resp := execCommand(r, "BOTH"); resp.Str != "context" {
+		t.Errorf("BOTH = %+v, want context", resp)
+	}
+	// 只实现 Handler 的处理器通过适配器继续工作
+	if resp := execCommand(r, "PING"); resp.Str != "PONG" {
+		t.Errorf("PING = %+v", resp)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"SELECT", "0"}, "OK"},
+		{[]string{"SELECT", "1"}, "ERR DB index is out of range"},
+		{[]string{"SELECT", "-1"}, "ERR DB index is out of range"},
+		{[]string{"SELECT", "x"}, "ERR value is not an integer or out of range"},
+		{[]string{"SELECT"}, "ERR wrong number of arguments for 'select' command"},
+	}
+	for _, tt := range tests {
+		if resp := execCommand(r, tt.args...); resp.Str != tt.want {
+			t.Errorf("%v = %+v, want %q", tt.args, resp, tt.want)
+		}
+	}
+}
@@ -16,16 +16,13 @@ func NewDelHandler(db *store.Store) *DelHandler {
 }
 
 func (h *DelHandler) Handle(args []protocol.Value) *protocol.Value {
-	if len(args) < 1 {
-		return protocol.Error("ERR wrong number of arguments for 'del' command")
-	}
-
 	var deletedCount int64 = 0
 
 	for _, keyVal := range args {
 		key := keyVal.Str
 		if h.db.Delete(key) {
 			deletedCount++
+			h.db.Notify(store.NotifyGeneric, "del", key)
 		}
 	}
 
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/types"
+	"strings"
+)
+
+// Exec 原子地执行事务中排队的命令（EXEC），返回每条命令的回复
+// 执行期间其他连接的命令不会穿插进来；事务中的阻塞命令按非阻塞执行，与 Redis 一致
+func (r *Router) Exec(client types.ClientInfo, cmds []*protocol.Value) *protocol.Value {
+	r.execMu.Lock()
+	defer r.execMu.Unlock()
+
+	values := make([]protocol.Value, len(cmds))
+	for i, cmd := range cmds {
+		values[i] = *r.execute(client, withoutBlock(cmd))
+	}
+
+	return protocol.Array(values)
+}
+
+// IsBlockingCommand 判断命令是否可能阻塞，事件循环等不能被阻塞的调用方需要在其他 goroutine 中执行这类命令
+func IsBlockingCommand(cmd *protocol.Value) bool {
+	if cmd.Type != protocol.ArrayType || len(cmd.Array) == 0 {
+		return false
+	}
+
+	c, ok := commandTable[strings.ToUpper(cmd.Array[0].Str)]
+	return ok && c.hasFlag("blocking")
+}
+
+// withoutBlock 去掉阻塞命令 STREAMS 之前的 BLOCK 选项
+func withoutBlock(cmd *protocol.Value) *protocol.Value {
+	if !IsBlockingCommand(cmd) {
+		return cmd
+	}
+
+	// XREADGROUP 的 GROUP group consumer 不是选项，原样保留
+	first := 1
+	if strings.EqualFold(cmd.Array[0].Str, "XREADGROUP") {
+		first = 4
+	}
+	if first > len(cmd.Array) {
+		return cmd
+	}
+
+	args := make([]protocol.Value, 0, len(cmd.Array))
+	args = append(args, cmd.Array[:first]...)
+	for i := first; i < len(cmd.Array); i++ {
+		arg := cmd.Array[i].Str
+		if strings.EqualFold(arg, "STREAMS") {
+			args = append(args, cmd.Array[i:]...)
+			break
+		}
+		if strings.EqualFold(arg, "BLOCK") && i+1 < len(cmd.Array) {
+			i++
+			continue
+		}
+		args = append(args, cmd.Array[i])
+	}
+
+	return protocol.Array(args)
+}
@@ -16,10 +16,6 @@ func NewExistsHandler(db *store.Store) *ExistsHandler {
 }
 
 func (h *ExistsHandler) Handle(args []protocol.Value) *protocol.Value {
-	if len(args) != 1 {
-		return protocol.Error("len of args mistake")
-	}
-
 	keyVal := args[0]
 	if keyVal.Type != protocol.BulkStringType {
 		return protocol.Error("mistake args type")
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strconv"
+	"time"
+)
+
+type ExpireHandler struct {
+	db   *store.Store
+	name string
+	unit time.Duration
+}
+
+// NewExpireHandler 创建 EXPIRE 命令处理器（单位：秒）
+func NewExpireHandler(db *store.Store) *ExpireHandler {
+	return &ExpireHandler{
+		db:   db,
+		name: "expire",
+		unit: time.Second,
+	}
+}
+
+// NewPExpireHandler 创建 PEXPIRE 命令处理器（单位：毫秒）
+func NewPExpireHandler(db *store.Store) *ExpireHandler {
+	return &ExpireHandler{
+		db:   db,
+		name: "pexpire",
+		unit: time.Millisecond,
+	}
+}
+
+// Handle 处理 EXPIRE/PEXPIRE 命令
+// EXPIRE key seconds
+func (h *ExpireHandler) Handle(args []protocol.Value) *protocol.Value {
+	key := args[0].Str
+	n, err := strconv.ParseInt(args[1].Str, 10, 64)
+	if err != nil {
+		return protocol.Error("ERR value is not an integer or out of range")
+	}
+
+	at := time.Now().Add(time.Duration(n) * h.unit)
+	if !h.db.ExpireAt(key, at) {
+		return protocol.Integer(0)
+	}
+
+	// 过期时间已经过去时键被直接删除，按 DEL 发布事件
+	if at.After(time.Now()) {
+		h.db.Notify(store.NotifyGeneric, "expire", key)
+	} else {
+		h.db.Notify(store.NotifyGeneric, "del", key)
+	}
+
+	return protocol.Integer(1)
+}
+
+// *****
+type TTLHandler struct {
+	db   *store.Store
+	name string
+	unit time.Duration
+}
+
+// NewTTLHandler 创建 TTL 命令处理器（单位：秒）
+func NewTTLHandler(db *store.Store) *TTLHandler {
+	return &TTLHandler{
+		db:   db,
+		name: "ttl",
+		unit: time.Second,
+	}
+}
+
+// NewPTTLHandler 创建 PTTL 命令处理器（单位：毫秒）
+func NewPTTLHandler(db *store.Store) *TTLHandler {
+	return &TTLHandler{
+		db:   db,
+		name: "pttl",
+		unit: time.Millisecond,
+	}
+}
+
+// Handle 处理 TTL/PTTL 命令
+// 键不存在返回 -2，键没有过期时间返回 -1
+func (h *TTLHandler) Handle(args []protocol.Value) *protocol.Value {
+	ms := h.db.PTTL(args[0].Str)
+	if ms < 0 || h.unit == time.Millisecond {
+		return protocol.Integer(ms)
+	}
+
+	// 与 Redis 一致，秒级 TTL 四舍五入
+	return protocol.Integer((ms + 500) / 1000)
+}
+
+// *****
+type PersistHandler struct {
+	db *store.Store
+}
+
+func NewPersistHandler(db *store.Store) *PersistHandler {
+	return &PersistHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 PERSIST 命令
+// PERSIST key - 移除键的过期时间
+func (h *PersistHandler) Handle(args []protocol.Value) *protocol.Value {
+	key := args[0].Str
+	if !h.db.Persist(key) {
+		return protocol.Integer(0)
+	}
+
+	h.db.Notify(store.NotifyGeneric, "persist", key)
+	return protocol.Integer(1)
+}
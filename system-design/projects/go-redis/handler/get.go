@@ -16,14 +16,6 @@ func NewGetHandler(db *store.Store) *GetHandler {
 }
 
 func (h *GetHandler) Handle(args []protocol.Value) *protocol.Value {
-	if len(args) < 1 {
-		return protocol.Error("too less args")
-	}
-
-	if len(args) > 1 {
-		return protocol.Error("too many args")
-	}
-
 	keyVal := args[0]
 	if keyVal.Type != protocol.BulkStringType {
 		return protocol.Error("ERR key type")
@@ -40,7 +32,7 @@ func (h *GetHandler) Handle(args []protocol.Value) *protocol.Value {
 	if !ok {
 		valueStr, ok := value.(string)
 		if !ok {
-			return protocol.Error("ERR unknown value type")
+			return protocol.Error(store.ErrWrongType.Error())
 		}
 		res = &protocol.Value{
 			Type:   protocol.BulkStringType,
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+)
+
+// *****
+type PFAddHandler struct {
+	db *store.Store
+}
+
+func NewPFAddHandler(db *store.Store) *PFAddHandler {
+	return &PFAddHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 PFADD 命令
+// PFADD key [element ...]
+func (h *PFAddHandler) Handle(args []protocol.Value) *protocol.Value {
+	key := args[0].Str
+	elements := make([]string, len(args)-1)
+	for i, arg := range args[1:] {
+		elements[i] = arg.Str
+	}
+
+	updated, err := h.db.PFAdd(key, elements)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if !updated {
+		return protocol.Integer(0)
+	}
+
+	h.db.Notify(store.NotifyString, "pfadd", key)
+	return protocol.Integer(1)
+}
+
+// *****
+type PFCountHandler struct {
+	db *store.Store
+}
+
+func NewPFCountHandler(db *store.Store) *PFCountHandler {
+	return &PFCountHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 PFCOUNT 命令，多个键时返回它们并集的基数
+// PFCOUNT key [key ...]
+func (h *PFCountHandler) Handle(args []protocol.Value) *protocol.Value {
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = arg.Str
+	}
+
+	n, err := h.db.PFCount(keys...)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	return protocol.Integer(int64(n))
+}
+
+// *****
+type PFMergeHandler struct {
+	db *store.Store
+}
+
+func NewPFMergeHandler(db *store.Store) *PFMergeHandler {
+	return &PFMergeHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 PFMERGE 命令
+// PFMERGE destkey [sourcekey ...]
+func (h *PFMergeHandler) Handle(args []protocol.Value) *protocol.Value {
+	dest := args[0].Str
+	keys := make([]string, len(args)-1)
+	for i, arg := range args[1:] {
+		keys[i] = arg.Str
+	}
+
+	if err := h.db.PFMerge(dest, keys); err != nil {
+		return protocol.Error(err.Error())
+	}
+	h.db.Notify(store.NotifyString, "pfadd", dest)
+
+	return protocol.SimpleString("OK")
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"go-redis/store"
+	"testing"
+)
+
+func TestHyperLogLogCommands(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	if resp := execCommand(r, "PFADD", "page:1", "alice", "bob", "carol"); resp.Int != 1 {
+		t.Fatalf("PFADD: %+v", resp)
+	}
+	// 重复的元素不改变寄存器
+	if resp := execCommand(r, "PFADD", "page:1", "alice", "bob"); resp.Int != 0 {
+		t.Errorf("PFADD duplicates: %+v", resp)
+	}
+	if resp := execCommand(r, "PFADD", "page:2"); resp.Int != 1 {
+		t.Errorf("PFADD without elements on missing key: %+v", resp)
+	}
+	execCommand(r, "PFADD", "page:2", "carol", "dave")
+
+	for _, tt := range []struct {
+		args []string
+		want int64
+	}{
+		{[]string{"PFCOUNT", "page:1"}, 3},
+		{[]string{"PFCOUNT", "page:2"}, 2},
+		{[]string{"PFCOUNT", "page:1", "page:2", "missing"}, 4},
+		{[]string{"PFCOUNT", "missing"}, 0},
+	} {
+		if resp := execCommand(r, tt.args...); resp.Int != tt.want {
+			t.Errorf("%v = %+v, want %d", tt.args, resp, tt.want)
+		}
+	}
+
+	if resp := execCommand(r, "PFMERGE", "site", "page:1", "page:2"); resp.Str != "OK" {
+		t.Fatalf("PFMERGE: %+v", resp)
+	}
+	if resp := execCommand(r, "PFCOUNT", "site"); resp.Int != 4 {
+		t.Errorf("PFCOUNT after merge: %+v", resp)
+	}
+
+	// GET 得到的内容 SET 回去之后仍然是同一个 HLL
+	dump := execCommand(r, "GET", "site")
+	execCommand(r, "SET", "copy", dump.Str)
+	if resp := execCommand(r, "PFCOUNT", "copy"); resp.Int != 4 {
+		t.Errorf("PFCOUNT on restored dump: %+v", resp)
+	}
+
+	execCommand(r, "SET", "plain", "hello")
+	execCommand(r, "XADD", "stream", "*", "a", "1")
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"PFADD", "plain", "x"}, store.ErrNotHLL.Error()},
+		{[]string{"PFCOUNT", "page:1", "plain"}, store.ErrNotHLL.Error()},
+		{[]string{"PFMERGE", "site", "plain"}, store.ErrNotHLL.Error()},
+		{[]string{"PFADD", "stream", "x"}, store.ErrWrongType.Error()},
+		{[]string{"PFCOUNT"}, "ERR wrong number of arguments for 'pfcount' command"},
+	} {
+		if resp := execCommand(r, tt.args...); resp.Str != tt.err {
+			t.Errorf("%v = %+v, want %q", tt.args, resp, tt.err)
+		}
+	}
+
+	if resp := execCommand(r, "CONFIG", "SET", "hll-sparse-max-bytes", "0"); resp.Str != "OK" {
+		t.Fatalf("CONFIG SET hll-sparse-max-bytes: %+v", resp)
+	}
+	execCommand(r, "PFADD", "dense", "x")
+	if resp := execCommand(r, "GET", "dense"); resp.Str[4] != 0 {
+		t.Errorf("hll-sparse-max-bytes 0 should create dense HLL, encoding %d", resp.Str[4])
+	}
+}
@@ -17,10 +17,6 @@ func NewIncrHandler(db *store.Store) *IncrHandler {
 }
 
 func (h *IncrHandler) Handle(args []protocol.Value) *protocol.Value {
-	if len(args) != 1 {
-		return protocol.Error("ERR mistake args len")
-	}
-
 	keyVal := args[0]
 	if keyVal.Type != protocol.BulkStringType {
 		return protocol.Error("ERR key type")
@@ -29,6 +25,7 @@ func (h *IncrHandler) Handle(args []protocol.Value) *protocol.Value {
 	if ok := h.db.Incr(keyVal.Str); !ok {
 		return protocol.Error("ERR value is not an integer or out of range")
 	}
+	h.db.Notify(store.NotifyString, "incrby", keyVal.Str)
 
 	return protocol.SimpleString("OK")
 }
@@ -45,10 +42,6 @@ func NewIncrByHandler(db *store.Store) *IncrByHandler {
 }
 
 func (h *IncrByHandler) Handle(args []protocol.Value) *protocol.Value {
-	if len(args) != 2 {
-		return protocol.Error("ERR mistake args len")
-	}
-
 	keyVal, valueVal := args[0], args[1]
 	if keyVal.Type != protocol.BulkStringType {
 		return protocol.Error("ERR key type")
@@ -62,6 +55,7 @@ func (h *IncrByHandler) Handle(args []protocol.Value) *protocol.Value {
 	if ok := h.db.IncrBy(keyVal.Str, count); !ok {
 		return protocol.Error("ERR value is not an integer or out of range")
 	}
+	h.db.Notify(store.NotifyString, "incrby", keyVal.Str)
 
 	return protocol.SimpleString("OK")
 }
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"fmt"
+	"go-redis/protocol"
+	"sort"
+	"strings"
+)
+
+// infoSectionOrder 是 INFO 输出各节的顺序，未列出的节按名字排在最后
+var infoSectionOrder = []string{"server", "clients", "memory", "persistence", "stats", "replication", "cpu", "commandstats", "keyspace"}
+
+// defaultInfoSections 是不带参数的 INFO 输出的节
+var defaultInfoSections = map[string]bool{
+	"server": true, "clients": true, "memory": true, "persistence": true,
+	"stats": true, "replication": true, "cpu": true, "keyspace": true,
+}
+
+// AddInfoSection 注册 INFO 的一节，fn 返回该节的 "field:value" 行（不含标题）
+// 服务器层用它补充 Router 无法得知的信息（如连接数）
+func (r *Router) AddInfoSection(name string, fn func() []string) {
+	r.infoSections[strings.ToLower(name)] = fn
+}
+
+// sortedInfoSections 返回按输出顺序排列的节名
+func (r *Router) sortedInfoSections() []string {
+	rank := make(map[string]int, len(infoSectionOrder))
+	for i, name := range infoSectionOrder {
+		rank[name] = i
+	}
+
+	names := make([]string, 0, len(r.infoSections))
+	for name := range r.infoSections {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, iok := rank[names[i]]
+		rj, jok := rank[names[j]]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		default:
+			return names[i] < names[j]
+		}
+	})
+	return names
+}
+
+// Info 生成 INFO 的文本，sections 为空时输出默认节
+func (r *Router) Info(sections ...string) string {
+	want := make(map[string]bool)
+	all := false
+	for _, s := range sections {
+		s = strings.ToLower(s)
+		switch s {
+		case "all", "everything":
+			all = true
+		case "default":
+			for name := range defaultInfoSections {
+				want[name] = true
+			}
+		default:
+			want[s] = true
+		}
+	}
+	if len(sections) == 0 {
+		want = defaultInfoSections
+	}
+
+	var b strings.Builder
+	for _, name := range r.sortedInfoSections() {
+		if !all && !want[name] {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		fmt.Fprintf(&b, "# %s\r\n", strings.ToUpper(name[:1])+name[1:])
+		for _, line := range r.infoSections[name]() {
+			b.WriteString(line)
+			b.WriteString("\r\n")
+		}
+	}
+
+	return b.String()
+}
+
+// statsInfo 生成 stats 节
+func (r *Router) statsInfo() []string {
+	pending, freed := r.db.LazyfreeStats()
+	return []string{
+		fmt.Sprintf("total_commands_processed:%d", r.totalCommands.Load()),
+		fmt.Sprintf("expired_keys:%d", r.db.ExpiredKeys()),
+		fmt.Sprintf("lazyfree_pending_objects:%d", pending),
+		fmt.Sprintf("lazyfreed_objects:%d", freed),
+	}
+}
+
+// keyspaceInfo 生成 keyspace 节
+func (r *Router) keyspaceInfo() []string {
+	keys, expires := r.db.KeyspaceStats()
+	if keys == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("db0:keys=%d,expires=%d,avg_ttl=0", keys, expires)}
+}
+
+// commandStatsInfo 生成 commandstats 节，只列出执行过的命令
+func (r *Router) commandStatsInfo() []string {
+	names := make([]string, 0, len(r.stats))
+	for name, s := range r.stats {
+		if s.Calls.Load() > 0 || s.RejectedCalls.Load() > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		s := r.stats[name]
+		lines[i] = fmt.Sprintf("cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=%d,failed_calls=%d",
+			strings.ToLower(name), s.Calls.Load(), s.Usec.Load(), s.UsecPerCall(), s.RejectedCalls.Load(), s.FailedCalls.Load())
+	}
+	return lines
+}
+
+// *****
+type InfoHandler struct {
+	router *Router
+}
+
+func NewInfoHandler(router *Router) *InfoHandler {
+	return &InfoHandler{
+		router: router,
+	}
+}
+
+// Handle 处理 INFO 命令
+// INFO [section ...]
+func (h *InfoHandler) Handle(args []protocol.Value) *protocol.Value {
+	sections := make([]string, len(args))
+	for i, arg := range args {
+		sections[i] = arg.Str
+	}
+
+	return protocol.BulkString(h.router.Info(sections...))
+}
@@ -19,11 +19,6 @@ func NewKeysHandler(db *store.Store) *KeysHandler {
 // Handle 处理 KEYS 命令
 // KEYS pattern - 查找所有匹配模式的键
 func (h *KeysHandler) Handle(args []protocol.Value) *protocol.Value {
-	// KEYS 需要恰好 1 个参数：pattern
-	if len(args) != 1 {
-		return protocol.Error("ERR wrong number of arguments for 'keys' command")
-	}
-
 	pattern := args[0].Str
 
 	// 获取所有键
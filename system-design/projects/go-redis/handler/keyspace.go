@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// *****
+type TypeHandler struct {
+	db *store.Store
+}
+
+func NewTypeHandler(db *store.Store) *TypeHandler {
+	return &TypeHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 TYPE 命令
+// TYPE key
+func (h *TypeHandler) Handle(args []protocol.Value) *protocol.Value {
+	return protocol.SimpleString(h.db.Type(args[0].Str))
+}
+
+// *****
+type RenameHandler struct {
+	db *store.Store
+	nx bool
+}
+
+// NewRenameHandler 创建 RENAME 命令处理器
+func NewRenameHandler(db *store.Store) *RenameHandler {
+	return &RenameHandler{
+		db: db,
+	}
+}
+
+// NewRenameNXHandler 创建 RENAMENX 命令处理器
+func NewRenameNXHandler(db *store.Store) *RenameHandler {
+	return &RenameHandler{
+		db: db,
+		nx: true,
+	}
+}
+
+// Handle 处理 RENAME/RENAMENX 命令
+// RENAME key newkey
+func (h *RenameHandler) Handle(args []protocol.Value) *protocol.Value {
+	src, dst := args[0].Str, args[1].Str
+	renamed, err := h.db.Rename(src, dst, h.nx)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if renamed && src != dst {
+		h.db.Notify(store.NotifyGeneric, "rename_from", src)
+		h.db.Notify(store.NotifyGeneric, "rename_to", dst)
+	}
+
+	if !h.nx {
+		return protocol.SimpleString("OK")
+	}
+	if renamed {
+		return protocol.Integer(1)
+	}
+	return protocol.Integer(0)
+}
+
+// *****
+type CopyHandler struct {
+	db *store.Store
+}
+
+func NewCopyHandler(db *store.Store) *CopyHandler {
+	return &CopyHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 COPY 命令，只有 0 号数据库
+// COPY source destination [DB destination-db] [REPLACE]
+func (h *CopyHandler) Handle(args []protocol.Value) *protocol.Value {
+	replace := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i].Str) {
+		case "REPLACE":
+			replace = true
+		case "DB":
+			if i+1 >= len(args) {
+				return protocol.Error("ERR syntax error")
+			}
+			i++
+			db, err := strconv.ParseInt(args[i].Str, 10, 64)
+			if err != nil {
+				return protocol.Error("ERR value is not an integer or out of range")
+			}
+			if db != 0 {
+				return protocol.Error("ERR DB index is out of range")
+			}
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+	}
+
+	dst := args[1].Str
+	copied, err := h.db.Copy(args[0].Str, dst, replace)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if !copied {
+		return protocol.Integer(0)
+	}
+
+	h.db.Notify(store.NotifyGeneric, "copy_to", dst)
+	return protocol.Integer(1)
+}
+
+// *****
+type DumpHandler struct {
+	db *store.Store
+}
+
+func NewDumpHandler(db *store.Store) *DumpHandler {
+	return &DumpHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 DUMP 命令，返回值的 RDB 格式序列化结果
+// DUMP key
+func (h *DumpHandler) Handle(args []protocol.Value) *protocol.Value {
+	payload, ok := h.db.Dump(args[0].Str)
+	if !ok {
+		return protocol.NullBulkString()
+	}
+	return protocol.BulkString(string(payload))
+}
+
+// *****
+type RestoreHandler struct {
+	db *store.Store
+}
+
+func NewRestoreHandler(db *store.Store) *RestoreHandler {
+	return &RestoreHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 RESTORE 命令，ttl 为 0 表示不过期
+// RESTORE key ttl serialized-value [REPLACE] [ABSTTL] [IDLETIME seconds] [FREQ frequency]
+func (h *RestoreHandler) Handle(args []protocol.Value) *protocol.Value {
+	opts := store.RestoreOptions{IdleTime: -1, Freq: -1}
+	absTTL := false
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i].Str) {
+		case "REPLACE":
+			opts.Replace = true
+		case "ABSTTL":
+			absTTL = true
+		case "IDLETIME":
+			if i+1 >= len(args) || opts.Freq >= 0 {
+				return protocol.Error("ERR syntax error")
+			}
+			i++
+			idle, err := strconv.ParseInt(args[i].Str, 10, 64)
+			if err != nil {
+				return protocol.Error("ERR value is not an integer or out of range")
+			}
+			if idle < 0 {
+				return protocol.Error("ERR Invalid IDLETIME value, must be >= 0")
+			}
+			opts.IdleTime = time.Duration(idle) * time.Second
+		case "FREQ":
+			if i+1 >= len(args) || opts.IdleTime >= 0 {
+				return protocol.Error("ERR syntax error")
+			}
+			i++
+			freq, err := strconv.ParseInt(args[i].Str, 10, 64)
+			if err != nil {
+				return protocol.Error("ERR value is not an integer or out of range")
+			}
+			if freq < 0 || freq > 255 {
+				return protocol.Error("ERR Invalid FREQ value, must be >= 0 and <= 255")
+			}
+			opts.Freq = int(freq)
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+	}
+
+	ttl, err := strconv.ParseInt(args[1].Str, 10, 64)
+	if err != nil {
+		return protocol.Error("ERR value is not an integer or out of range")
+	}
+	if ttl < 0 {
+		return protocol.Error("ERR Invalid TTL value, must be >= 0")
+	}
+	if ttl > 0 {
+		if absTTL {
+			opts.ExpireAt = time.UnixMilli(ttl)
+		} else {
+			opts.ExpireAt = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		}
+	}
+
+	key := args[0].Str
+	created, err := h.db.Restore(key, []byte(args[2].Str), opts)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if created {
+		h.db.Notify(store.NotifyGeneric, "restore", key)
+	}
+	return protocol.SimpleString("OK")
+}
+
+// *****
+type ObjectHandler struct {
+	db *store.Store
+}
+
+func NewObjectHandler(db *store.Store) *ObjectHandler {
+	return &ObjectHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 OBJECT 命令
+// OBJECT ENCODING|IDLETIME|FREQ|REFCOUNT key
+func (h *ObjectHandler) Handle(args []protocol.Value) *protocol.Value {
+	sub := strings.ToUpper(args[0].Str)
+	switch sub {
+	case "HELP":
+		lines := []string{
+			"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"ENCODING <key>",
+			"    Return the kind of internal representation used in order to store the value",
+			"    associated with a <key>.",
+			"FREQ <key>",
+			"    Return the access frequency index of the <key>. The returned integer is",
+			"    proportional to the logarithm of the recent access frequency of the key.",
+			"IDLETIME <key>",
+			"    Return the idle time of the <key>, that is the approximated number of",
+			"    seconds elapsed since the last access to the key.",
+			"REFCOUNT <key>",
+			"    Return the number of references of the value associated with the specified",
+			"    <key>.",
+			"HELP",
+			"    Print this help.",
+		}
+		values := make([]protocol.Value, len(lines))
+		for i, line := range lines {
+			values[i] = *protocol.SimpleString(line)
+		}
+		return protocol.Array(values)
+	case "ENCODING", "IDLETIME", "FREQ", "REFCOUNT":
+		if len(args) != 2 {
+			return protocol.Error("ERR wrong number of arguments for 'object|" + strings.ToLower(sub) + "' command")
+		}
+	default:
+		return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try OBJECT HELP.")
+	}
+
+	info, ok := h.db.Object(args[1].Str)
+	if !ok {
+		return protocol.NullBulkString()
+	}
+
+	switch sub {
+	case "ENCODING":
+		return protocol.BulkString(info.Encoding)
+	case "IDLETIME":
+		return protocol.Integer(int64(info.IdleTime / time.Second))
+	case "FREQ":
+		return protocol.Integer(int64(info.Freq))
+	default:
+		// 值不在键之间共享，引用计数总是 1
+		return protocol.Integer(1)
+	}
+}
+
+// *****
+type RandomKeyHandler struct {
+	db *store.Store
+}
+
+func NewRandomKeyHandler(db *store.Store) *RandomKeyHandler {
+	return &RandomKeyHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 RANDOMKEY 命令
+// RANDOMKEY
+func (h *RandomKeyHandler) Handle(args []protocol.Value) *protocol.Value {
+	key, ok := h.db.RandomKey()
+	if !ok {
+		return protocol.NullBulkString()
+	}
+	return protocol.BulkString(key)
+}
+
+// *****
+type TouchHandler struct {
+	db *store.Store
+}
+
+func NewTouchHandler(db *store.Store) *TouchHandler {
+	return &TouchHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 TOUCH 命令，返回存在的键的个数
+// TOUCH key [key ...]
+func (h *TouchHandler) Handle(args []protocol.Value) *protocol.Value {
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = arg.Str
+	}
+	return protocol.Integer(int64(h.db.Touch(keys)))
+}
+
+// *****
+type UnlinkHandler struct {
+	db *store.Store
+}
+
+func NewUnlinkHandler(db *store.Store) *UnlinkHandler {
+	return &UnlinkHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 UNLINK 命令，与 DEL 相同，但大对象的内存在后台释放
+// UNLINK key [key ...]
+func (h *UnlinkHandler) Handle(args []protocol.Value) *protocol.Value {
+	var count int64
+	for _, arg := range args {
+		if h.db.Unlink(arg.Str) {
+			count++
+			h.db.Notify(store.NotifyGeneric, "del", arg.Str)
+		}
+	}
+	return protocol.Integer(count)
+}
+
+// *****
+type DBSizeHandler struct {
+	db *store.Store
+}
+
+func NewDBSizeHandler(db *store.Store) *DBSizeHandler {
+	return &DBSizeHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 DBSIZE 命令，与 INFO keyspace 一样，尚未被删除的过期键也计算在内
+func (h *DBSizeHandler) Handle(args []protocol.Value) *protocol.Value {
+	keys, _ := h.db.KeyspaceStats()
+	return protocol.Integer(int64(keys))
+}
+
+// *****
+type ScanHandler struct {
+	db *store.Store
+}
+
+func NewScanHandler(db *store.Store) *ScanHandler {
+	return &ScanHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 SCAN 命令，回复 [下一次的游标, [键...]]，游标为 0 时遍历结束
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func (h *ScanHandler) Handle(args []protocol.Value) *protocol.Value {
+	cursor, err := strconv.ParseUint(args[0].Str, 10, 64)
+	if err != nil {
+		return protocol.Error("ERR invalid cursor")
+	}
+
+	var opts store.ScanOptions
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return protocol.Error("ERR syntax error")
+		}
+		switch strings.ToUpper(args[i].Str) {
+		case "MATCH":
+			opts.Match = args[i+1].Str
+		case "COUNT":
+			count, err := strconv.Atoi(args[i+1].Str)
+			if err != nil {
+				return protocol.Error("ERR value is not an integer or out of range")
+			}
+			if count < 1 {
+				return protocol.Error("ERR syntax error")
+			}
+			opts.Count = count
+		case "TYPE":
+			opts.Type = strings.ToLower(args[i+1].Str)
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+		i++
+	}
+
+	next, keys := h.db.Scan(cursor, opts)
+	items := make([]protocol.Value, len(keys))
+	for i, key := range keys {
+		items[i] = *protocol.BulkString(key)
+	}
+	return protocol.Array([]protocol.Value{
+		*protocol.BulkString(strconv.FormatUint(next, 10)),
+		*protocol.Array(items),
+	})
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypeRenameCopy(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "SET", "str", "v")
+	execCommand(r, "XADD", "s", "*", "f", "v")
+
+	for key, want := range map[string]string{"str": "string", "s": "stream", "missing": "none"} {
+		if resp := execCommand(r, "TYPE", key); resp.Type != protocol.StringType || resp.Str != want {
+			t.Errorf("TYPE %s = %+v, want %s", key, resp, want)
+		}
+	}
+
+	if resp := execCommand(r, "RENAME", "missing", "x"); resp.Str != store.ErrNoSuchKey.Error() {
+		t.Errorf("RENAME missing: %+v", resp)
+	}
+	if resp := execCommand(r, "RENAMENX", "str", "s"); resp.Int != 0 {
+		t.Errorf("RENAMENX to existing key: %+v", resp)
+	}
+	if resp := execCommand(r, "RENAME", "str", "s"); resp.Str != "OK" {
+		t.Fatalf("RENAME: %+v", resp)
+	}
+	if resp := execCommand(r, "GET", "s"); resp.Str != "v" {
+		t.Errorf("GET after RENAME: %+v", resp)
+	}
+	if resp := execCommand(r, "RENAMENX", "s", "t"); resp.Int != 1 {
+		t.Errorf("RENAMENX: %+v", resp)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"COPY", "t", "t"}, store.ErrSameObject.Error()},
+		{[]string{"COPY", "t", "u", "DB", "1"}, "ERR DB index is out of range"},
+		{[]string{"COPY", "t", "u", "DB"}, "ERR syntax error"},
+		{[]string{"COPY", "t", "u", "FORCE"}, "ERR syntax error"},
+	} {
+		if resp := execCommand(r, tt.args...); resp.Type != protocol.ErrorType || resp.Str != tt.want {
+			t.Errorf("%v = %+v, want %q", tt.args, resp, tt.want)
+		}
+	}
+
+	execCommand(r, "SET", "u", "old")
+	if resp := execCommand(r, "COPY", "t", "u"); resp.Int != 0 {
+		t.Errorf("COPY without REPLACE: %+v", resp)
+	}
+	if resp := execCommand(r, "COPY", "t", "u", "DB", "0", "REPLACE"); resp.Int != 1 {
+		t.Errorf("COPY REPLACE: %+v", resp)
+	}
+	if resp := execCommand(r, "GET", "u"); resp.Str != "v" {
+		t.Errorf("GET after COPY: %+v", resp)
+	}
+}
+
+func TestDumpRestore(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "XADD", "s", "1-1", "f", "v")
+
+	if resp := execCommand(r, "DUMP", "missing"); !resp.IsNull {
+		t.Errorf("DUMP missing: %+v", resp)
+	}
+	dump := execCommand(r, "DUMP", "s")
+	if dump.Type != protocol.BulkStringType || dump.IsNull {
+		t.Fatalf("DUMP: %+v", dump)
+	}
+
+	if resp := execCommand(r, "RESTORE", "s", "0", dump.Str); resp.Str != store.ErrBusyKey.Error() {
+		t.Errorf("RESTORE existing key: %+v", resp)
+	}
+	if resp := execCommand(r, "RESTORE", "copy", "0", "garbage"); resp.Str != store.ErrDumpPayload.Error() {
+		t.Errorf("RESTORE bad payload: %+v", resp)
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-1"}, "ERR Invalid TTL value, must be >= 0"},
+		{[]string{"0", dump.Str, "IDLETIME", "-1"}, "ERR Invalid IDLETIME value, must be >= 0"},
+		{[]string{"0", dump.Str, "FREQ", "256"}, "ERR Invalid FREQ value, must be >= 0 and <= 255"},
+		{[]string{"0", dump.Str, "IDLETIME", "1", "FREQ", "1"}, "ERR syntax error"},
+		{[]string{"0", dump.Str, "NOW"}, "ERR syntax error"},
+	} {
+		args := append([]string{"RESTORE", "copy"}, tt.args...)
+		if len(tt.args) == 1 {
+			args = append(args, dump.Str)
+		}
+		if resp := execCommand(r, args...); resp.Type != protocol.ErrorType || resp.Str != tt.want {
+			t.Errorf("%v = %+v, want %q", tt.args, resp, tt.want)
+		}
+	}
+
+	if resp := execCommand(r, "RESTORE", "copy", "100000", dump.Str, "IDLETIME", "1000"); resp.Str != "OK" {
+		t.Fatalf("RESTORE: %+v", resp)
+	}
+	// OBJECT 不算访问，XLEN 等命令会刷新空闲时间
+	if resp := execCommand(r, "OBJECT", "IDLETIME", "copy"); resp.Int < 1000 {
+		t.Errorf("OBJECT IDLETIME after RESTORE: %+v", resp)
+	}
+	if resp := execCommand(r, "XLEN", "copy"); resp.Int != 1 {
+		t.Errorf("XLEN after RESTORE: %+v", resp)
+	}
+	if resp := execCommand(r, "PTTL", "copy"); resp.Int <= 0 || resp.Int > 100000 {
+		t.Errorf("PTTL after RESTORE: %+v", resp)
+	}
+
+	// ABSTTL 的时间点已经过去时 REPLACE 只删除原有的键
+	past := strconv.FormatInt(time.Now().Add(-time.Second).UnixMilli(), 10)
+	if resp := execCommand(r, "RESTORE", "copy", past, dump.Str, "REPLACE", "ABSTTL"); resp.Str != "OK" {
+		t.Fatalf("RESTORE ABSTTL: %+v", resp)
+	}
+	if resp := execCommand(r, "EXISTS", "copy"); resp.Int != 0 {
+		t.Errorf("EXISTS after expired RESTORE: %+v", resp)
+	}
+}
+
+func TestObjectRandomKeyTouchUnlink(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	if resp := execCommand(r, "RANDOMKEY"); !resp.IsNull {
+		t.Errorf("RANDOMKEY on empty db: %+v", resp)
+	}
+
+	execCommand(r, "SET", "n", "100")
+	execCommand(r, "SET", "s", "hello")
+	if resp := execCommand(r, "RANDOMKEY"); resp.Str != "n" && resp.Str != "s" {
+		t.Errorf("RANDOMKEY: %+v", resp)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want protocol.Value
+	}{
+		{[]string{"OBJECT", "ENCODING", "n"}, *protocol.BulkString("int")},
+		{[]string{"OBJECT", "ENCODING", "s"}, *protocol.BulkString("embstr")},
+		{[]string{"OBJECT", "FREQ", "s"}, *protocol.Integer(store.LFUInitVal)},
+		{[]string{"OBJECT", "IDLETIME", "s"}, *protocol.Integer(0)},
+		{[]string{"OBJECT", "REFCOUNT", "s"}, *protocol.Integer(1)},
+		{[]string{"OBJECT", "ENCODING", "missing"}, *protocol.NullBulkString()},
+		{[]string{"OBJECT", "ENCODING"}, *protocol.Error("ERR wrong number of arguments for 'object|encoding' command")},
+		{[]string{"OBJECT", "SIZE", "s"}, *protocol.Error("ERR unknown subcommand 'SIZE'. Try OBJECT HELP.")},
+		{[]string{"TOUCH", "n", "s", "missing"}, *protocol.Integer(2)},
+	} {
+		resp := execCommand(r, tt.args...)
+		if resp.Type != tt.want.Type || resp.Str != tt.want.Str || resp.Int != tt.want.Int || resp.IsNull != tt.want.IsNull {
+			t.Errorf("%v = %+v, want %+v", tt.args, resp, tt.want)
+		}
+	}
+	if resp := execCommand(r, "OBJECT", "HELP"); len(resp.Array) == 0 {
+		t.Errorf("OBJECT HELP: %+v", resp)
+	}
+
+	for i := 0; i < store.LazyfreeThreshold+1; i++ {
+		execCommand(r, "XADD", "big", "*", "f", "v")
+	}
+	if resp := execCommand(r, "UNLINK", "big", "n", "missing"); resp.Int != 2 {
+		t.Errorf("UNLINK: %+v", resp)
+	}
+	if resp := execCommand(r, "EXISTS", "big", "n"); resp.Int != 0 {
+		t.Errorf("EXISTS after UNLINK: %+v", resp)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(execCommand(r, "INFO", "stats").Str, "lazyfreed_objects:1\r\n") {
+		if time.Now().After(deadline) {
+			t.Fatalf("INFO stats: %q", execCommand(r, "INFO", "stats").Str)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScan(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	for i := 0; i < 30; i++ {
+		execCommand(r, "SET", "key:"+strconv.Itoa(i), "v")
+	}
+	execCommand(r, "XADD", "s", "*", "f", "v")
+
+	seen := make(map[string]bool)
+	cursor, calls := "0", 0
+	for {
+		resp := execCommand(r, "SCAN", cursor, "MATCH", "key:*", "COUNT", "5")
+		if resp.Type != protocol.ArrayType || len(resp.Array) != 2 {
+			t.Fatalf("SCAN: %+v", resp)
+		}
+		for _, v := range resp.Array[1].Array {
+			seen[v.Str] = true
+		}
+		calls++
+		if cursor = resp.Array[0].Str; cursor == "0" {
+			break
+		}
+	}
+	if len(seen) != 30 || seen["s"] || calls < 2 {
+		t.Errorf("SCAN MATCH key:* returned %d keys in %d calls", len(seen), calls)
+	}
+
+	resp := execCommand(r, "SCAN", "0", "TYPE", "STREAM", "COUNT", "1000")
+	if len(resp.Array[1].Array) != 1 || resp.Array[1].Array[0].Str != "s" {
+		t.Errorf("SCAN TYPE stream: %+v", resp)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"SCAN", "-1"}, "ERR invalid cursor"},
+		{[]string{"SCAN", "x"}, "ERR invalid cursor"},
+		{[]string{"SCAN", "0", "COUNT", "0"}, "ERR syntax error"},
+		{[]string{"SCAN", "0", "COUNT", "x"}, "ERR value is not an integer or out of range"},
+		{[]string{"SCAN", "0", "MATCH"}, "ERR syntax error"},
+		{[]string{"SCAN", "0", "LIMIT", "1"}, "ERR syntax error"},
+	} {
+		if resp := execCommand(r, tt.args...); resp.Type != protocol.ErrorType || resp.Str != tt.want {
+			t.Errorf("%v = %+v, want %q", tt.args, resp, tt.want)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"fmt"
+	"go-redis/protocol"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 延迟监控
+//
+// 与 Redis 的 latency monitor 相同，耗时不小于 latency-monitor-threshold 毫秒的命令作为一次延迟事件记录：
+// 带 fast 标志的命令记为 fast-command，其余记为 command。每个事件保留最近 160 个采样，
+// 同一秒内的多个采样合并为其中最大的一个。阈值为 0 时不记录。
+// LATENCY HISTOGRAM 不使用这里的采样，而是直接输出 CommandStats 中每个命令的耗时直方图
+
+// latencyHistoryLen 是每个事件保留的采样数
+const latencyHistoryLen = 160
+
+// LatencySample 是延迟事件的一个采样
+type LatencySample struct {
+	Time    time.Time
+	Latency time.Duration
+}
+
+// LatencyLatest 是 LATENCY LATEST 中的一个事件
+type LatencyLatest struct {
+	Event  string
+	Time   time.Time
+	Latest time.Duration
+	Max    time.Duration
+}
+
+type latencyEvent struct {
+	samples []LatencySample
+	max     time.Duration
+}
+
+// LatencyMonitor 按事件记录超过阈值的延迟
+type LatencyMonitor struct {
+	threshold atomic.Int64 // 毫秒，0 表示关闭
+
+	mu     sync.Mutex
+	events map[string]*latencyEvent
+}
+
+// NewLatencyMonitor 创建延迟监控，默认关闭
+func NewLatencyMonitor() *LatencyMonitor {
+	return &LatencyMonitor{
+		events: make(map[string]*latencyEvent),
+	}
+}
+
+// Threshold 返回记录阈值（毫秒）
+func (m *LatencyMonitor) Threshold() int64 {
+	return m.threshold.Load()
+}
+
+// SetThreshold 设置记录阈值（毫秒）
+func (m *LatencyMonitor) SetThreshold(ms int64) {
+	m.threshold.Store(ms)
+}
+
+// Record 在 latency 不小于阈值时为 event 记录一个采样
+func (m *LatencyMonitor) Record(event string, at time.Time, latency time.Duration) {
+	threshold := m.threshold.Load()
+	if threshold <= 0 || latency < time.Duration(threshold)*time.Millisecond {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.events[event]
+	if !ok {
+		e = &latencyEvent{}
+		m.events[event] = e
+	}
+	e.max = max(e.max, latency)
+
+	if n := len(e.samples); n > 0 && e.samples[n-1].Time.Unix() == at.Unix() {
+		last := &e.samples[n-1]
+		last.Latency = max(last.Latency, latency)
+		return
+	}
+	if len(e.samples) == latencyHistoryLen {
+		copy(e.samples, e.samples[1:])
+		e.samples = e.samples[:latencyHistoryLen-1]
+	}
+	e.samples = append(e.samples, LatencySample{Time: at, Latency: latency})
+}
+
+// Latest 返回每个事件最近一次的采样和历史最大值，按事件名排序
+func (m *LatencyMonitor) Latest() []LatencyLatest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]LatencyLatest, 0, len(m.events))
+	for name, e := range m.events {
+		last := e.samples[len(e.samples)-1]
+		res = append(res, LatencyLatest{Event: name, Time: last.Time, Latest: last.Latency, Max: e.max})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Event < res[j].Event })
+	return res
+}
+
+// History 返回事件的采样，旧的在前
+func (m *LatencyMonitor) History(event string) []LatencySample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.events[event]
+	if !ok {
+		return nil
+	}
+	return append([]LatencySample(nil), e.samples...)
+}
+
+// Reset 清除指定的事件，没有指定时清除所有事件，返回清除的事件数
+func (m *LatencyMonitor) Reset(events ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(events) == 0 {
+		n := len(m.events)
+		m.events = make(map[string]*latencyEvent)
+		return n
+	}
+	n := 0
+	for _, name := range events {
+		if _, ok := m.events[name]; ok {
+			delete(m.events, name)
+			n++
+		}
+	}
+	return n
+}
+
+// doctor 生成 LATENCY DOCTOR 的报告
+func (m *LatencyMonitor) doctor() string {
+	latest := m.Latest()
+	if len(latest) == 0 {
+		if m.Threshold() == 0 {
+			return "I'm sorry, Dave, I can't do that. Latency monitoring is disabled in this Redis instance. " +
+				"You may use \"CONFIG SET latency-monitor-threshold <milliseconds>.\" in order to enable it. " +
+				"If we weren't in a deep space mission I'd suggest to take a look at https://redis.io/topics/latency-monitor.\n"
+		}
+		return "Dave, no latency spike was observed during the lifetime of this Redis instance, not in the slightest bit. " +
+			"I honestly think you ought to sleep tonight.\n"
+	}
+
+	var b strings.Builder
+	b.WriteString("Dave, I have observed latency spikes in this Redis instance. You don't mind talking about it, do you Dave?\n\n")
+	for i, l := range latest {
+		samples := m.History(l.Event)
+
+		var sum float64
+		for _, s := range samples {
+			sum += float64(s.Latency.Milliseconds())
+		}
+		avg := sum / float64(len(samples))
+		var dev float64
+		for _, s := range samples {
+			dev += math.Abs(float64(s.Latency.Milliseconds()) - avg)
+		}
+		dev /= float64(len(samples))
+		var period float64
+		if len(samples) > 1 {
+			period = samples[len(samples)-1].Time.Sub(samples[0].Time).Seconds() / float64(len(samples)-1)
+		}
+
+		fmt.Fprintf(&b, "%d. %s: %d latency spikes (average %dms, mean deviation %dms, period %.2f sec). Worst all time event %dms.\n",
+			i+1, l.Event, len(samples), int64(avg), int64(dev), period, l.Max.Milliseconds())
+	}
+
+	b.WriteString("\nI have a few advices for you:\n\n")
+	b.WriteString("- Check your Slow Log to understand what are the commands you are running which are too slow to execute. " +
+		"Please check https://redis.io/commands/slowlog for more information.\n")
+	for _, l := range latest {
+		if l.Event == "fast-command" {
+			b.WriteString("- The system is slow to execute Redis code paths not containing slow commands. " +
+				"This may signal a slow or overloaded host, or a very large number of connections.\n")
+			break
+		}
+	}
+	return b.String()
+}
+
+// *****
+type LatencyHandler struct {
+	router *Router
+}
+
+func NewLatencyHandler(r *Router) *LatencyHandler {
+	return &LatencyHandler{
+		router: r,
+	}
+}
+
+// Handle 处理 LATENCY 命令
+// LATENCY LATEST | LATENCY HISTORY event | LATENCY RESET [event ...] | LATENCY DOCTOR
+// LATENCY HISTOGRAM [command ...] | LATENCY HELP
+func (h *LatencyHandler) Handle(args []protocol.Value) *protocol.Value {
+	m := h.router.latency
+	sub := strings.ToUpper(args[0].Str)
+	switch sub {
+	case "HELP":
+		return statusArray([]string{
+			"LATENCY <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"DOCTOR",
+			"    Return a human readable latency analysis report.",
+			"HISTORY <event>",
+			"    Return time-latency samples for the <event> class.",
+			"LATEST",
+			"    Return the latest latency samples for all events.",
+			"RESET [<event> ...]",
+			"    Reset latency data of one or more <event> classes.",
+			"    (default: reset all data for all event classes)",
+			"HISTOGRAM [COMMAND ...]",
+			"    Return a cumulative distribution of latencies in the format of a histogram for the specified command names.",
+			"    If no commands are specified then all histograms are replied.",
+			"HELP",
+			"    Print this help.",
+		})
+
+	case "LATEST", "DOCTOR":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'latency|" + strings.ToLower(sub) + "' command")
+		}
+		if sub == "DOCTOR" {
+			return protocol.BulkString(m.doctor())
+		}
+		latest := m.Latest()
+		values := make([]protocol.Value, len(latest))
+		for i, l := range latest {
+			values[i] = *protocol.Array([]protocol.Value{
+				*protocol.BulkString(l.Event),
+				*protocol.Integer(l.Time.Unix()),
+				*protocol.Integer(l.Latest.Milliseconds()),
+				*protocol.Integer(l.Max.Milliseconds()),
+			})
+		}
+		return protocol.Array(values)
+
+	case "HISTORY":
+		if len(args) != 2 {
+			return protocol.Error("ERR wrong number of arguments for 'latency|history' command")
+		}
+		samples := m.History(args[1].Str)
+		values := make([]protocol.Value, len(samples))
+		for i, s := range samples {
+			values[i] = *protocol.Array([]protocol.Value{
+				*protocol.Integer(s.Time.Unix()),
+				*protocol.Integer(s.Latency.Milliseconds()),
+			})
+		}
+		return protocol.Array(values)
+
+	case "RESET":
+		events := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			events[i] = arg.Str
+		}
+		return protocol.Integer(int64(m.Reset(events...)))
+
+	case "HISTOGRAM":
+		return h.histogram(args[1:])
+	}
+
+	return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try LATENCY HELP.")
+}
+
+// histogram 返回 [命令名, [calls, 次数, histogram_usec, [上界(微秒), 累计次数, ...]], ...]，
+// 只列出执行过的命令和有采样落入的桶；没有指定命令时列出所有执行过的命令
+func (h *LatencyHandler) histogram(args []protocol.Value) *protocol.Value {
+	var names []string
+	if len(args) == 0 {
+		for name := range h.router.stats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	} else {
+		for _, arg := range args {
+			names = append(names, strings.ToUpper(arg.Str))
+		}
+	}
+
+	var values []protocol.Value
+	for _, name := range names {
+		s, ok := h.router.stats[name]
+		if !ok || s.Calls.Load() == 0 {
+			continue
+		}
+
+		counts := s.LatencyCounts()
+		var buckets []protocol.Value
+		var prev int64
+		for i, count := range counts {
+			if count == prev {
+				continue
+			}
+			prev = count
+			buckets = append(buckets,
+				*protocol.Integer(LatencyBuckets[i].Microseconds()),
+				*protocol.Integer(count))
+		}
+
+		values = append(values,
+			*protocol.BulkString(strings.ToLower(name)),
+			*fieldArray(
+				"calls", protocol.Integer(s.Calls.Load()),
+				"histogram_usec", protocol.Array(buckets),
+			))
+	}
+	return protocol.Array(values)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"go-redis/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyMonitor(t *testing.T) {
+	m := NewLatencyMonitor()
+	now := time.Unix(1700000000, 0)
+
+	// 阈值为 0 时关闭
+	m.Record("command", now, time.Second)
+	if len(m.Latest()) != 0 {
+		t.Fatal("disabled monitor should not record")
+	}
+
+	m.SetThreshold(100)
+	m.Record("command", now, 50*time.Millisecond)
+	m.Record("command", now, 200*time.Millisecond)
+	m.Record("command", now.Add(500*time.Millisecond), 300*time.Millisecond)
+	if h := m.History("command"); len(h) != 1 || h[0].Latency != 300*time.Millisecond {
+		t.Fatalf("samples in the same second should be merged: %+v", h)
+	}
+
+	for i := 1; i <= latencyHistoryLen; i++ {
+		m.Record("command", now.Add(time.Duration(i)*time.Second), 150*time.Millisecond)
+	}
+	h := m.History("command")
+	if len(h) != latencyHistoryLen || !h[0].Time.Equal(now.Add(time.Second)) {
+		t.Errorf("history should keep the latest %d samples, got %d starting at %v", latencyHistoryLen, len(h), h[0].Time)
+	}
+	latest := m.Latest()
+	if len(latest) != 1 || latest[0].Latest != 150*time.Millisecond || latest[0].Max != 300*time.Millisecond {
+		t.Errorf("Latest = %+v", latest)
+	}
+
+	m.Record("fast-command", now, time.Second)
+	if n := m.Reset("fast-command", "missing"); n != 1 {
+		t.Errorf("Reset(fast-command, missing) = %d", n)
+	}
+	if n := m.Reset(); n != 1 || len(m.Latest()) != 0 {
+		t.Errorf("Reset() = %d, events left %d", n, len(m.Latest()))
+	}
+}
+
+func TestLatencyCommand(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	if resp := execCommand(r, "LATENCY", "DOCTOR"); !strings.Contains(resp.Str, "Latency monitoring is disabled") {
+		t.Errorf("DOCTOR with monitoring disabled: %q", resp.Str)
+	}
+	execCommand(r, "CONFIG", "SET", "latency-monitor-threshold", "100")
+	if resp := execCommand(r, "CONFIG", "GET", "latency-monitor-threshold"); len(resp.Array) != 2 || resp.Array[1].Str != "100" {
+		t.Fatalf("CONFIG GET latency-monitor-threshold: %+v", resp)
+	}
+	if resp := execCommand(r, "LATENCY", "DOCTOR"); !strings.Contains(resp.Str, "no latency spike") {
+		t.Errorf("DOCTOR without events: %q", resp.Str)
+	}
+
+	now := time.Now()
+	r.latency.Record("command", now.Add(-time.Second), 120*time.Millisecond)
+	r.latency.Record("command", now, 250*time.Millisecond)
+
+	resp := execCommand(r, "LATENCY", "LATEST")
+	if len(resp.Array) != 1 {
+		t.Fatalf("LATEST: %+v", resp)
+	}
+	if e := resp.Array[0].Array; e[0].Str != "command" || e[1].Int != now.Unix() || e[2].Int != 250 || e[3].Int != 250 {
+		t.Errorf("LATEST entry: %+v", e)
+	}
+	if resp := execCommand(r, "LATENCY", "HISTORY", "command"); len(resp.Array) != 2 || resp.Array[0].Array[1].Int != 120 {
+		t.Errorf("HISTORY: %+v", resp)
+	}
+	if resp := execCommand(r, "LATENCY", "DOCTOR"); !strings.Contains(resp.Str, "1. command: 2 latency spikes (average 185ms") {
+		t.Errorf("DOCTOR: %q", resp.Str)
+	}
+	if resp := execCommand(r, "LATENCY", "RESET"); resp.Int != 1 {
+		t.Errorf("RESET: %+v", resp)
+	}
+
+	execCommand(r, "SET", "k", "v")
+	execCommand(r, "SET", "k", "v")
+	resp = execCommand(r, "LATENCY", "HISTOGRAM", "set", "get")
+	if len(resp.Array) != 2 || resp.Array[0].Str != "set" {
+		t.Fatalf("HISTOGRAM: %+v", resp)
+	}
+	fields := resp.Array[1].Array
+	if fields[0].Str != "calls" || fields[1].Int != 2 || fields[2].Str != "histogram_usec" {
+		t.Errorf("HISTOGRAM fields: %+v", fields)
+	}
+	if buckets := fields[3].Array; len(buckets) == 0 || buckets[len(buckets)-1].Int != 2 {
+		t.Errorf("HISTOGRAM buckets should be cumulative: %+v", buckets)
+	}
+
+	if resp := execCommand(r, "LATENCY", "NOPE"); !strings.HasPrefix(resp.Str, "ERR unknown subcommand") {
+		t.Errorf("LATENCY NOPE: %+v", resp)
+	}
+}
+
+// 阻塞命令等待数据的时间不计入耗时，不会触发慢日志和延迟事件
+func TestBlockedTimeExcluded(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "CONFIG", "SET", "slowlog-log-slower-than", "20000")
+	execCommand(r, "CONFIG", "SET", "latency-monitor-threshold", "20")
+
+	if resp := execCommand(r, "XREAD", "BLOCK", "50", "STREAMS", "s", "$"); !resp.IsNull {
+		t.Fatalf("XREAD BLOCK should time out: %+v", resp)
+	}
+	execCommand(r, "XGROUP", "CREATE", "g", "grp", "$", "MKSTREAM")
+	execCommand(r, "XREADGROUP", "GROUP", "grp", "c", "BLOCK", "50", "STREAMS", "g", ">")
+
+	for _, name := range []string{"XREAD", "XREADGROUP"} {
+		if s := r.stats[name]; s.Calls.Load() != 1 || s.Usec.Load() >= 20000 {
+			t.Errorf("%s: calls %d usec %d", name, s.Calls.Load(), s.Usec.Load())
+		}
+	}
+	if n := r.SlowLog().Len(); n != 0 {
+		t.Errorf("blocked commands should not be in the slowlog: %+v", r.SlowLog().Get(-1))
+	}
+	if latest := r.latency.Latest(); len(latest) != 0 {
+		t.Errorf("blocked commands should not be latency events: %+v", latest)
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// *****
+type MemoryHandler struct {
+	db      *store.Store
+	startup int64        // 创建时堆上已分配的字节数，作为服务器本身的开销
+	peak    atomic.Int64 // MEMORY 命令观察到的最大分配字节数
+}
+
+func NewMemoryHandler(db *store.Store) *MemoryHandler {
+	h := &MemoryHandler{
+		db: db,
+	}
+	h.startup = h.stats().allocated
+	return h
+}
+
+// Handle 处理 MEMORY 命令
+// MEMORY USAGE key [SAMPLES count] | MEMORY STATS | MEMORY DOCTOR | MEMORY PURGE | MEMORY HELP
+func (h *MemoryHandler) Handle(args []protocol.Value) *protocol.Value {
+	sub := strings.ToUpper(args[0].Str)
+	switch sub {
+	case "HELP":
+		return statusArray([]string{
+			"MEMORY <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"DOCTOR",
+			"    Return memory problems reports.",
+			"PURGE",
+			"    Return unused memory to the operating system.",
+			"STATS",
+			"    Return information about the memory usage of the server.",
+			"USAGE <key> [SAMPLES <count>]",
+			"    Return memory in bytes used by <key> and its value. Nested values are",
+			"    sampled up to <count> times (default: 5, 0 means sample all).",
+			"HELP",
+			"    Print this help.",
+		})
+	case "USAGE":
+		if len(args) < 2 {
+			return protocol.Error("ERR wrong number of arguments for 'memory|usage' command")
+		}
+		return h.usage(args[1].Str, args[2:])
+	case "STATS", "DOCTOR", "PURGE":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'memory|" + strings.ToLower(sub) + "' command")
+		}
+	default:
+		return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try MEMORY HELP.")
+	}
+
+	switch sub {
+	case "STATS":
+		return h.statsReply(h.stats())
+	case "DOCTOR":
+		return protocol.BulkString(memoryDoctor(h.stats()))
+	default:
+		debug.FreeOSMemory()
+		return protocol.SimpleString("OK")
+	}
+}
+
+func (h *MemoryHandler) usage(key string, opts []protocol.Value) *protocol.Value {
+	samples := store.DefaultMemorySamples
+	for i := 0; i < len(opts); i += 2 {
+		if !strings.EqualFold(opts[i].Str, "SAMPLES") || i+1 >= len(opts) {
+			return protocol.Error("ERR syntax error")
+		}
+		n, err := strconv.Atoi(opts[i+1].Str)
+		if err != nil {
+			return protocol.Error("ERR value is not an integer or out of range")
+		}
+		if n < 0 {
+			return protocol.Error("ERR syntax error")
+		}
+		samples = n
+	}
+
+	size, ok := h.db.MemoryUsage(key, samples)
+	if !ok {
+		return protocol.NullBulkString()
+	}
+	return protocol.Integer(size)
+}
+
+// memoryStats 是 MEMORY STATS 和 MEMORY DOCTOR 使用的内存信息，字节数来自 Go 运行时和键空间的估算
+type memoryStats struct {
+	peak      int64 // 观察到的最大分配字节数
+	allocated int64 // 堆上已分配的字节数
+	startup   int64
+	active    int64 // 正在使用的堆内存（包括分配器内部的碎片）
+	resident  int64 // 从操作系统获得、尚未归还的堆内存
+
+	keys            int
+	mainOverhead    int64
+	expiresOverhead int64
+}
+
+func (h *MemoryHandler) stats() memoryStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	st := memoryStats{
+		allocated: int64(ms.HeapAlloc),
+		startup:   h.startup,
+		active:    int64(ms.HeapInuse),
+		resident:  int64(ms.HeapSys - ms.HeapReleased),
+	}
+	for {
+		peak := h.peak.Load()
+		if st.allocated <= peak || h.peak.CompareAndSwap(peak, st.allocated) {
+			st.peak = max(peak, st.allocated)
+			break
+		}
+	}
+	if h.db != nil {
+		st.keys, _ = h.db.KeyspaceStats()
+		st.mainOverhead, st.expiresOverhead = h.db.MemoryOverhead()
+	}
+	return st
+}
+
+// overhead 返回不属于数据集的字节数：服务器启动时的开销和键空间的哈希表
+func (st memoryStats) overhead() int64 {
+	return st.startup + st.mainOverhead + st.expiresOverhead
+}
+
+// ratio 返回 a/b，b 为 0 时返回 0
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// statsReply 按 Redis 的 MEMORY STATS 格式回复，没有对应概念的字段（复制积压缓冲区、AOF、Lua）不出现
+func (h *MemoryHandler) statsReply(st memoryStats) *protocol.Value {
+	overhead := st.overhead()
+	dataset := max(st.allocated-overhead, 0)
+	var bytesPerKey int64
+	if st.keys > 0 {
+		bytesPerKey = max(st.allocated-st.startup, 0) / int64(st.keys)
+	}
+	float := func(f float64) *protocol.Value {
+		return protocol.BulkString(strconv.FormatFloat(f, 'f', 2, 64))
+	}
+
+	return fieldArray(
+		"peak.allocated", protocol.Integer(st.peak),
+		"total.allocated", protocol.Integer(st.allocated),
+		"startup.allocated", protocol.Integer(st.startup),
+		"db.0", fieldArray(
+			"overhead.hashtable.main", protocol.Integer(st.mainOverhead),
+			"overhead.hashtable.expires", protocol.Integer(st.expiresOverhead)),
+		"overhead.total", protocol.Integer(overhead),
+		"keys.count", protocol.Integer(int64(st.keys)),
+		"keys.bytes-per-key", protocol.Integer(bytesPerKey),
+		"dataset.bytes", protocol.Integer(dataset),
+		"dataset.percentage", float(100*ratio(dataset, st.allocated-st.startup)),
+		"peak.percentage", float(100*ratio(st.allocated, st.peak)),
+		"allocator.allocated", protocol.Integer(st.allocated),
+		"allocator.active", protocol.Integer(st.active),
+		"allocator.resident", protocol.Integer(st.resident),
+		"allocator-fragmentation.ratio", float(ratio(st.active, st.allocated)),
+		"allocator-fragmentation.bytes", protocol.Integer(st.active-st.allocated),
+		"fragmentation", float(ratio(st.resident, st.allocated)),
+		"fragmentation.bytes", protocol.Integer(st.resident-st.allocated),
+	)
+}
+
+// memoryDoctor 根据内存信息给出诊断报告，措辞与 Redis 的 MEMORY DOCTOR 相同
+func memoryDoctor(st memoryStats) string {
+	const mb = 1024 * 1024
+	if st.allocated < 5*mb {
+		return "Hi Sam, this instance is empty or is using very little memory, my issues detector can't be used in these conditions. " +
+			"Please, leave for your mission on Earth and fill it with some data. " +
+			"The new Sam and I will be back to our programming as soon as I finished rebooting."
+	}
+
+	var issues []string
+	if st.peak > st.allocated*3/2 {
+		issues = append(issues, " * Peak memory: In the past this instance used more than 150% the memory that is currently using. "+
+			"The allocator is normally not able to release memory after a peak, so you can expect to see a big fragmentation ratio, "+
+			"however this is actually harmless and is only due to the memory peak. "+
+			"If the memory peak was only occasional and you want to try to reclaim memory, please try the MEMORY PURGE command, "+
+			"otherwise the only other option is to shutdown and restart the instance.")
+	}
+	if ratio(st.active, st.allocated) > 1.1 && st.active-st.allocated > 10*mb {
+		issues = append(issues, " * High allocator fragmentation: This instance has an allocator external fragmentation greater than 1.1. "+
+			"This problem is usually due either to a large peak memory (check if there is a peak memory entry above in the report) "+
+			"or may result from a workload that causes the allocator to fragment memory a lot.")
+	}
+	if ratio(st.resident, st.active) > 1.1 && st.resident-st.active > 10*mb {
+		issues = append(issues, " * High process RSS overhead: This instance has non-allocator RSS overhead greater than 1.1. "+
+			"The memory was freed but not yet returned to the operating system, try the MEMORY PURGE command.")
+	}
+
+	if len(issues) == 0 {
+		return "Hi Sam, I can't find any memory issue in your instance. I can only account for what occurs on this base."
+	}
+	return "Sam, I detected a few issues in this Redis instance memory implants:\n\n" +
+		strings.Join(issues, "\n\n") +
+		"\n\nI'm here to keep you safe, Sam. I want to help you.\n"
+}
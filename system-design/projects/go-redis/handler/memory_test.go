@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strings"
+	"testing"
+)
+
+func TestMemoryUsageCommand(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "SET", "k", strings.Repeat("x", 100))
+
+	if resp := execCommand(r, "MEMORY", "USAGE", "k"); resp.Type != protocol.IntType || resp.Int < 100 {
+		t.Errorf("MEMORY USAGE k = %+v", resp)
+	}
+	if resp := execCommand(r, "MEMORY", "USAGE", "k", "SAMPLES", "0"); resp.Type != protocol.IntType {
+		t.Errorf("MEMORY USAGE k SAMPLES 0 = %+v", resp)
+	}
+	if resp := execCommand(r, "MEMORY", "USAGE", "missing"); !resp.IsNull {
+		t.Errorf("MEMORY USAGE missing = %+v, want nil", resp)
+	}
+
+	cases := map[string][]string{
+		"ERR syntax error": {"MEMORY", "USAGE", "k", "SAMPLES"},
+		"ERR value is not an integer or out of range":              {"MEMORY", "USAGE", "k", "SAMPLES", "x"},
+		"ERR unknown subcommand 'nope'. Try MEMORY HELP.":          {"MEMORY", "nope"},
+		"ERR wrong number of arguments for 'memory|usage' command": {"MEMORY", "USAGE"},
+	}
+	for want, args := range cases {
+		if resp := execCommand(r, args...); resp.Str != want {
+			t.Errorf("%v = %+v, want %q", args, resp, want)
+		}
+	}
+}
+
+func TestMemoryStats(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "SET", "a", "1")
+	execCommand(r, "SET", "b", "2")
+
+	resp := execCommand(r, "MEMORY", "STATS")
+	fields := make(map[string]protocol.Value)
+	for i := 0; i+1 < len(resp.Array); i += 2 {
+		fields[resp.Array[i].Str] = resp.Array[i+1]
+	}
+	if fields["keys.count"].Int != 2 {
+		t.Errorf("keys.count = %+v", fields["keys.count"])
+	}
+	if fields["total.allocated"].Int <= 0 || fields["peak.allocated"].Int < fields["total.allocated"].Int {
+		t.Errorf("total.allocated = %+v, peak.allocated = %+v", fields["total.allocated"], fields["peak.allocated"])
+	}
+	if db := fields["db.0"]; len(db.Array) != 4 || db.Array[1].Int <= 0 {
+		t.Errorf("db.0 = %+v", db)
+	}
+}
+
+func TestMemoryDoctor(t *testing.T) {
+	const mb = 1024 * 1024
+	tests := []struct {
+		stats memoryStats
+		want  string
+	}{
+		{memoryStats{allocated: mb, peak: mb}, "this instance is empty"},
+		{memoryStats{allocated: 100 * mb, peak: 100 * mb, active: 100 * mb, resident: 100 * mb}, "can't find any memory issue"},
+		{memoryStats{allocated: 100 * mb, peak: 300 * mb, active: 100 * mb, resident: 100 * mb}, "Peak memory"},
+		{memoryStats{allocated: 100 * mb, peak: 100 * mb, active: 150 * mb, resident: 150 * mb}, "High allocator fragmentation"},
+		{memoryStats{allocated: 100 * mb, peak: 100 * mb, active: 100 * mb, resident: 200 * mb}, "High process RSS overhead"},
+	}
+	for _, tt := range tests {
+		if got := memoryDoctor(tt.stats); !strings.Contains(got, tt.want) {
+			t.Errorf("memoryDoctor(%+v) = %q, want %q", tt.stats, got, tt.want)
+		}
+	}
+
+	r := NewRouter(store.NewStore())
+	if resp := execCommand(r, "MEMORY", "DOCTOR"); resp.Type != protocol.BulkStringType || resp.Str == "" {
+		t.Errorf("MEMORY DOCTOR = %+v", resp)
+	}
+}
+
+func TestStrlenAndDBSize(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "SET", "s", "hello")
+	execCommand(r, "INCR", "n")
+	execCommand(r, "XADD", "x", "*", "f", "v")
+
+	tests := []struct {
+		args []string
+		want int64
+	}{
+		{[]string{"STRLEN", "s"}, 5},
+		{[]string{"STRLEN", "n"}, 1},
+		{[]string{"STRLEN", "missing"}, 0},
+		{[]string{"DBSIZE"}, 3},
+	}
+	for _, tt := range tests {
+		if resp := execCommand(r, tt.args...); resp.Type != protocol.IntType || resp.Int != tt.want {
+			t.Errorf("%v = %+v, want %d", tt.args, resp, tt.want)
+		}
+	}
+	if resp := execCommand(r, "STRLEN", "x"); !strings.HasPrefix(resp.Str, "WRONGTYPE") {
+		t.Errorf("STRLEN on a stream = %+v", resp)
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"go-redis/metrics"
+	"sort"
+	"strings"
+)
+
+// WriteMetrics 写入命令和键空间的 Prometheus 指标
+// 命令统计都是原子计数，这里只读取，不会阻塞正在执行的命令
+func (r *Router) WriteMetrics(w *metrics.Writer) {
+	w.Counter("redis_commands_processed_total", "Total number of commands processed by the server",
+		float64(r.totalCommands.Load()))
+
+	names := make([]string, 0, len(r.stats))
+	for name, s := range r.stats {
+		if s.Calls.Load() > 0 || s.RejectedCalls.Load() > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	bounds := make([]float64, len(LatencyBuckets))
+	for i, b := range LatencyBuckets {
+		bounds[i] = b.Seconds()
+	}
+
+	// 同一指标的样本需要连续输出，因此按指标分轮遍历
+	for _, name := range names {
+		w.Counter("redis_commands_total", "Total number of calls per command",
+			float64(r.stats[name].Calls.Load()), metrics.Label{Name: "cmd", Value: strings.ToLower(name)})
+	}
+	for _, name := range names {
+		w.Counter("redis_commands_failed_calls_total", "Total number of calls per command that returned an error",
+			float64(r.stats[name].FailedCalls.Load()), metrics.Label{Name: "cmd", Value: strings.ToLower(name)})
+	}
+	for _, name := range names {
+		w.Counter("redis_commands_rejected_calls_total", "Total number of calls per command rejected before execution",
+			float64(r.stats[name].RejectedCalls.Load()), metrics.Label{Name: "cmd", Value: strings.ToLower(name)})
+	}
+	for _, name := range names {
+		s := r.stats[name]
+		counts := s.LatencyCounts()
+		// 各计数分别读取，并发记录时总数可能略小于桶的计数，取较大者保证直方图单调
+		count := max(s.Calls.Load(), counts[len(counts)-1])
+		w.Histogram("redis_command_duration_seconds", "Command execution time in seconds", metrics.Histogram{
+			Bounds: bounds,
+			Counts: counts,
+			Count:  count,
+			Sum:    float64(s.Usec.Load()) / 1e6,
+		}, metrics.Label{Name: "cmd", Value: strings.ToLower(name)})
+	}
+
+	keys, expires := r.db.KeyspaceStats()
+	db := metrics.Label{Name: "db", Value: "db0"}
+	w.Gauge("redis_db_keys", "Total number of keys by DB", float64(keys), db)
+	w.Gauge("redis_db_keys_expiring", "Total number of expiring keys by DB", float64(expires), db)
+	w.Counter("redis_expired_keys_total", "Total number of keys deleted because they expired",
+		float64(r.db.ExpiredKeys()))
+	// 还没有实现 maxmemory，不会淘汰键，保留这个指标以便沿用 Redis 的监控面板
+	w.Counter("redis_evicted_keys_total", "Total number of keys evicted due to maxmemory", 0)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/types"
+	"time"
+)
+
+// 中间件
+//
+// 所有命令（包括事务中的命令）在参数个数检查通过后依次经过中间件，最后交给命令的处理器。
+// 在连接层处理的命令（MULTI/EXEC/DISCARD、订阅命令、CLIENT、MONITOR、SHUTDOWN）通过 RouteLocal 同样经过中间件，
+// 只有事务中排队的命令要到 EXEC 时才经过。
+// 中间件可以在处理器之前拒绝命令（例如认证、只读），也可以在之后观察回复（例如统计、慢日志、传播写命令），
+// 不需要修改各个处理器。命令统计、慢日志和 CLIENT TRACKING 本身也是中间件。
+// 订阅命令自己写出确认，成功的 SHUTDOWN 不回复，这两种情况下中间件看到的回复为 nil
+
+// Command 是正在执行的命令，中间件通过它获得参数、发起命令的连接和命令表中的元数据
+type Command struct {
+	Name   string           // 大写的命令名
+	Argv   []protocol.Value // 包括命令名在内的所有参数
+	Client types.ClientInfo // 发起命令的连接，可以为 nil
+	Conn   types.Conn       // 连接上下文，不为 nil，Client 为 nil 时是不关联连接的默认上下文
+
+	spec    *commandSpec
+	handler types.ContextHandler
+	blocked time.Duration // 阻塞命令等待数据的时间
+}
+
+// Args 返回不含命令名的参数
+func (c *Command) Args() []protocol.Value {
+	return c.Argv[1:]
+}
+
+// HasFlag 判断命令是否带有命令表中的标志，例如 write、readonly、admin
+func (c *Command) HasFlag(flag string) bool {
+	return c.spec.hasFlag(flag)
+}
+
+// Flags 返回命令的标志
+func (c *Command) Flags() []string {
+	return c.spec.flags
+}
+
+// Categories 返回命令的 ACL 分类，例如 @write、@string
+func (c *Command) Categories() []string {
+	return c.spec.categories
+}
+
+// Keys 返回命令涉及的键
+func (c *Command) Keys() []string {
+	args := make([]string, len(c.Argv)-1)
+	for i, arg := range c.Argv[1:] {
+		args[i] = arg.Str
+	}
+	return c.spec.keys(args)
+}
+
+// HandlerFunc 执行一条命令并返回回复
+type HandlerFunc func(cmd *Command) *protocol.Value
+
+// Middleware 包装 HandlerFunc，next 是链中的下一环，不调用 next 即拒绝命令
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Use 在中间件链的末尾（更靠近处理器的一端）添加中间件，只能在服务器开始处理请求之前调用
+// 先添加的中间件在外层，先看到命令、后看到回复
+func (r *Router) Use(mws ...Middleware) {
+	r.middlewares = append(r.middlewares, mws...)
+
+	chain := callHandler
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		chain = r.middlewares[i](chain)
+	}
+	r.chain = chain
+}
+
+// blockingHandler 由 XREAD 等阻塞命令的处理器实现，等待数据的时间累加到 blocked
+type blockingHandler interface {
+	HandleBlocking(args []protocol.Value, blocked *time.Duration) *protocol.Value
+}
+
+func callHandler(cmd *Command) *protocol.Value {
+	if a, ok := cmd.handler.(types.HandlerAdapter); ok {
+		if h, ok := a.Handler.(blockingHandler); ok {
+			return h.HandleBlocking(cmd.Args(), &cmd.blocked)
+		}
+	}
+	return cmd.handler.HandleContext(cmd.Conn, cmd.Args())
+}
+
+// recordStats 统计命令的调用次数、耗时和错误，记录慢日志和延迟事件
+// 与 Redis 一样，阻塞命令等待数据的时间不算作执行时间
+func (r *Router) recordStats(next HandlerFunc) HandlerFunc {
+	return func(cmd *Command) *protocol.Value {
+		start := time.Now()
+		resp := next(cmd)
+		duration := time.Since(start) - cmd.blocked
+
+		r.totalCommands.Add(1)
+		r.stats[cmd.Name].record(duration, resp != nil && resp.Type == protocol.ErrorType)
+		r.slowlog.Record(cmd.Client, cmd.Argv, start, duration)
+
+		event := "command"
+		if cmd.HasFlag("fast") {
+			event = "fast-command"
+		}
+		r.latency.Record(event, time.Now(), duration)
+		return resp
+	}
+}
+
+// trackReads 为开启了 CLIENT TRACKING 的连接记录只读命令读取的键，
+// 执行期间连接自己修改的键按 NOLOOP 的设置处理
+func (r *Router) trackReads(next HandlerFunc) HandlerFunc {
+	return func(cmd *Command) *protocol.Value {
+		if cmd.Client == nil || !r.tracking.Tracking(cmd.Client.ID()) {
+			return next(cmd)
+		}
+
+		r.tracking.BeginCommand(cmd.Client.ID(), r.readKeys(cmd))
+		defer r.tracking.EndCommand(cmd.Client.ID())
+		return next(cmd)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	var trace []string
+	named := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(cmd *Command) *protocol.Value {
+				trace = append(trace, name+">"+cmd.Name)
+				resp := next(cmd)
+				trace = append(trace, name+"<"+cmd.Name)
+				return resp
+			}
+		}
+	}
+	r.Use(named("outer"), named("inner"))
+
+	execCommand(r, "SET", "k", "v")
+	want := []string{"outer>SET", "inner>SET", "inner<SET", "outer<SET"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %q, want %q", trace, want)
+	}
+
+	// 参数个数错误的命令不会到达中间件
+	trace = nil
+	execCommand(r, "GET")
+	if len(trace) != 0 {
+		t.Errorf("rejected command reached middleware: %q", trace)
+	}
+}
+
+func TestMiddlewareReadOnly(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "SET", "k", "v")
+
+	// 按命令表的标志拒绝写命令，处理器不需要知道
+	r.Use(func(next HandlerFunc) HandlerFunc {
+		return func(cmd *Command) *protocol.Value {
+			if cmd.HasFlag("write") {
+				return protocol.Error("READONLY You can't write against a read only replica.")
+			}
+			return next(cmd)
+		}
+	})
+
+	for _, args := range [][]string{{"SET", "k", "v2"}, {"DEL", "k"}, {"XADD", "s", "*", "f", "v"}} {
+		if resp := execCommand(r, args...); !strings.HasPrefix(resp.Str, "READONLY") {
+			t.Errorf("%v = %+v, want READONLY", args, resp)
+		}
+	}
+	if resp := execCommand(r, "GET", "k"); resp.Str != "v" {
+		t.Errorf("GET = %+v", resp)
+	}
+
+	// 被中间件拒绝的命令计入失败的调用
+	if s := r.CommandStats("SET"); s.Calls.Load() != 2 || s.FailedCalls.Load() != 1 {
+		t.Errorf("SET stats: calls=%d failed=%d", s.Calls.Load(), s.FailedCalls.Load())
+	}
+}
+
+func TestMiddlewareCommandContext(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	var seen []*Command
+	r.Use(func(next HandlerFunc) HandlerFunc {
+		return func(cmd *Command) *protocol.Value {
+			seen = append(seen, cmd)
+			return next(cmd)
+		}
+	})
+
+	cmd := protocol.Array([]protocol.Value{*protocol.BulkString("rename"), *protocol.BulkString("a"), *protocol.BulkString("b")})
+	r.RouteClient(fakeClient{}, cmd)
+
+	// 事务中的命令同样经过中间件
+	r.Exec(fakeClient{}, []*protocol.Value{
+		protocol.Array([]protocol.Value{*protocol.BulkString("GET"), *protocol.BulkString("a")}),
+	})
+
+	if len(seen) != 2 {
+		t.Fatalf("middleware saw %d commands", len(seen))
+	}
+	c := seen[0]
+	if c.Name != "RENAME" || c.Client == nil || c.Client.ID() != 7 || len(c.Args()) != 2 {
+		t.Errorf("command = %+v", c)
+	}
+	if keys := c.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("keys = %q", keys)
+	}
+	if !c.HasFlag("write") || !reflect.DeepEqual(c.Categories(), []string{"@keyspace", "@write", "@slow"}) {
+		t.Errorf("flags = %q, categories = %q", c.Flags(), c.Categories())
+	}
+	if seen[1].Name != "GET" || !seen[1].HasFlag("readonly") {
+		t.Errorf("command in EXEC = %+v", seen[1])
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"testing"
+	"time"
+)
+
+type recordSubscriber struct {
+	messages []*protocol.Value
+}
+
+func (s *recordSubscriber) Send(msg *protocol.Value) {
+	s.messages = append(s.messages, msg)
+}
+
+func TestConfigNotifyKeyspaceEvents(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	resp := execCommand(r, "CONFIG", "SET", "notify-keyspace-events", "KEA")
+	if resp.Str != "OK" {
+		t.Fatalf("Unexpected CONFIG SET response: %+v", resp)
+	}
+
+	resp = execCommand(r, "CONFIG", "GET", "notify-*")
+	if len(resp.Array) != 2 || resp.Array[1].Str != "AKE" {
+		t.Errorf("Unexpected CONFIG GET response: %+v", resp)
+	}
+
+	resp = execCommand(r, "CONFIG", "SET", "notify-keyspace-events", "KZ")
+	if resp.Type != protocol.ErrorType {
+		t.Errorf("Expected error for an invalid class, got %+v", resp)
+	}
+}
+
+func TestWriteHandlersEmitEvents(t *testing.T) {
+	s := store.NewStore()
+	defer s.Close()
+	r := NewRouter(s)
+	s.SetPublisher(r.PubSub())
+
+	sub := &recordSubscriber{}
+	r.PubSub().PSubscribe(sub, "__keyevent@0__:*")
+
+	// 关闭状态下不发布
+	execCommand(r, "SET", "k", "v")
+	if len(sub.messages) != 0 {
+		t.Fatalf("Expected no events while disabled, got %d", len(sub.messages))
+	}
+
+	execCommand(r, "CONFIG", "SET", "notify-keyspace-events", "EA")
+	execCommand(r, "SET", "k", "v")
+	execCommand(r, "INCR", "n")
+	execCommand(r, "EXPIRE", "k", "100")
+	execCommand(r, "PERSIST", "k")
+	execCommand(r, "DEL", "k", "missing")
+	execCommand(r, "XADD", "s", "*", "f", "v")
+
+	want := []string{"set", "incrby", "expire", "persist", "del", "xadd"}
+	if len(sub.messages) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(sub.messages))
+	}
+	for i, event := range want {
+		if got := sub.messages[i].Array[2].Str; got != "__keyevent@0__:"+event {
+			t.Errorf("Event %d: expected %s, got %s", i, event, got)
+		}
+	}
+}
+
+func TestExpireAndTTLHandlers(t *testing.T) {
+	s := store.NewStore()
+	defer s.Close()
+	r := NewRouter(s)
+
+	if resp := execCommand(r, "TTL", "k"); resp.Int != -2 {
+		t.Errorf("Expected -2, got %+v", resp)
+	}
+
+	execCommand(r, "SET", "k", "v")
+	if resp := execCommand(r, "TTL", "k"); resp.Int != -1 {
+		t.Errorf("Expected -1, got %+v", resp)
+	}
+
+	if resp := execCommand(r, "EXPIRE", "k", "100"); resp.Int != 1 {
+		t.Errorf("Expected 1, got %+v", resp)
+	}
+	if resp := execCommand(r, "TTL", "k"); resp.Int != 100 {
+		t.Errorf("Expected 100, got %+v", resp)
+	}
+
+	execCommand(r, "PEXPIRE", "k", "10")
+	time.Sleep(20 * time.Millisecond)
+	if resp := execCommand(r, "GET", "k"); !resp.IsNull {
+		t.Errorf("Expected key to be expired, got %+v", resp)
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/pubsub"
+	"strings"
+)
+
+type PublishHandler struct {
+	hub *pubsub.Hub
+}
+
+func NewPublishHandler(hub *pubsub.Hub) *PublishHandler {
+	return &PublishHandler{
+		hub: hub,
+	}
+}
+
+// Handle 处理 PUBLISH 命令
+// PUBLISH channel message - 返回收到消息的订阅数
+func (h *PublishHandler) Handle(args []protocol.Value) *protocol.Value {
+	return protocol.Integer(h.hub.Publish(args[0].Str, args[1].Str))
+}
+
+// *****
+type PubSubHandler struct {
+	hub *pubsub.Hub
+}
+
+func NewPubSubHandler(hub *pubsub.Hub) *PubSubHandler {
+	return &PubSubHandler{
+		hub: hub,
+	}
+}
+
+// Handle 处理 PUBSUB 命令
+// PUBSUB CHANNELS [pattern]
+// PUBSUB NUMSUB [channel ...]
+// PUBSUB NUMPAT
+func (h *PubSubHandler) Handle(args []protocol.Value) *protocol.Value {
+	switch strings.ToUpper(args[0].Str) {
+	case "CHANNELS":
+		if len(args) > 2 {
+			return protocol.Error("ERR wrong number of arguments for 'pubsub|channels' command")
+		}
+		pattern := ""
+		if len(args) == 2 {
+			pattern = args[1].Str
+		}
+		channels := h.hub.Channels(pattern)
+		values := make([]protocol.Value, len(channels))
+		for i, ch := range channels {
+			values[i] = *protocol.BulkString(ch)
+		}
+		return protocol.Array(values)
+
+	case "NUMSUB":
+		values := make([]protocol.Value, 0, 2*(len(args)-1))
+		for _, arg := range args[1:] {
+			values = append(values,
+				*protocol.BulkString(arg.Str),
+				*protocol.Integer(h.hub.NumSub(arg.Str)),
+			)
+		}
+		return protocol.Array(values)
+
+	case "NUMPAT":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'pubsub|numpat' command")
+		}
+		return protocol.Integer(h.hub.NumPat())
+	}
+
+	return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try PUBSUB HELP.")
+}
@@ -2,27 +2,88 @@ package handler
 
 import (
 	"go-redis/protocol"
+	"go-redis/pubsub"
 	"go-redis/store"
+	"go-redis/tracking"
 	"go-redis/types"
+	"math"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type Router struct {
-	handlers map[string]types.Handler
+	handlers map[string]types.ContextHandler
+	// specs 是 COMMAND 列出的命令，包括在连接层处理、没有 handler 的命令
+	specs    map[string]*commandSpec
 	db       *store.Store
+	hub      *pubsub.Hub
+	monitors *pubsub.Feed
+	tracking *tracking.Table
+
+	// 普通命令持有读锁，EXEC 持有写锁，保证事务中的命令不被其他命令穿插
+	execMu sync.RWMutex
+
+	// 命令统计和慢日志，stats 在 Register 和 Declare 时创建，之后只读
+	stats         map[string]*CommandStats
+	totalCommands atomic.Int64
+	slowlog       *SlowLog
+	latency       *LatencyMonitor
+	config        *ConfigHandler
+	infoSections  map[string]func() []string
+
+	// 所有连接的解析器共享的请求大小限制
+	limits *protocol.Limits
+
+	// 中间件链，chain 由 Use 组合，最内层调用命令的处理器
+	middlewares []Middleware
+	chain       HandlerFunc
 }
 
 func NewRouter(s *store.Store) *Router {
+	hub := pubsub.NewHub()
 	r := &Router{
-		handlers: make(map[string]types.Handler),
-		db:       s,
+		handlers:     make(map[string]types.ContextHandler),
+		specs:        make(map[string]*commandSpec),
+		db:           s,
+		hub:          hub,
+		monitors:     pubsub.NewFeed(),
+		tracking:     tracking.NewTable(hub),
+		stats:        make(map[string]*CommandStats),
+		slowlog:      NewSlowLog(),
+		latency:      NewLatencyMonitor(),
+		config:       NewConfigHandler(),
+		infoSections: make(map[string]func() []string),
+		limits:       protocol.NewLimits(),
 	}
 
+	// 统计在最外层，耗时包括之后添加的中间件
+	r.Use(r.recordStats, r.trackReads)
 	r.registerDefaultHandlers()
+	r.registerConfigParams()
+	r.registerInfoSections()
 	return r
 }
 
 func (r *Router) Route(cmd *protocol.Value) *protocol.Value {
+	return r.RouteClient(nil, cmd)
+}
+
+// RouteClient 执行一条命令，client 是发起命令的连接（可以为 nil）
+// client 实现了 types.Conn 时，需要连接上下文的处理器通过它读写连接的状态
+// 所有命令都经过这里，统计、慢日志等由中间件完成
+func (r *Router) RouteClient(client types.ClientInfo, cmd *protocol.Value) *protocol.Value {
+	// 阻塞命令可能长时间等待，不能持有事务锁，否则 EXEC 会被它们挡住
+	if !IsBlockingCommand(cmd) {
+		r.execMu.RLock()
+		defer r.execMu.RUnlock()
+	}
+
+	return r.execute(client, cmd)
+}
+
+// execute 检查参数个数后把命令交给中间件链，调用方负责持有事务锁
+func (r *Router) execute(client types.ClientInfo, cmd *protocol.Value) *protocol.Value {
 	if cmd.Type != protocol.ArrayType {
 		return protocol.Error("ERR expected array")
 	}
@@ -37,23 +98,235 @@ func (r *Router) Route(cmd *protocol.Value) *protocol.Value {
 	if !exists {
 		return protocol.Error("ERR unknown command: " + cmdName)
 	}
+	if err := r.CheckArity(cmd); err != nil {
+		r.stats[cmdName].RejectedCalls.Add(1)
+		return err
+	}
+
+	return r.chain(&Command{
+		Name:    cmdName,
+		Argv:    cmd.Array,
+		Client:  client,
+		Conn:    connOf(client),
+		spec:    r.specs[cmdName],
+		handler: handler,
+	})
+}
+
+// RouteLocal 执行在连接层处理的命令（Declare 声明的 MULTI、SUBSCRIBE、CLIENT 等，以及订阅模式下的 PING），
+// 与 RouteClient 一样检查参数个数并经过中间件链，只是链的最内层调用 exec 而不是注册的处理器。
+// exec 自己写出回复时返回 nil，此时 RouteLocal 也返回 nil；不持有事务锁，EXEC 由 Exec 自己加锁
+func (r *Router) RouteLocal(client types.ClientInfo, cmd *protocol.Value, exec func() *protocol.Value) *protocol.Value {
+	cmdName := strings.ToUpper(cmd.Array[0].Str)
+	spec, exists := r.specs[cmdName]
+	if !exists {
+		return protocol.Error("ERR unknown command: " + cmdName)
+	}
+	if err := r.CheckArity(cmd); err != nil {
+		r.stats[cmdName].RejectedCalls.Add(1)
+		return err
+	}
+
+	return r.chain(&Command{
+		Name:    cmdName,
+		Argv:    cmd.Array,
+		Client:  client,
+		Conn:    connOf(client),
+		spec:    spec,
+		handler: localHandler(exec),
+	})
+}
+
+// localHandler 让 RouteLocal 的 exec 作为链最内层的处理器
+type localHandler func() *protocol.Value
+
+func (h localHandler) HandleContext(types.Conn, []protocol.Value) *protocol.Value {
+	return h()
+}
+
+// PubSub 返回 Router 使用的发布订阅中心
+// 服务器层用它处理 SUBSCRIBE 等连接级命令，并把键空间通知接到这里
+func (r *Router) PubSub() *pubsub.Hub {
+	return r.hub
+}
+
+// Monitors 返回 MONITOR 观察者的集合
+// 观察者由服务器层管理，放在 Router 上是为了让所有连接共享同一个集合
+func (r *Router) Monitors() *pubsub.Feed {
+	return r.monitors
+}
+
+// Tracking 返回客户端缓存的失效表
+func (r *Router) Tracking() *tracking.Table {
+	return r.tracking
+}
+
+// SlowLog 返回慢日志
+func (r *Router) SlowLog() *SlowLog {
+	return r.slowlog
+}
+
+// Config 返回 CONFIG 命令的参数表，服务器层可以注册自己的参数
+func (r *Router) Config() *ConfigHandler {
+	return r.config
+}
+
+// ProtocolLimits 返回解析请求时使用的大小限制，由 CONFIG SET proto-max-bulk-len 等修改
+func (r *Router) ProtocolLimits() *protocol.Limits {
+	return r.limits
+}
+
+// CommandStats 返回命令的执行统计，命令未注册或声明时返回 nil
+func (r *Router) CommandStats(cmd string) *CommandStats {
+	return r.stats[strings.ToUpper(cmd)]
+}
+
+// ResetStats 清零所有命令统计（CONFIG RESETSTAT）
+func (r *Router) ResetStats() {
+	r.totalCommands.Store(0)
+	for _, s := range r.stats {
+		s.reset()
+	}
+}
 
-	args := cmd.Array[1:]
+// HasCommand 判断命令是否已注册
+func (r *Router) HasCommand(cmd string) bool {
+	_, ok := r.handlers[strings.ToUpper(cmd)]
+	return ok
+}
 
-	return handler.Handle(args)
+// NoMulti 判断命令是否不能放进事务（命令表中的 no_multi 标志），未知命令返回 false
+func (r *Router) NoMulti(cmd string) bool {
+	c := r.specs[strings.ToUpper(cmd)]
+	return c != nil && c.hasFlag("no_multi")
 }
 
+// CheckArity 按命令表检查参数个数，不符合时返回错误回复，符合或命令未注册时返回 nil
+func (r *Router) CheckArity(cmd *protocol.Value) *protocol.Value {
+	c := r.specs[strings.ToUpper(cmd.Array[0].Str)]
+	if c == nil || c.checkArity(len(cmd.Array)) {
+		return nil
+	}
+	return protocol.Error("ERR wrong number of arguments for '" + c.name + "' command")
+}
+
+// Register 注册命令处理器，只能在服务器开始处理请求之前调用
+// 命令的 arity、标志和键的位置来自命令表，不在表中的命令不检查参数个数
 func (r *Router) Register(cmd string, handler types.Handler) {
-	r.handlers[strings.ToUpper(cmd)] = handler
+	r.RegisterContext(cmd, types.AsContextHandler(handler))
+}
+
+// RegisterContext 注册需要连接上下文的命令处理器，其余与 Register 相同
+func (r *Router) RegisterContext(cmd string, handler types.ContextHandler) {
+	name := strings.ToUpper(cmd)
+	r.handlers[name] = handler
+	r.specs[name] = lookupSpec(name)
+	if _, ok := r.stats[name]; !ok {
+		r.stats[name] = &CommandStats{}
+	}
+}
+
+// Declare 声明在连接层处理的命令（MULTI、SUBSCRIBE 等），它们通过 RouteLocal 执行，出现在 COMMAND 的结果中
+func (r *Router) Declare(cmd string) {
+	name := strings.ToUpper(cmd)
+	r.specs[name] = lookupSpec(name)
+	if _, ok := r.stats[name]; !ok {
+		r.stats[name] = &CommandStats{}
+	}
 }
 
 func (r *Router) registerDefaultHandlers() {
 	r.Register("PING", NewPingHandler())
+	r.RegisterContext("SELECT", NewSelectHandler())
 	r.Register("SET", NewSetHandler(r.db))
 	r.Register("GET", NewGetHandler(r.db))
 	r.Register("DEL", NewDelHandler(r.db))
 	r.Register("EXISTS", NewExistsHandler(r.db))
 	r.Register("KEYS", NewKeysHandler(r.db))
+	r.Register("SCAN", NewScanHandler(r.db))
+	r.Register("STRLEN", NewStrlenHandler(r.db))
 	r.Register("INCR", NewIncrHandler(r.db))
 	r.Register("INCRBY", NewIncrByHandler(r.db))
+	r.Register("EXPIRE", NewExpireHandler(r.db))
+	r.Register("PEXPIRE", NewPExpireHandler(r.db))
+	r.Register("TTL", NewTTLHandler(r.db))
+	r.Register("PTTL", NewPTTLHandler(r.db))
+	r.Register("PERSIST", NewPersistHandler(r.db))
+	r.Register("TYPE", NewTypeHandler(r.db))
+	r.Register("RENAME", NewRenameHandler(r.db))
+	r.Register("RENAMENX", NewRenameNXHandler(r.db))
+	r.Register("COPY", NewCopyHandler(r.db))
+	r.Register("DUMP", NewDumpHandler(r.db))
+	r.Register("RESTORE", NewRestoreHandler(r.db))
+	r.Register("OBJECT", NewObjectHandler(r.db))
+	r.Register("DBSIZE", NewDBSizeHandler(r.db))
+	r.Register("RANDOMKEY", NewRandomKeyHandler(r.db))
+	r.Register("TOUCH", NewTouchHandler(r.db))
+	r.Register("UNLINK", NewUnlinkHandler(r.db))
+	r.Register("SETBIT", NewSetBitHandler(r.db))
+	r.Register("GETBIT", NewGetBitHandler(r.db))
+	r.Register("BITCOUNT", NewBitCountHandler(r.db))
+	r.Register("BITPOS", NewBitPosHandler(r.db))
+	r.Register("BITOP", NewBitOpHandler(r.db))
+	r.Register("BITFIELD", NewBitFieldHandler(r.db))
+	r.Register("BITFIELD_RO", NewBitFieldROHandler(r.db))
+	r.Register("PFADD", NewPFAddHandler(r.db))
+	r.Register("PFCOUNT", NewPFCountHandler(r.db))
+	r.Register("PFMERGE", NewPFMergeHandler(r.db))
+	r.Register("COMMAND", NewCommandHandler(r))
+	r.Register("CONFIG", r.config)
+	r.Register("SLOWLOG", NewSlowlogHandler(r.slowlog))
+	r.Register("LATENCY", NewLatencyHandler(r))
+	r.Register("INFO", NewInfoHandler(r))
+	r.Register("MEMORY", NewMemoryHandler(r.db))
+	r.Register("PUBLISH", NewPublishHandler(r.hub))
+	r.Register("PUBSUB", NewPubSubHandler(r.hub))
+
+	r.Register("XADD", NewXAddHandler(r.db))
+	r.Register("XLEN", NewXLenHandler(r.db))
+	r.Register("XRANGE", NewXRangeHandler(r.db))
+	r.Register("XREVRANGE", NewXRevRangeHandler(r.db))
+	r.Register("XDEL", NewXDelHandler(r.db))
+	r.Register("XTRIM", NewXTrimHandler(r.db))
+	r.Register("XREAD", NewXReadHandler(r.db))
+	r.Register("XGROUP", NewXGroupHandler(r.db))
+	r.Register("XREADGROUP", NewXReadGroupHandler(r.db))
+	r.Register("XACK", NewXAckHandler(r.db))
+	r.Register("XPENDING", NewXPendingHandler(r.db))
+	r.Register("XCLAIM", NewXClaimHandler(r.db))
+}
+
+func (r *Router) registerConfigParams() {
+	r.config.resetStat = r.ResetStats
+
+	r.config.AddParam(StringParam("notify-keyspace-events",
+		func() string { return store.NotifyFlagsString(r.db.NotifyFlags()) },
+		func(value string) error {
+			flags, err := store.ParseNotifyFlags(value)
+			if err != nil {
+				return err
+			}
+			r.db.SetNotifyFlags(flags)
+			return nil
+		}))
+
+	// 负数表示关闭慢日志，0 表示记录所有命令
+	r.config.AddParam(IntParam("slowlog-log-slower-than", math.MinInt64, math.MaxInt64,
+		r.slowlog.SlowerThan, r.slowlog.SetSlowerThan))
+	r.config.AddParam(IntParam("slowlog-max-len", 0, math.MaxInt64,
+		r.slowlog.MaxLen, r.slowlog.SetMaxLen))
+	r.config.AddParam(IntParam("latency-monitor-threshold", 0, math.MaxInt64,
+		r.latency.Threshold, r.latency.SetThreshold))
+	r.config.AddParam(MemoryParam("proto-max-bulk-len", 1024*1024, math.MaxInt64,
+		r.limits.MaxBulkLen, r.limits.SetMaxBulkLen))
+	r.config.AddParam(IntParam("proto-max-multibulk-len", 1, math.MaxInt32,
+		r.limits.MaxMultiBulkLen, r.limits.SetMaxMultiBulkLen))
+	r.config.AddParam(MemoryParam("hll-sparse-max-bytes", 0, math.MaxInt64,
+		r.db.HLLSparseMaxBytes, r.db.SetHLLSparseMaxBytes))
+}
+
+func (r *Router) registerInfoSections() {
+	r.AddInfoSection("stats", r.statsInfo)
+	r.AddInfoSection("keyspace", r.keyspaceInfo)
+	r.AddInfoSection("commandstats", r.commandStatsInfo)
 }
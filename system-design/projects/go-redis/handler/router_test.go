@@ -173,8 +173,8 @@ func TestGetHandler_TooFewArgs(t *testing.T) {
 	if resp.Type != protocol.ErrorType {
 		t.Errorf("expected ErrorType, got %v", resp.Type)
 	}
-	if !strings.Contains(resp.Str, "too less args") {
-		t.Errorf("expected 'too less args' error, got '%s'", resp.Str)
+	if resp.Str != "ERR wrong number of arguments for 'get' command" {
+		t.Errorf("expected arity error, got '%s'", resp.Str)
 	}
 
 	logger.Debugf("GET with no args error: %s", resp.Str)
@@ -198,8 +198,8 @@ func TestGetHandler_TooManyArgs(t *testing.T) {
 	if resp.Type != protocol.ErrorType {
 		t.Errorf("expected ErrorType, got %v", resp.Type)
 	}
-	if !strings.Contains(resp.Str, "too many args") {
-		t.Errorf("expected 'too many args' error, got '%s'", resp.Str)
+	if resp.Str != "ERR wrong number of arguments for 'get' command" {
+		t.Errorf("expected arity error, got '%s'", resp.Str)
 	}
 
 	logger.Debugf("GET with too many args error: %s", resp.Str)
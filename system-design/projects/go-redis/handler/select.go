@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/types"
+	"strconv"
+)
+
+// databases 是可以选择的数据库个数，所有键在同一个键空间中，只有 0 号数据库
+const databases = 1
+
+// *****
+type SelectHandler struct{}
+
+func NewSelectHandler() *SelectHandler {
+	return &SelectHandler{}
+}
+
+// HandleContext 处理 SELECT index，修改连接选择的数据库
+func (h *SelectHandler) HandleContext(conn types.Conn, args []protocol.Value) *protocol.Value {
+	index, err := strconv.Atoi(args[0].Str)
+	if err != nil {
+		return protocol.Error("ERR value is not an integer or out of range")
+	}
+	if index < 0 || index >= databases {
+		return protocol.Error("ERR DB index is out of range")
+	}
+
+	conn.SetDB(index)
+	return protocol.SimpleString("OK")
+}
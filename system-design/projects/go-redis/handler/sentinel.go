@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/sentinel"
+	"strconv"
+	"strings"
+)
+
+// *****
+type SentinelHandler struct {
+	s *sentinel.Sentinel
+}
+
+func NewSentinelHandler(s *sentinel.Sentinel) *SentinelHandler {
+	return &SentinelHandler{
+		s: s,
+	}
+}
+
+// Handle 处理 SENTINEL 命令（哨兵模式）
+// SENTINEL MASTERS | MASTER name | REPLICAS name | SENTINELS name | GET-MASTER-ADDR-BY-NAME name |
+// IS-MASTER-DOWN-BY-ADDR ip port current-epoch runid | CKQUORUM name | MYID
+func (h *SentinelHandler) Handle(args []protocol.Value) *protocol.Value {
+	sub := strings.ToUpper(args[0].Str)
+	argc := map[string]int{
+		"MASTERS": 1, "MYID": 1,
+		"MASTER": 2, "REPLICAS": 2, "SLAVES": 2, "SENTINELS": 2, "GET-MASTER-ADDR-BY-NAME": 2, "CKQUORUM": 2,
+		"IS-MASTER-DOWN-BY-ADDR": 5,
+	}
+	n, ok := argc[sub]
+	if !ok {
+		return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try SENTINEL HELP.")
+	}
+	if len(args) != n {
+		return protocol.Error("ERR wrong number of arguments for 'sentinel|" + strings.ToLower(sub) + "' command")
+	}
+
+	switch sub {
+	case "MASTERS":
+		return fieldsArray(h.s.Masters())
+	case "MASTER":
+		fields, err := h.s.Master(args[1].Str)
+		if err != nil {
+			return sentinelError(err)
+		}
+		return stringArray(fields)
+	case "REPLICAS", "SLAVES":
+		replicas, err := h.s.Replicas(args[1].Str)
+		if err != nil {
+			return sentinelError(err)
+		}
+		return fieldsArray(replicas)
+	case "SENTINELS":
+		sentinels, err := h.s.Sentinels(args[1].Str)
+		if err != nil {
+			return sentinelError(err)
+		}
+		return fieldsArray(sentinels)
+	case "GET-MASTER-ADDR-BY-NAME":
+		host, port, ok := h.s.MasterAddr(args[1].Str)
+		if !ok {
+			return protocol.NullArray()
+		}
+		return stringArray([]string{host, port})
+	case "IS-MASTER-DOWN-BY-ADDR":
+		if _, err := strconv.ParseUint(args[3].Str, 10, 64); err != nil {
+			return protocol.Error("ERR value is not an integer or out of range")
+		}
+		// 本哨兵不参与领导者选举，总是回复没有投票（* 和纪元 0）
+		var down int64
+		if h.s.IsMasterDownByAddr(args[1].Str, args[2].Str) {
+			down = 1
+		}
+		return protocol.Array([]protocol.Value{
+			*protocol.Integer(down),
+			*protocol.BulkString("*"),
+			*protocol.Integer(0),
+		})
+	case "CKQUORUM":
+		msg, err := h.s.CheckQuorum(args[1].Str)
+		if err != nil {
+			return sentinelError(err)
+		}
+		return protocol.SimpleString(msg)
+	default:
+		return protocol.BulkString(h.s.MyID())
+	}
+}
+
+// sentinelError 把哨兵的错误转换为回复，NOQUORUM 等错误本身带有前缀
+func sentinelError(err error) *protocol.Value {
+	if err == sentinel.ErrNoSuchMaster {
+		return protocol.Error("ERR No such master with that name")
+	}
+	return protocol.Error(err.Error())
+}
+
+func stringArray(strs []string) *protocol.Value {
+	values := make([]protocol.Value, len(strs))
+	for i, s := range strs {
+		values[i] = *protocol.BulkString(s)
+	}
+	return protocol.Array(values)
+}
+
+func fieldsArray(list [][]string) *protocol.Value {
+	values := make([]protocol.Value, len(list))
+	for i, fields := range list {
+		values[i] = *stringArray(fields)
+	}
+	return protocol.Array(values)
+}
+
+// SentinelParam 创建哨兵模式的 sentinel 配置参数：每一行 "sentinel ..." 交给哨兵解析，
+// CONFIG REWRITE 时写回哨兵的当前配置（包括发现的从节点和哨兵）
+func SentinelParam(s *sentinel.Sentinel) *ConfigParam {
+	return &ConfigParam{
+		Name:         "sentinel",
+		Get:          func() string { return strings.Join(s.RewriteLines(), "\n") },
+		Set:          func(value string) error { return s.Configure(strings.Fields(value)) },
+		Immutable:    true,
+		RewriteLines: s.RewriteLines,
+	}
+}
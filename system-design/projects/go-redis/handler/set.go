@@ -16,16 +16,13 @@ func NewSetHandler(db *store.Store) *SetHandler {
 }
 
 func (h *SetHandler) Handle(args []protocol.Value) *protocol.Value {
-	if len(args) != 2 {
-		return protocol.Error("ERR wrong number of arguments for 'set' command")
-	}
-
 	key := args[0].Str
 	value := args[1].Str
 
 	// SET 命令总是存储字符串
 	// INCR/DECR 等命令会在需要时将字符串解析为整数
 	h.db.Set(key, value)
+	h.db.Notify(store.NotifyString, "set", key)
 
 	return protocol.SimpleString("OK")
 }
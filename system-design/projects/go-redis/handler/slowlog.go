@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"fmt"
+	"go-redis/protocol"
+	"go-redis/types"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 与 Redis 相同的参数截断规则
+const (
+	slowlogMaxArgc   = 32  // 最多记录的参数个数
+	slowlogMaxString = 128 // 单个参数最多记录的字节数
+)
+
+// SlowLogEntry 是一条慢日志
+type SlowLogEntry struct {
+	ID         int64
+	Time       time.Time
+	Duration   time.Duration
+	Args       []string
+	ClientID   int64 // 发起命令的连接编号，没有连接时为 0；SLOWLOG GET 的回复与 Redis 相同，不包含它
+	ClientAddr string
+	ClientName string
+}
+
+// SlowLog 记录执行时间超过阈值的命令，最多保留 maxLen 条，新的在前
+type SlowLog struct {
+	slowerThan atomic.Int64 // 阈值（微秒），负数表示关闭，0 表示记录所有命令
+	maxLen     atomic.Int64
+
+	mu      sync.Mutex
+	entries []SlowLogEntry
+	nextID  int64
+}
+
+// NewSlowLog 创建慢日志，默认阈值 10ms、最多 128 条
+func NewSlowLog() *SlowLog {
+	l := &SlowLog{}
+	l.slowerThan.Store(10000)
+	l.maxLen.Store(128)
+	return l
+}
+
+// SlowerThan 返回记录阈值（微秒）
+func (l *SlowLog) SlowerThan() int64 {
+	return l.slowerThan.Load()
+}
+
+// SetSlowerThan 设置记录阈值（微秒）
+func (l *SlowLog) SetSlowerThan(us int64) {
+	l.slowerThan.Store(us)
+}
+
+// MaxLen 返回最多保留的条数
+func (l *SlowLog) MaxLen() int64 {
+	return l.maxLen.Load()
+}
+
+// SetMaxLen 设置最多保留的条数，多出的旧记录立即丢弃
+func (l *SlowLog) SetMaxLen(n int64) {
+	l.maxLen.Store(n)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if int64(len(l.entries)) > n {
+		l.entries = l.entries[:n]
+	}
+}
+
+// Record 在命令耗时超过阈值时记录一条慢日志
+func (l *SlowLog) Record(client types.ClientInfo, args []protocol.Value, start time.Time, duration time.Duration) {
+	threshold := l.slowerThan.Load()
+	if threshold < 0 || duration.Microseconds() < threshold {
+		return
+	}
+
+	entry := SlowLogEntry{
+		Time:     start,
+		Duration: duration,
+		Args:     slowlogArgs(args),
+	}
+	if client != nil {
+		entry.ClientID = client.ID()
+		entry.ClientAddr = client.Addr()
+		entry.ClientName = client.Name()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	maxLen := int(l.maxLen.Load())
+	if maxLen <= 0 {
+		return
+	}
+
+	entry.ID = l.nextID
+	l.nextID++
+
+	// 新记录插入到头部
+	l.entries = append(l.entries, SlowLogEntry{})
+	copy(l.entries[1:], l.entries)
+	l.entries[0] = entry
+	if len(l.entries) > maxLen {
+		l.entries = l.entries[:maxLen]
+	}
+}
+
+// slowlogArgs 按 Redis 的规则截断参数列表
+func slowlogArgs(args []protocol.Value) []string {
+	argc := len(args)
+	if argc > slowlogMaxArgc {
+		argc = slowlogMaxArgc
+	}
+
+	res := make([]string, argc)
+	for i := 0; i < argc; i++ {
+		if i == slowlogMaxArgc-1 && len(args) > slowlogMaxArgc {
+			res[i] = fmt.Sprintf("... (%d more arguments)", len(args)-slowlogMaxArgc+1)
+			break
+		}
+
+		s := args[i].Str
+		if args[i].Type == protocol.IntType {
+			s = strconv.FormatInt(args[i].Int, 10)
+		}
+		if len(s) > slowlogMaxString {
+			s = fmt.Sprintf("%s... (%d more bytes)", s[:slowlogMaxString], len(s)-slowlogMaxString)
+		}
+		res[i] = s
+	}
+
+	return res
+}
+
+// Get 返回最新的 count 条记录，count < 0 时返回全部
+func (l *SlowLog) Get(count int) []SlowLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if count < 0 || count > len(l.entries) {
+		count = len(l.entries)
+	}
+
+	res := make([]SlowLogEntry, count)
+	copy(res, l.entries[:count])
+	return res
+}
+
+// Len 返回当前记录数
+func (l *SlowLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// Reset 清空慢日志
+func (l *SlowLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// *****
+type SlowlogHandler struct {
+	log *SlowLog
+}
+
+func NewSlowlogHandler(log *SlowLog) *SlowlogHandler {
+	return &SlowlogHandler{
+		log: log,
+	}
+}
+
+// Handle 处理 SLOWLOG 命令
+// SLOWLOG GET [count]
+// SLOWLOG LEN
+// SLOWLOG RESET
+func (h *SlowlogHandler) Handle(args []protocol.Value) *protocol.Value {
+	switch strings.ToUpper(args[0].Str) {
+	case "GET":
+		if len(args) > 2 {
+			return protocol.Error("ERR wrong number of arguments for 'slowlog|get' command")
+		}
+		count := 10
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1].Str)
+			if err != nil || n < -1 {
+				return protocol.Error("ERR count should be greater than or equal to -1")
+			}
+			count = n
+		}
+		return slowlogEntriesValue(h.log.Get(count))
+
+	case "LEN":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'slowlog|len' command")
+		}
+		return protocol.Integer(int64(h.log.Len()))
+
+	case "RESET":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'slowlog|reset' command")
+		}
+		h.log.Reset()
+		return protocol.SimpleString("OK")
+	}
+
+	return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try SLOWLOG HELP.")
+}
+
+// slowlogEntriesValue 把慢日志转换为 [id, 时间戳, 耗时(微秒), [参数...], 客户端地址, 客户端名] 的数组
+func slowlogEntriesValue(entries []SlowLogEntry) *protocol.Value {
+	values := make([]protocol.Value, len(entries))
+	for i, e := range entries {
+		args := make([]protocol.Value, len(e.Args))
+		for j, a := range e.Args {
+			args[j] = *protocol.BulkString(a)
+		}
+
+		values[i] = *protocol.Array([]protocol.Value{
+			*protocol.Integer(e.ID),
+			*protocol.Integer(e.Time.Unix()),
+			*protocol.Integer(e.Duration.Microseconds()),
+			*protocol.Array(args),
+			*protocol.BulkString(e.ClientAddr),
+			*protocol.BulkString(e.ClientName),
+		})
+	}
+	return protocol.Array(values)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClient struct{}
+
+func (fakeClient) ID() int64    { return 7 }
+func (fakeClient) Addr() string { return "127.0.0.1:50000" }
+func (fakeClient) Name() string { return "worker" }
+
+func TestSlowlogThreshold(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	// 默认阈值 10ms，普通命令不会被记录
+	execCommand(r, "SET", "k", "v")
+	if resp := execCommand(r, "SLOWLOG", "LEN"); resp.Int != 0 {
+		t.Fatalf("Expected empty slowlog, got %d", resp.Int)
+	}
+
+	// 阈值为 0 时记录所有命令
+	execCommand(r, "CONFIG", "SET", "slowlog-log-slower-than", "0")
+	cmd := protocol.Array([]protocol.Value{*protocol.BulkString("GET"), *protocol.BulkString("k")})
+	r.RouteClient(fakeClient{}, cmd)
+
+	resp := execCommand(r, "SLOWLOG", "GET", "1")
+	if len(resp.Array) != 1 {
+		t.Fatalf("Expected 1 entry, got %+v", resp)
+	}
+	entry := resp.Array[0].Array
+	if len(entry) != 6 {
+		t.Fatalf("Unexpected entry: %+v", entry)
+	}
+	if args := entry[3].Array; len(args) != 2 || args[0].Str != "GET" || args[1].Str != "k" {
+		t.Errorf("Unexpected args: %+v", args)
+	}
+	if entry[4].Str != "127.0.0.1:50000" || entry[5].Str != "worker" {
+		t.Errorf("Unexpected client info: %q %q", entry[4].Str, entry[5].Str)
+	}
+	// 最新的一条是上面的 SLOWLOG GET，它没有连接
+	if entries := r.SlowLog().Get(2); entries[0].ClientID != 0 || entries[1].ClientID != 7 {
+		t.Errorf("Unexpected client ids: %d %d", entries[0].ClientID, entries[1].ClientID)
+	}
+	if ts := entry[1].Int; ts < time.Now().Add(-time.Minute).Unix() {
+		t.Errorf("Unexpected timestamp: %d", ts)
+	}
+
+	// 阈值为负数时关闭慢日志
+	execCommand(r, "CONFIG", "SET", "slowlog-log-slower-than", "-1")
+	execCommand(r, "SLOWLOG", "RESET")
+	execCommand(r, "GET", "k")
+	if resp := execCommand(r, "SLOWLOG", "LEN"); resp.Int != 0 {
+		t.Errorf("Expected slowlog disabled, got %d entries", resp.Int)
+	}
+}
+
+func TestSlowlogMaxLenAndOrder(t *testing.T) {
+	l := NewSlowLog()
+	l.SetSlowerThan(0)
+	l.SetMaxLen(3)
+
+	for i := 0; i < 5; i++ {
+		l.Record(nil, []protocol.Value{*protocol.BulkString("PING")}, time.Now(), 0)
+	}
+
+	entries := l.Get(-1)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	// 新的在前
+	if entries[0].ID != 4 || entries[2].ID != 2 {
+		t.Errorf("Unexpected order: %d..%d", entries[0].ID, entries[2].ID)
+	}
+
+	l.SetMaxLen(1)
+	if l.Len() != 1 {
+		t.Errorf("Expected 1 entry after shrinking, got %d", l.Len())
+	}
+}
+
+func TestSlowlogTruncation(t *testing.T) {
+	args := make([]protocol.Value, 40)
+	for i := range args {
+		args[i] = *protocol.BulkString("a")
+	}
+	args[1] = *protocol.BulkString(strings.Repeat("x", 200))
+
+	res := slowlogArgs(args)
+	if len(res) != slowlogMaxArgc {
+		t.Fatalf("Expected %d args, got %d", slowlogMaxArgc, len(res))
+	}
+	if res[1] != strings.Repeat("x", 128)+"... (72 more bytes)" {
+		t.Errorf("Unexpected truncated string: %q", res[1])
+	}
+	if res[31] != "... (9 more arguments)" {
+		t.Errorf("Unexpected last arg: %q", res[31])
+	}
+}
+
+func TestInfoCommandStats(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	execCommand(r, "SET", "k", "v")
+	execCommand(r, "GET", "k")
+	execCommand(r, "GET", "k")
+	execCommand(r, "INCR", "k") // 值不是整数，计为失败
+
+	info := execCommand(r, "INFO", "commandstats").Str
+	if !strings.HasPrefix(info, "# Commandstats\r\n") {
+		t.Fatalf("Unexpected INFO: %q", info)
+	}
+	for _, want := range []string{"cmdstat_get:calls=2,", "cmdstat_set:calls=1,", "cmdstat_incr:calls=1,"} {
+		if !strings.Contains(info, want) {
+			t.Errorf("INFO missing %q: %q", want, info)
+		}
+	}
+	if !strings.Contains(info, "failed_calls=1\r\n") {
+		t.Errorf("Expected a failed call: %q", info)
+	}
+
+	// 默认输出不包含 commandstats
+	info = execCommand(r, "INFO").Str
+	if strings.Contains(info, "cmdstat_") || !strings.Contains(info, "db0:keys=1,expires=0") {
+		t.Errorf("Unexpected default INFO: %q", info)
+	}
+
+	execCommand(r, "CONFIG", "RESETSTAT")
+	if s := r.CommandStats("get"); s.Calls.Load() != 0 {
+		t.Errorf("Expected stats reset, got %d calls", s.Calls.Load())
+	}
+}
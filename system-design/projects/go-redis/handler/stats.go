@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets 是命令耗时直方图各个桶的上界，超过最后一个上界的只计入 Calls
+var LatencyBuckets = [...]time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// CommandStats 是单个命令的执行统计
+// 每个命令在注册时创建自己的统计对象，字段都是原子计数，记录时不需要全局锁
+type CommandStats struct {
+	Calls       atomic.Int64
+	Usec        atomic.Int64
+	FailedCalls atomic.Int64
+	// RejectedCalls 是执行前就被拒绝（例如参数个数错误）的调用，不计入 Calls
+	RejectedCalls atomic.Int64
+
+	// latency[i] 是耗时落在 (LatencyBuckets[i-1], LatencyBuckets[i]] 的次数
+	latency [len(LatencyBuckets)]atomic.Int64
+}
+
+// record 记录一次执行
+func (s *CommandStats) record(duration time.Duration, failed bool) {
+	s.Calls.Add(1)
+	s.Usec.Add(duration.Microseconds())
+	if failed {
+		s.FailedCalls.Add(1)
+	}
+	for i, bound := range LatencyBuckets {
+		if duration <= bound {
+			s.latency[i].Add(1)
+			break
+		}
+	}
+}
+
+// reset 清零统计（CONFIG RESETSTAT）
+func (s *CommandStats) reset() {
+	s.Calls.Store(0)
+	s.Usec.Store(0)
+	s.FailedCalls.Store(0)
+	s.RejectedCalls.Store(0)
+	for i := range s.latency {
+		s.latency[i].Store(0)
+	}
+}
+
+// LatencyCounts 返回耗时不超过 LatencyBuckets 中各个上界的执行次数（累计值）
+func (s *CommandStats) LatencyCounts() []int64 {
+	counts := make([]int64, len(s.latency))
+	var total int64
+	for i := range s.latency {
+		total += s.latency[i].Load()
+		counts[i] = total
+	}
+	return counts
+}
+
+// UsecPerCall 返回平均每次调用的耗时（微秒）
+func (s *CommandStats) UsecPerCall() float64 {
+	calls := s.Calls.Load()
+	if calls == 0 {
+		return 0
+	}
+	return float64(s.Usec.Load()) / float64(calls)
+}
@@ -0,0 +1,500 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamEntryValue 把一条消息转换为 [id, [field, value, ...]] 形式的回复
+// Fields 为 nil 表示消息已被删除（XREADGROUP 读取历史时可能出现），此时第二项为 NULL
+func streamEntryValue(e store.StreamEntry) protocol.Value {
+	if e.Fields == nil {
+		return *protocol.Array([]protocol.Value{
+			*protocol.BulkString(e.ID.String()),
+			*protocol.NullArray(),
+		})
+	}
+
+	fields := make([]protocol.Value, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = *protocol.BulkString(f)
+	}
+
+	return *protocol.Array([]protocol.Value{
+		*protocol.BulkString(e.ID.String()),
+		*protocol.Array(fields),
+	})
+}
+
+func streamEntriesValue(entries []store.StreamEntry) *protocol.Value {
+	values := make([]protocol.Value, len(entries))
+	for i, e := range entries {
+		values[i] = streamEntryValue(e)
+	}
+	return protocol.Array(values)
+}
+
+// streamReadValue 把 XREAD/XREADGROUP 的结果转换为 [[key, entries], ...]，没有结果时为 NULL
+func streamReadValue(results []store.StreamReadResult) *protocol.Value {
+	if len(results) == 0 {
+		return protocol.NullArray()
+	}
+
+	values := make([]protocol.Value, len(results))
+	for i, r := range results {
+		values[i] = *protocol.Array([]protocol.Value{
+			*protocol.BulkString(r.Key),
+			*streamEntriesValue(r.Entries),
+		})
+	}
+	return protocol.Array(values)
+}
+
+// parseStreamIDs 解析一组完整或不完整的 ID
+func parseStreamIDs(args []protocol.Value) ([]store.StreamID, error) {
+	ids := make([]store.StreamID, len(args))
+	for i, arg := range args {
+		id, err := store.ParseStreamID(arg.Str, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// parseStreamTrim 尝试从 args[i] 开始解析 MAXLEN|MINID [=|~] threshold [LIMIT count]
+// 返回解析结果和消耗的参数个数；args[i] 不是裁剪选项时返回 (nil, 0, nil)
+func parseStreamTrim(args []protocol.Value, i int) (*store.StreamTrim, int, *protocol.Value) {
+	strategy := strings.ToUpper(args[i].Str)
+	if strategy != "MAXLEN" && strategy != "MINID" {
+		return nil, 0, nil
+	}
+
+	trim := &store.StreamTrim{Strategy: strategy}
+	j := i + 1
+
+	if j < len(args) && (args[j].Str == "~" || args[j].Str == "=") {
+		trim.Approx = args[j].Str == "~"
+		j++
+	}
+	if j >= len(args) {
+		return nil, 0, protocol.Error("ERR syntax error")
+	}
+
+	if strategy == "MAXLEN" {
+		n, err := strconv.ParseInt(args[j].Str, 10, 64)
+		if err != nil {
+			return nil, 0, protocol.Error("ERR value is not an integer or out of range")
+		}
+		if n < 0 {
+			return nil, 0, protocol.Error("ERR The MAXLEN argument must be >= 0.")
+		}
+		trim.MaxLen = n
+	} else {
+		id, err := store.ParseStreamID(args[j].Str, 0)
+		if err != nil {
+			return nil, 0, protocol.Error(err.Error())
+		}
+		trim.MinID = id
+	}
+	j++
+
+	if j+1 < len(args) && strings.ToUpper(args[j].Str) == "LIMIT" {
+		if !trim.Approx {
+			return nil, 0, protocol.Error("ERR syntax error, LIMIT cannot be used without the special ~ option")
+		}
+		n, err := strconv.ParseInt(args[j+1].Str, 10, 64)
+		if err != nil || n < 0 {
+			return nil, 0, protocol.Error("ERR The LIMIT argument must be >= 0.")
+		}
+		trim.Limit = n
+		j += 2
+	}
+
+	return trim, j - i, nil
+}
+
+// parseBlockTimeout 解析 BLOCK 参数（毫秒），0 表示无限等待
+func parseBlockTimeout(arg string) (time.Duration, *protocol.Value) {
+	ms, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, protocol.Error("ERR timeout is not an integer or out of range")
+	}
+	if ms < 0 {
+		return 0, protocol.Error("ERR timeout is negative")
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// blockingStreamRead 反复执行 read，直到读到数据、超时或 Store 关闭
+// timeout 为 0 表示一直等待；等待的时间累加到 blocked（可以为 nil）
+func blockingStreamRead(db *store.Store, keys []string, timeout time.Duration, blocked *time.Duration, read func() ([]store.StreamReadResult, error)) ([]store.StreamReadResult, error) {
+	// 先登记再读取，避免错过两步之间的 XADD
+	ch := db.WatchKeys(keys)
+	defer db.UnwatchKeys(keys, ch)
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		res, err := read()
+		if err != nil || len(res) > 0 {
+			return res, err
+		}
+
+		start := time.Now()
+		stop := false
+		select {
+		case <-ch:
+		case <-deadline:
+			stop = true
+		case <-db.Done():
+			stop = true
+		}
+		if blocked != nil {
+			*blocked += time.Since(start)
+		}
+		if stop {
+			return nil, nil
+		}
+	}
+}
+
+// parseStreamsClause 拆分 STREAMS 之后的 key... id... 列表
+func parseStreamsClause(args []protocol.Value, cmd string) ([]string, []string, *protocol.Value) {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, nil, protocol.Error("ERR Unbalanced '" + cmd + "' list of streams: for each stream key an ID or '$' must be specified.")
+	}
+
+	n := len(args) / 2
+	keys := make([]string, n)
+	ids := make([]string, n)
+	for i := 0; i < n; i++ {
+		keys[i] = args[i].Str
+		ids[i] = args[n+i].Str
+	}
+	return keys, ids, nil
+}
+
+// *****
+type XAddHandler struct {
+	db *store.Store
+}
+
+func NewXAddHandler(db *store.Store) *XAddHandler {
+	return &XAddHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XADD 命令
+// XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]] *|id field value [field value ...]
+func (h *XAddHandler) Handle(args []protocol.Value) *protocol.Value {
+	key := args[0].Str
+	noMkStream := false
+	var trim *store.StreamTrim
+
+	i := 1
+	for i < len(args) {
+		if strings.ToUpper(args[i].Str) == "NOMKSTREAM" {
+			noMkStream = true
+			i++
+			continue
+		}
+
+		t, n, errResp := parseStreamTrim(args, i)
+		if errResp != nil {
+			return errResp
+		}
+		if t == nil {
+			break
+		}
+		trim = t
+		i += n
+	}
+
+	if i >= len(args) {
+		return protocol.Error("ERR syntax error")
+	}
+	idSpec := args[i].Str
+
+	pairs := args[i+1:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return protocol.Error("ERR wrong number of arguments for 'xadd' command")
+	}
+
+	fields := make([]string, len(pairs))
+	for j, p := range pairs {
+		fields[j] = p.Str
+	}
+
+	id, trimmed, ok, err := h.db.XAdd(key, idSpec, fields, noMkStream, trim)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if !ok {
+		return protocol.NullBulkString()
+	}
+
+	h.db.Notify(store.NotifyStream, "xadd", key)
+	if trimmed > 0 {
+		h.db.Notify(store.NotifyStream, "xtrim", key)
+	}
+
+	return protocol.BulkString(id.String())
+}
+
+// *****
+type XLenHandler struct {
+	db *store.Store
+}
+
+func NewXLenHandler(db *store.Store) *XLenHandler {
+	return &XLenHandler{
+		db: db,
+	}
+}
+
+func (h *XLenHandler) Handle(args []protocol.Value) *protocol.Value {
+	n, err := h.db.XLen(args[0].Str)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	return protocol.Integer(n)
+}
+
+// *****
+type XRangeHandler struct {
+	db  *store.Store
+	rev bool
+}
+
+func NewXRangeHandler(db *store.Store) *XRangeHandler {
+	return &XRangeHandler{
+		db: db,
+	}
+}
+
+func NewXRevRangeHandler(db *store.Store) *XRangeHandler {
+	return &XRangeHandler{
+		db:  db,
+		rev: true,
+	}
+}
+
+// Handle 处理 XRANGE/XREVRANGE 命令
+// XRANGE key start end [COUNT count]
+// XREVRANGE key end start [COUNT count]
+func (h *XRangeHandler) Handle(args []protocol.Value) *protocol.Value {
+	name := "xrange"
+	if h.rev {
+		name = "xrevrange"
+	}
+
+	if len(args) != 3 && len(args) != 5 {
+		return protocol.Error("ERR wrong number of arguments for '" + name + "' command")
+	}
+
+	startArg, endArg := args[1].Str, args[2].Str
+	if h.rev {
+		startArg, endArg = endArg, startArg
+	}
+
+	start, err := store.ParseStreamRange(startArg, true)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	end, err := store.ParseStreamRange(endArg, false)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	count := -1
+	if len(args) == 5 {
+		if strings.ToUpper(args[3].Str) != "COUNT" {
+			return protocol.Error("ERR syntax error")
+		}
+		n, err := strconv.Atoi(args[4].Str)
+		if err != nil {
+			return protocol.Error("ERR value is not an integer or out of range")
+		}
+		if n <= 0 {
+			return protocol.EmptyArray()
+		}
+		count = n
+	}
+
+	entries, err := h.db.XRange(args[0].Str, start, end, count, h.rev)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	return streamEntriesValue(entries)
+}
+
+// *****
+type XDelHandler struct {
+	db *store.Store
+}
+
+func NewXDelHandler(db *store.Store) *XDelHandler {
+	return &XDelHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XDEL 命令
+// XDEL key id [id ...]
+func (h *XDelHandler) Handle(args []protocol.Value) *protocol.Value {
+	ids, err := parseStreamIDs(args[1:])
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	n, err := h.db.XDel(args[0].Str, ids)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if n > 0 {
+		h.db.Notify(store.NotifyStream, "xdel", args[0].Str)
+	}
+
+	return protocol.Integer(n)
+}
+
+// *****
+type XTrimHandler struct {
+	db *store.Store
+}
+
+func NewXTrimHandler(db *store.Store) *XTrimHandler {
+	return &XTrimHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XTRIM 命令
+// XTRIM key MAXLEN|MINID [=|~] threshold [LIMIT count]
+func (h *XTrimHandler) Handle(args []protocol.Value) *protocol.Value {
+	trim, n, errResp := parseStreamTrim(args, 1)
+	if errResp != nil {
+		return errResp
+	}
+	if trim == nil || 1+n != len(args) {
+		return protocol.Error("ERR syntax error")
+	}
+
+	deleted, err := h.db.XTrim(args[0].Str, trim)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	if deleted > 0 {
+		h.db.Notify(store.NotifyStream, "xtrim", args[0].Str)
+	}
+
+	return protocol.Integer(deleted)
+}
+
+// *****
+type XReadHandler struct {
+	db *store.Store
+}
+
+func NewXReadHandler(db *store.Store) *XReadHandler {
+	return &XReadHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XREAD 命令，等待的时间不单独统计
+func (h *XReadHandler) Handle(args []protocol.Value) *protocol.Value {
+	return h.HandleBlocking(args, nil)
+}
+
+// HandleBlocking 处理 XREAD 命令，BLOCK 等待数据的时间累加到 blocked
+// XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]
+func (h *XReadHandler) HandleBlocking(args []protocol.Value, blocked *time.Duration) *protocol.Value {
+	count := -1
+	block := false
+	var timeout time.Duration
+
+	i := 0
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i].Str)
+		if opt == "STREAMS" {
+			break
+		}
+		if i+1 >= len(args) {
+			return protocol.Error("ERR syntax error")
+		}
+
+		switch opt {
+		case "COUNT":
+			n, err := strconv.Atoi(args[i+1].Str)
+			if err != nil {
+				return protocol.Error("ERR value is not an integer or out of range")
+			}
+			count = n
+		case "BLOCK":
+			t, errResp := parseBlockTimeout(args[i+1].Str)
+			if errResp != nil {
+				return errResp
+			}
+			block, timeout = true, t
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+		i++
+	}
+
+	if i >= len(args) {
+		return protocol.Error("ERR syntax error")
+	}
+
+	keys, idArgs, errResp := parseStreamsClause(args[i+1:], "xread")
+	if errResp != nil {
+		return errResp
+	}
+
+	// "$" 在阻塞前解析为当前最后 ID，之后只等待更新的消息
+	ids := make([]store.StreamID, len(keys))
+	for j, arg := range idArgs {
+		var id store.StreamID
+		var err error
+		if arg == "$" {
+			id, err = h.db.StreamLastID(keys[j])
+		} else {
+			id, err = store.ParseStreamID(arg, 0)
+		}
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		ids[j] = id
+	}
+
+	read := func() ([]store.StreamReadResult, error) {
+		return h.db.XRead(keys, ids, count)
+	}
+
+	var results []store.StreamReadResult
+	var err error
+	if block {
+		results, err = blockingStreamRead(h.db, keys, timeout, blocked, read)
+	} else {
+		results, err = read()
+	}
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	return streamReadValue(results)
+}
@@ -0,0 +1,447 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type XGroupHandler struct {
+	db *store.Store
+}
+
+func NewXGroupHandler(db *store.Store) *XGroupHandler {
+	return &XGroupHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XGROUP 命令
+// XGROUP CREATE key group id|$ [MKSTREAM]
+// XGROUP SETID key group id|$
+// XGROUP DESTROY key group
+// XGROUP CREATECONSUMER key group consumer
+// XGROUP DELCONSUMER key group consumer
+func (h *XGroupHandler) Handle(args []protocol.Value) *protocol.Value {
+	sub := strings.ToUpper(args[0].Str)
+	rest := args[1:]
+
+	switch sub {
+	case "CREATE":
+		if len(rest) != 3 && len(rest) != 4 {
+			return protocol.Error("ERR wrong number of arguments for 'xgroup|create' command")
+		}
+		mkStream := false
+		if len(rest) == 4 {
+			if strings.ToUpper(rest[3].Str) != "MKSTREAM" {
+				return protocol.Error("ERR syntax error")
+			}
+			mkStream = true
+		}
+		if err := h.db.XGroupCreate(rest[0].Str, rest[1].Str, rest[2].Str, mkStream); err != nil {
+			return protocol.Error(err.Error())
+		}
+		h.db.Notify(store.NotifyStream, "xgroup-create", rest[0].Str)
+		return protocol.SimpleString("OK")
+
+	case "SETID":
+		if len(rest) != 3 {
+			return protocol.Error("ERR wrong number of arguments for 'xgroup|setid' command")
+		}
+		if err := h.db.XGroupSetID(rest[0].Str, rest[1].Str, rest[2].Str); err != nil {
+			return protocol.Error(err.Error())
+		}
+		h.db.Notify(store.NotifyStream, "xgroup-setid", rest[0].Str)
+		return protocol.SimpleString("OK")
+
+	case "DESTROY":
+		if len(rest) != 2 {
+			return protocol.Error("ERR wrong number of arguments for 'xgroup|destroy' command")
+		}
+		ok, err := h.db.XGroupDestroy(rest[0].Str, rest[1].Str)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		if ok {
+			h.db.Notify(store.NotifyStream, "xgroup-destroy", rest[0].Str)
+		}
+		return protocol.Integer(boolToInt(ok))
+
+	case "CREATECONSUMER":
+		if len(rest) != 3 {
+			return protocol.Error("ERR wrong number of arguments for 'xgroup|createconsumer' command")
+		}
+		ok, err := h.db.XGroupCreateConsumer(rest[0].Str, rest[1].Str, rest[2].Str)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		if ok {
+			h.db.Notify(store.NotifyStream, "xgroup-createconsumer", rest[0].Str)
+		}
+		return protocol.Integer(boolToInt(ok))
+
+	case "DELCONSUMER":
+		if len(rest) != 3 {
+			return protocol.Error("ERR wrong number of arguments for 'xgroup|delconsumer' command")
+		}
+		n, err := h.db.XGroupDelConsumer(rest[0].Str, rest[1].Str, rest[2].Str)
+		if err != nil {
+			return protocol.Error(err.Error())
+		}
+		h.db.Notify(store.NotifyStream, "xgroup-delconsumer", rest[0].Str)
+		return protocol.Integer(n)
+	}
+
+	return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try XGROUP HELP.")
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// *****
+type XReadGroupHandler struct {
+	db *store.Store
+}
+
+func NewXReadGroupHandler(db *store.Store) *XReadGroupHandler {
+	return &XReadGroupHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XREADGROUP 命令，等待的时间不单独统计
+func (h *XReadGroupHandler) Handle(args []protocol.Value) *protocol.Value {
+	return h.HandleBlocking(args, nil)
+}
+
+// HandleBlocking 处理 XREADGROUP 命令，BLOCK 等待数据的时间累加到 blocked
+// XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]
+func (h *XReadGroupHandler) HandleBlocking(args []protocol.Value, blocked *time.Duration) *protocol.Value {
+	if strings.ToUpper(args[0].Str) != "GROUP" {
+		return protocol.Error("ERR syntax error")
+	}
+	group, consumer := args[1].Str, args[2].Str
+
+	count := -1
+	block := false
+	noAck := false
+	var timeout time.Duration
+
+	i := 3
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i].Str)
+		if opt == "STREAMS" {
+			break
+		}
+		if opt == "NOACK" {
+			noAck = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return protocol.Error("ERR syntax error")
+		}
+
+		switch opt {
+		case "COUNT":
+			n, err := strconv.Atoi(args[i+1].Str)
+			if err != nil {
+				return protocol.Error("ERR value is not an integer or out of range")
+			}
+			count = n
+		case "BLOCK":
+			t, errResp := parseBlockTimeout(args[i+1].Str)
+			if errResp != nil {
+				return errResp
+			}
+			block, timeout = true, t
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+		i++
+	}
+
+	if i >= len(args) {
+		return protocol.Error("ERR syntax error")
+	}
+
+	keys, ids, errResp := parseStreamsClause(args[i+1:], "xreadgroup")
+	if errResp != nil {
+		return errResp
+	}
+
+	// 只有全部读取新消息（">"）时才会阻塞，读取历史消息总是立即返回
+	onlyNew := true
+	for _, id := range ids {
+		if id == "$" {
+			return protocol.Error("ERR The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set.")
+		}
+		if id != ">" {
+			onlyNew = false
+		}
+	}
+
+	read := func() ([]store.StreamReadResult, error) {
+		return h.db.XReadGroup(group, consumer, keys, ids, count, noAck)
+	}
+
+	var results []store.StreamReadResult
+	var err error
+	if block && onlyNew {
+		results, err = blockingStreamRead(h.db, keys, timeout, blocked, read)
+	} else {
+		results, err = read()
+	}
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	return streamReadValue(results)
+}
+
+// *****
+type XAckHandler struct {
+	db *store.Store
+}
+
+func NewXAckHandler(db *store.Store) *XAckHandler {
+	return &XAckHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XACK 命令
+// XACK key group id [id ...]
+func (h *XAckHandler) Handle(args []protocol.Value) *protocol.Value {
+	ids, err := parseStreamIDs(args[2:])
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	n, err := h.db.XAck(args[0].Str, args[1].Str, ids)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	return protocol.Integer(n)
+}
+
+// *****
+type XPendingHandler struct {
+	db *store.Store
+}
+
+func NewXPendingHandler(db *store.Store) *XPendingHandler {
+	return &XPendingHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XPENDING 命令
+// XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+func (h *XPendingHandler) Handle(args []protocol.Value) *protocol.Value {
+	key, group := args[0].Str, args[1].Str
+
+	if len(args) == 2 {
+		return h.summary(key, group)
+	}
+
+	rest := args[2:]
+	var minIdle time.Duration
+	if strings.ToUpper(rest[0].Str) == "IDLE" {
+		if len(rest) < 2 {
+			return protocol.Error("ERR syntax error")
+		}
+		ms, err := strconv.ParseInt(rest[1].Str, 10, 64)
+		if err != nil {
+			return protocol.Error("ERR value is not an integer or out of range")
+		}
+		minIdle = time.Duration(ms) * time.Millisecond
+		rest = rest[2:]
+	}
+
+	if len(rest) != 3 && len(rest) != 4 {
+		return protocol.Error("ERR syntax error")
+	}
+
+	start, err := store.ParseStreamRange(rest[0].Str, true)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	end, err := store.ParseStreamRange(rest[1].Str, false)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+	count, err := strconv.Atoi(rest[2].Str)
+	if err != nil {
+		return protocol.Error("ERR value is not an integer or out of range")
+	}
+	if count < 0 {
+		count = 0
+	}
+
+	consumer := ""
+	if len(rest) == 4 {
+		consumer = rest[3].Str
+	}
+
+	pending, err := h.db.XPendingRange(key, group, start, end, count, consumer, minIdle)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	now := time.Now()
+	values := make([]protocol.Value, len(pending))
+	for i, pe := range pending {
+		values[i] = *protocol.Array([]protocol.Value{
+			*protocol.BulkString(pe.ID.String()),
+			*protocol.BulkString(pe.Consumer),
+			*protocol.Integer(now.Sub(pe.DeliveryTime).Milliseconds()),
+			*protocol.Integer(pe.DeliveryCount),
+		})
+	}
+
+	return protocol.Array(values)
+}
+
+// summary 返回 [总数, 最小 ID, 最大 ID, [[consumer, count], ...]]
+func (h *XPendingHandler) summary(key, group string) *protocol.Value {
+	s, err := h.db.XPendingSummary(key, group)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	if s.Count == 0 {
+		return protocol.Array([]protocol.Value{
+			*protocol.Integer(0),
+			*protocol.NullBulkString(),
+			*protocol.NullBulkString(),
+			*protocol.NullArray(),
+		})
+	}
+
+	consumers := make([]protocol.Value, len(s.Consumers))
+	for i, c := range s.Consumers {
+		consumers[i] = *protocol.Array([]protocol.Value{
+			*protocol.BulkString(c.Name),
+			*protocol.BulkString(strconv.FormatInt(c.Count, 10)),
+		})
+	}
+
+	return protocol.Array([]protocol.Value{
+		*protocol.Integer(s.Count),
+		*protocol.BulkString(s.MinID.String()),
+		*protocol.BulkString(s.MaxID.String()),
+		*protocol.Array(consumers),
+	})
+}
+
+// *****
+type XClaimHandler struct {
+	db *store.Store
+}
+
+func NewXClaimHandler(db *store.Store) *XClaimHandler {
+	return &XClaimHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 XCLAIM 命令
+// XCLAIM key group consumer min-idle-time id [id ...] [IDLE ms] [TIME unix-time-ms]
+// [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID id]
+func (h *XClaimHandler) Handle(args []protocol.Value) *protocol.Value {
+	key, group, consumer := args[0].Str, args[1].Str, args[2].Str
+
+	minIdleMs, err := strconv.ParseInt(args[3].Str, 10, 64)
+	if err != nil {
+		return protocol.Error("ERR Invalid min-idle-time argument for XCLAIM")
+	}
+	if minIdleMs < 0 {
+		minIdleMs = 0
+	}
+
+	// ID 列表一直延续到第一个无法解析为 ID 的参数
+	i := 4
+	ids := make([]store.StreamID, 0)
+	for ; i < len(args); i++ {
+		id, err := store.ParseStreamID(args[i].Str, 0)
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return protocol.Error(store.ErrInvalidStreamID.Error())
+	}
+
+	opts := store.StreamClaimOptions{RetryCount: -1}
+	now := time.Now()
+
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i].Str)
+		switch opt {
+		case "FORCE":
+			opts.Force = true
+			continue
+		case "JUSTID":
+			opts.JustID = true
+			continue
+		}
+
+		if i+1 >= len(args) {
+			return protocol.Error("ERR Unrecognized XCLAIM option '" + args[i].Str + "'")
+		}
+		val := args[i+1].Str
+		i++
+
+		switch opt {
+		case "IDLE":
+			ms, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return protocol.Error("ERR Invalid IDLE option argument for XCLAIM")
+			}
+			opts.DeliveryTime = now.Add(-time.Duration(ms) * time.Millisecond)
+		case "TIME":
+			ms, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return protocol.Error("ERR Invalid TIME option argument for XCLAIM")
+			}
+			opts.DeliveryTime = time.UnixMilli(ms)
+		case "RETRYCOUNT":
+			n, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || n < 0 {
+				return protocol.Error("ERR Invalid RETRYCOUNT option argument for XCLAIM")
+			}
+			opts.RetryCount = n
+		case "LASTID":
+			id, err := store.ParseStreamID(val, 0)
+			if err != nil {
+				return protocol.Error(err.Error())
+			}
+			opts.LastID = &id
+		default:
+			return protocol.Error("ERR Unrecognized XCLAIM option '" + args[i-1].Str + "'")
+		}
+	}
+
+	entries, err := h.db.XClaim(key, group, consumer, time.Duration(minIdleMs)*time.Millisecond, ids, opts)
+	if err != nil {
+		return protocol.Error(err.Error())
+	}
+
+	if opts.JustID {
+		values := make([]protocol.Value, len(entries))
+		for j, e := range entries {
+			values[j] = *protocol.BulkString(e.ID.String())
+		}
+		return protocol.Array(values)
+	}
+
+	return streamEntriesValue(entries)
+}
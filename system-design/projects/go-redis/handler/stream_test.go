@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"testing"
+	"time"
+)
+
+// execCommand 直接构造命令数组并交给 Router 执行
+func execCommand(r *Router, args ...string) *protocol.Value {
+	values := make([]protocol.Value, len(args))
+	for i, arg := range args {
+		values[i] = *protocol.BulkString(arg)
+	}
+	return r.Route(protocol.Array(values))
+}
+
+func TestXAddAndXRange(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	resp := execCommand(r, "XADD", "s", "1-1", "name", "alice")
+	if resp.Type != protocol.BulkStringType || resp.Str != "1-1" {
+		t.Fatalf("Unexpected XADD response: %+v", resp)
+	}
+	execCommand(r, "XADD", "s", "2-1", "name", "bob")
+
+	resp = execCommand(r, "XRANGE", "s", "-", "+")
+	if resp.Type != protocol.ArrayType || len(resp.Array) != 2 {
+		t.Fatalf("Unexpected XRANGE response: %+v", resp)
+	}
+
+	entry := resp.Array[1]
+	if entry.Array[0].Str != "2-1" || entry.Array[1].Array[1].Str != "bob" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	resp = execCommand(r, "XREVRANGE", "s", "+", "-", "COUNT", "1")
+	if len(resp.Array) != 1 || resp.Array[0].Array[0].Str != "2-1" {
+		t.Errorf("Unexpected XREVRANGE response: %+v", resp)
+	}
+}
+
+func TestXAddArgs(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	tests := []struct {
+		name string
+		args []string
+		want protocol.ValueType
+	}{
+		{"odd field count", []string{"XADD", "s", "*", "a"}, protocol.ErrorType},
+		{"maxlen", []string{"XADD", "s", "MAXLEN", "~", "10", "*", "a", "b"}, protocol.BulkStringType},
+		{"limit without tilde", []string{"XADD", "s", "MAXLEN", "10", "LIMIT", "5", "*", "a", "b"}, protocol.ErrorType},
+		{"minid", []string{"XADD", "s", "MINID", "0", "*", "a", "b"}, protocol.BulkStringType},
+		{"bad id", []string{"XADD", "s", "abc", "a", "b"}, protocol.ErrorType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := execCommand(r, tt.args...)
+			if resp.Type != tt.want {
+				t.Errorf("Expected %v, got %+v", tt.want, resp)
+			}
+		})
+	}
+
+	resp := execCommand(r, "XADD", "missing", "NOMKSTREAM", "*", "a", "b")
+	if resp.Type != protocol.BulkStringType || !resp.IsNull {
+		t.Errorf("Expected null reply for NOMKSTREAM, got %+v", resp)
+	}
+}
+
+func TestXReadBlock(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "XADD", "s", "1-0", "a", "b")
+
+	// 非阻塞读取已有数据
+	resp := execCommand(r, "XREAD", "COUNT", "10", "STREAMS", "s", "0")
+	if len(resp.Array) != 1 || resp.Array[0].Array[0].Str != "s" {
+		t.Fatalf("Unexpected XREAD response: %+v", resp)
+	}
+
+	// 超时返回 NULL
+	resp = execCommand(r, "XREAD", "BLOCK", "20", "STREAMS", "s", "$")
+	if resp.Type != protocol.ArrayType || !resp.IsNull {
+		t.Errorf("Expected null array on timeout, got %+v", resp)
+	}
+
+	// 阻塞期间写入的数据会唤醒读取者
+	done := make(chan *protocol.Value)
+	go func() {
+		done <- execCommand(r, "XREAD", "BLOCK", "0", "STREAMS", "s", "$")
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	execCommand(r, "XADD", "s", "2-0", "c", "d")
+
+	select {
+	case resp = <-done:
+		entries := resp.Array[0].Array[1].Array
+		if len(entries) != 1 || entries[0].Array[0].Str != "2-0" {
+			t.Errorf("Unexpected entries: %+v", entries)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("XREAD BLOCK was not woken up by XADD")
+	}
+}
+
+func TestXReadGroupFlow(t *testing.T) {
+	r := NewRouter(store.NewStore())
+
+	resp := execCommand(r, "XGROUP", "CREATE", "s", "g", "$", "MKSTREAM")
+	if resp.Str != "OK" {
+		t.Fatalf("Unexpected XGROUP CREATE response: %+v", resp)
+	}
+	execCommand(r, "XADD", "s", "1-0", "a", "1")
+	execCommand(r, "XADD", "s", "2-0", "a", "2")
+
+	resp = execCommand(r, "XREADGROUP", "GROUP", "g", "c1", "COUNT", "1", "STREAMS", "s", ">")
+	if len(resp.Array) != 1 || len(resp.Array[0].Array[1].Array) != 1 {
+		t.Fatalf("Unexpected XREADGROUP response: %+v", resp)
+	}
+
+	resp = execCommand(r, "XPENDING", "s", "g")
+	if resp.Array[0].Int != 1 || resp.Array[1].Str != "1-0" {
+		t.Errorf("Unexpected XPENDING summary: %+v", resp)
+	}
+
+	resp = execCommand(r, "XPENDING", "s", "g", "-", "+", "10")
+	if len(resp.Array) != 1 || resp.Array[0].Array[1].Str != "c1" || resp.Array[0].Array[3].Int != 1 {
+		t.Errorf("Unexpected XPENDING range: %+v", resp)
+	}
+
+	resp = execCommand(r, "XCLAIM", "s", "g", "c2", "0", "1-0", "JUSTID")
+	if len(resp.Array) != 1 || resp.Array[0].Str != "1-0" {
+		t.Errorf("Unexpected XCLAIM response: %+v", resp)
+	}
+
+	resp = execCommand(r, "XACK", "s", "g", "1-0")
+	if resp.Int != 1 {
+		t.Errorf("Expected 1 acked, got %+v", resp)
+	}
+
+	resp = execCommand(r, "XPENDING", "s", "g")
+	if resp.Array[0].Int != 0 || !resp.Array[1].IsNull {
+		t.Errorf("Expected empty PEL, got %+v", resp)
+	}
+
+	resp = execCommand(r, "XREADGROUP", "GROUP", "missing", "c1", "STREAMS", "s", ">")
+	if resp.Type != protocol.ErrorType {
+		t.Errorf("Expected NOGROUP error, got %+v", resp)
+	}
+}
+
+func TestStreamWrongType(t *testing.T) {
+	r := NewRouter(store.NewStore())
+	execCommand(r, "SET", "str", "v")
+
+	resp := execCommand(r, "XADD", "str", "*", "a", "b")
+	if resp.Type != protocol.ErrorType || resp.Str != store.ErrWrongType.Error() {
+		t.Errorf("Expected WRONGTYPE, got %+v", resp)
+	}
+
+	execCommand(r, "XADD", "s", "*", "a", "b")
+	resp = execCommand(r, "GET", "s")
+	if resp.Type != protocol.ErrorType || resp.Str != store.ErrWrongType.Error() {
+		t.Errorf("Expected WRONGTYPE from GET, got %+v", resp)
+	}
+}
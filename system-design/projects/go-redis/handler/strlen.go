@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"go-redis/protocol"
+	"go-redis/store"
+	"strconv"
+)
+
+type StrlenHandler struct {
+	db *store.Store
+}
+
+func NewStrlenHandler(db *store.Store) *StrlenHandler {
+	return &StrlenHandler{
+		db: db,
+	}
+}
+
+// Handle 处理 STRLEN key，键不存在时返回 0
+func (h *StrlenHandler) Handle(args []protocol.Value) *protocol.Value {
+	value, exists := h.db.Get(args[0].Str)
+	if !exists {
+		return protocol.Integer(0)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return protocol.Integer(int64(len(v)))
+	case int64:
+		return protocol.Integer(int64(len(strconv.FormatInt(v, 10))))
+	default:
+		return protocol.Error(store.ErrWrongType.Error())
+	}
+}
@@ -0,0 +1,9 @@
+package handler
+
+// readKeys 返回只读命令读取的键，键的位置来自命令表，写命令和不涉及键的命令返回 nil
+func (r *Router) readKeys(cmd *Command) []string {
+	if !cmd.HasFlag("readonly") {
+		return nil
+	}
+	return cmd.Keys()
+}
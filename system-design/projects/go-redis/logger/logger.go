@@ -99,6 +99,11 @@ func SetLevel(level logrus.Level) {
 	Log.SetLevel(level)
 }
 
+// GetLevel 返回当前日志级别
+func GetLevel() logrus.Level {
+	return Log.GetLevel()
+}
+
 // SetOutput 设置日志输出位置
 func SetOutput(output io.Writer) {
 	Log.SetOutput(output)
@@ -1,50 +1,154 @@
 package main
 
 import (
-	"flag"
 	"fmt"
 	"go-redis/logger"
+	"go-redis/protocol"
 	"go-redis/server"
 	"go-redis/store"
 	"os"
 	"os/signal"
 	"strings"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
 )
 
-func main() {
-	var port int
-	var logLevel string
+const usage = `Usage: go-redis [/path/to/redis.conf] [options]
+       go-redis /path/to/sentinel.conf --sentinel [options]
+       go-redis -h or --help
 
-	flag.IntVar(&port, "port", 16379, "端口")
-	flag.StringVar(&logLevel, "loglevel", "info", "日志级别: debug | info | warn | error")
-	flag.Parse()
+Examples:
+       go-redis (run the server with default config)
+       go-redis /etc/redis/6379.conf
+       go-redis --port 7777
+       go-redis /etc/myredis.conf --loglevel verbose --io-model epoll
+       go-redis /etc/sentinel.conf --sentinel
 
-	level, err := logrus.ParseLevel(strings.ToLower(logLevel))
-	if err != nil {
-		logger.Errorf("非法日志级别: %s", logLevel)
+配置文件的格式与 redis.conf 相同，--name value 与配置文件中的 "name value" 一行等价，
+并且覆盖配置文件中的值。为了兼容，-port、-loglevel 等单横线写法同样有效。
+--sentinel 以哨兵模式运行，默认端口为 26379，哨兵需要把状态写回配置文件，因此必须指定配置文件
+`
+
+// parseArgs 解析命令行：第一个不以 - 开头的参数是配置文件，
+// 之后的每个 --name value... 转换为一行配置，值加上引号以保留其中的空白
+func parseArgs(args []string) (file string, overrides []string, err error) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		file, args = args[0], args[1:]
+	}
+
+	for len(args) > 0 {
+		if !isOption(args[0]) {
+			return "", nil, fmt.Errorf("unexpected argument '%s'", args[0])
+		}
+		name := strings.TrimLeft(args[0], "-")
+		args = args[1:]
+
+		parts := []string{name}
+		if name, value, ok := strings.Cut(name, "="); ok {
+			// 兼容 flag 包的 -port=7777 写法
+			parts = []string{name, protocol.Quote(value)}
+		}
+		for len(args) > 0 && !isOption(args[0]) {
+			parts = append(parts, protocol.Quote(args[0]))
+			args = args[1:]
+		}
+		overrides = append(overrides, strings.Join(parts, " "))
 	}
+	return file, overrides, nil
+}
 
-	logger.SetLevel(level)
+// cutSentinelFlag 从参数中去掉 --sentinel（可以出现在任何位置），返回是否以哨兵模式运行
+func cutSentinelFlag(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	found := false
+	for _, arg := range args {
+		if arg == "--sentinel" || arg == "-sentinel" {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
 
-	s := store.NewStore()
+// isOption 判断参数是否是选项名，-1 这样的负数是值
+func isOption(arg string) bool {
+	return strings.HasPrefix(arg, "--") ||
+		len(arg) > 1 && arg[0] == '-' && (arg[1] < '0' || arg[1] > '9')
+}
 
-	srv := server.NewServer(fmt.Sprintf(":%d", port), s)
+func main() {
+	for _, arg := range os.Args[1:] {
+		if arg == "-h" || arg == "--help" {
+			fmt.Print(usage)
+			return
+		}
+	}
 
+	args, sentinelMode := cutSentinelFlag(os.Args[1:])
+	file, overrides, err := parseArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n%s", err, usage)
+		os.Exit(1)
+	}
+	if sentinelMode && file == "" {
+		fmt.Fprintln(os.Stderr, "Sentinel needs config file on disk to save state. Exiting...")
+		os.Exit(1)
+	}
+
+	// 默认日志级别，可以被配置文件和命令行中的 loglevel 覆盖
+	logger.SetLevel(logrus.InfoLevel)
+
+	addr := ":16379"
+	if sentinelMode {
+		addr = ":26379"
+	}
+	srv := server.NewServer(addr, store.NewStore())
+	if sentinelMode {
+		srv.EnableSentinel()
+	}
+	if err := srv.Config().Load(file, overrides); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if file == "" {
+		logger.Warn("No config file specified, using the default config")
+	} else {
+		logger.Infof("Configuration loaded from %s", file)
+	}
+
+	// 收到信号后与 SHUTDOWN 命令一样优雅关闭，关闭失败时继续运行；
+	// 关闭过程中再次按下 Ctrl-C 立即退出
 	go func() {
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-		<-sigCh
 
-		logger.Info("Received shutdown signal")
-		srv.Stop()
-		os.Exit(0)
+		var shuttingDown atomic.Bool
+		for sig := range sigCh {
+			if shuttingDown.Load() {
+				if sig == syscall.SIGINT {
+					logger.Warn("You insist... exiting now.")
+					os.Exit(1)
+				}
+				continue
+			}
+
+			logger.Infof("Received %v, scheduling shutdown...", sig)
+			shuttingDown.Store(true)
+			go func() {
+				if err := srv.Shutdown(server.ShutdownOptions{}); err != nil {
+					logger.Errorf("Errors trying to shut down the server, check the logs for more information: %v", err)
+					shuttingDown.Store(false)
+				}
+			}()
+		}
 	}()
 
-	logger.Info("Starting Go-Redis server on :", port)
+	logger.Infof("Starting Go-Redis server on %s", srv.Addr())
 	if err := srv.Start(); err != nil {
 		logger.Fatalf("Server error: %v", err)
 	}
+	logger.Info("Go-Redis is now ready to exit, bye bye...")
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go-redis/client"
+	"go-redis/logger"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 多进程集成测试：子进程是测试二进制本身，设置了 mainEnv 时 TestMain 直接运行 main，
+// 这样不需要另外编译服务器，每个实例都是监听 localhost 端口的独立进程
+
+const mainEnv = "GO_REDIS_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(mainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	logger.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// freePort 返回一个当前空闲的本地端口
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// process 是一个运行中的 go-redis 进程
+type process struct {
+	cmd  *exec.Cmd
+	addr string
+	log  string
+}
+
+// startProcess 以 args 启动 go-redis 进程并等待它开始监听，测试结束时结束进程
+func startProcess(t *testing.T, port int, args ...string) *process {
+	t.Helper()
+	logFile, err := os.CreateTemp(t.TempDir(), "go-redis-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), mainEnv+"=1")
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	p := &process{cmd: cmd, addr: fmt.Sprintf("127.0.0.1:%d", port), log: logFile.Name()}
+	t.Cleanup(func() {
+		p.kill()
+		if t.Failed() {
+			data, _ := os.ReadFile(p.log)
+			t.Logf("log of %s %v:\n%s", p.addr, args, data)
+		}
+	})
+
+	waitUntil(t, 10*time.Second, p.addr+" to listen", func() bool {
+		conn, err := net.Dial("tcp", p.addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	})
+	return p
+}
+
+func (p *process) kill() {
+	if p.cmd.ProcessState == nil {
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+	}
+}
+
+func waitUntil(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// masterFlags 返回哨兵对主节点的 flags（SENTINEL MASTER 的 flags 字段）
+func masterFlags(ctx context.Context, c *client.Client) string {
+	fields, _ := c.Do(ctx, "sentinel", "master", "mymaster").Val().([]interface{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == "flags" {
+			s, _ := fields[i+1].(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// TestSentinelProcesses 启动一个主节点和三个哨兵进程：哨兵通过 hello 频道互相发现，
+// 主节点进程被杀死后每个哨兵依次判定主观下线和客观下线。哨兵不执行故障转移，主节点地址不变
+func TestSentinelProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts several processes and waits for failure detection")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	masterPort := freePort(t)
+	master := startProcess(t, masterPort, "--port", strconv.Itoa(masterPort), "--loglevel", "warning")
+
+	const numSentinels = 3
+	sentinels := make([]*client.Client, numSentinels)
+	events := make([]*client.PubSub, numSentinels)
+	for i := range sentinels {
+		port := freePort(t)
+		conf := filepath.Join(t.TempDir(), "sentinel.conf")
+		lines := []string{
+			fmt.Sprintf("port %d", port),
+			fmt.Sprintf("sentinel monitor mymaster 127.0.0.1 %d 2", masterPort),
+			"sentinel down-after-milliseconds mymaster 1000",
+		}
+		if err := os.WriteFile(conf, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		p := startProcess(t, port, conf, "--sentinel", "--loglevel", "warning")
+
+		sentinels[i] = client.NewClient(&client.Options{Addr: p.addr})
+		t.Cleanup(func() { sentinels[i].Close() })
+		events[i] = sentinels[i].PSubscribe(ctx, "*")
+		t.Cleanup(func() { events[i].Close() })
+		if _, err := events[i].Receive(ctx); err != nil {
+			t.Fatalf("PSUBSCRIBE on sentinel %d: %v", i, err)
+		}
+	}
+
+	for i, c := range sentinels {
+		waitUntil(t, 20*time.Second, fmt.Sprintf("sentinel %d to discover the others", i), func() bool {
+			others, _ := c.Do(ctx, "sentinel", "sentinels", "mymaster").Val().([]interface{})
+			return len(others) == numSentinels-1
+		})
+	}
+
+	master.kill()
+
+	// 每个哨兵的事件各由一个 goroutine 收集
+	type event struct {
+		sentinel int
+		channel  string
+	}
+	received := make(chan event, 64)
+	for i, ps := range events {
+		go func() {
+			for {
+				m, err := ps.ReceiveMessage(ctx)
+				if err != nil {
+					return
+				}
+				received <- event{i, m.Channel}
+			}
+		}()
+	}
+
+	// 每个哨兵都先发布 +sdown，再在询问其他哨兵之后发布 +odown
+	seen := make([]map[string]bool, numSentinels)
+	for i := range seen {
+		seen[i] = make(map[string]bool)
+	}
+	for odown := 0; odown < numSentinels; {
+		select {
+		case e := <-received:
+			switch e.channel {
+			case "+odown":
+				if !seen[e.sentinel]["+sdown"] {
+					t.Errorf("sentinel %d: +odown before +sdown", e.sentinel)
+				}
+				odown++
+			case "+try-failover", "+switch-master":
+				t.Fatalf("sentinel %d published %s", e.sentinel, e.channel)
+			}
+			seen[e.sentinel][e.channel] = true
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for objective down, events seen: %v", seen)
+		}
+	}
+
+	for i, c := range sentinels {
+		if flags := masterFlags(ctx, c); flags != "master,s_down,o_down" {
+			t.Errorf("sentinel %d: flags %q", i, flags)
+		}
+		addr, _ := c.Do(ctx, "sentinel", "get-master-addr-by-name", "mymaster").Val().([]interface{})
+		if len(addr) != 2 || addr[1] != strconv.Itoa(masterPort) {
+			t.Errorf("sentinel %d: master address %v, expected the original port %d", i, addr, masterPort)
+		}
+	}
+}
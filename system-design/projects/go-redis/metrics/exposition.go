@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContentType 是 Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Label 是指标的一个标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Histogram 是直方图的一次采样
+// Bounds 是各个桶的上界（升序，不含 +Inf），Counts[i] 是不大于 Bounds[i] 的观测数（累计值），
+// Count 是观测总数，即 +Inf 桶的值
+type Histogram struct {
+	Bounds []float64
+	Counts []int64
+	Count  int64
+	Sum    float64
+}
+
+// Writer 按 Prometheus 文本格式（0.0.4）生成指标
+// 同一指标的多个样本（不同标签）需要连续写入，HELP 和 TYPE 只在第一个样本前输出一次
+type Writer struct {
+	b    strings.Builder
+	last string // 上一个写入的指标名
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Counter 写入一个计数器样本
+func (w *Writer) Counter(name, help string, value float64, labels ...Label) {
+	w.header(name, help, "counter")
+	w.sample(name, labels, nil, value)
+}
+
+// Gauge 写入一个仪表样本
+func (w *Writer) Gauge(name, help string, value float64, labels ...Label) {
+	w.header(name, help, "gauge")
+	w.sample(name, labels, nil, value)
+}
+
+// Histogram 写入一个直方图样本，输出 _bucket、_sum 和 _count 三组序列
+func (w *Writer) Histogram(name, help string, h Histogram, labels ...Label) {
+	w.header(name, help, "histogram")
+	for i, bound := range h.Bounds {
+		w.sample(name+"_bucket", labels, &Label{"le", formatFloat(bound)}, float64(h.Counts[i]))
+	}
+	w.sample(name+"_bucket", labels, &Label{"le", "+Inf"}, float64(h.Count))
+	w.sample(name+"_sum", labels, nil, h.Sum)
+	w.sample(name+"_count", labels, nil, float64(h.Count))
+}
+
+// String 返回已写入的全部指标
+func (w *Writer) String() string {
+	return w.b.String()
+}
+
+// WriteTo 把已写入的指标写到 out
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	n, err := io.WriteString(out, w.b.String())
+	return int64(n), err
+}
+
+func (w *Writer) header(name, help, typ string) {
+	if name == w.last {
+		return
+	}
+	w.last = name
+	w.b.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.b.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// sample 写入一行样本，extra 是附加在 labels 之后的标签（直方图的 le）
+func (w *Writer) sample(name string, labels []Label, extra *Label, value float64) {
+	w.b.WriteString(name)
+	if len(labels) > 0 || extra != nil {
+		w.b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			w.writeLabel(l)
+		}
+		if extra != nil {
+			if len(labels) > 0 {
+				w.b.WriteByte(',')
+			}
+			w.writeLabel(*extra)
+		}
+		w.b.WriteByte('}')
+	}
+	w.b.WriteByte(' ')
+	w.b.WriteString(formatFloat(value))
+	w.b.WriteByte('\n')
+}
+
+func (w *Writer) writeLabel(l Label) {
+	w.b.WriteString(l.Name)
+	w.b.WriteString(`="`)
+	w.b.WriteString(escapeLabelValue(l.Value))
+	w.b.WriteByte('"')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
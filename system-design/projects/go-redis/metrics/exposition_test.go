@@ -0,0 +1,35 @@
+package metrics
+
+import "testing"
+
+func TestWriter(t *testing.T) {
+	w := NewWriter()
+	w.Gauge("redis_connected_clients", "Number of client connections", 3)
+	w.Counter("redis_commands_total", "Commands processed", 10, Label{"cmd", "get"})
+	w.Counter("redis_commands_total", "Commands processed", 2, Label{"cmd", `we"ird\`})
+	w.Histogram("redis_command_duration_seconds", "Command latency\nin seconds", Histogram{
+		Bounds: []float64{0.001, 0.01},
+		Counts: []int64{4, 9},
+		Count:  10,
+		Sum:    0.05,
+	}, Label{"cmd", "get"})
+
+	want := `# HELP redis_connected_clients Number of client connections
+# TYPE redis_connected_clients gauge
+redis_connected_clients 3
+# HELP redis_commands_total Commands processed
+# TYPE redis_commands_total counter
+redis_commands_total{cmd="get"} 10
+redis_commands_total{cmd="we\"ird\\"} 2
+# HELP redis_command_duration_seconds Command latency\nin seconds
+# TYPE redis_command_duration_seconds histogram
+redis_command_duration_seconds_bucket{cmd="get",le="0.001"} 4
+redis_command_duration_seconds_bucket{cmd="get",le="0.01"} 9
+redis_command_duration_seconds_bucket{cmd="get",le="+Inf"} 10
+redis_command_duration_seconds_sum{cmd="get"} 0.05
+redis_command_duration_seconds_count{cmd="get"} 10
+`
+	if got := w.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnbalancedQuotes 表示引号没有闭合，或引号结束后没有紧跟空白
+var ErrUnbalancedQuotes = errors.New("unbalanced quotes")
+
+// SplitArgs 把一行输入拆分成参数（内联命令、redis-cli 输入、配置文件），规则与 Redis 的 sdssplitargs 相同：
+// 双引号内支持 \n \r \t \b \a \xHH 等转义，单引号内只支持 \'，
+// 引号结束后必须紧跟空白或行尾
+func SplitArgs(line string) ([]string, error) {
+	var args []string
+	i := 0
+
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var cur strings.Builder
+		inDouble, inSingle := false, false
+
+		for done := false; !done; {
+			if i >= len(line) {
+				if inDouble || inSingle {
+					return nil, ErrUnbalancedQuotes
+				}
+				break
+			}
+			c := line[i]
+
+			switch {
+			case inDouble:
+				if c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]) {
+					cur.WriteByte(hexValue(line[i+2])<<4 | hexValue(line[i+3]))
+					i += 3
+				} else if c == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						cur.WriteByte('\n')
+					case 'r':
+						cur.WriteByte('\r')
+					case 't':
+						cur.WriteByte('\t')
+					case 'b':
+						cur.WriteByte('\b')
+					case 'a':
+						cur.WriteByte('\a')
+					default:
+						cur.WriteByte(line[i])
+					}
+				} else if c == '"' {
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				} else {
+					cur.WriteByte(c)
+				}
+			case inSingle:
+				if c == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					cur.WriteByte('\'')
+					i++
+				} else if c == '\'' {
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				} else {
+					cur.WriteByte(c)
+				}
+			default:
+				switch c {
+				case ' ', '\n', '\r', '\t', 0:
+					done = true
+				case '"':
+					inDouble = true
+				case '\'':
+					inSingle = true
+				default:
+					cur.WriteByte(c)
+				}
+			}
+			i++
+		}
+
+		args = append(args, cur.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
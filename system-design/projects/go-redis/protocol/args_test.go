@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"set foo bar", []string{"set", "foo", "bar"}},
+		{"  set   foo  bar  ", []string{"set", "foo", "bar"}},
+		{`set "hello world" 'it\'s'`, []string{"set", "hello world", "it's"}},
+		{`set k "a\r\n\x41\""`, []string{"set", "k", "a\r\nA\""}},
+		{`set k ""`, []string{"set", "k", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.line)
+		if err != nil {
+			t.Errorf("SplitArgs(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{`set "foo`, `set 'foo`, `set "foo"bar`} {
+		if _, err := SplitArgs(line); err == nil {
+			t.Errorf("SplitArgs(%q): expected error", line)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package protocol
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Decode 从 data 开头解析一个完整的值，返回值和它占用的字节数
+//
+// 与 Parser 不同，Decode 不从 io.Reader 阻塞读取，而是解析调用方已经收到的数据，
+// 供事件循环等自己管理读缓冲区的场景使用。数据不完整时返回 n == 0 且 err == nil，
+// 调用方应在收到更多数据后用整个缓冲区重试。判断是否完整时只扫描各层的长度，
+// 不分配内存，因此大的值分多次到达时不会被反复解析。解析规则和错误与 Parser 相同
+func Decode(data []byte, limits *Limits) (*Value, int, error) {
+	if limits == nil {
+		limits = defaultLimits
+	}
+
+	d := decoder{data: data, limits: limits}
+	if err := d.skip(); err != nil || d.incomplete {
+		return nil, 0, err
+	}
+	n := d.pos
+
+	d = decoder{data: data[:n], limits: limits}
+	v, err := d.value()
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, n, nil
+}
+
+type decoder struct {
+	data       []byte
+	pos        int
+	limits     *Limits
+	depth      int
+	incomplete bool
+}
+
+// line 读取一行，不包括结尾的 \r\n 或 \n
+func (d *decoder) line() ([]byte, bool, error) {
+	rest := d.data[d.pos:]
+	i := bytes.IndexByte(rest, '\n')
+	if i < 0 {
+		if len(rest) > maxLineLen+1 {
+			return nil, false, protocolError("too big line")
+		}
+		d.incomplete = true
+		return nil, false, nil
+	}
+
+	line := rest[:i]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	if len(line) > maxLineLen {
+		return nil, false, protocolError("too big line")
+	}
+	d.pos += i + 1
+	return line, true, nil
+}
+
+// header 读取类型字节和之后的一行
+func (d *decoder) header() (byte, []byte, bool, error) {
+	if d.pos >= len(d.data) {
+		d.incomplete = true
+		return 0, nil, false, nil
+	}
+	t := d.data[d.pos]
+	d.pos++
+
+	switch t {
+	case '+', '-', ':', '$', '*':
+	default:
+		return 0, nil, false, protocolError("unknown RESP type: " + strconv.Quote(string(t)))
+	}
+
+	line, ok, err := d.line()
+	return t, line, ok, err
+}
+
+func (d *decoder) bulkLen(line []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(line), 10, 64)
+	if err != nil || n < -1 || n > d.limits.MaxBulkLen() {
+		return 0, protocolError("invalid bulk length")
+	}
+	return n, nil
+}
+
+func (d *decoder) multiBulkLen(line []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(line), 10, 64)
+	if err != nil || n < -1 || n > d.limits.MaxMultiBulkLen() {
+		return 0, protocolError("invalid multibulk length")
+	}
+	return n, nil
+}
+
+// skip 跳过一个完整的值，只校验格式，不构造 Value
+func (d *decoder) skip() error {
+	t, line, ok, err := d.header()
+	if !ok {
+		return err
+	}
+
+	switch t {
+	case ':':
+		_, err := parseIntLine(line)
+		return err
+	case '$':
+		n, err := d.bulkLen(line)
+		if err != nil || n < 0 {
+			return err
+		}
+		// 内容之后还有 \r\n
+		if int64(len(d.data)-d.pos) < n+2 {
+			d.incomplete = true
+			return nil
+		}
+		d.pos += int(n) + 2
+		return nil
+	case '*':
+		n, err := d.multiBulkLen(line)
+		if err != nil || n <= 0 {
+			return err
+		}
+		if d.depth >= maxNestingDepth {
+			return protocolError("too deeply nested array")
+		}
+		d.depth++
+		defer func() { d.depth-- }()
+
+		for range n {
+			if err := d.skip(); err != nil || d.incomplete {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// value 解析一个值，调用前已经用 skip 确认数据完整
+func (d *decoder) value() (*Value, error) {
+	t, line, _, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case '+':
+		return &Value{Type: StringType, Str: string(line)}, nil
+	case '-':
+		return &Value{Type: ErrorType, Str: string(line)}, nil
+	case ':':
+		n, err := parseIntLine(line)
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Type: IntType, Int: n}, nil
+	case '$':
+		n, err := d.bulkLen(line)
+		if err != nil {
+			return nil, err
+		}
+		if n == -1 {
+			return &Value{Type: BulkStringType, IsNull: true}, nil
+		}
+		s := string(d.data[d.pos : d.pos+int(n)])
+		d.pos += int(n) + 2
+		return &Value{Type: BulkStringType, Str: s}, nil
+	default: // '*'
+		n, err := d.multiBulkLen(line)
+		if err != nil {
+			return nil, err
+		}
+		if n == -1 {
+			return &Value{Type: ArrayType, IsNull: true}, nil
+		}
+
+		array := make([]Value, n)
+		for i := range array {
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			array[i] = *v
+		}
+		return &Value{Type: ArrayType, Array: array}, nil
+	}
+}
+
+// parseIntLine 解析整数行，规则与 Parser.parseInt 相同
+func parseIntLine(line []byte) (int64, error) {
+	parts := strings.Fields(string(line))
+	if len(parts) != 1 {
+		return 0, protocolError("multi int please use array")
+	}
+	n, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, protocolError("invalid int value: " + parts[0])
+	}
+	return n, nil
+}
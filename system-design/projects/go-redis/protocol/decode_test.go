@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	cmd := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"
+	data := []byte(cmd + "*1\r\n$4\r\nPING\r\n")
+
+	v, n, err := Decode(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(cmd) {
+		t.Errorf("consumed %d bytes, want %d", n, len(cmd))
+	}
+	if len(v.Array) != 3 || v.Array[2].Str != "value" {
+		t.Errorf("Decode = %+v", v)
+	}
+
+	v, n, err = Decode(data[n:], nil)
+	if err != nil || n != len("*1\r\n$4\r\nPING\r\n") || v.Array[0].Str != "PING" {
+		t.Errorf("second value = %+v, %d, %v", v, n, err)
+	}
+}
+
+func TestDecodeIncomplete(t *testing.T) {
+	full := "*2\r\n$3\r\nfoo\r\n*2\r\n:1\r\n$-1\r\n"
+	for i := 0; i < len(full); i++ {
+		v, n, err := Decode([]byte(full[:i]), nil)
+		if v != nil || n != 0 || err != nil {
+			t.Fatalf("prefix %q: got %+v, %d, %v; want incomplete", full[:i], v, n, err)
+		}
+	}
+
+	if _, n, err := Decode([]byte(full), nil); err != nil || n != len(full) {
+		t.Errorf("full input: n=%d err=%v", n, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	limits := NewLimits()
+	limits.SetMaxBulkLen(10)
+	limits.SetMaxMultiBulkLen(2)
+
+	inputs := []string{
+		"?\r\n",
+		"$11\r\n",
+		"*3\r\n",
+		"$-2\r\n",
+		":12 34\r\n",
+		"+" + strings.Repeat("a", maxLineLen+2),
+		strings.Repeat("*1\r\n", maxNestingDepth+1),
+	}
+	for _, input := range inputs {
+		if _, _, err := Decode([]byte(input), limits); !IsProtocolError(err) {
+			t.Errorf("Decode(%.20q): got %v, want protocol error", input, err)
+		}
+	}
+}
+
+// FuzzDecode 检查 Decode 与 Parser 对同一输入的解析结果一致
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte("*2\r\n$3\r\nfoo\r\n:1\r\n"))
+	f.Add([]byte("+OK\r\n-ERR x\r\n"))
+	f.Add([]byte("$5\r\nhe"))
+	f.Add([]byte("*-1\r\n*0\r\n$-1\r\n"))
+	f.Add([]byte(":1\n$1\nab\n"))
+
+	limits := NewLimits()
+	limits.SetMaxBulkLen(1024)
+	limits.SetMaxMultiBulkLen(64)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, n, err := Decode(data, limits)
+		if err != nil || n == 0 {
+			return
+		}
+		if n > len(data) {
+			t.Fatalf("consumed %d of %d bytes", n, len(data))
+		}
+
+		p := NewParser(strings.NewReader(string(data[:n])))
+		p.SetLimits(limits)
+		want, perr := p.Parse()
+		if perr != nil {
+			t.Fatalf("Decode accepted %q but Parser failed: %v", data[:n], perr)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Fatalf("Decode(%q) = %+v, Parser = %+v", data[:n], v, want)
+		}
+	})
+}
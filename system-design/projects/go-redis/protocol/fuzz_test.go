@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// FuzzParse 检查任意输入都不会让解析器 panic 或越过限制分配内存，
+// 并且解析成功的值经过 Serialize 之后能解析回同样的值
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"+OK\r\n",
+		"-ERR unknown command\r\n",
+		":42\r\n",
+		":-1\r\n",
+		"$5\r\nhello\r\n",
+		"$0\r\n\r\n",
+		"$-1\r\n",
+		"*-1\r\n",
+		"*0\r\n",
+		"*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n",
+		"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\na\r\nb\r\n",
+		"*2\r\n*1\r\n:1\r\n$-1\r\n",
+		"*2147483647\r\n",
+		"$999999999\r\n",
+		"$3\r\nfooXY",
+		"*1\r\n*1\r\n*1\r\n",
+		"?\r\n",
+	}
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+
+	limits := NewLimits()
+	limits.SetMaxBulkLen(1024)
+	limits.SetMaxMultiBulkLen(64)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := NewParser(strings.NewReader(string(data)))
+		p.SetLimits(limits)
+
+		for {
+			v, err := p.Parse()
+			if err != nil {
+				return
+			}
+			checkRoundTrip(t, v)
+		}
+	})
+}
+
+// FuzzSerializeRoundTrip 从任意字符串构造各种类型的值，检查序列化再解析后保持不变
+func FuzzSerializeRoundTrip(f *testing.F) {
+	f.Add("hello", int64(1), "OK")
+	f.Add("a\r\nb\x00", int64(-9223372036854775808), "")
+	f.Add("", int64(0), "ERR boom")
+
+	f.Fuzz(func(t *testing.T, bulk string, n int64, line string) {
+		// 简单字符串和错误不能包含换行
+		line = strings.NewReplacer("\r", "", "\n", "").Replace(line)
+
+		values := []*Value{
+			BulkString(bulk),
+			NullBulkString(),
+			Integer(n),
+			SimpleString(line),
+			Error(line),
+			EmptyArray(),
+			NullArray(),
+			Array([]Value{*BulkString(bulk), *Integer(n), *Array([]Value{*SimpleString(line)})}),
+		}
+		for _, v := range values {
+			checkRoundTrip(t, v)
+		}
+	})
+}
+
+func checkRoundTrip(t *testing.T, v *Value) {
+	t.Helper()
+
+	encoded := Serialize(v)
+	if n := EncodedLen(v); n != len(encoded) {
+		t.Fatalf("EncodedLen(%+v) = %d, encoded %d bytes", v, n, len(encoded))
+	}
+	got, err := NewParser(strings.NewReader(encoded)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(Serialize(%+v)) = %v, encoded %q", v, err, encoded)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Fatalf("round trip mismatch:\n got  %+v\n want %+v\n encoded %q", got, v, encoded)
+	}
+}
@@ -0,0 +1,84 @@
+package protocol
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// 解析器的大小限制
+//
+// 声明的长度只用于校验，不会按声明的长度一次性分配内存：
+// 大的批量字符串和数组随着数据实际到达逐步增长，客户端发送 *2147483647 或 $999999999
+// 而不跟上数据时，服务器只为已经收到的数据付出内存
+
+const (
+	// DefaultMaxBulkLen 是批量字符串的默认最大长度，与 Redis 的 proto-max-bulk-len 默认值相同
+	DefaultMaxBulkLen = 512 * 1024 * 1024
+	// DefaultMaxMultiBulkLen 是数组的默认最大元素个数，与 Redis 一样为 INT_MAX
+	DefaultMaxMultiBulkLen = math.MaxInt32
+
+	// maxLineLen 是类型字节之后一行（简单字符串、错误、整数、长度）的最大长度，
+	// 与 Redis 的 PROTO_INLINE_MAX_SIZE 相同
+	maxLineLen = 64 * 1024
+	// maxPrealloc 是按声明长度预先分配的上限，超过的部分随数据到达再增长
+	maxPrealloc = 64 * 1024
+	// maxPreallocElems 是按声明长度预先分配的数组元素个数上限
+	maxPreallocElems = 1024
+	// maxNestingDepth 是数组的最大嵌套层数，避免恶意输入造成过深的递归
+	maxNestingDepth = 1000
+)
+
+// ProtocolError 表示输入不符合 RESP 协议，此后连接上的数据已经无法可靠地切分成命令，
+// 服务器回复错误后应当关闭连接
+type ProtocolError struct {
+	msg string
+}
+
+func (e *ProtocolError) Error() string {
+	return "Protocol error: " + e.msg
+}
+
+func protocolError(msg string) error {
+	return &ProtocolError{msg: msg}
+}
+
+// IsProtocolError 判断 err 是否是协议错误
+func IsProtocolError(err error) bool {
+	var pe *ProtocolError
+	return errors.As(err, &pe)
+}
+
+// Limits 限制解析器接受的批量字符串长度和数组元素个数，
+// 可以在多个 Parser 之间共享，并在运行时修改（CONFIG SET）
+type Limits struct {
+	maxBulkLen      atomic.Int64
+	maxMultiBulkLen atomic.Int64
+}
+
+// NewLimits 创建默认的限制
+func NewLimits() *Limits {
+	l := &Limits{}
+	l.maxBulkLen.Store(DefaultMaxBulkLen)
+	l.maxMultiBulkLen.Store(DefaultMaxMultiBulkLen)
+	return l
+}
+
+func (l *Limits) MaxBulkLen() int64 {
+	return l.maxBulkLen.Load()
+}
+
+func (l *Limits) SetMaxBulkLen(n int64) {
+	l.maxBulkLen.Store(n)
+}
+
+func (l *Limits) MaxMultiBulkLen() int64 {
+	return l.maxMultiBulkLen.Load()
+}
+
+func (l *Limits) SetMaxMultiBulkLen(n int64) {
+	l.maxMultiBulkLen.Store(n)
+}
+
+// defaultLimits 是没有调用 SetLimits 的解析器使用的限制
+var defaultLimits = NewLimits()
@@ -0,0 +1,111 @@
+package protocol
+
+import (
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func parseString(input string, limits *Limits) (*Value, error) {
+	p := NewParser(strings.NewReader(input))
+	if limits != nil {
+		p.SetLimits(limits)
+	}
+	return p.Parse()
+}
+
+func TestParseProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"negative bulk length", "$-2\r\n", "invalid bulk length"},
+		{"non-numeric bulk length", "$abc\r\n", "invalid bulk length"},
+		{"negative multibulk length", "*-5\r\n", "invalid multibulk length"},
+		{"non-numeric multibulk length", "*x\r\n", "invalid multibulk length"},
+		{"line too long", "+" + strings.Repeat("a", maxLineLen+1) + "\r\n", "too big line"},
+		{"unknown type", "?\r\n", "unknown RESP type"},
+		{"too deeply nested", strings.Repeat("*1\r\n", maxNestingDepth+1) + ":1\r\n", "too deeply nested array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseString(tt.input, nil)
+			if !IsProtocolError(err) {
+				t.Fatalf("expected protocol error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	// 连接中途断开不是协议错误
+	if _, err := parseString("$5\r\nab", nil); err == nil || IsProtocolError(err) {
+		t.Errorf("truncated bulk: got %v, want an I/O error", err)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits := NewLimits()
+	limits.SetMaxBulkLen(4)
+	limits.SetMaxMultiBulkLen(2)
+
+	if _, err := parseString("$4\r\nabcd\r\n", limits); err != nil {
+		t.Errorf("bulk at limit: %v", err)
+	}
+	if _, err := parseString("$5\r\nabcde\r\n", limits); !IsProtocolError(err) {
+		t.Errorf("bulk over limit: got %v", err)
+	}
+	if _, err := parseString("*2\r\n:1\r\n:2\r\n", limits); err != nil {
+		t.Errorf("multibulk at limit: %v", err)
+	}
+	if _, err := parseString("*3\r\n:1\r\n:2\r\n:3\r\n", limits); !IsProtocolError(err) {
+		t.Errorf("multibulk over limit: got %v", err)
+	}
+
+	// 默认限制与 Redis 相同
+	if _, err := parseString("$536870913\r\n", nil); !IsProtocolError(err) {
+		t.Errorf("bulk over default limit: got %v", err)
+	}
+	if _, err := parseString("*2147483648\r\n", nil); !IsProtocolError(err) {
+		t.Errorf("multibulk over default limit: got %v", err)
+	}
+}
+
+// 声明了巨大长度但不发送数据的请求不应该按声明的长度分配内存
+func TestParseBoundedAllocation(t *testing.T) {
+	inputs := []string{
+		"*2147483647\r\n:1\r\n",
+		"$536870912\r\n" + strings.Repeat("x", 1000),
+		"*2\r\n$536870912\r\nabc",
+	}
+
+	for _, input := range inputs {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := parseString(input, nil)
+		runtime.ReadMemStats(&after)
+
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			t.Errorf("%.20q: got %v, want EOF", input, err)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("%.20q: allocated %d bytes", input, allocated)
+		}
+	}
+}
+
+func TestParseLargeBulk(t *testing.T) {
+	data := strings.Repeat("0123456789", maxPrealloc/5)
+	v, err := parseString("$"+strconv.Itoa(len(data))+"\r\n"+data+"\r\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Str != data {
+		t.Errorf("large bulk mismatch: got %d bytes", len(v.Str))
+	}
+}
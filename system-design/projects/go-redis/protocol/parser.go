@@ -2,15 +2,16 @@ package protocol
 
 import (
 	"bufio"
-	"errors"
 	"go-redis/logger"
 	"io"
 	"strconv"
-	"strings"
+	"unsafe"
 )
 
 type Parser struct {
 	reader *bufio.Reader
+	limits *Limits
+	depth  int // 当前数组的嵌套层数
 }
 
 func NewParser(reader io.Reader) *Parser {
@@ -21,9 +22,16 @@ func NewParser(reader io.Reader) *Parser {
 	}
 	return &Parser{
 		reader: bufReader,
+		limits: defaultLimits,
 	}
 }
 
+// SetLimits 设置解析器的大小限制，多个解析器可以共享同一个 Limits
+func (p *Parser) SetLimits(limits *Limits) {
+	p.limits = limits
+}
+
+// getFullLine 读取一行，超过 maxLineLen 时返回协议错误
 func getFullLine(reader *bufio.Reader) ([]byte, error) {
 	var fullLine []byte
 	for {
@@ -31,6 +39,9 @@ func getFullLine(reader *bufio.Reader) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		if len(fullLine)+len(line) > maxLineLen {
+			return nil, protocolError("too big line")
+		}
 		fullLine = append(fullLine, line...)
 		if !isPrefix {
 			break
@@ -81,15 +92,9 @@ func (p *Parser) parseInt() (*Value, error) {
 
 	logger.Debug("parseInt fullLine: ", string(fullLine))
 
-	strVal := string(fullLine)
-	parts := strings.Fields(strVal)
-	if len(parts) != 1 {
-		return nil, errors.New("multi int please use array")
-	}
-
-	intVal, err := strconv.ParseInt(parts[0], 10, 64)
+	intVal, err := parseIntLine(fullLine)
 	if err != nil {
-		return nil, errors.New("invalid int value: " + parts[0])
+		return nil, err
 	}
 
 	value := &Value{
@@ -107,9 +112,9 @@ func (p *Parser) parseBulkString() (*Value, error) {
 		return nil, err
 	}
 
-	length, err := strconv.Atoi(string(lenByte))
-	if err != nil {
-		return nil, err
+	length, err := strconv.ParseInt(string(lenByte), 10, 64)
+	if err != nil || length < -1 || length > p.limits.MaxBulkLen() {
+		return nil, protocolError("invalid bulk length")
 	}
 
 	if length == -1 {
@@ -121,13 +126,12 @@ func (p *Parser) parseBulkString() (*Value, error) {
 
 	logger.Debug("length: ", length)
 
-	buf := make([]byte, length)
-	_, err = io.ReadFull(p.reader, buf)
+	buf, err := p.readBulk(length)
 	if err != nil {
 		return nil, err
 	}
 
-	// 读取并丢弃 \r\n
+	// 读取并丢弃 \r\n，与 Redis 一样不检查这两个字节的内容
 	_, err = p.reader.ReadByte() // \r
 	if err != nil {
 		return nil, err
@@ -137,15 +141,48 @@ func (p *Parser) parseBulkString() (*Value, error) {
 		return nil, err
 	}
 
+	// buf 是为这个值单独分配的，之后不会再被修改，直接作为字符串的内容，省去一次复制
 	value := &Value{
 		Type:   BulkStringType,
-		Str:    string(buf),
+		Str:    unsafe.String(unsafe.SliceData(buf), len(buf)),
 		IsNull: false,
 	}
 
 	return value, nil
 }
 
+// readBulk 读取 length 个字节，超过 maxPrealloc 时随数据到达逐步增长，
+// 而不是按客户端声明的长度一次性分配
+func (p *Parser) readBulk(length int64) ([]byte, error) {
+	if length <= maxPrealloc {
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(p.reader, buf); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+
+	// 容量按两倍增长，最后一次增长正好到 length
+	buf := make([]byte, 0, maxPrealloc)
+	for int64(len(buf)) < length {
+		if len(buf) == cap(buf) {
+			grown := make([]byte, len(buf), min(int64(cap(buf))*2, length))
+			copy(grown, buf)
+			buf = grown
+		}
+		end := min(int64(cap(buf)), length)
+		n, err := io.ReadFull(p.reader, buf[len(buf):end])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			if err == io.EOF && len(buf) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
 func (p *Parser) parseArray() (*Value, error) {
 	// 解析长度
 	lenByte, err := getFullLine(p.reader)
@@ -153,9 +190,9 @@ func (p *Parser) parseArray() (*Value, error) {
 		return nil, err
 	}
 
-	length, err := strconv.Atoi(string(lenByte))
-	if err != nil {
-		return nil, err
+	length, err := strconv.ParseInt(string(lenByte), 10, 64)
+	if err != nil || length < -1 || length > p.limits.MaxMultiBulkLen() {
+		return nil, protocolError("invalid multibulk length")
 	}
 
 	if length == -1 {
@@ -165,13 +202,20 @@ func (p *Parser) parseArray() (*Value, error) {
 		return &Value{Type: ArrayType, Array: []Value{}}, nil
 	}
 
-	array := make([]Value, length)
-	for i := range length {
+	if p.depth >= maxNestingDepth {
+		return nil, protocolError("too deeply nested array")
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	// 预分配不超过 maxPreallocElems 个元素，更长的数组随元素到达逐步增长
+	array := make([]Value, 0, min(length, maxPreallocElems))
+	for range length {
 		value, err := p.Parse()
 		if err != nil {
 			return nil, err
 		}
-		array[i] = *value
+		array = append(array, *value)
 	}
 
 	return &Value{Type: ArrayType, Array: array}, nil
@@ -201,7 +245,7 @@ func (p *Parser) Parse() (*Value, error) {
 	case '*':
 		value, err = p.parseArray()
 	default:
-		value, err = nil, errors.New("unknown RESP type: "+string(b))
+		value, err = nil, protocolError("unknown RESP type: "+strconv.Quote(string(b)))
 	}
 
 	return value, err
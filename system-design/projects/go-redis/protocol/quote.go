@@ -0,0 +1,42 @@
+package protocol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Quote 把字符串转换为带双引号的可读形式，规则与 Redis 的 sdscatrepr 相同
+// 用于 MONITOR 输出等需要原样展示二进制参数的场景，例如 "a\r\n" -> "\"a\\r\\n\""
+func Quote(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		default:
+			if c >= ' ' && c <= '~' {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			}
+		}
+	}
+
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package protocol
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"keys", `"keys"`},
+		{"", `""`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"a\\b", `"a\\b"`},
+		{"line\r\n", `"line\r\n"`},
+		{"\x00\xff", `"\x00\xff"`},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.expected {
+			t.Errorf("Quote(%q) = %s, expected %s", tt.in, got, tt.expected)
+		}
+	}
+}
@@ -1,55 +1,133 @@
 package protocol
 
-import "fmt"
+import (
+	"math"
+	"strconv"
+	"sync"
+)
 
-func serializeSimpleString(v *Value) string {
-	return fmt.Sprintf("+%s\r\n", v.Str)
-}
-
-func serializeBulkString(v *Value) string {
-	if v.IsNull {
-		return "$-1\r\n"
-	}
+// 编码直接追加字节，不经过 fmt 和字符串拼接
 
-	return fmt.Sprintf("$%d\r\n%s\r\n", len(v.Str), v.Str)
-}
+// maxPooledBuffer 是放回缓冲池的缓冲区的最大容量，更大的缓冲区直接丢弃，避免池中常驻大块内存
+const maxPooledBuffer = 256 * 1024
 
-func serializeError(v *Value) string {
-	return fmt.Sprintf("-%s\r\n", v.Str)
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 512)
+		return &b
+	},
 }
 
-func serializeInt(v *Value) string {
-	return fmt.Sprintf(":%d\r\n", v.Int)
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
 }
 
-func serializeArray(v *Value) string {
-	if v.IsNull {
-		return "*-1\r\n"
+func putBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBuffer {
+		return
 	}
+	*b = (*b)[:0]
+	bufferPool.Put(b)
+}
 
-	length := len(v.Array)
-	res := fmt.Sprintf("*%d\r\n", length)
-
-	for _, array := range v.Array {
-		res += Serialize(&array)
+// AppendValue 把 v 按 RESP 编码追加到 dst 并返回追加后的切片
+func AppendValue(dst []byte, v *Value) []byte {
+	switch v.Type {
+	case StringType:
+		dst = append(dst, '+')
+		dst = append(dst, v.Str...)
+		return append(dst, '\r', '\n')
+	case BulkStringType:
+		if v.IsNull {
+			return append(dst, "$-1\r\n"...)
+		}
+		dst = appendHeader(dst, '$', len(v.Str))
+		dst = append(dst, v.Str...)
+		return append(dst, '\r', '\n')
+	case ErrorType:
+		dst = append(dst, '-')
+		dst = append(dst, v.Str...)
+		return append(dst, '\r', '\n')
+	case IntType:
+		dst = append(dst, ':')
+		dst = strconv.AppendInt(dst, v.Int, 10)
+		return append(dst, '\r', '\n')
+	case ArrayType:
+		if v.IsNull {
+			return append(dst, "*-1\r\n"...)
+		}
+		dst = appendHeader(dst, '*', len(v.Array))
+		for i := range v.Array {
+			dst = AppendValue(dst, &v.Array[i])
+		}
+		return dst
+	default:
+		return append(dst, "-ERR unknown command\r\n"...)
 	}
+}
 
-	return res
+// appendHeader 追加 $<n>\r\n 或 *<n>\r\n
+func appendHeader(dst []byte, prefix byte, n int) []byte {
+	dst = append(dst, prefix)
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	return append(dst, '\r', '\n')
 }
 
+// Serialize 把 v 编码为字符串，编码过程使用池中的缓冲区，只为结果分配一次内存
+// 写到连接上时应使用 Writer，避免再转换回 []byte
 func Serialize(v *Value) string {
+	b := getBuffer()
+	*b = AppendValue(*b, v)
+	s := string(*b)
+	putBuffer(b)
+	return s
+}
+
+// EncodedLen 返回 v 编码后的字节数，与 len(AppendValue(nil, v)) 相同但不分配内存
+// 用于统计客户端输出缓冲区的大小
+func EncodedLen(v *Value) int {
 	switch v.Type {
-	case StringType:
-		return serializeSimpleString(v)
+	case StringType, ErrorType:
+		return len(v.Str) + 3
 	case BulkStringType:
-		return serializeBulkString(v)
-	case ErrorType:
-		return serializeError(v)
+		if v.IsNull {
+			return 5
+		}
+		return headerLen(len(v.Str)) + len(v.Str) + 2
 	case IntType:
-		return serializeInt(v)
+		return intLen(v.Int) + 3
 	case ArrayType:
-		return serializeArray(v)
+		if v.IsNull {
+			return 5
+		}
+		n := headerLen(len(v.Array))
+		for i := range v.Array {
+			n += EncodedLen(&v.Array[i])
+		}
+		return n
 	default:
-		return "-ERR unknown command\r\n"
+		return len("-ERR unknown command\r\n")
+	}
+}
+
+// headerLen 返回 $<n>\r\n 的长度
+func headerLen(n int) int {
+	return intLen(int64(n)) + 3
+}
+
+// intLen 返回 n 的十进制表示的长度
+func intLen(n int64) int {
+	l := 1
+	if n < 0 {
+		l++
+		if n == math.MinInt64 {
+			return 20
+		}
+		n = -n
+	}
+	for n >= 10 {
+		n /= 10
+		l++
 	}
+	return l
 }
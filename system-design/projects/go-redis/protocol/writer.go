@@ -0,0 +1,127 @@
+package protocol
+
+import (
+	"io"
+	"net"
+	"unsafe"
+)
+
+const (
+	// largeBulkThreshold 是不复制进缓冲区的批量字符串的最小长度，
+	// 这样的内容在 Flush 时与缓冲区一起用 writev 直接发送
+	largeBulkThreshold = 16 * 1024
+	// flushThreshold 是缓冲的数据量上限，超过时 WriteValue 自动写出
+	flushThreshold = 64 * 1024
+)
+
+// splice 表示在缓冲区的 at 位置插入的一段不经复制的数据
+type splice struct {
+	at   int
+	data []byte
+}
+
+// Writer 把回复按 RESP 编码写到 w
+//
+// 编码直接追加到缓冲池中的缓冲区，只在有待写出的数据时占用缓冲区，Flush 后归还，
+// 大量空闲连接不会各自常驻一块缓冲区。大的批量字符串不复制，直接引用原内容写出。
+// Writer 不是并发安全的
+type Writer struct {
+	w       io.Writer
+	buf     *[]byte // 从缓冲池取得，没有待写出的数据时为 nil
+	splices []splice
+	pending int   // 待写出的字节数，包括 splices 中的数据
+	written int64 // 累计写到 w 的字节数
+	vec     net.Buffers
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Buffered 返回还没有写出的字节数
+func (w *Writer) Buffered() int {
+	return w.pending
+}
+
+// Written 返回累计写到底层 io.Writer 的字节数
+func (w *Writer) Written() int64 {
+	return w.written
+}
+
+// WriteValue 编码一条回复，缓冲的数据较多时自动写出，否则需要调用 Flush
+func (w *Writer) WriteValue(v *Value) error {
+	if w.buf == nil {
+		w.buf = getBuffer()
+	}
+
+	before := len(*w.buf)
+	w.appendValue(v)
+	w.pending += len(*w.buf) - before
+
+	if w.pending >= flushThreshold {
+		return w.Flush()
+	}
+	return nil
+}
+
+func (w *Writer) appendValue(v *Value) {
+	switch {
+	case v.Type == BulkStringType && !v.IsNull && len(v.Str) >= largeBulkThreshold:
+		// 只读引用字符串的内容，writev 不会修改它
+		data := unsafe.Slice(unsafe.StringData(v.Str), len(v.Str))
+		*w.buf = appendHeader(*w.buf, '$', len(data))
+		w.splices = append(w.splices, splice{at: len(*w.buf), data: data})
+		w.pending += len(data)
+		*w.buf = append(*w.buf, '\r', '\n')
+	case v.Type == ArrayType && !v.IsNull:
+		*w.buf = appendHeader(*w.buf, '*', len(v.Array))
+		for i := range v.Array {
+			w.appendValue(&v.Array[i])
+		}
+	default:
+		*w.buf = AppendValue(*w.buf, v)
+	}
+}
+
+// Flush 写出所有缓冲的数据并归还缓冲区
+func (w *Writer) Flush() error {
+	if w.buf == nil {
+		return nil
+	}
+
+	b := *w.buf
+	var n int64
+	var err error
+	if len(w.splices) == 0 {
+		var m int
+		m, err = w.w.Write(b)
+		n = int64(m)
+	} else {
+		prev := 0
+		for _, s := range w.splices {
+			if s.at > prev {
+				w.vec = append(w.vec, b[prev:s.at])
+			}
+			w.vec = append(w.vec, s.data)
+			prev = s.at
+		}
+		if prev < len(b) {
+			w.vec = append(w.vec, b[prev:])
+		}
+
+		// WriteTo 会消耗切片本身，用副本写出，保留 w.vec 的底层数组复用
+		vec := w.vec
+		n, err = vec.WriteTo(w.w)
+
+		clear(w.vec)
+		w.vec = w.vec[:0]
+		clear(w.splices)
+		w.splices = w.splices[:0]
+	}
+
+	w.written += n
+	putBuffer(w.buf)
+	w.buf = nil
+	w.pending = 0
+	return err
+}
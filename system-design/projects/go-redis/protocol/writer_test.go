@@ -0,0 +1,221 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+)
+
+func writerTestValues() []*Value {
+	large := strings.Repeat("x", largeBulkThreshold+1)
+	return []*Value{
+		SimpleString("OK"),
+		Error("ERR boom"),
+		Integer(-42),
+		Integer(math.MinInt64),
+		Integer(1234567890),
+		BulkString("hello"),
+		BulkString(""),
+		BulkString("a\r\n\x00b"),
+		NullBulkString(),
+		NullArray(),
+		EmptyArray(),
+		BulkString(large),
+		Array([]Value{*BulkString("a"), *BulkString(large), *Integer(1), *Array([]Value{*BulkString(large)})}),
+	}
+}
+
+func TestWriterMatchesAppendValue(t *testing.T) {
+	for _, v := range writerTestValues() {
+		var out bytes.Buffer
+		w := NewWriter(&out)
+		if err := w.WriteValue(v); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := AppendValue(nil, v)
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("Writer output for %.40q differs from AppendValue", want)
+		}
+		if Serialize(v) != string(want) {
+			t.Errorf("Serialize output for %.40q differs from AppendValue", want)
+		}
+		if n := EncodedLen(v); n != len(want) {
+			t.Errorf("EncodedLen(%.40q) = %d, want %d", want, n, len(want))
+		}
+		if n := w.Written(); n != int64(len(want)) {
+			t.Errorf("Written() = %d after writing %.40q, want %d", n, want, len(want))
+		}
+	}
+}
+
+func TestWriterBuffersUntilFlush(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+
+	w.WriteValue(SimpleString("OK"))
+	w.WriteValue(Integer(1))
+	if out.Len() != 0 || w.Buffered() != len("+OK\r\n:1\r\n") {
+		t.Fatalf("written=%d buffered=%d before Flush", out.Len(), w.Buffered())
+	}
+
+	w.Flush()
+	if out.String() != "+OK\r\n:1\r\n" || w.Buffered() != 0 {
+		t.Errorf("after Flush: %q, buffered=%d", out.String(), w.Buffered())
+	}
+
+	// 超过阈值时自动写出
+	big := BulkString(strings.Repeat("y", flushThreshold))
+	if err := w.WriteValue(big); err != nil {
+		t.Fatal(err)
+	}
+	if w.Buffered() != 0 || out.Len() != len("+OK\r\n:1\r\n")+len(AppendValue(nil, big)) {
+		t.Errorf("large value not flushed automatically: buffered=%d", w.Buffered())
+	}
+}
+
+// 通过 TCP 连接写出时，大的批量字符串经由 writev 发送，内容不变
+func TestWriterOverTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	v := Array([]Value{*BulkString(strings.Repeat("a", 100000)), *BulkString("b"), *BulkString(strings.Repeat("\x00\x01", 30000))})
+
+	go func() {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		w := NewWriter(conn)
+		w.WriteValue(v)
+		w.Flush()
+	}()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, AppendValue(nil, v)) {
+		t.Errorf("received %d bytes, want %d", len(got), len(AppendValue(nil, v)))
+	}
+}
+
+func TestAppendValueDoesNotAllocate(t *testing.T) {
+	v := Array([]Value{*BulkString("SET"), *BulkString("key"), *Integer(12345), *BulkString("value")})
+	buf := make([]byte, 0, 1024)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = AppendValue(buf[:0], v)
+	})
+	if allocs != 0 {
+		t.Errorf("AppendValue allocated %v times", allocs)
+	}
+
+	w := NewWriter(io.Discard)
+	allocs = testing.AllocsPerRun(100, func() {
+		w.WriteValue(v)
+		w.Flush()
+	})
+	if allocs != 0 {
+		t.Errorf("Writer allocated %v times", allocs)
+	}
+}
+
+// serializeConcat 是原来基于 fmt 和字符串拼接的实现，作为基准测试的对照
+func serializeConcat(v *Value) string {
+	switch v.Type {
+	case StringType:
+		return fmt.Sprintf("+%s\r\n", v.Str)
+	case BulkStringType:
+		if v.IsNull {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v.Str), v.Str)
+	case ErrorType:
+		return fmt.Sprintf("-%s\r\n", v.Str)
+	case IntType:
+		return fmt.Sprintf(":%d\r\n", v.Int)
+	case ArrayType:
+		if v.IsNull {
+			return "*-1\r\n"
+		}
+		res := fmt.Sprintf("*%d\r\n", len(v.Array))
+		for _, e := range v.Array {
+			res += serializeConcat(&e)
+		}
+		return res
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+var benchmarkValues = []struct {
+	name string
+	v    *Value
+}{
+	{"Command", Array([]Value{*BulkString("SET"), *BulkString("key"), *BulkString("value")})},
+	{"Array100", Array(func() []Value {
+		values := make([]Value, 100)
+		for i := range values {
+			values[i] = *BulkString(fmt.Sprintf("member:%d", i))
+		}
+		return values
+	}())},
+	{"Bulk1MB", BulkString(strings.Repeat("x", 1<<20))},
+}
+
+// 对比原实现（写到连接前还要再转换一次 []byte）与 Writer 写出一条回复的开销
+func BenchmarkReply(b *testing.B) {
+	for _, bv := range benchmarkValues {
+		b.Run(bv.name+"/Concat", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				io.Discard.Write([]byte(serializeConcat(bv.v)))
+			}
+		})
+		b.Run(bv.name+"/Serialize", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				io.Discard.Write([]byte(Serialize(bv.v)))
+			}
+		})
+		b.Run(bv.name+"/Writer", func(b *testing.B) {
+			w := NewWriter(io.Discard)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w.WriteValue(bv.v)
+				w.Flush()
+			}
+		})
+	}
+}
+
+func BenchmarkParseBulk(b *testing.B) {
+	input := AppendValue(nil, BulkString(strings.Repeat("x", 1<<20)))
+	r := bytes.NewReader(input)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(input)
+		if _, err := NewParser(r).Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"go-redis/protocol"
+	"sync"
+	"sync/atomic"
+)
+
+// Feed 把消息广播给所有订阅者，不区分频道（用于 MONITOR）
+// 订阅者为空时 Len 只做一次原子读取，调用方可以据此跳过消息的构造
+type Feed struct {
+	mu   sync.RWMutex
+	subs map[Subscriber]struct{}
+	n    atomic.Int64
+}
+
+// NewFeed 创建一个新的 Feed
+func NewFeed() *Feed {
+	return &Feed{
+		subs: make(map[Subscriber]struct{}),
+	}
+}
+
+// Add 添加订阅者，返回是否为新订阅者
+func (f *Feed) Add(sub Subscriber) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subs[sub]; ok {
+		return false
+	}
+	f.subs[sub] = struct{}{}
+	f.n.Store(int64(len(f.subs)))
+	return true
+}
+
+// Remove 移除订阅者，返回是否移除成功
+func (f *Feed) Remove(sub Subscriber) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subs[sub]; !ok {
+		return false
+	}
+	delete(f.subs, sub)
+	f.n.Store(int64(len(f.subs)))
+	return true
+}
+
+// Len 返回订阅者数量
+func (f *Feed) Len() int64 {
+	return f.n.Load()
+}
+
+// Broadcast 把消息发送给除 except 之外的所有订阅者（except 可以为 nil）
+func (f *Feed) Broadcast(msg *protocol.Value, except Subscriber) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for sub := range f.subs {
+		if sub != except {
+			sub.Send(msg)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package pubsub
+
+import (
+	"go-redis/glob"
+	"go-redis/protocol"
+	"sort"
+	"sync"
+)
+
+// Subscriber 是订阅消息的一方（通常是一个客户端连接）
+// Send 会在发布者的 goroutine 中被调用，实现方不能阻塞
+type Subscriber interface {
+	Send(msg *protocol.Value)
+}
+
+// Hub 维护频道和模式的订阅关系，负责把消息投递给订阅者
+type Hub struct {
+	mu       sync.RWMutex
+	channels map[string]map[Subscriber]struct{}
+	patterns map[string]map[Subscriber]struct{}
+}
+
+// NewHub 创建一个新的 Hub
+func NewHub() *Hub {
+	return &Hub{
+		channels: make(map[string]map[Subscriber]struct{}),
+		patterns: make(map[string]map[Subscriber]struct{}),
+	}
+}
+
+func add(m map[string]map[Subscriber]struct{}, name string, sub Subscriber) bool {
+	subs, ok := m[name]
+	if !ok {
+		subs = make(map[Subscriber]struct{})
+		m[name] = subs
+	}
+	if _, exists := subs[sub]; exists {
+		return false
+	}
+	subs[sub] = struct{}{}
+	return true
+}
+
+func remove(m map[string]map[Subscriber]struct{}, name string, sub Subscriber) bool {
+	subs, ok := m[name]
+	if !ok {
+		return false
+	}
+	if _, exists := subs[sub]; !exists {
+		return false
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(m, name)
+	}
+	return true
+}
+
+// Subscribe 订阅频道，返回是否为新订阅
+func (h *Hub) Subscribe(sub Subscriber, channel string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return add(h.channels, channel, sub)
+}
+
+// Unsubscribe 取消订阅频道，返回之前是否订阅过
+func (h *Hub) Unsubscribe(sub Subscriber, channel string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return remove(h.channels, channel, sub)
+}
+
+// PSubscribe 订阅模式，返回是否为新订阅
+func (h *Hub) PSubscribe(sub Subscriber, pattern string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return add(h.patterns, pattern, sub)
+}
+
+// PUnsubscribe 取消订阅模式，返回之前是否订阅过
+func (h *Hub) PUnsubscribe(sub Subscriber, pattern string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return remove(h.patterns, pattern, sub)
+}
+
+// Publish 向频道发布消息，返回收到消息的订阅数
+func (h *Hub) Publish(channel, message string) int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var receivers int64
+
+	if subs, ok := h.channels[channel]; ok {
+		msg := protocol.Array([]protocol.Value{
+			*protocol.BulkString("message"),
+			*protocol.BulkString(channel),
+			*protocol.BulkString(message),
+		})
+		for sub := range subs {
+			sub.Send(msg)
+			receivers++
+		}
+	}
+
+	for pattern, subs := range h.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		msg := protocol.Array([]protocol.Value{
+			*protocol.BulkString("pmessage"),
+			*protocol.BulkString(pattern),
+			*protocol.BulkString(channel),
+			*protocol.BulkString(message),
+		})
+		for sub := range subs {
+			sub.Send(msg)
+			receivers++
+		}
+	}
+
+	return receivers
+}
+
+// Channels 返回至少有一个订阅者、且匹配 pattern 的频道；pattern 为空时返回全部
+func (h *Hub) Channels(pattern string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	res := make([]string, 0, len(h.channels))
+	for channel := range h.channels {
+		if pattern == "" || glob.Match(pattern, channel) {
+			res = append(res, channel)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// NumSub 返回频道的订阅者数量
+func (h *Hub) NumSub(channel string) int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return int64(len(h.channels[channel]))
+}
+
+// NumPat 返回所有客户端订阅的模式总数
+func (h *Hub) NumPat() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var n int64
+	for _, subs := range h.patterns {
+		n += int64(len(subs))
+	}
+	return n
+}
+
+// IsSubscribed 判断 sub 是否订阅了频道
+func (h *Hub) IsSubscribed(sub Subscriber, channel string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.channels[channel][sub]
+	return ok
+}
@@ -0,0 +1,101 @@
+package pubsub
+
+import (
+	"go-redis/protocol"
+	"testing"
+)
+
+type recordSubscriber struct {
+	messages []*protocol.Value
+}
+
+func (s *recordSubscriber) Send(msg *protocol.Value) {
+	s.messages = append(s.messages, msg)
+}
+
+func TestPublishToChannel(t *testing.T) {
+	h := NewHub()
+	a, b := &recordSubscriber{}, &recordSubscriber{}
+
+	if !h.Subscribe(a, "news") || h.Subscribe(a, "news") {
+		t.Error("Expected only the first Subscribe to be new")
+	}
+	h.Subscribe(b, "sports")
+
+	if n := h.Publish("news", "hello"); n != 1 {
+		t.Errorf("Expected 1 receiver, got %d", n)
+	}
+	if len(a.messages) != 1 || len(b.messages) != 0 {
+		t.Fatalf("Unexpected deliveries: a=%d b=%d", len(a.messages), len(b.messages))
+	}
+
+	msg := a.messages[0]
+	if msg.Array[0].Str != "message" || msg.Array[1].Str != "news" || msg.Array[2].Str != "hello" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	h.Unsubscribe(a, "news")
+	if n := h.Publish("news", "again"); n != 0 {
+		t.Errorf("Expected 0 receivers after unsubscribe, got %d", n)
+	}
+}
+
+func TestPublishToPattern(t *testing.T) {
+	h := NewHub()
+	sub := &recordSubscriber{}
+	h.PSubscribe(sub, "__keyspace@0__:user:*")
+
+	h.Publish("__keyspace@0__:user:1", "set")
+	h.Publish("__keyspace@0__:order:1", "set")
+
+	if len(sub.messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(sub.messages))
+	}
+
+	msg := sub.messages[0]
+	if msg.Array[0].Str != "pmessage" || msg.Array[1].Str != "__keyspace@0__:user:*" || msg.Array[2].Str != "__keyspace@0__:user:1" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+	if h.NumPat() != 1 {
+		t.Errorf("Expected 1 pattern, got %d", h.NumPat())
+	}
+}
+
+func TestChannelsAndNumSub(t *testing.T) {
+	h := NewHub()
+	a, b := &recordSubscriber{}, &recordSubscriber{}
+	h.Subscribe(a, "news.tech")
+	h.Subscribe(b, "news.tech")
+	h.Subscribe(b, "weather")
+
+	if got := h.Channels("news.*"); len(got) != 1 || got[0] != "news.tech" {
+		t.Errorf("Unexpected channels: %v", got)
+	}
+	if got := h.Channels(""); len(got) != 2 {
+		t.Errorf("Expected 2 channels, got %v", got)
+	}
+	if n := h.NumSub("news.tech"); n != 2 {
+		t.Errorf("Expected 2 subscribers, got %d", n)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	f := NewFeed()
+	a, b := &recordSubscriber{}, &recordSubscriber{}
+
+	if !f.Add(a) || f.Add(a) || !f.Add(b) {
+		t.Fatal("Unexpected Add result")
+	}
+	if f.Len() != 2 {
+		t.Fatalf("Expected 2 subscribers, got %d", f.Len())
+	}
+
+	f.Broadcast(protocol.SimpleString("x"), a)
+	if len(a.messages) != 0 || len(b.messages) != 1 {
+		t.Errorf("Unexpected delivery: a=%d b=%d", len(a.messages), len(b.messages))
+	}
+
+	if !f.Remove(b) || f.Remove(b) || f.Len() != 1 {
+		t.Errorf("Unexpected Remove result, len=%d", f.Len())
+	}
+}
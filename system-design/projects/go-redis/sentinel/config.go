@@ -0,0 +1,176 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 配置
+//
+// 哨兵的配置与 Redis Sentinel 的 sentinel.conf 相同，每行以 sentinel 开头，例如：
+//
+//	sentinel monitor mymaster 127.0.0.1 6379 2
+//	sentinel down-after-milliseconds mymaster 5000
+//
+// 运行中发现的从节点和哨兵通过 RewriteLines 写回配置文件，重启后从上次的状态继续。
+// 哨兵不执行故障转移，不支持 failover-timeout、parallel-syncs 以及纪元相关的指令
+
+var (
+	ErrNoSuchMaster = errors.New("No such master with specified name.")
+
+	errUnknownDirective = errors.New("Unrecognized sentinel configuration statement.")
+	errDuplicateMaster  = errors.New("Duplicated master name.")
+	errBadQuorum        = errors.New("Quorum must be 1 or greater.")
+	errBadMyID          = errors.New("Malformed Sentinel id in myid option.")
+)
+
+// Configure 应用一行 sentinel 配置，args 是 sentinel 之后的部分，只能在 Start 之前调用
+func (s *Sentinel) Configure(args []string) error {
+	if len(args) == 0 {
+		return errUnknownDirective
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	directive := strings.ToLower(args[0])
+	args = args[1:]
+	switch {
+	case directive == "myid" && len(args) == 1:
+		if len(args[0]) != 40 {
+			return errBadMyID
+		}
+		s.myID = args[0]
+		return nil
+	case directive == "monitor" && len(args) == 4:
+		return s.monitor(args[0], args[1], args[2], args[3])
+	}
+
+	// 其余的指令都针对一个已经配置的主节点
+	if len(args) < 2 {
+		return errUnknownDirective
+	}
+	m := s.masters[args[0]]
+	if m == nil {
+		return ErrNoSuchMaster
+	}
+	args = args[1:]
+
+	switch {
+	case directive == "down-after-milliseconds" && len(args) == 1:
+		d, err := parseMillis(args[0])
+		if err != nil {
+			return err
+		}
+		m.downAfter = d
+	case (directive == "known-replica" || directive == "known-slave") && len(args) == 2:
+		addr, err := joinAddr(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if addr != m.inst.addr && m.replicas[addr] == nil {
+			m.replicas[addr] = s.newInstance(kindReplica, addr)
+		}
+	case directive == "known-sentinel" && len(args) == 3:
+		addr, err := joinAddr(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if args[2] != s.myID && m.sentinels[args[2]] == nil {
+			p := s.newInstance(kindSentinel, addr)
+			p.runID = args[2]
+			m.sentinels[args[2]] = p
+		}
+	default:
+		return errUnknownDirective
+	}
+	return nil
+}
+
+func (s *Sentinel) monitor(name, host, port, quorum string) error {
+	if s.masters[name] != nil {
+		return errDuplicateMaster
+	}
+	addr, err := joinAddr(host, port)
+	if err != nil {
+		return err
+	}
+	q, err := strconv.Atoi(quorum)
+	if err != nil || q <= 0 {
+		return errBadQuorum
+	}
+
+	s.masters[name] = &master{
+		name:      name,
+		inst:      s.newInstance(kindMaster, addr),
+		quorum:    q,
+		downAfter: defaultDownAfter,
+		replicas:  make(map[string]*instance),
+		sentinels: make(map[string]*instance),
+	}
+	return nil
+}
+
+func parseMillis(value string) (time.Duration, error) {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, fmt.Errorf("Invalid value '%s', must be a positive number of milliseconds", value)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func joinAddr(host, port string) (string, error) {
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", fmt.Errorf("Invalid port number '%s'", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// RewriteLines 返回写回配置文件的当前配置，每项是一行 sentinel 之后的部分
+func (s *Sentinel) RewriteLines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines := []string{"myid " + s.myID}
+	for _, m := range s.sortedMasters() {
+		host, port, _ := net.SplitHostPort(m.inst.addr)
+		lines = append(lines, fmt.Sprintf("monitor %s %s %s %d", m.name, host, port, m.quorum))
+		if m.downAfter != defaultDownAfter {
+			lines = append(lines, fmt.Sprintf("down-after-milliseconds %s %d", m.name, m.downAfter.Milliseconds()))
+		}
+
+		for _, r := range sortedInstances(m.replicas) {
+			host, port, _ := net.SplitHostPort(r.addr)
+			lines = append(lines, fmt.Sprintf("known-replica %s %s %s", m.name, host, port))
+		}
+		for _, p := range sortedInstances(m.sentinels) {
+			host, port, _ := net.SplitHostPort(p.addr)
+			lines = append(lines, fmt.Sprintf("known-sentinel %s %s %s %s", m.name, host, port, p.runID))
+		}
+	}
+	return lines
+}
+
+func (s *Sentinel) sortedMasters() []*master {
+	masters := make([]*master, 0, len(s.masters))
+	for _, m := range s.masters {
+		masters = append(masters, m)
+	}
+	sort.Slice(masters, func(i, j int) bool { return masters[i].name < masters[j].name })
+	return masters
+}
+
+// sortedInstances 返回按地址排序的实例
+func sortedInstances(insts map[string]*instance) []*instance {
+	sorted := make([]*instance, 0, len(insts))
+	for _, inst := range insts {
+		sorted = append(sorted, inst)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].addr < sorted[j].addr })
+	return sorted
+}
@@ -0,0 +1,93 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"go-redis/protocol"
+)
+
+// checkSubjectivelyDown 检查实例是否主观下线：有 PING 还没有得到有效回复时从最早的这个 PING 发送时算起，
+// 否则从最近一次有效回复算起，超过 down-after-milliseconds 即为主观下线
+// PING 的周期可能与 down-after-milliseconds 相同，只按最近一次回复计时会误判正常的实例
+func (s *Sentinel) checkSubjectivelyDown(m *master, inst *instance, now time.Time) {
+	elapsed := now.Sub(inst.lastAvail)
+	if !inst.unanswered.IsZero() {
+		elapsed = now.Sub(inst.unanswered)
+	}
+	down := elapsed > m.downAfter
+	switch {
+	case down && !inst.sdown():
+		inst.sdownSince = now
+		s.event("+sdown", m, inst, "")
+	case !down && inst.sdown():
+		inst.sdownSince = time.Time{}
+		s.event("-sdown", m, inst, "")
+	}
+}
+
+// checkObjectivelyDown 统计认为主节点下线的哨兵（包括自己），达到 quorum 时主节点客观下线
+func (s *Sentinel) checkObjectivelyDown(m *master, now time.Time) {
+	votes := 0
+	if m.inst.sdown() {
+		votes = 1
+		for _, p := range m.sentinels {
+			if p.masterDown {
+				votes++
+			}
+		}
+	}
+	odown := votes >= m.quorum && votes > 0
+
+	switch {
+	case odown && m.odownSince.IsZero():
+		m.odownSince = now
+		s.event("+odown", m, m.inst, fmt.Sprintf("#quorum %d/%d", votes, m.quorum))
+	case !odown && !m.odownSince.IsZero():
+		m.odownSince = time.Time{}
+		s.event("-odown", m, m.inst, "")
+	}
+}
+
+// askMasterState 主节点主观下线时每 askPeriod 询问一次其他哨兵是否也认为它下线
+func (s *Sentinel) askMasterState(m *master, now time.Time) {
+	host, port, _ := net.SplitHostPort(m.inst.addr)
+	for _, p := range m.sentinels {
+		// 太久以前的回复不再可信
+		if now.Sub(p.askReply) > askForgetTime {
+			p.masterDown = false
+		}
+		if !m.inst.sdown() || p.askPending || p.sdown() || now.Sub(p.askSent) < askPeriod {
+			continue
+		}
+		s.sendAsk(m, p, host, port, now)
+	}
+}
+
+// sendAsk 发送 SENTINEL is-master-down-by-addr，runid 为 * 表示只询问状态、不请求投票
+func (s *Sentinel) sendAsk(m *master, p *instance, host, port string, now time.Time) {
+	p.askPending = true
+	p.askSent = now
+	timeout := m.pingPeriod() * 5
+
+	go func() {
+		ctx, cancel := context.WithTimeout(p.ctx, timeout)
+		defer cancel()
+		reply, err := p.link.do(ctx, "SENTINEL", "is-master-down-by-addr", host, port, "0", "*")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		p.askPending = false
+		if err != nil {
+			return
+		}
+		// 回复是 [是否下线, 投票给的领导者或 *, 领导者的纪元]，只使用第一项
+		if reply.Type != protocol.ArrayType || len(reply.Array) != 3 || reply.Array[0].Type != protocol.IntType {
+			return
+		}
+		p.askReply = time.Now()
+		p.masterDown = reply.Array[0].Int == 1
+	}()
+}
@@ -0,0 +1,102 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-redis/protocol"
+)
+
+// subscribeHello 订阅实例的 __sentinel__:hello 频道，连接断开后每 helloPeriod 重连一次，直到实例被移除
+// 订阅会独占连接，因此不使用实例的连接池
+func (s *Sentinel) subscribeHello(inst *instance) {
+	for {
+		s.readHello(inst)
+		select {
+		case <-inst.ctx.Done():
+			return
+		case <-time.After(helloPeriod):
+		}
+	}
+}
+
+func (s *Sentinel) readHello(inst *instance) {
+	d := net.Dialer{Timeout: time.Second}
+	conn, err := d.DialContext(inst.ctx, "tcp", inst.addr)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(inst.ctx, func() { conn.Close() })
+	defer stop()
+
+	w := protocol.NewWriter(conn)
+	if err := w.WriteValue(commandValue("SUBSCRIBE", helloChannel)); err != nil {
+		return
+	}
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	parser := protocol.NewParser(conn)
+	for {
+		v, err := parser.Parse()
+		if err != nil {
+			return
+		}
+		if v.Type == protocol.ArrayType && len(v.Array) == 3 && strings.EqualFold(v.Array[0].Str, "message") {
+			s.processHello(strings.Clone(v.Array[2].Str))
+		}
+	}
+}
+
+// processHello 处理其他哨兵的 hello 消息，记录新发现的哨兵
+// 消息中的纪元和主节点地址用于传播故障转移的结果，这里只检查它们的格式
+func (s *Sentinel) processHello(msg string) {
+	parts := strings.Split(msg, ",")
+	if len(parts) != 8 {
+		return
+	}
+	ip, port, runID, name := parts[0], parts[1], parts[2], parts[4]
+	_, err1 := strconv.ParseUint(parts[3], 10, 64)
+	_, err2 := strconv.ParseUint(parts[7], 10, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.masters[name]
+	if m == nil || runID == s.myID {
+		return
+	}
+
+	addr := net.JoinHostPort(ip, port)
+	peer := m.sentinels[runID]
+	if peer != nil && peer.addr != addr {
+		// 哨兵换了地址，按新地址重新连接
+		peer.close()
+		delete(m.sentinels, runID)
+		peer = nil
+	}
+	if peer == nil {
+		// 同一地址上的哨兵换了运行 ID（例如重启时丢失了配置），旧的记录作废
+		for id, p := range m.sentinels {
+			if p.addr == addr {
+				s.event("-dup-sentinel", m, p, "#duplicate of "+addr+" or "+runID)
+				p.close()
+				delete(m.sentinels, id)
+			}
+		}
+		peer = s.newInstance(kindSentinel, addr)
+		peer.runID = runID
+		m.sentinels[runID] = peer
+		s.event("+sentinel", m, peer, "")
+		s.configDirty = true
+	}
+	peer.lastHello = time.Now()
+}
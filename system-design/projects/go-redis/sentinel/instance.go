@@ -0,0 +1,334 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type instanceKind int
+
+const (
+	kindMaster instanceKind = iota
+	kindReplica
+	kindSentinel
+)
+
+// String 返回事件和 SENTINEL 命令中使用的实例类型名
+func (k instanceKind) String() string {
+	switch k {
+	case kindMaster:
+		return "master"
+	case kindReplica:
+		return "slave"
+	default:
+		return "sentinel"
+	}
+}
+
+// instance 是哨兵连接的一个实例：主节点、从节点或其他哨兵
+type instance struct {
+	kind   instanceKind
+	addr   string // host:port
+	runID  string
+	link   *link
+	ctx    context.Context // 实例被移除时取消，结束 hello 订阅等后台任务
+	cancel context.CancelFunc
+
+	created    time.Time
+	lastAvail  time.Time // 最近一次有效回复 PING 的时间
+	pingSent   time.Time
+	pinging    bool
+	unanswered time.Time // 最早一个还没有得到有效回复的 PING 的发送时间
+	sdownSince time.Time // 主观下线的起始时间，零值表示在线
+
+	// 主节点和从节点：INFO 中的信息，以及 hello 的发布和订阅
+	infoSent      time.Time
+	infoPending   bool
+	infoRefresh   time.Time // 最近一次收到 INFO 回复的时间
+	role          string
+	roleReported  time.Time
+	masterAddr    string // 从节点正在复制的主节点
+	linkUp        bool
+	linkDownSince time.Duration
+	priority      int
+	replOffset    int64
+	helloSent     time.Time
+	subscribed    bool
+	announceIP    string
+
+	// 其他哨兵：hello 消息和 is-master-down-by-addr 的回复
+	lastHello  time.Time
+	askSent    time.Time
+	askPending bool
+	askReply   time.Time
+	masterDown bool
+}
+
+func (s *Sentinel) newInstance(kind instanceKind, addr string) *instance {
+	ctx, cancel := context.WithCancel(s.ctx)
+	now := time.Now()
+	return &instance{
+		kind:      kind,
+		addr:      addr,
+		link:      newLink(ctx, addr),
+		ctx:       ctx,
+		cancel:    cancel,
+		created:   now,
+		lastAvail: now,
+		priority:  defaultReplicaPriority,
+	}
+}
+
+func (inst *instance) close() {
+	inst.cancel()
+}
+
+// name 返回实例在 SENTINEL 命令和事件中的名字：主节点是配置的名字，从节点是地址，哨兵是运行 ID
+func (inst *instance) name(m *master) string {
+	switch inst.kind {
+	case kindMaster:
+		return m.name
+	case kindSentinel:
+		return inst.runID
+	default:
+		return inst.addr
+	}
+}
+
+func (inst *instance) sdown() bool {
+	return !inst.sdownSince.IsZero()
+}
+
+// master 是一个被监控的主节点及其从节点和监控它的其他哨兵
+type master struct {
+	name      string
+	inst      *instance
+	quorum    int
+	downAfter time.Duration
+	replicas  map[string]*instance // 按地址
+	sentinels map[string]*instance // 按运行 ID
+
+	odownSince time.Time
+}
+
+// instances 返回主节点、从节点和其他哨兵
+func (m *master) instances() []*instance {
+	insts := make([]*instance, 0, 1+len(m.replicas)+len(m.sentinels))
+	insts = append(insts, m.inst)
+	for _, r := range m.replicas {
+		insts = append(insts, r)
+	}
+	for _, p := range m.sentinels {
+		insts = append(insts, p)
+	}
+	return insts
+}
+
+// pingPeriod 返回 PING 的周期，down-after-milliseconds 较短时按它发送
+func (m *master) pingPeriod() time.Duration {
+	return min(pingPeriod, m.downAfter)
+}
+
+// sendPeriodicCommands 按周期向实例发送 PING、INFO 和 hello 消息
+// 命令在后台执行，回复到达时再加锁更新状态
+func (s *Sentinel) sendPeriodicCommands(m *master, inst *instance, now time.Time) {
+	if !inst.pinging && now.Sub(inst.pingSent) >= m.pingPeriod() {
+		s.sendPing(m, inst, now)
+	}
+	if inst.kind == kindSentinel {
+		return
+	}
+
+	if !inst.subscribed {
+		inst.subscribed = true
+		go s.subscribeHello(inst)
+	}
+
+	// 与 Redis Sentinel 相同，主节点客观下线时更频繁地刷新从节点的状态
+	period := infoPeriod
+	if inst.kind == kindReplica && !m.odownSince.IsZero() {
+		period = time.Second
+	}
+	if !inst.infoPending && now.Sub(inst.infoSent) >= period {
+		s.refreshInfo(m, inst, now)
+	}
+
+	if now.Sub(inst.helloSent) >= helloPeriod {
+		s.sendHello(m, inst, now)
+	}
+}
+
+func (s *Sentinel) sendPing(m *master, inst *instance, now time.Time) {
+	inst.pinging = true
+	inst.pingSent = now
+	if inst.unanswered.IsZero() {
+		inst.unanswered = now
+	}
+	timeout := m.downAfter
+
+	go func() {
+		ctx, cancel := context.WithTimeout(inst.ctx, timeout)
+		defer cancel()
+		_, err := inst.link.do(ctx, "PING")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		inst.pinging = false
+		if validPingReply(err) {
+			inst.lastAvail = time.Now()
+			inst.unanswered = time.Time{}
+		}
+	}()
+}
+
+// validPingReply 判断 PING 的回复是否说明实例可用，正在加载数据的实例也算可用
+func validPingReply(err error) bool {
+	if err == nil {
+		return true
+	}
+	var re replyError
+	if errors.As(err, &re) {
+		return re.prefix() == "LOADING" || re.prefix() == "MASTERDOWN"
+	}
+	return false
+}
+
+func (s *Sentinel) refreshInfo(m *master, inst *instance, now time.Time) {
+	inst.infoPending = true
+	inst.infoSent = now
+	timeout := m.pingPeriod() * 5
+
+	go func() {
+		ctx, cancel := context.WithTimeout(inst.ctx, timeout)
+		defer cancel()
+		reply, err := inst.link.do(ctx, "INFO")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		inst.infoPending = false
+		if err != nil {
+			return
+		}
+		s.processInfo(m, inst, parseInfo(reply.Str), time.Now())
+	}()
+}
+
+// parseInfo 把 INFO 的回复解析为 field -> value
+func parseInfo(text string) map[string]string {
+	info := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if field, value, ok := strings.Cut(line, ":"); ok {
+			info[field] = value
+		}
+	}
+	return info
+}
+
+// parseInfoFields 解析 INFO 中 "a=1,b=2" 形式的值
+func parseInfoFields(value string) map[string]string {
+	fields := make(map[string]string)
+	for _, kv := range strings.Split(value, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// processInfo 根据 INFO 的回复更新实例的状态，主节点的回复中列出的从节点加入监控
+func (s *Sentinel) processInfo(m *master, inst *instance, info map[string]string, now time.Time) {
+	inst.infoRefresh = now
+	if id := info["run_id"]; id != "" {
+		inst.runID = id
+	}
+	if role := info["role"]; role != inst.role {
+		inst.role = role
+		inst.roleReported = now
+	}
+
+	switch inst.role {
+	case "master":
+		if inst == m.inst {
+			s.discoverReplicas(m, info)
+		}
+	case "slave":
+		inst.masterAddr = net.JoinHostPort(info["master_host"], info["master_port"])
+		inst.linkUp = info["master_link_status"] == "up"
+		inst.linkDownSince = 0
+		if secs, err := strconv.ParseInt(info["master_link_down_since_seconds"], 10, 64); err == nil && secs > 0 {
+			inst.linkDownSince = time.Duration(secs) * time.Second
+		}
+		inst.priority = defaultReplicaPriority
+		for _, field := range []string{"slave_priority", "replica_priority"} {
+			if n, err := strconv.Atoi(info[field]); err == nil {
+				inst.priority = n
+			}
+		}
+		inst.replOffset, _ = strconv.ParseInt(info["slave_repl_offset"], 10, 64)
+	}
+}
+
+// discoverReplicas 把主节点 INFO 中 slave0、slave1... 列出的从节点加入监控
+func (s *Sentinel) discoverReplicas(m *master, info map[string]string) {
+	for i := 0; ; i++ {
+		value, ok := info["slave"+strconv.Itoa(i)]
+		if !ok {
+			return
+		}
+		fields := parseInfoFields(value)
+		if fields["ip"] == "" || fields["port"] == "" {
+			continue
+		}
+		addr := net.JoinHostPort(fields["ip"], fields["port"])
+		if addr == m.inst.addr || m.replicas[addr] != nil {
+			continue
+		}
+		r := s.newInstance(kindReplica, addr)
+		m.replicas[addr] = r
+		s.event("+slave", m, r, "")
+		s.configDirty = true
+	}
+}
+
+// sendHello 通过实例的 __sentinel__:hello 频道发布本哨兵的地址和主节点的地址
+// 格式与 Redis Sentinel 相同：ip,port,runid,current_epoch,master_name,master_ip,master_port,master_config_epoch，
+// 两个纪元总是 0，见 sentinel.go 的说明
+func (s *Sentinel) sendHello(m *master, inst *instance, now time.Time) {
+	inst.helloSent = now
+	if inst.announceIP == "" {
+		ip, err := localIP(inst.addr)
+		if err != nil {
+			return
+		}
+		inst.announceIP = ip
+	}
+
+	mhost, mport, _ := net.SplitHostPort(m.inst.addr)
+	msg := fmt.Sprintf("%s,%d,%s,0,%s,%s,%s,0", inst.announceIP, s.port(), s.myID, m.name, mhost, mport)
+	timeout := m.pingPeriod() * 5
+
+	go func() {
+		ctx, cancel := context.WithTimeout(inst.ctx, timeout)
+		defer cancel()
+		inst.link.do(ctx, "PUBLISH", helloChannel, msg)
+	}()
+}
+
+// localIP 返回连接 addr 时使用的本机地址，其他哨兵通过这个地址连接本哨兵
+func localIP(addr string) (string, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
@@ -0,0 +1,117 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go-redis/protocol"
+)
+
+// link 是到实例的命令连接：断开后在下一条命令时重连，命令依次执行
+// 哨兵只需要少量简单的命令，不使用 client 包，也避免了 client 包的测试经由 server 引用本包造成循环引用
+type link struct {
+	addr string
+	ctx  context.Context // 实例被移除时取消，正在执行的命令随之结束
+
+	mu     sync.Mutex
+	conn   net.Conn
+	writer *protocol.Writer
+	parser *protocol.Parser
+}
+
+// replyError 是实例返回的错误回复
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
+// prefix 返回错误的前缀，例如 LOADING
+func (e replyError) prefix() string {
+	prefix, _, _ := strings.Cut(string(e), " ")
+	return prefix
+}
+
+var errLinkClosed = errors.New("link closed")
+
+// newLink 创建到 addr 的连接，ctx 取消后连接关闭
+func newLink(ctx context.Context, addr string) *link {
+	l := &link{addr: addr, ctx: ctx}
+	context.AfterFunc(ctx, l.close)
+	return l
+}
+
+// do 执行一条命令并返回回复，错误回复以 replyError 返回
+// ctx 结束或实例被移除时关闭连接，使阻塞的读写立即返回
+func (l *link) do(ctx context.Context, args ...string) (*protocol.Value, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ctx.Err() != nil {
+		return nil, errLinkClosed
+	}
+	if l.conn == nil {
+		d := net.Dialer{Timeout: time.Second}
+		conn, err := d.DialContext(ctx, "tcp", l.addr)
+		if err != nil {
+			return nil, err
+		}
+		l.conn = conn
+		l.writer = protocol.NewWriter(conn)
+		l.parser = protocol.NewParser(conn)
+	}
+
+	conn := l.conn
+	stop1 := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop1()
+	stop2 := context.AfterFunc(l.ctx, func() { conn.Close() })
+	defer stop2()
+
+	reply, err := l.roundTrip(commandValue(args...))
+	if err != nil {
+		conn.Close()
+		l.conn = nil
+		l.writer = nil
+		l.parser = nil
+		return nil, err
+	}
+	if reply.Type == protocol.ErrorType {
+		return reply, replyError(reply.Str)
+	}
+	return reply, nil
+}
+
+func (l *link) roundTrip(cmd *protocol.Value) (*protocol.Value, error) {
+	if err := l.writer.WriteValue(cmd); err != nil {
+		return nil, err
+	}
+	if err := l.writer.Flush(); err != nil {
+		return nil, err
+	}
+	return l.parser.Parse()
+}
+
+// commandValue 把命令编码为批量字符串的数组
+func commandValue(args ...string) *protocol.Value {
+	values := make([]protocol.Value, len(args))
+	for i, arg := range args {
+		values[i] = *protocol.BulkString(arg)
+	}
+	return protocol.Array(values)
+}
+
+// close 在 ctx 取消后关闭空闲的连接，之后的命令都返回 errLinkClosed
+func (l *link) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+		l.writer = nil
+		l.parser = nil
+	}
+}
@@ -0,0 +1,209 @@
+package sentinel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SENTINEL 命令和 INFO 使用的查询接口
+
+// MyID 返回本哨兵的运行 ID
+func (s *Sentinel) MyID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.myID
+}
+
+// MasterAddr 返回主节点的地址
+func (s *Sentinel) MasterAddr(name string) (host, port string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.masters[name]
+	if m == nil {
+		return "", "", false
+	}
+	host, port, _ = net.SplitHostPort(m.inst.addr)
+	return host, port, true
+}
+
+// Masters 返回所有主节点的状态，每个主节点是 SENTINEL MASTERS 回复中的一组 field/value
+func (s *Sentinel) Masters() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var res [][]string
+	for _, m := range s.sortedMasters() {
+		res = append(res, s.instanceFields(m, m.inst, now))
+	}
+	return res
+}
+
+// Master 返回一个主节点的状态
+func (s *Sentinel) Master(name string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.masters[name]
+	if m == nil {
+		return nil, ErrNoSuchMaster
+	}
+	return s.instanceFields(m, m.inst, time.Now()), nil
+}
+
+// Replicas 返回主节点的从节点的状态
+func (s *Sentinel) Replicas(name string) ([][]string, error) {
+	return s.listInstances(name, func(m *master) map[string]*instance { return m.replicas })
+}
+
+// Sentinels 返回监控主节点的其他哨兵的状态
+func (s *Sentinel) Sentinels(name string) ([][]string, error) {
+	return s.listInstances(name, func(m *master) map[string]*instance { return m.sentinels })
+}
+
+func (s *Sentinel) listInstances(name string, which func(m *master) map[string]*instance) ([][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.masters[name]
+	if m == nil {
+		return nil, ErrNoSuchMaster
+	}
+	now := time.Now()
+	var res [][]string
+	for _, inst := range sortedInstances(which(m)) {
+		res = append(res, s.instanceFields(m, inst, now))
+	}
+	return res, nil
+}
+
+// instanceFields 返回实例的状态，字段名与 Redis Sentinel 相同，时间以毫秒为单位
+func (s *Sentinel) instanceFields(m *master, inst *instance, now time.Time) []string {
+	host, port, _ := net.SplitHostPort(inst.addr)
+	millis := func(t time.Time) string { return strconv.FormatInt(now.Sub(t).Milliseconds(), 10) }
+
+	pingSent := "0"
+	if !inst.unanswered.IsZero() {
+		pingSent = millis(inst.unanswered)
+	}
+	fields := []string{
+		"name", inst.name(m),
+		"ip", host,
+		"port", port,
+		"runid", inst.runID,
+		"flags", s.instanceFlags(m, inst),
+		"last-ping-sent", pingSent,
+		"last-ok-ping-reply", millis(inst.lastAvail),
+		"last-ping-reply", millis(inst.lastAvail),
+		"down-after-milliseconds", strconv.FormatInt(m.downAfter.Milliseconds(), 10),
+	}
+	if inst.sdown() {
+		fields = append(fields, "s-down-time", millis(inst.sdownSince))
+	}
+
+	switch inst.kind {
+	case kindMaster:
+		if !m.odownSince.IsZero() {
+			fields = append(fields, "o-down-time", millis(m.odownSince))
+		}
+		fields = append(fields,
+			"info-refresh", millis(inst.infoRefresh),
+			"role-reported", "master",
+			"role-reported-time", millis(inst.roleReported),
+			"num-slaves", strconv.Itoa(len(m.replicas)),
+			"num-other-sentinels", strconv.Itoa(len(m.sentinels)),
+			"quorum", strconv.Itoa(m.quorum))
+	case kindReplica:
+		linkStatus := "err"
+		if inst.linkUp {
+			linkStatus = "ok"
+		}
+		masterHost, masterPort, _ := net.SplitHostPort(inst.masterAddr)
+		fields = append(fields,
+			"info-refresh", millis(inst.infoRefresh),
+			"role-reported", inst.role,
+			"role-reported-time", millis(inst.roleReported),
+			"master-link-down-time", strconv.FormatInt(inst.linkDownSince.Milliseconds(), 10),
+			"master-link-status", linkStatus,
+			"master-host", masterHost,
+			"master-port", masterPort,
+			"slave-priority", strconv.Itoa(inst.priority),
+			"slave-repl-offset", strconv.FormatInt(inst.replOffset, 10))
+	case kindSentinel:
+		fields = append(fields, "last-hello-message", millis(inst.lastHello))
+	}
+	return fields
+}
+
+func (s *Sentinel) instanceFlags(m *master, inst *instance) string {
+	flags := []string{inst.kind.String()}
+	if inst.sdown() {
+		flags = append(flags, "s_down")
+	}
+	switch {
+	case inst == m.inst && !m.odownSince.IsZero():
+		flags = append(flags, "o_down")
+	case inst.kind == kindSentinel && inst.masterDown:
+		flags = append(flags, "master_down")
+	}
+	return strings.Join(flags, ",")
+}
+
+// IsMasterDownByAddr 回答其他哨兵的询问：地址为 host:port 的主节点是否主观下线
+func (s *Sentinel) IsMasterDownByAddr(host, port string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addr := net.JoinHostPort(host, port)
+	for _, m := range s.masters {
+		if m.inst.addr == addr {
+			return m.inst.sdown()
+		}
+	}
+	return false
+}
+
+// CheckQuorum 检查当前可用的哨兵（包括自己）能否使主节点客观下线
+func (s *Sentinel) CheckQuorum(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.masters[name]
+	if m == nil {
+		return "", ErrNoSuchMaster
+	}
+
+	usable := 1
+	for _, p := range m.sentinels {
+		if !p.sdown() {
+			usable++
+		}
+	}
+	if usable < m.quorum {
+		return "", fmt.Errorf("NOQUORUM %d usable Sentinels. Not enough available Sentinels to reach the specified quorum for this master", usable)
+	}
+	return fmt.Sprintf("OK %d usable Sentinels. Quorum can be reached", usable), nil
+}
+
+// InfoLines 返回 INFO 的 sentinel 节
+func (s *Sentinel) InfoLines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines := []string{"sentinel_masters:" + strconv.Itoa(len(s.masters))}
+	for i, m := range s.sortedMasters() {
+		status := "ok"
+		if !m.odownSince.IsZero() {
+			status = "odown"
+		} else if m.inst.sdown() {
+			status = "sdown"
+		}
+		lines = append(lines, fmt.Sprintf("master%d:name=%s,status=%s,address=%s,slaves=%d,sentinels=%d",
+			i, m.name, status, m.inst.addr, len(m.replicas), len(m.sentinels)+1))
+	}
+	return lines
+}
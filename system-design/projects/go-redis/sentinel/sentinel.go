@@ -0,0 +1,177 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go-redis/logger"
+)
+
+// 哨兵模式
+//
+// 与 Redis Sentinel 相同，哨兵监控配置的主节点以及从主节点的 INFO 中发现的从节点：
+//   - 定期向每个实例发送 PING，超过 down-after-milliseconds 没有有效回复时认为实例主观下线（SDOWN）
+//   - 主节点主观下线后通过 SENTINEL is-master-down-by-addr 询问其他哨兵，
+//     认为它下线的哨兵（包括自己）达到 quorum 时主节点客观下线（ODOWN）
+//   - 哨兵之间通过主从节点的 __sentinel__:hello 频道互相发现
+//   - 客户端通过 SENTINEL get-master-addr-by-name 获得主节点的地址
+//
+// 哨兵只通过 PING、INFO 和 PUBLISH/SUBSCRIBE 与被监控的实例交互，可以监控任何兼容 Redis 协议的实例。
+//
+// 哨兵只负责监控和发现，不执行故障转移：提升从节点依赖主从复制（被提升的从节点必须已经拥有主节点的数据），
+// go-redis 还没有实现主从复制，领导者选举、提升和重新配置从节点要等复制实现之后再加入。
+// 因此 hello 消息和 is-master-down-by-addr 中的纪元总是 0，本哨兵也不为其他哨兵投票
+
+const (
+	helloChannel = "__sentinel__:hello"
+
+	defaultDownAfter       = 30 * time.Second
+	defaultReplicaPriority = 100
+
+	// 定时任务的周期，与 Redis Sentinel 相同
+	cronPeriod  = 100 * time.Millisecond
+	pingPeriod  = time.Second
+	infoPeriod  = 10 * time.Second
+	helloPeriod = 2 * time.Second
+	askPeriod   = time.Second
+
+	// askForgetTime 之后不再采用其他哨兵关于主节点是否下线的回复
+	askForgetTime = 5 * time.Second
+)
+
+// Sentinel 监控一组主节点，与其他哨兵一起判定它们是否下线
+type Sentinel struct {
+	mu          sync.Mutex
+	myID        string
+	masters     map[string]*master
+	configDirty bool // 配置有变化，需要写回配置文件
+
+	port     func() int
+	publish  func(channel, message string) int64
+	onConfig func()
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建哨兵，监控的主节点通过 Configure 设置，Start 之后开始监控
+func New() *Sentinel {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Sentinel{
+		myID:    randomID(),
+		masters: make(map[string]*master),
+		port:    func() int { return 26379 },
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// randomID 生成 40 个十六进制字符的运行 ID
+func randomID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SetPort 设置获取本哨兵监听端口的函数，端口通过 hello 消息告诉其他哨兵
+func (s *Sentinel) SetPort(port func() int) {
+	s.port = port
+}
+
+// SetPublisher 设置事件的发布函数，+sdown、+odown 等事件发布到与事件同名的频道
+func (s *Sentinel) SetPublisher(publish func(channel, message string) int64) {
+	s.publish = publish
+}
+
+// SetConfigChangeHandler 设置配置变化（发现新的从节点或哨兵）后调用的函数，用于写回配置文件
+// fn 在定时任务中调用，调用时不持有哨兵的锁
+func (s *Sentinel) SetConfigChangeHandler(fn func()) {
+	s.onConfig = fn
+}
+
+// Start 启动定时任务
+func (s *Sentinel) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(cronPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.cron()
+			}
+		}
+	}()
+}
+
+// Stop 停止监控并关闭到所有实例的连接
+func (s *Sentinel) Stop() {
+	s.cancel()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.masters {
+		for _, inst := range m.instances() {
+			inst.close()
+		}
+	}
+}
+
+// cron 每 cronPeriod 执行一次：发送周期性命令、检查下线状态
+func (s *Sentinel) cron() {
+	s.mu.Lock()
+	now := time.Now()
+	for _, m := range s.masters {
+		s.handleMaster(m, now)
+	}
+	dirty := s.configDirty
+	s.configDirty = false
+	s.mu.Unlock()
+
+	if dirty && s.onConfig != nil {
+		s.onConfig()
+	}
+}
+
+func (s *Sentinel) handleMaster(m *master, now time.Time) {
+	for _, inst := range m.instances() {
+		s.sendPeriodicCommands(m, inst, now)
+		s.checkSubjectivelyDown(m, inst, now)
+	}
+
+	s.checkObjectivelyDown(m, now)
+	s.askMasterState(m, now)
+}
+
+// event 记录并发布一个事件，频道名是事件类型
+// 消息的格式与 Redis Sentinel 相同："<实例类型> <名字> <ip> <port> @ <主节点名> <ip> <port>"，
+// 实例是主节点时省略 @ 之后的部分；inst 为 nil 时消息只有 extra
+func (s *Sentinel) event(typ string, m *master, inst *instance, extra string) {
+	msg := extra
+	if inst != nil {
+		host, port, _ := net.SplitHostPort(inst.addr)
+		msg = fmt.Sprintf("%s %s %s %s", inst.kind, inst.name(m), host, port)
+		if inst != m.inst {
+			mhost, mport, _ := net.SplitHostPort(m.inst.addr)
+			msg += fmt.Sprintf(" @ %s %s %s", m.name, mhost, mport)
+		}
+		if extra != "" {
+			msg += " " + extra
+		}
+	}
+
+	logger.Infof("%s %s", typ, msg)
+	if s.publish != nil {
+		s.publish(typ, msg)
+	}
+}
@@ -0,0 +1,192 @@
+package sentinel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSentinel(t *testing.T, lines ...string) *Sentinel {
+	t.Helper()
+	s := New()
+	t.Cleanup(s.Stop)
+	for _, line := range lines {
+		if err := s.Configure(strings.Fields(line)); err != nil {
+			t.Fatalf("Configure(%q): %v", line, err)
+		}
+	}
+	return s
+}
+
+func TestConfigureRewrite(t *testing.T) {
+	id := strings.Repeat("a", 40)
+	peer := strings.Repeat("b", 40)
+	lines := []string{
+		"myid " + id,
+		"monitor mymaster 127.0.0.1 6379 2",
+		"down-after-milliseconds mymaster 5000",
+		"known-replica mymaster 127.0.0.1 6380",
+		"known-sentinel mymaster 127.0.0.1 26380 " + peer,
+	}
+	s := newTestSentinel(t, lines...)
+
+	want := []string{
+		"myid " + id,
+		"monitor mymaster 127.0.0.1 6379 2",
+		"down-after-milliseconds mymaster 5000",
+		"known-replica mymaster 127.0.0.1 6380",
+		"known-sentinel mymaster 127.0.0.1 26380 " + peer,
+	}
+	if got := s.RewriteLines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RewriteLines() = %q, want %q", got, want)
+	}
+
+	// 写回的配置重新加载后得到相同的状态
+	s2 := newTestSentinel(t, s.RewriteLines()...)
+	if got := s2.RewriteLines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded RewriteLines() = %q, want %q", got, want)
+	}
+
+	host, port, ok := s.MasterAddr("mymaster")
+	if !ok || host != "127.0.0.1" || port != "6379" {
+		t.Errorf("MasterAddr = %s %s %v", host, port, ok)
+	}
+	if _, _, ok := s.MasterAddr("other"); ok {
+		t.Error("MasterAddr of unknown master should fail")
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	s := newTestSentinel(t, "monitor mymaster 127.0.0.1 6379 2")
+
+	tests := []struct {
+		line string
+		err  error
+	}{
+		{"monitor mymaster 127.0.0.1 6380 2", errDuplicateMaster},
+		{"monitor other 127.0.0.1 6380 0", errBadQuorum},
+		{"down-after-milliseconds other 5000", ErrNoSuchMaster},
+		{"myid abc", errBadMyID},
+		{"no-such-directive mymaster 1", errUnknownDirective},
+		{"failover-timeout mymaster 60000", errUnknownDirective},
+	}
+	for _, tt := range tests {
+		if err := s.Configure(strings.Fields(tt.line)); err != tt.err {
+			t.Errorf("Configure(%q) = %v, want %v", tt.line, err, tt.err)
+		}
+	}
+	for _, line := range []string{"monitor other 127.0.0.1 99999 2", "down-after-milliseconds mymaster -1"} {
+		if err := s.Configure(strings.Fields(line)); err == nil {
+			t.Errorf("Configure(%q) should fail", line)
+		}
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	info := parseInfo("# Replication\r\nrole:master\r\nconnected_slaves:1\r\nslave0:ip=127.0.0.1,port=6380,state=online,offset=10,lag=0\r\n")
+	if info["role"] != "master" {
+		t.Errorf("role = %q", info["role"])
+	}
+	fields := parseInfoFields(info["slave0"])
+	if fields["ip"] != "127.0.0.1" || fields["port"] != "6380" || fields["offset"] != "10" {
+		t.Errorf("slave0 fields = %v", fields)
+	}
+}
+
+func TestObjectivelyDown(t *testing.T) {
+	s := newTestSentinel(t,
+		"monitor mymaster 127.0.0.1 6379 2",
+		"known-sentinel mymaster 127.0.0.1 26380 "+strings.Repeat("b", 40),
+		"known-sentinel mymaster 127.0.0.1 26381 "+strings.Repeat("c", 40))
+	var events []string
+	s.SetPublisher(func(channel, message string) int64 {
+		events = append(events, channel)
+		return 0
+	})
+	m := s.masters["mymaster"]
+	now := time.Now()
+
+	// 其他哨兵认为主节点下线，但本哨兵没有判定主观下线，不计票
+	p := m.sentinels[strings.Repeat("b", 40)]
+	p.masterDown = true
+	s.checkObjectivelyDown(m, now)
+	if !m.odownSince.IsZero() {
+		t.Fatal("master should not be objectively down without a subjective down")
+	}
+
+	// 自己和一个哨兵共两票，达到 quorum
+	m.inst.sdownSince = now
+	s.checkObjectivelyDown(m, now)
+	if m.odownSince.IsZero() {
+		t.Fatal("master should be objectively down with 2/2 votes")
+	}
+	if flags := s.instanceFlags(m, m.inst); flags != "master,s_down,o_down" {
+		t.Errorf("flags = %q", flags)
+	}
+
+	// 其他哨兵的回复过期后票数不足；询问标记为正在进行，不向测试中不存在的哨兵发送命令
+	for _, p := range m.sentinels {
+		p.askPending = true
+	}
+	s.askMasterState(m, now.Add(askForgetTime+time.Second))
+	s.checkObjectivelyDown(m, now)
+	if !m.odownSince.IsZero() {
+		t.Error("master should leave objective down after the replies expire")
+	}
+	if !reflect.DeepEqual(events, []string{"+odown", "-odown"}) {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestProcessHello(t *testing.T) {
+	s := newTestSentinel(t,
+		"monitor mymaster 127.0.0.1 6379 1",
+		"known-replica mymaster 127.0.0.1 6380")
+	peer := strings.Repeat("b", 40)
+
+	s.processHello("127.0.0.1,26380," + peer + ",5,mymaster,127.0.0.1,6379,0")
+	m := s.masters["mymaster"]
+	if m.sentinels[peer] == nil {
+		t.Fatal("hello should add the sentinel")
+	}
+
+	// 哨兵换了地址，按新地址重新连接
+	s.processHello("127.0.0.1,26390," + peer + ",5,mymaster,127.0.0.1,6379,0")
+	if p := m.sentinels[peer]; p == nil || p.addr != "127.0.0.1:26390" {
+		t.Fatalf("sentinel after address change = %+v", p)
+	}
+
+	// 本哨兵不执行故障转移，其他哨兵的 hello 不会改变主节点
+	s.processHello("127.0.0.1,26390," + peer + ",6,mymaster,127.0.0.1,6380,6")
+	if _, port, _ := s.MasterAddr("mymaster"); port != "6379" {
+		t.Errorf("hello switched master to port %s", port)
+	}
+
+	// 格式错误和未知主节点的消息被忽略
+	for _, msg := range []string{"bad", "127.0.0.1,26391,x,e,mymaster,127.0.0.1,6379,0", "127.0.0.1,26391,x,0,other,127.0.0.1,6379,0"} {
+		s.processHello(msg)
+	}
+	if len(m.sentinels) != 1 {
+		t.Errorf("sentinels = %v", m.sentinels)
+	}
+}
+
+func TestCheckQuorum(t *testing.T) {
+	s := newTestSentinel(t,
+		"monitor mymaster 127.0.0.1 6379 2",
+		"known-sentinel mymaster 127.0.0.1 26380 "+strings.Repeat("b", 40))
+
+	if msg, err := s.CheckQuorum("mymaster"); err != nil || !strings.HasPrefix(msg, "OK 2 usable") {
+		t.Errorf("CheckQuorum = %q, %v", msg, err)
+	}
+	for _, p := range s.masters["mymaster"].sentinels {
+		p.sdownSince = time.Now()
+	}
+	if _, err := s.CheckQuorum("mymaster"); err == nil || !strings.HasPrefix(err.Error(), "NOQUORUM 1 usable") {
+		t.Errorf("CheckQuorum error = %v", err)
+	}
+	if _, err := s.CheckQuorum("other"); err != ErrNoSuchMaster {
+		t.Errorf("CheckQuorum(other) = %v", err)
+	}
+}
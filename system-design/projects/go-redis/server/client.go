@@ -1,36 +1,97 @@
 package server
 
 import (
-	"fmt"
 	"go-redis/handler"
 	"go-redis/logger"
 	"go-redis/protocol"
+	"go-redis/pubsub"
 	"io"
 	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// outboxSize 是每个客户端待推送消息队列的容量
+// 订阅者消费跟不上、队列写满时直接断开该客户端，避免拖慢发布者
+const outboxSize = 1024
+
 type Client struct {
-	id       string
+	id       int64
+	name     atomic.Value // CLIENT SETNAME 设置的名字，慢日志等会在其他 goroutine 读取
 	conn     net.Conn
-	parser   *protocol.Parser
 	router   *handler.Router
+	hub      *pubsub.Hub
+	monitors *pubsub.Feed
+	server   *Server      // 为 nil 时不支持 SHUTDOWN
+	stats    *serverStats // 为 nil 时不统计网络流量
 	shutdown chan struct{}
+	done     chan struct{} // Serve 退出时关闭
+
+	writeMu     sync.Mutex       // 串行化命令回复和推送消息对 conn 的写入
+	writer      *protocol.Writer // 只在持有 writeMu 时使用
+	outbox      chan pushMessage // 待推送的消息，由 writeLoop 写出，第一次推送时创建
+	outboxOnce  sync.Once
+	queuedBytes atomic.Int64 // outbox 中消息编码后的总字节数
+
+	// 连接限制，为 nil 时不检查输出缓冲区限制
+	limits         *connLimits
+	softLimitSince atomic.Int64 // 输出缓冲区开始超过软限制的时间（UnixNano），0 表示未超过
+
+	// 空闲检查在其他 goroutine 中读取的状态
+	lastInteraction atomic.Int64 // 最后一次收到命令的时间（UnixNano）
+	blocked         atomic.Bool  // 正在执行 XREAD BLOCK 等阻塞命令
+	inPubSub        atomic.Bool  // 处于订阅模式
+	monitoring      atomic.Bool  // 执行过 MONITOR
+
+	// 订阅的频道和模式，只在 Serve 所在的 goroutine 中访问
+	channels map[string]struct{}
+	patterns map[string]struct{}
+
+	// MULTI 之后排队的命令，只在 Serve 所在的 goroutine 中访问
+	inMulti    bool
+	multiDirty bool // 排队时出现错误，EXEC 时放弃事务
+	queued     []*protocol.Value
+	execing    bool // 正在执行 EXEC 排队的命令
+
+	db int // SELECT 选择的数据库，只在 Serve 所在的 goroutine 中访问
 }
 
-func NewClient(conn net.Conn, router *handler.Router, id string) *Client {
-	return &Client{
+// pushMessage 是排队等待推送的消息及其编码后的大小
+type pushMessage struct {
+	msg  *protocol.Value
+	size int64
+}
+
+func NewClient(conn net.Conn, router *handler.Router, id int64) *Client {
+	c := &Client{
 		id:       id,
 		conn:     conn,
-		parser:   protocol.NewParser(conn),
+		writer:   protocol.NewWriter(conn),
 		router:   router,
+		hub:      router.PubSub(),
+		monitors: router.Monitors(),
 		shutdown: make(chan struct{}),
+		done:     make(chan struct{}),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
 	}
+	c.lastInteraction.Store(time.Now().UnixNano())
+	return c
 }
 
+// Serve 在当前 goroutine 中读取并执行命令，直到连接关闭
 func (c *Client) Serve() {
-	logger.Infof("[%s] Client connected from %s", c.id, c.conn.RemoteAddr())
-	defer logger.Infof("[%s] Client disconnected", c.id)
-	defer c.conn.Close()
+	logger.Infof("[client-%d] Client connected from %s", c.id, c.conn.RemoteAddr())
+	defer c.release()
+
+	var r io.Reader = c.conn
+	if c.stats != nil {
+		r = countingReader{r: c.conn, count: &c.stats.netInputBytes}
+	}
+	parser := protocol.NewParser(r)
+	parser.SetLimits(c.router.ProtocolLimits())
 
 	for {
 		select {
@@ -39,42 +100,252 @@ func (c *Client) Serve() {
 		default:
 		}
 
-		cmd, err := c.parser.Parse()
+		cmd, err := parser.Parse()
 		if err != nil {
-			if err == io.EOF {
-				return
+			if err != io.EOF {
+				c.readError(err)
 			}
+			return
+		}
+
+		if err := c.handleCommand(cmd); err != nil {
+			return
+		}
+	}
+}
+
+// readError 处理读取请求时的错误
+// 与 Redis 一样，协议错误之后无法确定下一条命令从哪里开始，回复错误后由调用方关闭连接
+func (c *Client) readError(err error) {
+	if protocol.IsProtocolError(err) {
+		logger.Warnf("[client-%d] %v, closing connection", c.id, err)
+		c.sendResponse(protocol.Error("ERR " + err.Error()))
+	} else {
+		logger.Debugf("[client-%d] Read error: %v", c.id, err)
+	}
+}
+
+// handleCommand 执行一条命令并写出回复，返回错误时应关闭连接
+func (c *Client) handleCommand(cmd *protocol.Value) error {
+	logger.Debugf("[client-%d] Received command: %+v", c.id, cmd)
+	c.lastInteraction.Store(time.Now().UnixNano())
+
+	name, args, ok := splitCommand(cmd)
+	if ok && name != "MONITOR" {
+		c.feedMonitors(cmd)
+	}
 
-			logger.Errorf("[%s] Parse error: %v", c.id, err)
-			errorResp := protocol.Error(fmt.Sprintf("ERR parse error: %v", err))
-			c.sendResponse(errorResp)
-			continue
+	// SHUTDOWN 要等待正在执行的命令，不能经过命令入口
+	if ok && name == "SHUTDOWN" && !c.inMulti {
+		return c.routeLocal(cmd, func() (*protocol.Value, error) {
+			return c.handleShutdown(args)
+		})
+	}
+
+	blocking := handler.IsBlockingCommand(cmd)
+	if c.server != nil {
+		if !c.server.gate.enter(!blocking) {
+			return errShuttingDown
+		}
+		if !blocking {
+			defer c.server.gate.leave()
 		}
+	}
+
+	// 在连接层处理的命令通过 routeLocal 执行，与其他命令一样经过参数个数检查和中间件
+	var response *protocol.Value
+	if ok && c.inMulti && !isMultiCommand(name) {
+		response = c.handleMulti(name, cmd)
+	} else if ok && isMultiCommand(name) {
+		return c.routeLocal(cmd, func() (*protocol.Value, error) {
+			return c.handleMulti(name, cmd), nil
+		})
+	} else if ok && isPubSubCommand(name) {
+		return c.routeLocal(cmd, func() (*protocol.Value, error) {
+			if err := c.handlePubSub(name, args); err != nil {
+				logger.Errorf("[client-%d] Failed to send response: %v", c.id, err)
+				return nil, err
+			}
+			return nil, nil
+		})
+	} else if ok && c.subscribed() && name == "PING" {
+		return c.routeLocal(cmd, func() (*protocol.Value, error) {
+			return c.subscribedReply(name, args), nil
+		})
+	} else if ok && c.subscribed() {
+		response = c.subscribedReply(name, args)
+	} else if ok && name == "CLIENT" {
+		return c.routeLocal(cmd, func() (*protocol.Value, error) {
+			return c.handleClient(args), nil
+		})
+	} else if ok && name == "MONITOR" {
+		return c.routeLocal(cmd, func() (*protocol.Value, error) {
+			return c.handleMonitor(), nil
+		})
+	} else if blocking {
+		c.blocked.Store(true)
+		response = c.router.RouteClient(c, cmd)
+		c.blocked.Store(false)
+		c.lastInteraction.Store(time.Now().UnixNano())
+	} else {
+		response = c.router.RouteClient(c, cmd)
+	}
 
-		logger.Debugf("[%s] Received command: %+v", c.id, cmd)
+	if err := c.sendResponse(response); err != nil {
+		logger.Errorf("[client-%d] Failed to send response: %v", c.id, err)
+		return err
+	}
+	return nil
+}
 
-		response := c.router.Route(cmd)
+// routeLocal 通过 Router.RouteLocal 执行在连接层处理的命令并写出回复
+// exec 自己写出回复时返回 nil 回复；exec 返回的错误表示应关闭连接，中间件拒绝命令时 exec 不会执行
+func (c *Client) routeLocal(cmd *protocol.Value, exec func() (*protocol.Value, error)) error {
+	var execErr error
+	response := c.router.RouteLocal(c, cmd, func() *protocol.Value {
+		var resp *protocol.Value
+		resp, execErr = exec()
+		return resp
+	})
 
+	if response != nil {
 		if err := c.sendResponse(response); err != nil {
-			logger.Errorf("[%s] Failed to send response: %v", c.id, err)
-			return
+			logger.Errorf("[client-%d] Failed to send response: %v", c.id, err)
+			return err
 		}
 	}
+	return execErr
+}
+
+// release 在连接结束时清理订阅、监视和跟踪状态并关闭连接，只能调用一次
+func (c *Client) release() {
+	c.router.Tracking().Disable(c.id)
+	c.monitors.Remove(c)
+	c.unsubscribeAll()
+	close(c.done)
+	c.conn.Close()
+	logger.Infof("[client-%d] Client disconnected", c.id)
+}
+
+// ID 返回连接的唯一编号（实现 types.ClientInfo）
+func (c *Client) ID() int64 {
+	return c.id
+}
+
+// Addr 返回客户端地址
+func (c *Client) Addr() string {
+	return c.conn.RemoteAddr().String()
+}
+
+// Name 返回 CLIENT SETNAME 设置的名字
+func (c *Client) Name() string {
+	name, _ := c.name.Load().(string)
+	return name
+}
+
+// DB 返回 SELECT 选择的数据库（实现 types.Conn）
+func (c *Client) DB() int {
+	return c.db
+}
+
+func (c *Client) SetDB(index int) {
+	c.db = index
+}
+
+// User 返回连接的用户，还不支持认证，所有连接都是 default 用户
+func (c *Client) User() string {
+	return "default"
+}
+
+// ProtocolVersion 返回连接使用的 RESP 版本，目前只支持 RESP2
+func (c *Client) ProtocolVersion() int {
+	return 2
+}
+
+// InTransaction 判断是否正在执行 EXEC 排队的命令
+func (c *Client) InTransaction() bool {
+	return c.execing
+}
+
+// Push 推送一条不属于命令回复的消息，与发布订阅的消息使用同一个队列
+func (c *Client) Push(msg *protocol.Value) {
+	c.Send(msg)
+}
+
+// splitCommand 拆出命令名（大写）和参数
+func splitCommand(cmd *protocol.Value) (string, []protocol.Value, bool) {
+	if cmd.Type != protocol.ArrayType || len(cmd.Array) == 0 {
+		return "", nil, false
+	}
+	return strings.ToUpper(cmd.Array[0].Str), cmd.Array[1:], true
 }
 
 func (c *Client) sendResponse(resp *protocol.Value) error {
-	data := protocol.Serialize(resp)
-	logger.Debug(resp)
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 
-	_, err := c.conn.Write([]byte(data))
-	if err != nil {
+	return c.writeLocked(resp)
+}
+
+// writeLocked 写出一条回复，调用前需持有 writeMu
+func (c *Client) writeLocked(resp *protocol.Value) error {
+	if c.stats != nil {
+		written := c.writer.Written()
+		defer func() { c.stats.netOutputBytes.Add(c.writer.Written() - written) }()
+	}
+
+	if err := c.writer.WriteValue(resp); err != nil {
+		return err
+	}
+	if err := c.writer.Flush(); err != nil {
 		return err
 	}
 
-	logger.Debugf("[%s] Sent response: %s", c.id, data)
+	logger.Debugf("[client-%d] Sent response: %+v", c.id, resp)
 	return nil
 }
 
+// Send 异步推送一条消息（实现 pubsub.Subscriber）
+// 在发布者的 goroutine 中调用，只入队不阻塞；队列满或输出缓冲区超过限制时断开该客户端
+func (c *Client) Send(msg *protocol.Value) {
+	c.outboxOnce.Do(func() {
+		c.outbox = make(chan pushMessage, outboxSize)
+		go c.writeLoop()
+	})
+
+	size := int64(protocol.EncodedLen(msg))
+	c.queuedBytes.Add(size)
+	select {
+	case c.outbox <- pushMessage{msg: msg, size: size}:
+		if c.outputLimitReached(c.outputBufferSize()) {
+			logger.Warnf("[client-%d] Output buffer limit reached, closing slow consumer", c.id)
+			c.conn.Close()
+		}
+	default:
+		c.queuedBytes.Add(-size)
+		logger.Warnf("[client-%d] Output queue full, closing slow consumer", c.id)
+		c.conn.Close()
+	}
+}
+
+// writeLoop 把队列中的推送消息写到连接上，第一次 Send 时启动
+func (c *Client) writeLoop() {
+	for {
+		select {
+		case m := <-c.outbox:
+			err := c.sendResponse(m.msg)
+			c.queuedBytes.Add(-m.size)
+			if err != nil {
+				logger.Debugf("[client-%d] Failed to push message: %v", c.id, err)
+				c.conn.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
 func (c *Client) Close() error {
 	close(c.shutdown)
 	return c.conn.Close()
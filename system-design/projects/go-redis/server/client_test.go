@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bufio"
+	"go-redis/handler"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"go-redis/store"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func startTestServer(t *testing.T) *Server {
+	t.Helper()
+	logger.SetLevel(logrus.PanicLevel)
+
+	srv := NewServer("127.0.0.1:0", store.NewStore())
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Stop() })
+	return srv
+}
+
+// testConn 是测试用的 RESP 连接
+type testConn struct {
+	net.Conn
+	parser *protocol.Parser
+}
+
+func dialTest(tb testing.TB, addr string) *testConn {
+	tb.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &testConn{Conn: conn, parser: protocol.NewParser(bufio.NewReader(conn))}
+}
+
+func command(args ...string) string {
+	values := make([]protocol.Value, len(args))
+	for i, arg := range args {
+		values[i] = *protocol.BulkString(arg)
+	}
+	return protocol.Serialize(protocol.Array(values))
+}
+
+func (c *testConn) send(tb testing.TB, args ...string) {
+	tb.Helper()
+	if _, err := io.WriteString(c, command(args...)); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func (c *testConn) reply(tb testing.TB) *protocol.Value {
+	tb.Helper()
+	v, err := c.parser.Parse()
+	if err != nil {
+		tb.Fatalf("read reply: %v", err)
+	}
+	return v
+}
+
+func (c *testConn) do(tb testing.TB, args ...string) *protocol.Value {
+	tb.Helper()
+	c.send(tb, args...)
+	return c.reply(tb)
+}
+
+func TestProtocolErrorClosesConnection(t *testing.T) {
+	srv := startTestServer(t)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"huge multibulk", "*2147483648\r\n", "-ERR Protocol error: invalid multibulk length\r\n"},
+		{"bulk over limit", "*1\r\n$536870913\r\n", "-ERR Protocol error: invalid bulk length\r\n"},
+		{"unknown type", "?\r\n", "-ERR Protocol error: unknown RESP type: \"?\"\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", srv.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			// 协议错误之后的命令不会被执行
+			if _, err := io.WriteString(conn, tt.input+"*1\r\n$4\r\nPING\r\n"); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtocolLimitsFollowConfig(t *testing.T) {
+	srv := startTestServer(t)
+
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	io.WriteString(conn, "*4\r\n$6\r\nCONFIG\r\n$3\r\nSET\r\n$23\r\nproto-max-multibulk-len\r\n$1\r\n2\r\n")
+	if line, _ := r.ReadString('\n'); line != "+OK\r\n" {
+		t.Fatalf("CONFIG SET: %q", line)
+	}
+
+	io.WriteString(conn, "*3\r\n$3\r\nDEL\r\n$1\r\na\r\n$1\r\nb\r\n")
+	data, _ := io.ReadAll(r)
+	if !strings.Contains(string(data), "invalid multibulk length") {
+		t.Errorf("got %q after lowering proto-max-multibulk-len", data)
+	}
+}
+
+// TestMiddlewareCoversConnectionCommands 检查在连接层处理的命令同样经过中间件，被拒绝时不会执行
+func TestMiddlewareCoversConnectionCommands(t *testing.T) {
+	logger.SetLevel(logrus.PanicLevel)
+	var mu sync.Mutex
+	var seen []string
+	srv := startSetupServer(t, func(srv *Server) {
+		srv.Use(func(next handler.HandlerFunc) handler.HandlerFunc {
+			return func(cmd *handler.Command) *protocol.Value {
+				mu.Lock()
+				seen = append(seen, cmd.Name)
+				mu.Unlock()
+				if cmd.Name == "SHUTDOWN" || cmd.Name == "MONITOR" {
+					return protocol.Error("NOPERM this user has no permissions to run the '" + strings.ToLower(cmd.Name) + "' command")
+				}
+				return next(cmd)
+			}
+		})
+	})
+
+	conn := dialTest(t, srv.Addr())
+	for _, name := range []string{"SHUTDOWN", "MONITOR"} {
+		if v := conn.do(t, name); v.Type != protocol.ErrorType || !strings.HasPrefix(v.Str, "NOPERM") {
+			t.Errorf("%s = %+v, expected the middleware to reject it", name, v)
+		}
+		if s := srv.router.CommandStats(name); s == nil || s.FailedCalls.Load() != 1 {
+			t.Errorf("%s stats = %+v", name, s)
+		}
+	}
+
+	// 服务器没有关闭，连接也没有变成观察者：其他连接的命令不会出现在它上面
+	other := dialTest(t, srv.Addr())
+	if v := other.do(t, "SET", "k", "v"); v.Str != "OK" {
+		t.Fatalf("SET = %+v", v)
+	}
+	if v := conn.do(t, "PING"); v.Str != "PONG" {
+		t.Errorf("PING after rejected MONITOR = %+v", v)
+	}
+
+	conn.do(t, "MULTI")
+	conn.do(t, "GET", "k")
+	conn.do(t, "EXEC")
+	conn.do(t, "CLIENT", "ID")
+	conn.do(t, "SUBSCRIBE", "ch")
+	conn.do(t, "PING")
+	conn.do(t, "UNSUBSCRIBE")
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"SHUTDOWN", "MONITOR", "SET", "PING", "MULTI", "EXEC", "GET", "CLIENT", "SUBSCRIBE", "PING", "UNSUBSCRIBE"}
+	if strings.Join(seen, " ") != strings.Join(want, " ") {
+		t.Errorf("middleware saw %v, want %v", seen, want)
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"go-redis/protocol"
+	"go-redis/tracking"
+	"strconv"
+	"strings"
+)
+
+// CLIENT 命令读写的是连接自身的状态，因此同订阅命令一样在连接层处理
+
+// handleClient 处理 CLIENT 命令
+// CLIENT ID
+// CLIENT SETNAME name
+// CLIENT GETNAME
+// CLIENT TRACKING ON|OFF [REDIRECT id] [PREFIX prefix ...] [BCAST] [OPTIN] [OPTOUT] [NOLOOP]
+// CLIENT CACHING YES|NO
+// CLIENT GETREDIR
+// CLIENT TRACKINGINFO
+func (c *Client) handleClient(args []protocol.Value) *protocol.Value {
+	switch strings.ToUpper(args[0].Str) {
+	case "ID":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'client|id' command")
+		}
+		return protocol.Integer(c.id)
+
+	case "SETNAME":
+		if len(args) != 2 {
+			return protocol.Error("ERR wrong number of arguments for 'client|setname' command")
+		}
+		name := args[1].Str
+		// 与 Redis 一致，名字中不能包含空格和换行等字符
+		for i := 0; i < len(name); i++ {
+			if name[i] < '!' || name[i] > '~' {
+				return protocol.Error("ERR Client names cannot contain spaces, newlines or special characters.")
+			}
+		}
+		c.name.Store(name)
+		return protocol.SimpleString("OK")
+
+	case "GETNAME":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'client|getname' command")
+		}
+		name := c.Name()
+		if name == "" {
+			return protocol.NullBulkString()
+		}
+		return protocol.BulkString(name)
+
+	case "TRACKING":
+		return c.clientTracking(args[1:])
+
+	case "CACHING":
+		if len(args) != 2 {
+			return protocol.Error("ERR wrong number of arguments for 'client|caching' command")
+		}
+		var yes bool
+		switch strings.ToUpper(args[1].Str) {
+		case "YES":
+			yes = true
+		case "NO":
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+		if err := c.router.Tracking().Caching(c.id, yes); err != nil {
+			return protocol.Error(err.Error())
+		}
+		return protocol.SimpleString("OK")
+
+	case "GETREDIR":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'client|getredir' command")
+		}
+		info, ok := c.router.Tracking().Info(c.id)
+		if !ok {
+			return protocol.Integer(-1)
+		}
+		return protocol.Integer(info.Redirect)
+
+	case "TRACKINGINFO":
+		if len(args) != 1 {
+			return protocol.Error("ERR wrong number of arguments for 'client|trackinginfo' command")
+		}
+		return c.trackingInfo()
+	}
+
+	return protocol.Error("ERR unknown subcommand '" + args[0].Str + "'. Try CLIENT HELP.")
+}
+
+// clientTracking 处理 CLIENT TRACKING ON|OFF [选项...]
+func (c *Client) clientTracking(args []protocol.Value) *protocol.Value {
+	if len(args) < 1 {
+		return protocol.Error("ERR wrong number of arguments for 'client|tracking' command")
+	}
+
+	var on bool
+	switch strings.ToUpper(args[0].Str) {
+	case "ON":
+		on = true
+	case "OFF":
+	default:
+		return protocol.Error("ERR syntax error")
+	}
+
+	var opts tracking.Options
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i].Str) {
+		case "REDIRECT":
+			if i+1 >= len(args) {
+				return protocol.Error("ERR syntax error")
+			}
+			id, err := strconv.ParseInt(args[i+1].Str, 10, 64)
+			if err != nil {
+				return protocol.Error("ERR value is not an integer or out of range")
+			}
+			opts.Redirect = id
+			i++
+		case "PREFIX":
+			if i+1 >= len(args) {
+				return protocol.Error("ERR syntax error")
+			}
+			opts.Prefixes = append(opts.Prefixes, args[i+1].Str)
+			i++
+		case "BCAST":
+			opts.BCast = true
+		case "OPTIN":
+			opts.OptIn = true
+		case "OPTOUT":
+			opts.OptOut = true
+		case "NOLOOP":
+			opts.NoLoop = true
+		default:
+			return protocol.Error("ERR syntax error")
+		}
+	}
+
+	if !on {
+		c.router.Tracking().Disable(c.id)
+		return protocol.SimpleString("OK")
+	}
+
+	if err := c.router.Tracking().Enable(c.id, c, opts); err != nil {
+		return protocol.Error(err.Error())
+	}
+	return protocol.SimpleString("OK")
+}
+
+// trackingInfo 返回 CLIENT TRACKINGINFO 的回复：flags、redirect、prefixes
+func (c *Client) trackingInfo() *protocol.Value {
+	info, ok := c.router.Tracking().Info(c.id)
+
+	var flags []string
+	redirect := int64(-1)
+	if !ok {
+		flags = append(flags, "off")
+	} else {
+		flags = append(flags, "on")
+		redirect = info.Redirect
+		if info.BCast {
+			flags = append(flags, "bcast")
+		}
+		if info.OptIn {
+			flags = append(flags, "optin")
+		}
+		if info.OptOut {
+			flags = append(flags, "optout")
+		}
+		switch info.Caching {
+		case 1:
+			flags = append(flags, "caching-yes")
+		case -1:
+			flags = append(flags, "caching-no")
+		}
+		if info.NoLoop {
+			flags = append(flags, "noloop")
+		}
+	}
+
+	flagValues := make([]protocol.Value, len(flags))
+	for i, f := range flags {
+		flagValues[i] = *protocol.BulkString(f)
+	}
+	prefixValues := make([]protocol.Value, len(info.Prefixes))
+	for i, p := range info.Prefixes {
+		prefixValues[i] = *protocol.BulkString(p)
+	}
+
+	return protocol.Array([]protocol.Value{
+		*protocol.BulkString("flags"),
+		*protocol.Array(flagValues),
+		*protocol.BulkString("redirect"),
+		*protocol.Integer(redirect),
+		*protocol.BulkString("prefixes"),
+		*protocol.Array(prefixValues),
+	})
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"go-redis/handler"
+	"go-redis/logger"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 服务器层的配置参数。port、bind、io-model、event-loops、metrics-addr 只能在启动时通过配置文件或命令行设置
+
+const (
+	ioModelGoroutine = "goroutine"
+	ioModelEpoll     = "epoll"
+
+	maxEventLoops = 1024
+)
+
+// logLevels 是 loglevel 接受的取值，兼容 Redis 的 verbose/notice/warning
+var logLevels = map[string]logrus.Level{
+	"debug":   logrus.DebugLevel,
+	"verbose": logrus.DebugLevel,
+	"info":    logrus.InfoLevel,
+	"notice":  logrus.InfoLevel,
+	"warn":    logrus.WarnLevel,
+	"warning": logrus.WarnLevel,
+	"error":   logrus.ErrorLevel,
+}
+
+// Config 返回服务器的配置表，用于在启动前加载配置文件
+func (s *Server) Config() *handler.ConfigHandler {
+	return s.router.Config()
+}
+
+func (s *Server) registerConfigParams() {
+	config := s.router.Config()
+
+	config.AddParam(immutable(handler.IntParam("port", 0, 65535, s.port, s.setPort)))
+	config.AddParam(immutable(handler.StringParam("bind", s.bind, s.setBind)))
+	config.AddParam(immutable(handler.EnumParam("io-model", []string{ioModelGoroutine, ioModelEpoll},
+		func() string { return s.ioModel },
+		func(v string) { s.ioModel = v })))
+	// 0 表示与 CPU 核数相同
+	config.AddParam(immutable(handler.IntParam("event-loops", 0, maxEventLoops,
+		func() int64 { return int64(s.eventLoops) },
+		func(n int64) { s.eventLoops = int(n) })))
+
+	// 指标 HTTP 服务的监听地址，例如 ":9121"，为空时不启动
+	config.AddParam(immutable(handler.StringParam("metrics-addr",
+		func() string { return s.metricsAddr },
+		func(v string) error { s.metricsAddr = v; return nil })))
+
+	config.AddParam(handler.EnumParam("loglevel", []string{"debug", "verbose", "info", "notice", "warn", "warning", "error"},
+		func() string { return logger.GetLevel().String() },
+		func(v string) { logger.SetLevel(logLevels[v]) }))
+
+	config.AddParam(atomicIntParam("timeout", 0, &s.limits.timeout))
+	config.AddParam(atomicIntParam("tcp-keepalive", 0, &s.limits.keepAlive))
+	config.AddParam(atomicIntParam("maxclients", 1, &s.limits.maxClients))
+	config.AddParam(atomicIntParam("shutdown-timeout", 0, &s.limits.shutdownTimeout))
+	config.AddParam(handler.StringParam("client-output-buffer-limit",
+		s.limits.outputLimitsString, s.limits.setOutputLimits))
+}
+
+func immutable(p *handler.ConfigParam) *handler.ConfigParam {
+	p.Immutable = true
+	return p
+}
+
+// atomicIntParam 返回取值范围为 [min, math.MaxInt32] 的整数参数
+func atomicIntParam(name string, min int64, v *atomic.Int64) *handler.ConfigParam {
+	return handler.IntParam(name, min, math.MaxInt32, v.Load, v.Store)
+}
+
+// port 返回监听地址中的端口
+func (s *Server) port() int64 {
+	_, port, _ := net.SplitHostPort(s.addr)
+	n, _ := strconv.ParseInt(port, 10, 64)
+	return n
+}
+
+func (s *Server) setPort(n int64) {
+	host, _, _ := net.SplitHostPort(s.addr)
+	s.addr = net.JoinHostPort(host, strconv.FormatInt(n, 10))
+}
+
+// bind 返回监听的地址，"*" 表示所有地址
+func (s *Server) bind() string {
+	host, _, _ := net.SplitHostPort(s.addr)
+	if host == "" {
+		return "*"
+	}
+	return host
+}
+
+func (s *Server) setBind(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 1 {
+		return errors.New("only one bind address is supported")
+	}
+
+	host := fields[0]
+	if host == "*" {
+		host = ""
+	}
+	s.addr = net.JoinHostPort(host, strconv.FormatInt(s.port(), 10))
+	return nil
+}
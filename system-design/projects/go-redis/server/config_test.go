@@ -0,0 +1,62 @@
+package server
+
+import (
+	"go-redis/logger"
+	"go-redis/store"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestServerConfigFile(t *testing.T) {
+	logger.SetLevel(logrus.PanicLevel)
+	path := filepath.Join(t.TempDir(), "redis.conf")
+	if err := os.WriteFile(path, []byte("bind 127.0.0.1\nport 0\nmaxclients 50\ntimeout 0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(":16379", store.NewStore())
+	if err := srv.Config().Load(path, []string{`maxclients "100"`}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.addr != "127.0.0.1:0" {
+		t.Fatalf("addr = %q, want 127.0.0.1:0", srv.addr)
+	}
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Stop() })
+
+	conn := dialTest(t, srv.Addr())
+	if v := conn.do(t, "CONFIG", "GET", "maxclients"); len(v.Array) != 2 || v.Array[1].Str != "100" {
+		t.Fatalf("CONFIG GET maxclients: %+v", v)
+	}
+	if v := conn.do(t, "CONFIG", "SET", "port", "7000"); !strings.Contains(v.Str, "can't set immutable config") {
+		t.Fatalf("CONFIG SET port: %+v", v)
+	}
+	if v := conn.do(t, "CONFIG", "SET", "loglevel", "notice"); v.Str != "OK" {
+		t.Fatalf("CONFIG SET loglevel: %+v", v)
+	}
+	if v := conn.do(t, "CONFIG", "GET", "loglevel"); len(v.Array) != 2 || v.Array[1].Str != "info" {
+		t.Fatalf("CONFIG GET loglevel: %+v", v)
+	}
+	logger.SetLevel(logrus.PanicLevel)
+
+	if v := conn.do(t, "CONFIG", "SET", "maxclients", "200"); v.Str != "OK" {
+		t.Fatalf("CONFIG SET maxclients: %+v", v)
+	}
+	if v := conn.do(t, "CONFIG", "REWRITE"); v.Str != "OK" {
+		t.Fatalf("CONFIG REWRITE: %+v", v)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "bind 127.0.0.1\nport 0\nmaxclients 200\ntimeout 0\n"; string(data) != want {
+		t.Errorf("rewritten config:\n%s\nwant:\n%s", data, want)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"go-redis/protocol"
+	"go-redis/types"
+	"strconv"
+	"testing"
+)
+
+// connInfoHandler 先向连接推送一条消息，再回复连接的编号、数据库和是否在事务中
+type connInfoHandler struct{}
+
+func (connInfoHandler) HandleContext(conn types.Conn, args []protocol.Value) *protocol.Value {
+	conn.Push(protocol.Array([]protocol.Value{*protocol.BulkString("notice"), *protocol.BulkString("hello")}))
+	inTx := "0"
+	if conn.InTransaction() {
+		inTx = "1"
+	}
+	return protocol.Array([]protocol.Value{
+		*protocol.BulkString(strconv.FormatInt(conn.ID(), 10)),
+		*protocol.BulkString(strconv.Itoa(conn.DB())),
+		*protocol.BulkString(inTx),
+	})
+}
+
+func TestConnContext(t *testing.T) {
+	srv := startSetupServer(t, func(srv *Server) {
+		srv.router.RegisterContext("CONNINFO", connInfoHandler{})
+	})
+	c := dialTest(t, srv.Addr())
+	defer c.Close()
+
+	id := c.do(t, "CLIENT", "ID")
+	if resp := c.do(t, "SELECT", "0"); resp.Str != "OK" {
+		t.Fatalf("SELECT 0 = %+v", resp)
+	}
+
+	c.send(t, "CONNINFO")
+	resp := replyAfterPush(t, c)
+	if len(resp.Array) != 3 || resp.Array[0].Str != strconv.FormatInt(id.Int, 10) || resp.Array[2].Str != "0" {
+		t.Fatalf("CONNINFO = %+v, client id %d", resp, id.Int)
+	}
+
+	c.do(t, "MULTI")
+	c.do(t, "CONNINFO")
+	c.send(t, "EXEC")
+	exec := replyAfterPush(t, c)
+	if len(exec.Array) != 1 || len(exec.Array[0].Array) != 3 || exec.Array[0].Array[2].Str != "1" {
+		t.Fatalf("EXEC = %+v, want CONNINFO in transaction", exec)
+	}
+}
+
+// replyAfterPush 读取一条推送消息和一条命令回复，返回命令回复，推送与回复的先后顺序不确定
+func replyAfterPush(t *testing.T, c *testConn) *protocol.Value {
+	t.Helper()
+	first, second := c.reply(t), c.reply(t)
+	isPush := func(v *protocol.Value) bool {
+		return len(v.Array) == 2 && v.Array[0].Str == "notice" && v.Array[1].Str == "hello"
+	}
+	switch {
+	case isPush(first):
+		return second
+	case isPush(second):
+		return first
+	}
+	t.Fatalf("no push message in %+v and %+v", first, second)
+	return nil
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// redisVersion 是 INFO 中报告的兼容版本，部分客户端据此判断支持的命令
+const redisVersion = "7.2.0"
+
+// INFO 中 server 和 clients 两节的信息只有服务器层知道，由这里提供给 Router
+
+func (s *Server) serverInfo() []string {
+	port := ""
+	if _, p, err := net.SplitHostPort(s.Addr()); err == nil {
+		port = p
+	}
+	uptime := int64(time.Since(s.startTime).Seconds())
+	mode := "standalone"
+	if s.sentinel != nil {
+		mode = "sentinel"
+	}
+
+	return []string{
+		"redis_version:" + redisVersion,
+		"redis_mode:" + mode,
+		"os:" + runtime.GOOS,
+		"arch_bits:" + strconv.Itoa(strconv.IntSize),
+		"go_version:" + runtime.Version(),
+		"process_id:" + strconv.Itoa(os.Getpid()),
+		"run_id:" + s.runID,
+		"tcp_port:" + port,
+		"uptime_in_seconds:" + strconv.FormatInt(uptime, 10),
+		"uptime_in_days:" + strconv.FormatInt(uptime/86400, 10),
+	}
+}
+
+// newRunID 生成每次启动都不同的运行 ID，与 Redis 相同是 40 个十六进制字符
+func newRunID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Server) clientsInfo() []string {
+	return []string{
+		"connected_clients:" + strconv.FormatInt(atomic.LoadInt64(&s.numClients), 10),
+		"maxclients:" + strconv.FormatInt(s.limits.maxClients.Load(), 10),
+	}
+}
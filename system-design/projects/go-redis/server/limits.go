@@ -0,0 +1,249 @@
+package server
+
+import (
+	"errors"
+	"go-redis/handler"
+	"go-redis/logger"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 连接限制，与 Redis 的同名配置相同，可以通过 CONFIG GET/SET 修改：
+//   - timeout：客户端空闲超过指定秒数后断开，订阅、MONITOR 和阻塞中的客户端除外
+//   - tcp-keepalive：TCP keepalive 的间隔秒数，对之后建立的连接生效
+//   - maxclients：最大连接数，超过时新连接收到错误后被关闭
+//   - client-output-buffer-limit：各类客户端的输出缓冲区限制，
+//     超过硬限制，或持续超过软限制达到指定秒数时断开，避免消费跟不上的客户端耗尽内存
+
+const (
+	defaultTCPKeepAlive = 300
+	defaultMaxClients   = 10000
+
+	// cronInterval 是检查空闲连接的间隔
+	cronInterval = 250 * time.Millisecond
+)
+
+// errOutputLimit 表示客户端的输出缓冲区超过了限制
+var errOutputLimit = errors.New("output buffer limit reached")
+
+// clientClass 是输出缓冲区限制区分的客户端类别
+type clientClass int
+
+const (
+	classNormal clientClass = iota
+	classReplica
+	classPubSub
+	numClientClasses
+)
+
+// clientClassNames 是 CONFIG 中各类别的名字，replica 也可以写作 slave
+var clientClassNames = [numClientClasses]string{"normal", "slave", "pubsub"}
+
+// outputLimit 是一类客户端的输出缓冲区限制，各项为 0 表示不限制
+type outputLimit struct {
+	hard        int64
+	soft        int64
+	softSeconds int64
+}
+
+type connLimits struct {
+	timeout    atomic.Int64 // 空闲多少秒后断开，0 表示不断开
+	keepAlive  atomic.Int64 // TCP keepalive 的间隔秒数，0 表示关闭
+	maxClients atomic.Int64
+	// shutdownTimeout 是关闭时等待正在执行的命令的最长秒数
+	shutdownTimeout atomic.Int64
+
+	mu     sync.RWMutex
+	output [numClientClasses]outputLimit
+}
+
+func newConnLimits() *connLimits {
+	l := &connLimits{
+		output: [numClientClasses]outputLimit{
+			classNormal:  {},
+			classReplica: {hard: 256 << 20, soft: 64 << 20, softSeconds: 60},
+			classPubSub:  {hard: 32 << 20, soft: 8 << 20, softSeconds: 60},
+		},
+	}
+	l.keepAlive.Store(defaultTCPKeepAlive)
+	l.maxClients.Store(defaultMaxClients)
+	l.shutdownTimeout.Store(defaultShutdownTimeout)
+	return l
+}
+
+func (l *connLimits) outputLimit(class clientClass) outputLimit {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.output[class]
+}
+
+// outputLimitsString 按 CONFIG GET 的格式返回输出缓冲区限制
+func (l *connLimits) outputLimitsString() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	parts := make([]string, 0, 4*numClientClasses)
+	for class, limit := range l.output {
+		parts = append(parts,
+			clientClassNames[class],
+			strconv.FormatInt(limit.hard, 10),
+			strconv.FormatInt(limit.soft, 10),
+			strconv.FormatInt(limit.softSeconds, 10),
+		)
+	}
+	return strings.Join(parts, " ")
+}
+
+// setOutputLimits 解析 "<class> <hard> <soft> <soft seconds> ..."，只修改出现的类别
+func (l *connLimits) setOutputLimits(value string) error {
+	fields := strings.Fields(value)
+	if len(fields)%4 != 0 {
+		return errors.New("Wrong number of arguments in buffer limit configuration.")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	output := l.output
+	for i := 0; i < len(fields); i += 4 {
+		class, ok := parseClientClass(fields[i])
+		if !ok {
+			return errors.New("Invalid client class specified in buffer limit configuration.")
+		}
+		hard, err1 := handler.ParseMemory(fields[i+1])
+		soft, err2 := handler.ParseMemory(fields[i+2])
+		seconds, err3 := strconv.ParseInt(fields[i+3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || hard < 0 || soft < 0 || seconds < 0 {
+			return errors.New("Error in hard, soft or soft_seconds setting in buffer limit configuration.")
+		}
+		output[class] = outputLimit{hard: hard, soft: soft, softSeconds: seconds}
+	}
+	l.output = output
+	return nil
+}
+
+func parseClientClass(name string) (clientClass, bool) {
+	switch strings.ToLower(name) {
+	case "normal":
+		return classNormal, true
+	case "replica", "slave":
+		return classReplica, true
+	case "pubsub":
+		return classPubSub, true
+	}
+	return 0, false
+}
+
+// acceptConn 配置新连接的 keepalive 并检查最大连接数，返回 false 表示连接已被拒绝并关闭
+func (s *Server) acceptConn(conn net.Conn) bool {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		setKeepAlive(tcpConn, s.limits.keepAlive.Load())
+	}
+
+	if atomic.LoadInt64(&s.numClients) >= s.limits.maxClients.Load() {
+		logger.Warnf("Rejected connection from %s: max number of clients reached", conn.RemoteAddr())
+		io.WriteString(conn, "-ERR max number of clients reached\r\n")
+		conn.Close()
+		return false
+	}
+	return true
+}
+
+// setKeepAlive 与 Redis 的 anetKeepAlive 相同：空闲 interval 秒后开始探测，
+// 每 interval/3 秒探测一次，连续 3 次没有响应时断开
+func setKeepAlive(conn *net.TCPConn, interval int64) {
+	if interval <= 0 {
+		conn.SetKeepAlive(false)
+		return
+	}
+
+	idle := time.Duration(interval) * time.Second
+	conn.SetKeepAliveConfig(net.KeepAliveConfig{
+		Enable:   true,
+		Idle:     idle,
+		Interval: max(idle/3, time.Second),
+		Count:    3,
+	})
+}
+
+// clientsCron 定期断开空闲超过 timeout 的连接，直到服务器停止
+func (s *Server) clientsCron() {
+	ticker := time.NewTicker(cronInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case now := <-ticker.C:
+			timeout := s.limits.timeout.Load()
+			if timeout <= 0 {
+				continue
+			}
+			s.clients.Range(func(_, value interface{}) bool {
+				client := value.(*Client)
+				if client.idleTimedOut(now, time.Duration(timeout)*time.Second) {
+					logger.Infof("[client-%d] Closing idle client", client.id)
+					client.conn.Close()
+				}
+				return true
+			})
+		}
+	}
+}
+
+// idleTimedOut 判断客户端是否空闲超时
+// 与 Redis 一样，订阅模式、MONITOR 和阻塞在命令上的客户端不会因空闲被断开
+func (c *Client) idleTimedOut(now time.Time, timeout time.Duration) bool {
+	if c.blocked.Load() || c.inPubSub.Load() || c.monitoring.Load() {
+		return false
+	}
+	return now.Sub(time.Unix(0, c.lastInteraction.Load())) > timeout
+}
+
+// outputLimitReached 判断输出缓冲区中 used 字节的待写数据是否超过了客户端所属类别的限制
+// 超过硬限制立即返回 true；超过软限制时记录开始时间，持续超过 softSeconds 秒后返回 true
+func (c *Client) outputLimitReached(used int64) bool {
+	if c.limits == nil {
+		return false
+	}
+
+	class := classNormal
+	if c.inPubSub.Load() {
+		class = classPubSub
+	}
+	limit := c.limits.outputLimit(class)
+
+	hard := limit.hard > 0 && used >= limit.hard
+	if limit.soft <= 0 || used < limit.soft {
+		c.softLimitSince.Store(0)
+		return hard
+	}
+
+	now := time.Now().UnixNano()
+	since := c.softLimitSince.Load()
+	if since == 0 {
+		c.softLimitSince.CompareAndSwap(0, now)
+		return hard
+	}
+	return hard || now-since > limit.softSeconds*int64(time.Second)
+}
+
+// bufferedConn 是自己缓存待写数据的连接（事件循环模式），输出缓冲区的大小包括这部分数据
+type bufferedConn interface {
+	pendingOutput() int64
+}
+
+// outputBufferSize 返回还没有写到 socket 的数据量
+func (c *Client) outputBufferSize() int64 {
+	n := c.queuedBytes.Load()
+	if bc, ok := c.conn.(bufferedConn); ok {
+		n += bc.pendingOutput()
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxClients(t *testing.T) {
+	srv := startTestServer(t)
+	admin := dialTest(t, srv.Addr())
+
+	if v := admin.do(t, "CONFIG", "SET", "maxclients", "1"); v.Str != "OK" {
+		t.Fatalf("CONFIG SET maxclients: %+v", v)
+	}
+
+	conn := dialTest(t, srv.Addr())
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-ERR max number of clients reached\r\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+
+	// 已有的连接不受影响
+	if v := admin.do(t, "PING"); v.Str != "PONG" {
+		t.Fatalf("PING: %+v", v)
+	}
+	if v := admin.do(t, "CONFIG", "SET", "maxclients", "0"); !strings.Contains(v.Str, "argument must be between 1 and") {
+		t.Fatalf("CONFIG SET maxclients 0: %+v", v)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	srv := startTestServer(t)
+	idle := dialTest(t, srv.Addr())
+	sub := dialTest(t, srv.Addr())
+
+	sub.do(t, "SUBSCRIBE", "news")
+	if v := idle.do(t, "CONFIG", "SET", "timeout", "1"); v.Str != "OK" {
+		t.Fatalf("CONFIG SET timeout: %+v", v)
+	}
+
+	start := time.Now()
+	if _, err := idle.parser.Parse(); err == nil {
+		t.Fatal("expected idle client to be closed")
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("idle client closed after %v, before timeout", elapsed)
+	}
+
+	// 订阅模式下的客户端不会因空闲被断开
+	pub := dialTest(t, srv.Addr())
+	if v := pub.do(t, "PUBLISH", "news", "hello"); v.Int != 1 {
+		t.Fatalf("PUBLISH: %+v", v)
+	}
+	if v := sub.reply(t); len(v.Array) != 3 || v.Array[2].Str != "hello" {
+		t.Fatalf("message: %+v", v)
+	}
+}
+
+func TestOutputBufferLimitConfig(t *testing.T) {
+	srv := startTestServer(t)
+	conn := dialTest(t, srv.Addr())
+
+	v := conn.do(t, "CONFIG", "GET", "client-output-buffer-limit")
+	want := "normal 0 0 0 slave 268435456 67108864 60 pubsub 33554432 8388608 60"
+	if len(v.Array) != 2 || v.Array[1].Str != want {
+		t.Fatalf("CONFIG GET: %+v", v)
+	}
+
+	if v := conn.do(t, "CONFIG", "SET", "client-output-buffer-limit", "normal 1mb 512kb 10 replica 0 0 0"); v.Str != "OK" {
+		t.Fatalf("CONFIG SET: %+v", v)
+	}
+	v = conn.do(t, "CONFIG", "GET", "client-output-buffer-limit")
+	want = "normal 1048576 524288 10 slave 0 0 0 pubsub 33554432 8388608 60"
+	if v.Array[1].Str != want {
+		t.Fatalf("CONFIG GET after SET: %q, want %q", v.Array[1].Str, want)
+	}
+
+	for _, value := range []string{"normal 1 2", "master 0 0 0", "normal 1x 0 0", "pubsub 0 0 -1"} {
+		if v := conn.do(t, "CONFIG", "SET", "client-output-buffer-limit", value); !strings.HasPrefix(v.Str, "ERR CONFIG SET failed") {
+			t.Errorf("CONFIG SET %q: %+v", value, v)
+		}
+	}
+}
+
+func TestPubSubOutputBufferLimit(t *testing.T) {
+	srv := startTestServer(t)
+	sub := dialTest(t, srv.Addr())
+	pub := dialTest(t, srv.Addr())
+
+	pub.do(t, "CONFIG", "SET", "client-output-buffer-limit", "pubsub 256kb 0 0")
+	sub.do(t, "SUBSCRIBE", "news")
+
+	// 订阅者不读取，socket 缓冲区写满后消息堆积在输出队列中，超过限制时被断开
+	msg := strings.Repeat("x", 64*1024)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if pub.do(t, "PUBLISH", "news", msg).Int == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("slow subscriber was not disconnected")
+		}
+	}
+}
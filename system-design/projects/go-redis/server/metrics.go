@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"go-redis/logger"
+	"go-redis/metrics"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Prometheus 指标
+//
+// 配置了 metrics-addr 时在该地址上启动 HTTP 服务，/metrics 按 Prometheus 文本格式输出：
+// 连接数、网络流量和持久化状态由服务器统计，命令、耗时直方图和键空间由 Router 统计。
+// 所有计数都是原子变量，采集时不会阻塞命令的执行
+
+// serverStats 是服务器层的统计
+type serverStats struct {
+	netInputBytes  atomic.Int64
+	netOutputBytes atomic.Int64
+
+	lastSaveTime atomic.Int64 // 最近一次保存数据的时间（Unix 秒），0 表示没有保存过
+	lastSaveErr  atomic.Bool  // 最近一次保存是否失败
+}
+
+// countingReader 统计从连接读取的字节数
+type countingReader struct {
+	r     io.Reader
+	count *atomic.Int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+// MetricsHandler 返回输出 Prometheus 指标的 HTTP 处理器
+func (s *Server) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mw := metrics.NewWriter()
+		s.writeMetrics(mw)
+		s.router.WriteMetrics(mw)
+
+		w.Header().Set("Content-Type", metrics.ContentType)
+		mw.WriteTo(w)
+	})
+}
+
+func (s *Server) writeMetrics(w *metrics.Writer) {
+	w.Gauge("redis_uptime_in_seconds", "Number of seconds since the server started",
+		time.Since(s.startTime).Seconds())
+	w.Gauge("redis_connected_clients", "Number of client connections",
+		float64(atomic.LoadInt64(&s.numClients)))
+	w.Gauge("redis_max_clients", "Maximum number of client connections",
+		float64(s.limits.maxClients.Load()))
+	w.Counter("redis_net_input_bytes_total", "Total number of bytes read from the network",
+		float64(s.stats.netInputBytes.Load()))
+	w.Counter("redis_net_output_bytes_total", "Total number of bytes written to the network",
+		float64(s.stats.netOutputBytes.Load()))
+
+	enabled, status := 0.0, 1.0
+	if s.persister != nil {
+		enabled = 1
+	}
+	if s.stats.lastSaveErr.Load() {
+		status = 0
+	}
+	w.Gauge("redis_persistence_enabled", "Whether a persistence module is configured", enabled)
+	w.Gauge("redis_rdb_last_save_timestamp_seconds", "Time of the last successful or failed save",
+		float64(s.stats.lastSaveTime.Load()))
+	w.Gauge("redis_rdb_last_bgsave_status", "Whether the last save succeeded (1) or failed (0)", status)
+}
+
+// listenMetrics 监听 metrics-addr，没有配置时什么也不做
+func (s *Server) listenMetrics() error {
+	if s.metricsAddr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.metricsAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s for metrics: %w", s.metricsAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.MetricsHandler())
+	s.metricsListener = listener
+	s.metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger.Infof("Serving metrics on http://%s/metrics", listener.Addr())
+	return nil
+}
+
+// serveMetrics 在后台处理指标请求，直到 Stop 关闭 HTTP 服务
+func (s *Server) serveMetrics() {
+	if s.metricsServer == nil {
+		return
+	}
+	go func() {
+		if err := s.metricsServer.Serve(s.metricsListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("Metrics server error: %v", err)
+		}
+	}()
+}
+
+// MetricsAddr 返回指标 HTTP 服务实际监听的地址，没有启动时返回空字符串
+func (s *Server) MetricsAddr() string {
+	if s.metricsListener == nil {
+		return ""
+	}
+	return s.metricsListener.Addr().String()
+}
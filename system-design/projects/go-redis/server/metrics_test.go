@@ -0,0 +1,98 @@
+package server
+
+import (
+	"go-redis/logger"
+	"go-redis/metrics"
+	"go-redis/store"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func scrapeMetrics(t *testing.T, addr string) string {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// metricValue 返回样本行的值，没有找到时测试失败
+func metricValue(t *testing.T, body, series string) float64 {
+	t.Helper()
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(series) + ` (\S+)$`)
+	m := re.FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("series %s not found in:\n%s", series, body)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	logger.SetLevel(logrus.PanicLevel)
+	srv := NewServer("127.0.0.1:0", store.NewStore())
+	if err := srv.Config().Load("", []string{"metrics-addr 127.0.0.1:0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Stop() })
+
+	conn := dialTest(t, srv.Addr())
+	conn.do(t, "SET", "a", "1")
+	conn.do(t, "SET", "b", "2")
+	conn.do(t, "EXPIRE", "b", "100")
+	conn.do(t, "GET", "a")
+	conn.do(t, "INCR", "nope", "extra")
+
+	body := scrapeMetrics(t, srv.MetricsAddr())
+	for series, want := range map[string]float64{
+		`redis_connected_clients`:                                 1,
+		`redis_commands_total{cmd="set"}`:                         2,
+		`redis_commands_total{cmd="expire"}`:                      1,
+		`redis_commands_total{cmd="get"}`:                         1,
+		`redis_commands_rejected_calls_total{cmd="incr"}`:         1,
+		`redis_command_duration_seconds_count{cmd="set"}`:         2,
+		`redis_command_duration_seconds_bucket{cmd="set",le="1"}`: 2,
+		`redis_db_keys{db="db0"}`:                                 2,
+		`redis_db_keys_expiring{db="db0"}`:                        1,
+		`redis_persistence_enabled`:                               0,
+	} {
+		if got := metricValue(t, body, series); got != want {
+			t.Errorf("%s = %v, want %v", series, got, want)
+		}
+	}
+
+	// 所有命令的请求和回复都计入网络流量
+	in := metricValue(t, body, "redis_net_input_bytes_total")
+	out := metricValue(t, body, "redis_net_output_bytes_total")
+	wantIn := len(command("SET", "a", "1")) + len(command("SET", "b", "2")) + len(command("EXPIRE", "b", "100")) +
+		len(command("GET", "a")) + len(command("INCR", "nope", "extra"))
+	if in != float64(wantIn) || out <= 0 {
+		t.Errorf("net input %v (want %d), output %v", in, wantIn, out)
+	}
+
+	if !strings.Contains(body, "# TYPE redis_command_duration_seconds histogram\n") {
+		t.Error("missing histogram TYPE line")
+	}
+}
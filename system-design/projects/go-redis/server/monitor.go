@@ -0,0 +1,48 @@
+package server
+
+import (
+	"go-redis/protocol"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MONITOR 把连接变成命令流的观察者，同订阅命令一样在连接层处理
+// 观察者通过 Client.Send 异步接收消息，消费跟不上时被断开，不会拖慢被观察的客户端
+
+// handleMonitor 处理 MONITOR 命令
+func (c *Client) handleMonitor() *protocol.Value {
+	c.monitors.Add(c)
+	c.monitoring.Store(true)
+	return protocol.SimpleString("OK")
+}
+
+// feedMonitors 把即将执行的命令发送给所有观察者
+// 格式与 Redis 相同：1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+func (c *Client) feedMonitors(cmd *protocol.Value) {
+	if c.monitors.Len() == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	var b strings.Builder
+	b.WriteString(strconv.FormatInt(now.Unix(), 10))
+	b.WriteByte('.')
+	usec := strconv.Itoa(now.Nanosecond() / 1000)
+	b.WriteString(strings.Repeat("0", 6-len(usec)))
+	b.WriteString(usec)
+	b.WriteString(" [0 ")
+	b.WriteString(c.Addr())
+	b.WriteByte(']')
+	for _, arg := range cmd.Array {
+		b.WriteByte(' ')
+		if arg.Type == protocol.IntType {
+			b.WriteString(protocol.Quote(strconv.FormatInt(arg.Int, 10)))
+		} else {
+			b.WriteString(protocol.Quote(arg.Str))
+		}
+	}
+
+	c.monitors.Broadcast(protocol.SimpleString(b.String()), nil)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"go-redis/protocol"
+	"strings"
+)
+
+// 事务（MULTI/EXEC/DISCARD）需要在连接上暂存命令，因此在连接层处理，
+// 真正的执行交给 Router.Exec，由它保证执行期间不被其他命令穿插
+
+func isMultiCommand(name string) bool {
+	switch name {
+	case "MULTI", "EXEC", "DISCARD":
+		return true
+	}
+	return false
+}
+
+// handleMulti 处理事务相关命令，以及事务中需要排队的命令
+func (c *Client) handleMulti(name string, cmd *protocol.Value) *protocol.Value {
+	switch name {
+	case "MULTI":
+		if c.inMulti {
+			return protocol.Error("ERR MULTI calls can not be nested")
+		}
+		c.inMulti = true
+		return protocol.SimpleString("OK")
+
+	case "EXEC":
+		if !c.inMulti {
+			return protocol.Error("ERR EXEC without MULTI")
+		}
+		queued, dirty := c.queued, c.multiDirty
+		c.resetMulti()
+		if dirty {
+			return protocol.Error("EXECABORT Transaction discarded because of previous errors.")
+		}
+		c.execing = true
+		defer func() { c.execing = false }()
+		return c.router.Exec(c, queued)
+
+	case "DISCARD":
+		if !c.inMulti {
+			return protocol.Error("ERR DISCARD without MULTI")
+		}
+		c.resetMulti()
+		return protocol.SimpleString("OK")
+	}
+
+	// 命令表中带 no_multi 标志的命令（订阅、MONITOR、SHUTDOWN 等连接级命令）在入队时拒绝，不会等到 EXEC
+	if c.router.NoMulti(name) {
+		c.multiDirty = true
+		return protocol.Error("ERR Command not allowed inside a transaction")
+	}
+	if !c.router.HasCommand(name) {
+		c.multiDirty = true
+		return protocol.Error("ERR unknown command '" + strings.ToLower(name) + "'")
+	}
+	// 与 Redis 一样，参数个数错误的命令在入队时就拒绝，EXEC 时放弃整个事务
+	if err := c.router.CheckArity(cmd); err != nil {
+		c.multiDirty = true
+		return err
+	}
+
+	c.queued = append(c.queued, cmd)
+	return protocol.SimpleString("QUEUED")
+}
+
+// resetMulti 退出事务状态
+func (c *Client) resetMulti() {
+	c.inMulti = false
+	c.multiDirty = false
+	c.queued = nil
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"go-redis/protocol"
+	"sort"
+	"strings"
+)
+
+// 订阅相关命令需要修改连接自身的状态（订阅了哪些频道、是否处于订阅模式），
+// 因此在连接层处理，而不是交给注册的处理器；参数检查和中间件仍由 Router.RouteLocal 完成
+
+func isPubSubCommand(name string) bool {
+	switch name {
+	case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE":
+		return true
+	}
+	return false
+}
+
+// subscribed 判断连接是否处于订阅模式
+func (c *Client) subscribed() bool {
+	return len(c.channels)+len(c.patterns) > 0
+}
+
+// subscriptionCount 返回当前订阅的频道和模式总数
+func (c *Client) subscriptionCount() int64 {
+	return int64(len(c.channels) + len(c.patterns))
+}
+
+// subscribedReply 处理订阅模式下的其他命令，除订阅命令外只允许 PING
+func (c *Client) subscribedReply(name string, args []protocol.Value) *protocol.Value {
+	if name == "PING" {
+		msg := ""
+		if len(args) > 0 {
+			msg = args[0].Str
+		}
+		return protocol.Array([]protocol.Value{
+			*protocol.BulkString("pong"),
+			*protocol.BulkString(msg),
+		})
+	}
+
+	return protocol.Error("ERR Can't execute '" + strings.ToLower(name) +
+		"': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context")
+}
+
+// handlePubSub 执行 (P)SUBSCRIBE / (P)UNSUBSCRIBE，为每个频道或模式回复一条确认
+// 修改订阅和写出确认都在 writeMu 内完成，保证确认先于该频道上的第一条消息到达
+func (c *Client) handlePubSub(name string, args []protocol.Value) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	kind := strings.ToLower(name)
+
+	var names []string
+	for _, arg := range args {
+		names = append(names, arg.Str)
+	}
+
+	// 不带参数的 UNSUBSCRIBE/PUNSUBSCRIBE 取消全部订阅
+	if len(names) == 0 {
+		set := c.channels
+		if name == "PUNSUBSCRIBE" {
+			set = c.patterns
+		}
+		for n := range set {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		if len(names) == 0 {
+			return c.writeLocked(protocol.Array([]protocol.Value{
+				*protocol.BulkString(kind),
+				*protocol.NullBulkString(),
+				*protocol.Integer(c.subscriptionCount()),
+			}))
+		}
+	}
+
+	for _, n := range names {
+		switch name {
+		case "SUBSCRIBE":
+			c.hub.Subscribe(c, n)
+			c.channels[n] = struct{}{}
+		case "UNSUBSCRIBE":
+			c.hub.Unsubscribe(c, n)
+			delete(c.channels, n)
+		case "PSUBSCRIBE":
+			c.hub.PSubscribe(c, n)
+			c.patterns[n] = struct{}{}
+		case "PUNSUBSCRIBE":
+			c.hub.PUnsubscribe(c, n)
+			delete(c.patterns, n)
+		}
+		c.inPubSub.Store(c.subscribed())
+
+		err := c.writeLocked(protocol.Array([]protocol.Value{
+			*protocol.BulkString(kind),
+			*protocol.BulkString(n),
+			*protocol.Integer(c.subscriptionCount()),
+		}))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unsubscribeAll 在连接断开时清理全部订阅
+func (c *Client) unsubscribeAll() {
+	for ch := range c.channels {
+		c.hub.Unsubscribe(c, ch)
+	}
+	for p := range c.patterns {
+		c.hub.PUnsubscribe(c, p)
+	}
+}
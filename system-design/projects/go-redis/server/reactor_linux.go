@@ -0,0 +1,557 @@
+//go:build linux
+
+package server
+
+import (
+	"errors"
+	"go-redis/handler"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// epoll 事件循环模式
+//
+// 默认模式下每个连接占用一个 goroutine 和一个读缓冲区，空闲连接很多时内存随连接数线性增长。
+// 事件循环模式下连接只是注册在少数几个 epoll 实例上的非阻塞 fd：
+// 每个事件循环 goroutine 等待自己负责的连接可读，把数据读进连接自己的缓冲区，
+// 解析出完整的命令后在循环中直接交给 Router 执行。空闲连接没有 goroutine，
+// 读写缓冲区也只在有数据时才占用。
+//
+// 命令的执行逻辑与默认模式相同（Client.handleCommand），连接通过实现 net.Conn 的 reactorConn 接入：
+// 回复先尝试直接写 fd，写不完的部分缓存起来，注册 EPOLLOUT 后由事件循环继续写出。
+// XREAD BLOCK 等可能阻塞的命令放到单独的 goroutine 中执行，期间暂停处理该连接后续的命令，
+// 不会阻塞事件循环上的其他连接。暂停期间也不再关注连接可读，客户端继续发送的数据留在 socket 缓冲区中，
+// 由 TCP 流量控制挡住，不会在服务器上无限堆积。
+
+const (
+	// readBufferSize 是事件循环每次从连接读取的最大字节数
+	readBufferSize = 64 * 1024
+	// maxEvents 是每次 epoll_wait 返回的最大事件数
+	maxEvents = 256
+)
+
+type reactor struct {
+	loops []*eventLoop
+	next  atomic.Uint64
+}
+
+func newReactor(n int, limits *protocol.Limits) (*reactor, error) {
+	r := &reactor{}
+	for range n {
+		l, err := newEventLoop(limits)
+		if err != nil {
+			r.stop()
+			return nil, err
+		}
+		r.loops = append(r.loops, l)
+		go l.run()
+	}
+	return r, nil
+}
+
+// pick 按轮询选择一个事件循环
+func (r *reactor) pick() *eventLoop {
+	return r.loops[r.next.Add(1)%uint64(len(r.loops))]
+}
+
+func (r *reactor) stop() {
+	for _, l := range r.loops {
+		l.stop()
+	}
+}
+
+type eventLoop struct {
+	epfd   int
+	wake   [2]int // 用于唤醒 epoll_wait 以停止事件循环的管道
+	limits *protocol.Limits
+	buf    []byte // 读缓冲区，只在事件循环 goroutine 中使用
+	done   chan struct{}
+
+	mu    sync.Mutex
+	conns map[int]*reactorConn
+}
+
+func newEventLoop(limits *protocol.Limits) (*eventLoop, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &eventLoop{
+		epfd:   epfd,
+		limits: limits,
+		buf:    make([]byte, readBufferSize),
+		done:   make(chan struct{}),
+		conns:  make(map[int]*reactorConn),
+	}
+	if err := syscall.Pipe2(l.wake[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		syscall.Close(epfd)
+		return nil, err
+	}
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(l.wake[0])}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, l.wake[0], &ev); err != nil {
+		l.close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *eventLoop) run() {
+	defer close(l.done)
+
+	events := make([]syscall.EpollEvent, maxEvents)
+	for {
+		n, err := syscall.EpollWait(l.epfd, events, -1)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			logger.Errorf("epoll_wait failed: %v", err)
+			return
+		}
+
+		for i := range n {
+			fd := int(events[i].Fd)
+			if fd == l.wake[0] {
+				return
+			}
+
+			l.mu.Lock()
+			rc := l.conns[fd]
+			l.mu.Unlock()
+			if rc == nil {
+				continue
+			}
+
+			if events[i].Events&syscall.EPOLLOUT != 0 {
+				rc.flushPending()
+			}
+			if events[i].Events&(syscall.EPOLLIN|syscall.EPOLLERR|syscall.EPOLLHUP) != 0 {
+				l.read(rc)
+			}
+		}
+	}
+}
+
+// read 从连接读取一次数据并处理其中完整的命令
+// 使用水平触发，一次没有读完的数据会在下一轮 epoll_wait 中继续读取，各连接之间比较公平
+func (l *eventLoop) read(rc *reactorConn) {
+	rc.mu.Lock()
+	if rc.closed {
+		rc.mu.Unlock()
+		return
+	}
+	n, err := syscall.Read(rc.fd, l.buf)
+	if n > 0 {
+		rc.in = append(rc.in, l.buf[:n]...)
+		if stats := rc.client.stats; stats != nil {
+			stats.netInputBytes.Add(int64(n))
+		}
+	}
+	rc.mu.Unlock()
+
+	if err == syscall.EAGAIN || err == syscall.EINTR {
+		return
+	}
+	if err != nil || n == 0 {
+		// n == 0 表示对端关闭了连接
+		rc.Close()
+		return
+	}
+
+	rc.process()
+}
+
+func (l *eventLoop) add(rc *reactorConn) error {
+	l.mu.Lock()
+	l.conns[rc.fd] = rc
+	l.mu.Unlock()
+
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN | syscall.EPOLLRDHUP, Fd: int32(rc.fd)}
+	if err := syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_ADD, rc.fd, &ev); err != nil {
+		l.mu.Lock()
+		delete(l.conns, rc.fd)
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// watch 设置是否关注连接可读、可写
+func (l *eventLoop) watch(fd int, read, write bool) error {
+	var events uint32
+	if read {
+		events |= syscall.EPOLLIN | syscall.EPOLLRDHUP
+	}
+	if write {
+		events |= syscall.EPOLLOUT
+	}
+	ev := syscall.EpollEvent{Events: events, Fd: int32(fd)}
+	return syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_MOD, fd, &ev)
+}
+
+// remove 注销连接，必须在关闭 fd 之前调用，否则 fd 被复用后会误删新连接
+func (l *eventLoop) remove(fd int) {
+	syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_DEL, fd, nil)
+	l.mu.Lock()
+	delete(l.conns, fd)
+	l.mu.Unlock()
+}
+
+func (l *eventLoop) stop() {
+	syscall.Write(l.wake[1], []byte{0})
+	<-l.done
+	l.close()
+}
+
+func (l *eventLoop) close() {
+	syscall.Close(l.wake[0])
+	syscall.Close(l.wake[1])
+	syscall.Close(l.epfd)
+}
+
+// reactorConn 是注册在事件循环上的连接，实现 net.Conn 以便复用 Client 的命令处理逻辑
+// Client 不会调用 Read，请求由事件循环读取
+type reactorConn struct {
+	fd      int
+	loop    *eventLoop
+	local   net.Addr
+	remote  net.Addr
+	client  *Client
+	onClose func()
+
+	closing atomic.Bool
+
+	mu      sync.Mutex
+	closed  bool
+	in      []byte // 已读取、尚未解析的请求数据
+	out     []byte // 没能立即写出的回复数据
+	writing bool   // 是否注册了 EPOLLOUT
+	busy    bool   // 是否有 goroutine 正在处理该连接的命令，保证命令按顺序执行
+	paused  bool   // 是否因为阻塞命令暂停读取
+}
+
+// process 依次执行缓冲区中完整的命令
+// 同一时刻只有一个 goroutine 在处理，其他调用直接返回，新到达的数据由正在处理的 goroutine 接着处理
+func (rc *reactorConn) process() {
+	rc.mu.Lock()
+	if rc.busy || rc.closed {
+		rc.mu.Unlock()
+		return
+	}
+	rc.busy = true
+
+	consumed := false
+	for !rc.closed {
+		cmd, n, err := protocol.Decode(rc.in, rc.loop.limits)
+		if err != nil {
+			rc.busy = false
+			rc.mu.Unlock()
+			rc.client.readError(err)
+			rc.Close()
+			return
+		}
+		if n == 0 {
+			break
+		}
+		rc.in = rc.in[n:]
+		consumed = true
+
+		if handler.IsBlockingCommand(cmd) || isShutdownCommand(cmd) {
+			// 保持 busy，执行完之后在这个 goroutine 中继续处理后续命令，处理完已经读到的命令后才恢复读取
+			if !rc.paused {
+				if err := rc.loop.watch(rc.fd, false, rc.writing); err != nil {
+					rc.busy = false
+					rc.mu.Unlock()
+					rc.Close()
+					return
+				}
+				rc.paused = true
+			}
+			rc.mu.Unlock()
+			go func() {
+				if err := rc.client.handleCommand(cmd); err != nil {
+					rc.Close()
+					return
+				}
+				rc.mu.Lock()
+				rc.busy = false
+				rc.mu.Unlock()
+				rc.process()
+			}()
+			return
+		}
+
+		rc.mu.Unlock()
+		err = rc.client.handleCommand(cmd)
+		rc.mu.Lock()
+		if err != nil {
+			rc.busy = false
+			rc.mu.Unlock()
+			rc.Close()
+			return
+		}
+	}
+
+	// 空闲连接不保留读缓冲区；剩余半条命令时挪到新的缓冲区，释放已经处理过的部分
+	if len(rc.in) == 0 {
+		rc.in = nil
+	} else if consumed {
+		rc.in = append([]byte(nil), rc.in...)
+	}
+	rc.busy = false
+	if rc.paused && !rc.closed {
+		rc.paused = false
+		if err := rc.loop.watch(rc.fd, true, rc.writing); err != nil {
+			rc.mu.Unlock()
+			rc.Close()
+			return
+		}
+	}
+	rc.mu.Unlock()
+}
+
+// Write 先尝试直接写 fd，写不完的部分缓存起来等连接可写时由事件循环写出
+// 可以在任意 goroutine 中调用（命令回复、发布订阅推送等）
+func (rc *reactorConn) Write(b []byte) (int, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.closed {
+		return 0, net.ErrClosed
+	}
+
+	total := len(b)
+	if len(rc.out) == 0 {
+		for len(b) > 0 {
+			n, err := syscall.Write(rc.fd, b)
+			if n > 0 {
+				b = b[n:]
+			}
+			if err == syscall.EINTR {
+				continue
+			}
+			if err == syscall.EAGAIN {
+				break
+			}
+			if err != nil {
+				return total - len(b), err
+			}
+		}
+		if len(b) == 0 {
+			return total, nil
+		}
+	}
+
+	if rc.client.outputLimitReached(int64(len(rc.out)+len(b)) + rc.client.queuedBytes.Load()) {
+		logger.Warnf("[client-%d] Output buffer limit reached, closing client", rc.client.id)
+		return total - len(b), errOutputLimit
+	}
+	rc.out = append(rc.out, b...)
+	if !rc.writing {
+		if err := rc.loop.watch(rc.fd, !rc.paused, true); err != nil {
+			return total - len(b), err
+		}
+		rc.writing = true
+	}
+	return total, nil
+}
+
+// flushPending 在连接可写时写出缓存的回复
+func (rc *reactorConn) flushPending() {
+	rc.mu.Lock()
+	if rc.closed {
+		rc.mu.Unlock()
+		return
+	}
+
+	var err error
+	for len(rc.out) > 0 {
+		var n int
+		n, err = syscall.Write(rc.fd, rc.out)
+		if n > 0 {
+			rc.out = rc.out[n:]
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if len(rc.out) == 0 {
+		rc.out = nil
+		rc.writing = false
+		err = rc.loop.watch(rc.fd, !rc.paused, false)
+	} else if err == syscall.EAGAIN {
+		err = nil
+	}
+	rc.mu.Unlock()
+
+	if err != nil {
+		rc.Close()
+	}
+}
+
+// Close 注销并关闭 fd，清理客户端状态
+// 可能在发布者等持有其他锁的 goroutine 中被调用，因此清理放到新的 goroutine 中进行
+func (rc *reactorConn) Close() error {
+	if !rc.closing.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	rc.mu.Lock()
+	rc.closed = true
+	rc.loop.remove(rc.fd)
+	// 尽量写出剩余的回复，写不进 socket 的部分只能丢弃；需要等待写完时由 Server.Stop 先等待
+	if len(rc.out) > 0 {
+		syscall.Write(rc.fd, rc.out)
+	}
+	syscall.Close(rc.fd)
+	rc.in, rc.out = nil, nil
+	rc.mu.Unlock()
+
+	go func() {
+		rc.client.release()
+		if rc.onClose != nil {
+			rc.onClose()
+		}
+	}()
+	return nil
+}
+
+// pendingOutput 返回缓存的待写字节数（实现 bufferedConn）
+func (rc *reactorConn) pendingOutput() int64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return int64(len(rc.out))
+}
+
+var errReactorRead = errors.New("reactor connections are read by the event loop")
+
+func (rc *reactorConn) Read(b []byte) (int, error) {
+	return 0, errReactorRead
+}
+
+func (rc *reactorConn) LocalAddr() net.Addr  { return rc.local }
+func (rc *reactorConn) RemoteAddr() net.Addr { return rc.remote }
+
+// 事件循环模式下的读写都是非阻塞的，不支持超时
+func (rc *reactorConn) SetDeadline(t time.Time) error      { return nil }
+func (rc *reactorConn) SetReadDeadline(t time.Time) error  { return nil }
+func (rc *reactorConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// serveReactor 以事件循环模式处理连接
+// 监听仍由 Go 的网络轮询器处理，接受的连接复制出 fd 交给事件循环，原来的 net.Conn 随即关闭
+func (s *Server) serveReactor() error {
+	r, err := newReactor(s.eventLoops, s.router.ProtocolLimits())
+	if err != nil {
+		return err
+	}
+	s.reactor.Store(r)
+	select {
+	case <-s.shutdown:
+		// Stop 已经在创建事件循环之前执行完
+		if r := s.reactor.Swap(nil); r != nil {
+			r.stop()
+		}
+		return s.serveDone()
+	default:
+	}
+
+	logger.Infof("Using epoll event loops: %d", s.eventLoops)
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.shutdown:
+				return s.serveDone()
+			default:
+				logger.Errorf("Failed to accept connection: %v", err)
+				continue
+			}
+		}
+
+		if !s.acceptConn(conn) {
+			continue
+		}
+		if err := s.serveConn(conn); err != nil {
+			logger.Errorf("Failed to register connection: %v", err)
+		}
+	}
+}
+
+// serveConn 为新连接创建客户端并注册到事件循环
+func (s *Server) serveConn(conn net.Conn) error {
+	defer conn.Close()
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return errors.New("event loop mode requires TCP connections")
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	fd := -1
+	var dupErr error
+	err = raw.Control(func(cfd uintptr) {
+		fd, dupErr = dupSocket(int(cfd))
+	})
+	if err == nil {
+		err = dupErr
+	}
+	if err != nil {
+		return err
+	}
+
+	r := s.reactor.Load()
+	if r == nil {
+		syscall.Close(fd)
+		return nil
+	}
+	loop := r.pick()
+	rc := &reactorConn{
+		fd:     fd,
+		loop:   loop,
+		local:  conn.LocalAddr(),
+		remote: conn.RemoteAddr(),
+	}
+	client := s.newClient(rc)
+	rc.client = client
+
+	s.clients.Store(client.id, client)
+	atomic.AddInt64(&s.numClients, 1)
+	s.wg.Add(1)
+	rc.onClose = func() {
+		s.clients.Delete(client.id)
+		atomic.AddInt64(&s.numClients, -1)
+		s.wg.Done()
+	}
+
+	if err := loop.add(rc); err != nil {
+		rc.Close()
+		return err
+	}
+	logger.Infof("[client-%d] Client connected from %s", client.id, rc.remote)
+	return nil
+}
+
+// dupSocket 复制一个非阻塞、close-on-exec 的 fd
+func dupSocket(fd int) (int, error) {
+	nfd, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_DUPFD_CLOEXEC, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	if err := syscall.SetNonblock(int(nfd), true); err != nil {
+		syscall.Close(int(nfd))
+		return -1, err
+	}
+	return int(nfd), nil
+}
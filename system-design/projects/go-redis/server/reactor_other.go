@@ -0,0 +1,15 @@
+//go:build !linux
+
+package server
+
+import "errors"
+
+// 事件循环模式依赖 epoll，其他平台只支持每个连接一个 goroutine 的模式
+
+type reactor struct{}
+
+func (r *reactor) stop() {}
+
+func (s *Server) serveReactor() error {
+	return errors.New("epoll event loop is only supported on linux")
+}
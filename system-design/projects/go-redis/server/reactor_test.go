@@ -0,0 +1,345 @@
+//go:build linux
+
+package server
+
+import (
+	"fmt"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"go-redis/store"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func startReactorServer(tb testing.TB, loops int) *Server {
+	tb.Helper()
+	logger.SetLevel(logrus.PanicLevel)
+
+	srv := NewServer("127.0.0.1:0", store.NewStore())
+	srv.SetEventLoops(loops)
+	if err := srv.Listen(); err != nil {
+		tb.Fatal(err)
+	}
+	go srv.Serve()
+	tb.Cleanup(func() { srv.Stop() })
+	return srv
+}
+
+func TestReactorCommands(t *testing.T) {
+	srv := startReactorServer(t, 2)
+	conn := dialTest(t, srv.Addr())
+
+	if v := conn.do(t, "PING"); v.Str != "PONG" {
+		t.Fatalf("PING: %+v", v)
+	}
+	conn.do(t, "SET", "k", "v")
+	if v := conn.do(t, "GET", "k"); v.Str != "v" {
+		t.Fatalf("GET: %+v", v)
+	}
+	if v := conn.do(t, "CLIENT", "ID"); v.Type != protocol.IntType || v.Int <= 0 {
+		t.Fatalf("CLIENT ID: %+v", v)
+	}
+}
+
+func TestReactorPipeline(t *testing.T) {
+	srv := startReactorServer(t, 2)
+	conn := dialTest(t, srv.Addr())
+
+	const n = 1000
+	var b strings.Builder
+	for i := range n {
+		b.WriteString(command("SET", fmt.Sprintf("key:%d", i), fmt.Sprint(i)))
+		b.WriteString(command("GET", fmt.Sprintf("key:%d", i)))
+	}
+	// 回复写满 socket 缓冲区时服务器要等连接可写，因此一边写一边读
+	go io.WriteString(conn, b.String())
+
+	for i := range n {
+		if v := conn.reply(t); v.Str != "OK" {
+			t.Fatalf("SET %d: %+v", i, v)
+		}
+		if v := conn.reply(t); v.Str != fmt.Sprint(i) {
+			t.Fatalf("GET %d: %+v", i, v)
+		}
+	}
+}
+
+func TestReactorLargeValue(t *testing.T) {
+	srv := startReactorServer(t, 1)
+	conn := dialTest(t, srv.Addr())
+
+	value := strings.Repeat("abcdefgh", 512*1024)
+	req := command("SET", "big", value)
+	// 分成多次小的写入，请求会分多次到达
+	for len(req) > 0 {
+		n := min(len(req), 1000+len(req)%7919)
+		if _, err := io.WriteString(conn, req[:n]); err != nil {
+			t.Fatal(err)
+		}
+		req = req[n:]
+	}
+	if v := conn.reply(t); v.Str != "OK" {
+		t.Fatalf("SET: %+v", v)
+	}
+	if v := conn.do(t, "GET", "big"); v.Str != value {
+		t.Fatalf("GET returned %d bytes, want %d", len(v.Str), len(value))
+	}
+}
+
+func TestReactorProtocolError(t *testing.T) {
+	srv := startReactorServer(t, 1)
+	conn := dialTest(t, srv.Addr())
+
+	io.WriteString(conn, "*2147483648\r\n"+command("PING"))
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-ERR Protocol error: invalid multibulk length\r\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestReactorBlockingCommand(t *testing.T) {
+	srv := startReactorServer(t, 1)
+	reader := dialTest(t, srv.Addr())
+	writer := dialTest(t, srv.Addr())
+
+	// XREAD BLOCK 之后的命令要等它返回后才执行
+	io.WriteString(reader, command("XREAD", "BLOCK", "0", "STREAMS", "s", "$")+command("PING"))
+	time.Sleep(50 * time.Millisecond)
+
+	// 同一个事件循环上的其他连接不受影响
+	if v := writer.do(t, "PING"); v.Str != "PONG" {
+		t.Fatalf("PING: %+v", v)
+	}
+	writer.do(t, "XADD", "s", "*", "f", "v")
+
+	if v := reader.reply(t); v.Type != protocol.ArrayType || len(v.Array) != 1 {
+		t.Fatalf("XREAD: %+v", v)
+	}
+	if v := reader.reply(t); v.Str != "PONG" {
+		t.Fatalf("PING after XREAD: %+v", v)
+	}
+}
+
+// 阻塞命令执行期间不读取连接，客户端继续发送的请求不会堆积在服务器的缓冲区中
+func TestReactorBlockingCommandPausesReading(t *testing.T) {
+	srv := startReactorServer(t, 1)
+	reader := dialTest(t, srv.Addr())
+	writer := dialTest(t, srv.Addr())
+
+	io.WriteString(reader, command("XREAD", "BLOCK", "0", "STREAMS", "s", "$"))
+	time.Sleep(50 * time.Millisecond)
+
+	const n = 2000
+	pad := command("SET", "pad", strings.Repeat("x", 16*1024))
+	go io.WriteString(reader, strings.Repeat(pad, n))
+	time.Sleep(200 * time.Millisecond)
+
+	loop := srv.reactor.Load().loops[0]
+	loop.mu.Lock()
+	var buffered int
+	for _, rc := range loop.conns {
+		rc.mu.Lock()
+		buffered = max(buffered, len(rc.in))
+		rc.mu.Unlock()
+	}
+	loop.mu.Unlock()
+	if buffered > readBufferSize {
+		t.Errorf("%d bytes buffered while blocked, want at most %d", buffered, readBufferSize)
+	}
+
+	writer.do(t, "XADD", "s", "*", "f", "v")
+	if v := reader.reply(t); v.Type != protocol.ArrayType || len(v.Array) != 1 {
+		t.Fatalf("XREAD: %+v", v)
+	}
+	// 恢复读取后剩下的请求照常执行
+	for i := range n {
+		if v := reader.reply(t); v.Str != "OK" {
+			t.Fatalf("SET %d: %+v", i, v)
+		}
+	}
+}
+
+func TestReactorPubSub(t *testing.T) {
+	srv := startReactorServer(t, 2)
+	sub := dialTest(t, srv.Addr())
+	pub := dialTest(t, srv.Addr())
+
+	sub.do(t, "SUBSCRIBE", "news")
+	if v := pub.do(t, "PUBLISH", "news", "hello"); v.Int != 1 {
+		t.Fatalf("PUBLISH: %+v", v)
+	}
+	v := sub.reply(t)
+	if len(v.Array) != 3 || v.Array[0].Str != "message" || v.Array[2].Str != "hello" {
+		t.Fatalf("message: %+v", v)
+	}
+}
+
+func TestReactorStop(t *testing.T) {
+	logger.SetLevel(logrus.PanicLevel)
+	srv := NewServer("127.0.0.1:0", store.NewStore())
+	srv.SetEventLoops(2)
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve() }()
+
+	conn := dialTest(t, srv.Addr())
+	conn.do(t, "PING")
+
+	srv.Stop()
+	if err := <-served; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	if _, err := conn.parser.Parse(); err == nil {
+		t.Fatal("connection is still open after Stop")
+	}
+	if n := atomic.LoadInt64(&srv.numClients); n != 0 {
+		t.Fatalf("%d clients left after Stop", n)
+	}
+}
+
+func TestReactorStopFlushesOutput(t *testing.T) {
+	logger.SetLevel(logrus.PanicLevel)
+	srv := NewServer("127.0.0.1:0", store.NewStore())
+	srv.SetEventLoops(1)
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve() }()
+
+	conn := dialTest(t, srv.Addr())
+	value := strings.Repeat("abcdefgh", 2*1024*1024)
+	conn.do(t, "SET", "big", value)
+
+	// 不读取回复，让回复的大部分缓存在连接上，然后停止服务器
+	conn.send(t, "GET", "big")
+	waitFor(t, 5*time.Second, "reply to be buffered", func() bool {
+		pending := false
+		srv.clients.Range(func(_, v interface{}) bool {
+			pending = pending || v.(*Client).outputBufferSize() > 0
+			return true
+		})
+		return pending
+	})
+	go srv.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	if v := conn.reply(t); v.Str != value {
+		t.Fatalf("reply was cut off at shutdown: got %d bytes, want %d", len(v.Str), len(value))
+	}
+	if _, err := conn.parser.Parse(); err == nil {
+		t.Fatal("connection is still open after Stop")
+	}
+	waitServed(t, served)
+}
+
+// 两种网络模型的对比：
+//
+//	go test ./server -run XXX -bench 'Reactor|Goroutine' -benchmem
+//
+// Throughput 用 50 个连接并发执行 SET，IdleConns 测量每个空闲连接占用的内存
+
+func benchmarkThroughput(b *testing.B, loops int) {
+	srv := startReactorServer(b, loops)
+	conns := make(chan *testConn, 50)
+	for range cap(conns) {
+		conns <- dialTest(b, srv.Addr())
+	}
+
+	b.SetParallelism(max(1, cap(conns)/runtime.GOMAXPROCS(0)))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		conn := <-conns
+		conn.SetDeadline(time.Time{})
+		req := command("SET", "key", "value")
+		for pb.Next() {
+			io.WriteString(conn, req)
+			if _, err := conn.parser.Parse(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkGoroutineThroughput(b *testing.B) { benchmarkThroughput(b, 0) }
+func BenchmarkReactorThroughput(b *testing.B)   { benchmarkThroughput(b, runtime.NumCPU()) }
+
+// 客户端的连接也在同一个进程中，两种模式下这部分开销相同，比较的是两者的差值
+func benchmarkIdleConns(b *testing.B, loops int) {
+	const n = 1000
+	logger.SetLevel(logrus.PanicLevel)
+
+	for range b.N {
+		srv := NewServer("127.0.0.1:0", store.NewStore())
+		srv.SetEventLoops(loops)
+		if err := srv.Listen(); err != nil {
+			b.Fatal(err)
+		}
+		go srv.Serve()
+		before := heapInUse()
+
+		conns := make([]net.Conn, n)
+		for i := range conns {
+			conn, err := net.Dial("tcp", srv.Addr())
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.WriteString(conn, command("PING"))
+			conns[i] = conn
+		}
+		buf := make([]byte, len("+PONG\r\n"))
+		for _, conn := range conns {
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		b.ReportMetric(float64(heapInUse()-before)/n, "bytes/conn")
+		for _, conn := range conns {
+			conn.Close()
+		}
+		srv.Stop()
+	}
+}
+
+func heapInUse() int64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64(m.HeapInuse + m.StackInuse)
+}
+
+func BenchmarkGoroutineIdleConns(b *testing.B) { benchmarkIdleConns(b, 0) }
+func BenchmarkReactorIdleConns(b *testing.B)   { benchmarkIdleConns(b, runtime.NumCPU()) }
+
+func TestReactorOutputBufferLimit(t *testing.T) {
+	srv := startReactorServer(t, 1)
+	conn := dialTest(t, srv.Addr())
+
+	value := strings.Repeat("x", 512*1024)
+	conn.do(t, "SET", "big", value)
+	conn.do(t, "CONFIG", "SET", "client-output-buffer-limit", "normal 1mb 0 0")
+
+	// 不读取回复，socket 缓冲区写满后回复缓存在连接上，超过限制时连接被断开
+	const n = 100
+	io.WriteString(conn, strings.Repeat(command("GET", "big"), n))
+	time.Sleep(200 * time.Millisecond)
+
+	read, _ := io.Copy(io.Discard, conn)
+	if read >= int64(n*len(value)) {
+		t.Fatalf("read %d bytes, expected the connection to be closed early", read)
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"go-redis/handler"
+	"go-redis/logger"
+	"go-redis/sentinel"
+	"net"
+	"strconv"
+)
+
+// EnableSentinel 让服务器以哨兵模式运行：注册 SENTINEL 命令、sentinel 配置参数和 INFO 的 sentinel 节，
+// 需要在加载配置文件和 Serve 之前调用。哨兵在 Serve 时启动，Stop 时停止
+// 哨兵发现新的从节点或哨兵后会写回配置文件
+func (s *Server) EnableSentinel() *sentinel.Sentinel {
+	st := sentinel.New()
+	st.SetPort(func() int {
+		_, port, _ := net.SplitHostPort(s.Addr())
+		n, _ := strconv.Atoi(port)
+		return n
+	})
+	st.SetPublisher(s.router.PubSub().Publish)
+	st.SetConfigChangeHandler(func() {
+		if s.Config().File() == "" {
+			return
+		}
+		if err := s.Config().Rewrite(); err != nil {
+			logger.Errorf("Failed to rewrite sentinel config: %v", err)
+		}
+	})
+
+	s.router.Register("sentinel", handler.NewSentinelHandler(st))
+	s.router.Config().AddParam(handler.SentinelParam(st))
+	s.router.AddInfoSection("sentinel", st.InfoLines)
+	s.sentinel = st
+	return st
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"go-redis/store"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// replicaInfo 返回从节点的 INFO replication 节：本服务器没有主从复制，从节点的角色只在 INFO 中模拟
+func replicaInfo(masterAddr string) func() []string {
+	host, port, _ := net.SplitHostPort(masterAddr)
+	return func() []string {
+		return []string{
+			"role:slave",
+			"master_host:" + host,
+			"master_port:" + port,
+			"master_link_status:up",
+			"slave_priority:100",
+			"slave_repl_offset:0",
+		}
+	}
+}
+
+// startSetupServer 启动服务器，setup 在 Serve 之前执行
+func startSetupServer(t *testing.T, setup func(srv *Server)) *Server {
+	t.Helper()
+	srv := NewServer("127.0.0.1:0", store.NewStore())
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	setup(srv)
+	go srv.Serve()
+	t.Cleanup(func() { srv.Stop() })
+	return srv
+}
+
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// sentinelFlags 返回哨兵对主节点的 flags
+func sentinelFlags(srv *Server) string {
+	fields, _ := srv.sentinel.Master("mymaster")
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == "flags" {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+// TestSentinelMonitoring 在一个进程内走完监控的流程：发现从节点和其他哨兵，主节点停止后判定主观下线和客观下线；
+// 真实进程之间的下线判定见根目录的 TestSentinelProcesses
+func TestSentinelMonitoring(t *testing.T) {
+	if testing.Short() {
+		t.Skip("failure detection takes several seconds")
+	}
+	logger.SetLevel(logrus.PanicLevel)
+
+	// 主节点先监听，从节点需要它的地址，INFO 的 replication 节在 Serve 之前注册
+	master := NewServer("127.0.0.1:0", store.NewStore())
+	if err := master.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { master.Stop() })
+	var replicaAddrs []string
+	for i := 0; i < 2; i++ {
+		srv := startSetupServer(t, func(srv *Server) {
+			srv.router.AddInfoSection("replication", replicaInfo(master.Addr()))
+		})
+		replicaAddrs = append(replicaAddrs, srv.Addr())
+	}
+	// 主节点的 INFO 列出从节点，哨兵由此发现它们
+	master.router.AddInfoSection("replication", func() []string {
+		lines := []string{"role:master", fmt.Sprintf("connected_slaves:%d", len(replicaAddrs))}
+		for i, addr := range replicaAddrs {
+			host, port, _ := net.SplitHostPort(addr)
+			lines = append(lines, fmt.Sprintf("slave%d:ip=%s,port=%s,state=online,offset=0,lag=0", i, host, port))
+		}
+		return lines
+	})
+	go master.Serve()
+
+	host, port, _ := net.SplitHostPort(master.Addr())
+	var sentinels []*Server
+	for i := 0; i < 3; i++ {
+		srv := startSetupServer(t, func(srv *Server) {
+			st := srv.EnableSentinel()
+			for _, line := range []string{
+				fmt.Sprintf("monitor mymaster %s %s 2", host, port),
+				"down-after-milliseconds mymaster 300",
+			} {
+				if err := st.Configure(strings.Fields(line)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+		sentinels = append(sentinels, srv)
+	}
+
+	// 哨兵通过 hello 频道互相发现，通过主节点的 INFO 发现从节点
+	for _, srv := range sentinels {
+		waitFor(t, 10*time.Second, "discovery", func() bool {
+			peers, _ := srv.sentinel.Sentinels("mymaster")
+			replicas, _ := srv.sentinel.Replicas("mymaster")
+			return len(peers) == 2 && len(replicas) == 2
+		})
+	}
+
+	conn := dialTest(t, sentinels[0].Addr())
+	if v := conn.do(t, "SENTINEL", "GET-MASTER-ADDR-BY-NAME", "mymaster"); len(v.Array) != 2 || v.Array[1].Str != port {
+		t.Fatalf("GET-MASTER-ADDR-BY-NAME = %+v", v)
+	}
+	if v := conn.do(t, "SENTINEL", "CKQUORUM", "mymaster"); v.Type != protocol.StringType || !strings.HasPrefix(v.Str, "OK 3 usable") {
+		t.Fatalf("CKQUORUM = %+v", v)
+	}
+	if v := conn.do(t, "INFO", "server"); !strings.Contains(v.Str, "redis_mode:sentinel") {
+		t.Errorf("INFO server = %q", v.Str)
+	}
+	if flags := sentinelFlags(sentinels[0]); flags != "master" {
+		t.Errorf("flags before the master stops = %q", flags)
+	}
+
+	// 主节点停止后每个哨兵先判定主观下线，再通过询问其他哨兵判定客观下线，主节点的地址不变
+	master.Stop()
+	for _, srv := range sentinels {
+		waitFor(t, 10*time.Second, "objective down", func() bool {
+			return sentinelFlags(srv) == "master,s_down,o_down"
+		})
+	}
+	conn = dialTest(t, sentinels[1].Addr())
+	if v := conn.do(t, "SENTINEL", "GET-MASTER-ADDR-BY-NAME", "mymaster"); len(v.Array) != 2 || v.Array[1].Str != port {
+		t.Errorf("GET-MASTER-ADDR-BY-NAME after the master stopped = %+v", v)
+	}
+	// 回答其他哨兵的询问时不投票
+	v := conn.do(t, "SENTINEL", "IS-MASTER-DOWN-BY-ADDR", host, port, "1", sentinels[0].sentinel.MyID())
+	if len(v.Array) != 3 || v.Array[0].Int != 1 || v.Array[1].Str != "*" || v.Array[2].Int != 0 {
+		t.Errorf("IS-MASTER-DOWN-BY-ADDR = %+v", v)
+	}
+}
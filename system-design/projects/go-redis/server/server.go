@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"go-redis/handler"
 	"go-redis/logger"
+	"go-redis/pubsub"
+	"go-redis/sentinel"
 	"go-redis/store"
 	"net"
+	"net/http"
+	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Server struct {
@@ -19,43 +24,150 @@ type Server struct {
 	shutdown chan struct{}
 	wg       sync.WaitGroup
 	clientID int64
+
+	startTime  time.Time
+	numClients int64
+
+	limits *connLimits
+	stats  serverStats
+
+	// 指标 HTTP 服务，metricsAddr 为空时不启动
+	metricsAddr     string
+	metricsListener net.Listener
+	metricsServer   *http.Server
+
+	// 优雅关闭
+	gate          *commandGate
+	persister     Persister
+	shutdownMu    sync.Mutex
+	shutdownAbort chan struct{} // 关闭进行中时不为 nil，关闭它取消关闭
+	stopOnce      sync.Once
+	stopped       chan struct{} // Stop 完成后关闭
+
+	// ioModel 为 epoll 时使用事件循环模式处理连接（仅 Linux），eventLoops 是事件循环的个数，0 表示 CPU 核数
+	ioModel    string
+	eventLoops int
+	reactor    atomic.Pointer[reactor] // 事件循环模式下由 Serve 创建
+
+	runID    string
+	sentinel *sentinel.Sentinel // 哨兵模式下由 EnableSentinel 创建
 }
 
 func NewServer(addr string, s *store.Store) *Server {
 	router := handler.NewRouter(s)
+	// 键空间通知通过 Router 的发布订阅中心投递给订阅者
+	s.SetPublisher(router.PubSub())
+	// 键被修改时由失效表通知开启了 CLIENT TRACKING 的客户端
+	s.SetKeyTracker(router.Tracking())
 
-	return &Server{
-		addr:     addr,
-		router:   router,
-		db:       s,
-		shutdown: make(chan struct{}),
+	srv := &Server{
+		addr:      addr,
+		router:    router,
+		db:        s,
+		shutdown:  make(chan struct{}),
+		startTime: time.Now(),
+		limits:    newConnLimits(),
+		gate:      newCommandGate(),
+		stopped:   make(chan struct{}),
+		ioModel:   ioModelGoroutine,
+		runID:     newRunID(),
 	}
+	srv.registerConfigParams()
+	// 连接层处理的命令没有注册处理器，在命令表中声明，使 COMMAND 能列出它们，并通过 RouteLocal 经过中间件
+	for _, cmd := range []string{"MULTI", "EXEC", "DISCARD", "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE",
+		"CLIENT", "MONITOR", "SHUTDOWN"} {
+		router.Declare(cmd)
+	}
+	router.AddInfoSection("server", srv.serverInfo)
+	router.AddInfoSection("clients", srv.clientsInfo)
+	router.Tracking().SetLookup(srv.lookupClient)
+
+	return srv
 }
 
+// Start 监听地址并处理连接，直到 Stop 被调用
 func (s *Server) Start() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Listen 只监听地址，不处理连接
+// 地址的端口为 0 时由系统分配端口，之后可以通过 Addr 获得实际地址（用于测试）
+func (s *Server) Listen() error {
 	listener, err := net.Listen("tcp", s.addr)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
 	}
 
 	s.listener = listener
-	logger.Infof("Redis server listening on %s", s.addr)
+	logger.Infof("Redis server listening on %s", listener.Addr())
+
+	if err := s.listenMetrics(); err != nil {
+		listener.Close()
+		return err
+	}
+	return nil
+}
+
+// Addr 返回实际监听的地址，Listen 之前返回配置的地址
+func (s *Server) Addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+// Use 添加命令中间件（认证、只读、审计等），需要在 Serve 之前调用，见 handler.Middleware
+func (s *Server) Use(mws ...handler.Middleware) {
+	s.router.Use(mws...)
+}
+
+// SetEventLoops 设置事件循环的个数，n 大于 0 时改用 epoll 事件循环模式，
+// 等于 0 时每个连接使用一个 goroutine（默认），需要在 Serve 之前调用
+func (s *Server) SetEventLoops(n int) {
+	s.eventLoops = n
+	s.ioModel = ioModelGoroutine
+	if n > 0 {
+		s.ioModel = ioModelEpoll
+	}
+}
+
+// Serve 处理 Listen 之后到达的连接，直到 Stop 被调用
+func (s *Server) Serve() error {
+	go s.clientsCron()
+	s.serveMetrics()
+	if s.sentinel != nil {
+		s.sentinel.Start()
+	}
+
+	if s.ioModel == ioModelEpoll {
+		if s.eventLoops <= 0 {
+			s.eventLoops = runtime.NumCPU()
+		}
+		return s.serveReactor()
+	}
 
 	for {
-		conn, err := listener.Accept()
+		conn, err := s.listener.Accept()
 		if err != nil {
 			select {
 			case <-s.shutdown:
-				logger.Info("Server is shutting down")
-				return nil
+				return s.serveDone()
 			default:
 				logger.Errorf("Failed to accept connection: %v", err)
 				continue
 			}
 		}
 
-		client := NewClient(conn, s.router, s.nextClientID())
+		if !s.acceptConn(conn) {
+			continue
+		}
+
+		client := s.newClient(conn)
 		s.clients.Store(client.id, client)
+		atomic.AddInt64(&s.numClients, 1)
 
 		s.wg.Add(1)
 
@@ -63,32 +175,111 @@ func (s *Server) Start() error {
 			defer s.wg.Done()
 			client.Serve()
 			s.clients.Delete(client.id)
+			atomic.AddInt64(&s.numClients, -1)
 		}()
 	}
 }
 
+// serveDone 在 Serve 因 Stop 退出时调用，等 Stop 完成后再返回，
+// 这样 Start 返回时连接已经全部关闭，进程可以安全退出
+func (s *Server) serveDone() error {
+	logger.Info("Server is shutting down")
+	<-s.stopped
+	return nil
+}
+
+// Stop 立即停止服务器：不再执行新的命令，关闭监听和所有连接，可以多次调用
+// 需要等待正在执行的命令并保存数据时使用 Shutdown
 func (s *Server) Stop() error {
+	s.stopOnce.Do(s.stop)
+	<-s.stopped
+	return nil
+}
+
+func (s *Server) stop() {
 	logger.Info("Stopping server...")
 
 	close(s.shutdown)
+	s.gate.close()
+
+	if s.sentinel != nil {
+		s.sentinel.Stop()
+	}
 
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	if s.metricsServer != nil {
+		s.metricsServer.Close()
+	}
+
+	// 唤醒阻塞在 XREAD BLOCK 等命令上的客户端，避免 wg.Wait 一直等待
+	s.db.Close()
+
+	timeout := time.Duration(s.limits.shutdownTimeout.Load()) * time.Second
+	s.closeClients(time.Now().Add(timeout))
+
+	s.wg.Wait()
+
+	if r := s.reactor.Swap(nil); r != nil {
+		r.stop()
+	}
+
+	logger.Info("Server stopped")
+	close(s.stopped)
+}
 
+// closeClients 关闭所有连接
+// 还有回复或推送消息没写出的连接（事件循环模式下的大回复、慢消费者）等它写完再关闭，最多等到 deadline，
+// 之后的连接直接关闭，没写完的数据被丢弃
+func (s *Server) closeClients(deadline time.Time) {
+	var pending []*Client
 	s.clients.Range(func(key, value interface{}) bool {
 		client := value.(*Client)
-		client.Close()
+		if client.outputBufferSize() > 0 {
+			pending = append(pending, client)
+		} else {
+			client.Close()
+		}
 		return true
 	})
 
-	s.wg.Wait()
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		time.Sleep(flushPollInterval)
+		n := 0
+		for _, client := range pending {
+			if client.outputBufferSize() > 0 {
+				pending[n] = client
+				n++
+			} else {
+				client.Close()
+			}
+		}
+		pending = pending[:n]
+	}
+	for _, client := range pending {
+		logger.Warnf("[client-%d] Output not flushed before shutdown, dropping %d bytes", client.id, client.outputBufferSize())
+		client.Close()
+	}
+}
 
-	logger.Info("Server stopped")
-	return nil
+// newClient 为新连接创建客户端
+func (s *Server) newClient(conn net.Conn) *Client {
+	client := NewClient(conn, s.router, s.nextClientID())
+	client.limits = s.limits
+	client.server = s
+	client.stats = &s.stats
+	return client
 }
 
-func (s *Server) nextClientID() string {
-	id := atomic.AddInt64(&s.clientID, 1)
-	return fmt.Sprintf("client-%d", id)
+func (s *Server) nextClientID() int64 {
+	return atomic.AddInt64(&s.clientID, 1)
+}
+
+// lookupClient 按 ID 查找连接，供 CLIENT TRACKING 的 REDIRECT 使用
+func (s *Server) lookupClient(id int64) pubsub.Subscriber {
+	if client, ok := s.clients.Load(id); ok {
+		return client.(*Client)
+	}
+	return nil
 }
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"errors"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 优雅关闭
+//
+// SHUTDOWN 命令和 SIGTERM 都通过 Server.Shutdown 关闭服务器，分为三步：
+//  1. 暂停：之后到达的命令在 commandGate 处等待，已经开始执行的命令继续执行并写出回复，
+//     最多等待 shutdown-timeout 秒（NOW 跳过等待）。这一步可以被 SHUTDOWN ABORT 取消
+//  2. 持久化：配置了 Persister 时保存数据（NOSAVE 跳过，SAVE 要求必须保存），
+//     失败时取消关闭并返回错误，FORCE 忽略失败继续关闭
+//  3. 停止：关闭入口，在入口等待的命令不再执行，随后由 Stop 关闭监听和所有连接，
+//     还有回复没写出的连接最多再等待 shutdown-timeout 秒
+//
+// 阻塞在 XREAD BLOCK 等命令上的客户端不计入正在执行的命令，由 Stop 唤醒。
+// 事件循环模式下在入口等待的命令会占住所在的事件循环，同一循环上的 SHUTDOWN ABORT 要等暂停结束才能执行
+
+const (
+	defaultShutdownTimeout = 10
+	// flushPollInterval 是 Stop 检查连接的输出是否写完的间隔
+	flushPollInterval = 10 * time.Millisecond
+)
+
+var (
+	errShutdownFailed     = errors.New("ERR Errors trying to SHUTDOWN. Check logs.")
+	errNoShutdown         = errors.New("ERR No shutdown in progress.")
+	errShuttingDown       = errors.New("server is shutting down")
+	errPersistenceMissing = errors.New("persistence is not configured")
+)
+
+// Persister 在关闭前保存数据，由持久化模块实现
+type Persister interface {
+	Save() error
+}
+
+// ShutdownOptions 对应 SHUTDOWN 命令的选项
+type ShutdownOptions struct {
+	NoSave bool // 不保存数据
+	Save   bool // 必须保存数据，没有配置持久化时失败
+	Now    bool // 不等待正在执行的命令
+	Force  bool // 忽略保存失败
+}
+
+// commandGate 是命令执行的入口，关闭过程中暂停新命令并统计正在执行的命令
+type commandGate struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	paused   bool
+	closed   bool
+	inflight int
+}
+
+func newCommandGate() *commandGate {
+	g := &commandGate{}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// enter 在执行命令前调用，暂停期间等待；返回 false 表示服务器正在关闭，不应执行命令
+// counted 为 false 的命令（阻塞命令）不计入正在执行的命令
+func (g *commandGate) enter(counted bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for g.paused && !g.closed {
+		g.cond.Wait()
+	}
+	if g.closed {
+		return false
+	}
+	if counted {
+		g.inflight++
+	}
+	return true
+}
+
+// leave 在命令执行完并写出回复后调用
+func (g *commandGate) leave() {
+	g.mu.Lock()
+	g.inflight--
+	g.mu.Unlock()
+	g.cond.Broadcast()
+}
+
+func (g *commandGate) pause() {
+	g.mu.Lock()
+	g.paused = true
+	g.mu.Unlock()
+}
+
+func (g *commandGate) resume() {
+	g.mu.Lock()
+	g.paused = false
+	g.mu.Unlock()
+	g.cond.Broadcast()
+}
+
+func (g *commandGate) close() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+	g.cond.Broadcast()
+}
+
+// wake 唤醒所有等待者，先获取锁保证等待者已经进入 Wait，不会错过唤醒
+func (g *commandGate) wake() {
+	g.mu.Lock()
+	g.mu.Unlock()
+	g.cond.Broadcast()
+}
+
+// drain 等待正在执行的命令完成，超时或 abort 关闭时提前返回，返回是否全部完成
+func (g *commandGate) drain(timeout time.Duration, abort <-chan struct{}) bool {
+	// cond 不支持超时，由定时器和 abort 唤醒等待
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, g.wake)
+	defer timer.Stop()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-abort:
+			g.wake()
+		case <-stop:
+		}
+	}()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.inflight > 0 {
+		select {
+		case <-abort:
+			return false
+		default:
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		g.cond.Wait()
+	}
+	return true
+}
+
+// SetPersister 设置关闭前保存数据的持久化模块
+func (s *Server) SetPersister(p Persister) {
+	s.persister = p
+}
+
+// Shutdown 优雅地关闭服务器，返回错误时服务器继续运行
+func (s *Server) Shutdown(opts ShutdownOptions) error {
+	if err := s.prepareShutdown(opts); err != nil {
+		return err
+	}
+	return s.Stop()
+}
+
+// prepareShutdown 执行关闭的前两步，成功后不再执行新的命令，调用方随后应调用 Stop
+func (s *Server) prepareShutdown(opts ShutdownOptions) error {
+	s.shutdownMu.Lock()
+	if s.shutdownAbort != nil {
+		s.shutdownMu.Unlock()
+		logger.Warn("SHUTDOWN is already in progress")
+		return errShutdownFailed
+	}
+	abort := make(chan struct{})
+	s.shutdownAbort = abort
+	s.shutdownMu.Unlock()
+
+	defer func() {
+		s.shutdownMu.Lock()
+		s.shutdownAbort = nil
+		s.shutdownMu.Unlock()
+	}()
+
+	logger.Info("User requested shutdown...")
+	s.gate.pause()
+
+	if !opts.Now {
+		timeout := time.Duration(s.limits.shutdownTimeout.Load()) * time.Second
+		if !s.gate.drain(timeout, abort) {
+			select {
+			case <-abort:
+				logger.Warn("Shutdown aborted")
+				s.gate.resume()
+				return errShutdownFailed
+			default:
+				logger.Warnf("Commands still running after %v, shutting down anyway", timeout)
+			}
+		}
+	}
+
+	if err := s.persist(opts); err != nil {
+		if !opts.Force {
+			logger.Errorf("Error trying to save the DB, can't exit: %v", err)
+			s.gate.resume()
+			return errShutdownFailed
+		}
+		logger.Warnf("Error trying to save the DB, exiting anyway (FORCE): %v", err)
+	}
+
+	s.gate.close()
+	return nil
+}
+
+// persist 按选项保存数据
+func (s *Server) persist(opts ShutdownOptions) error {
+	if opts.NoSave {
+		return nil
+	}
+	if s.persister == nil {
+		if opts.Save {
+			return errPersistenceMissing
+		}
+		return nil
+	}
+
+	logger.Info("Saving the final snapshot before exiting.")
+	err := s.persister.Save()
+	s.stats.lastSaveTime.Store(time.Now().Unix())
+	s.stats.lastSaveErr.Store(err != nil)
+	return err
+}
+
+// abortShutdown 取消正在进行的关闭
+func (s *Server) abortShutdown() error {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+
+	if s.shutdownAbort == nil {
+		return errNoShutdown
+	}
+	select {
+	case <-s.shutdownAbort:
+	default:
+		close(s.shutdownAbort)
+	}
+	return nil
+}
+
+func isShutdownCommand(cmd *protocol.Value) bool {
+	name, _, ok := splitCommand(cmd)
+	return ok && name == "SHUTDOWN"
+}
+
+// handleShutdown 处理 SHUTDOWN [NOSAVE|SAVE] [NOW] [FORCE] [ABORT]
+// 成功时与 Redis 一样不回复，返回 errShuttingDown 让连接退出；失败和 ABORT 返回要写出的回复
+func (c *Client) handleShutdown(args []protocol.Value) (*protocol.Value, error) {
+	var opts ShutdownOptions
+	var abort bool
+	for _, arg := range args {
+		switch strings.ToUpper(arg.Str) {
+		case "NOSAVE":
+			opts.NoSave = true
+		case "SAVE":
+			opts.Save = true
+		case "NOW":
+			opts.Now = true
+		case "FORCE":
+			opts.Force = true
+		case "ABORT":
+			abort = true
+		default:
+			return protocol.Error("ERR syntax error"), nil
+		}
+	}
+	if (opts.NoSave && opts.Save) || (abort && len(args) > 1) {
+		return protocol.Error("ERR syntax error"), nil
+	}
+
+	if c.server == nil {
+		return protocol.Error("ERR shutdown is not supported"), nil
+	}
+
+	if abort {
+		if err := c.server.abortShutdown(); err != nil {
+			return protocol.Error(err.Error()), nil
+		}
+		return protocol.SimpleString("OK"), nil
+	}
+
+	if err := c.server.prepareShutdown(opts); err != nil {
+		return protocol.Error(err.Error()), nil
+	}
+	// Stop 会等待所有连接退出，包括当前连接，因此在另一个 goroutine 中执行
+	go c.server.Stop()
+	return nil, errShuttingDown
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"errors"
+	"go-redis/logger"
+	"go-redis/protocol"
+	"go-redis/store"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// slowHandler 模拟执行时间较长的命令
+type slowHandler struct {
+	d time.Duration
+}
+
+func (h slowHandler) Handle(args []protocol.Value) *protocol.Value {
+	time.Sleep(h.d)
+	return protocol.SimpleString("OK")
+}
+
+type fakePersister struct {
+	err   error
+	saves atomic.Int32
+}
+
+func (p *fakePersister) Save() error {
+	p.saves.Add(1)
+	return p.err
+}
+
+// startShutdownServer 启动服务器并返回 Serve 的结果
+func startShutdownServer(t *testing.T) (*Server, <-chan error) {
+	t.Helper()
+	logger.SetLevel(logrus.PanicLevel)
+
+	srv := NewServer("127.0.0.1:0", store.NewStore())
+	srv.router.Register("SLOW", slowHandler{d: 300 * time.Millisecond})
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve() }()
+	t.Cleanup(func() { srv.Stop() })
+	return srv, served
+}
+
+func waitServed(t *testing.T, served <-chan error) {
+	t.Helper()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestShutdownCommand(t *testing.T) {
+	srv, served := startShutdownServer(t)
+	conn := dialTest(t, srv.Addr())
+
+	conn.send(t, "SHUTDOWN", "NOSAVE")
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("SHUTDOWN replied %q, expected the connection to be closed", data)
+	}
+	waitServed(t, served)
+}
+
+func TestShutdownDrainsInflightCommands(t *testing.T) {
+	srv, served := startShutdownServer(t)
+	slow := dialTest(t, srv.Addr())
+	late := dialTest(t, srv.Addr())
+	admin := dialTest(t, srv.Addr())
+
+	slow.send(t, "SLOW")
+	time.Sleep(50 * time.Millisecond)
+	admin.send(t, "SHUTDOWN")
+	time.Sleep(50 * time.Millisecond)
+	// 关闭开始后到达的命令不再执行
+	late.send(t, "SET", "k", "v")
+
+	if v := slow.reply(t); v.Str != "OK" {
+		t.Fatalf("in-flight command reply: %+v", v)
+	}
+	if data, _ := io.ReadAll(late); len(data) != 0 {
+		t.Errorf("command after SHUTDOWN replied %q", data)
+	}
+	waitServed(t, served)
+
+	if _, ok := srv.db.Get("k"); ok {
+		t.Error("command after SHUTDOWN was executed")
+	}
+}
+
+func TestShutdownAbort(t *testing.T) {
+	srv, _ := startShutdownServer(t)
+	slow := dialTest(t, srv.Addr())
+	admin := dialTest(t, srv.Addr())
+	other := dialTest(t, srv.Addr())
+
+	if v := other.do(t, "SHUTDOWN", "ABORT"); v.Str != "ERR No shutdown in progress." {
+		t.Fatalf("SHUTDOWN ABORT without shutdown: %+v", v)
+	}
+
+	slow.send(t, "SLOW")
+	time.Sleep(50 * time.Millisecond)
+	admin.send(t, "SHUTDOWN")
+	time.Sleep(50 * time.Millisecond)
+
+	if v := other.do(t, "SHUTDOWN", "ABORT"); v.Str != "OK" {
+		t.Fatalf("SHUTDOWN ABORT: %+v", v)
+	}
+	if v := admin.reply(t); v.Str != "ERR Errors trying to SHUTDOWN. Check logs." {
+		t.Fatalf("aborted SHUTDOWN: %+v", v)
+	}
+
+	// 服务器继续运行
+	slow.reply(t)
+	if v := admin.do(t, "PING"); v.Str != "PONG" {
+		t.Fatalf("PING after abort: %+v", v)
+	}
+}
+
+func TestShutdownPersistence(t *testing.T) {
+	srv, served := startShutdownServer(t)
+	conn := dialTest(t, srv.Addr())
+
+	// 没有配置持久化时 SAVE 失败
+	if v := conn.do(t, "SHUTDOWN", "SAVE"); v.Type != protocol.ErrorType {
+		t.Fatalf("SHUTDOWN SAVE without persistence: %+v", v)
+	}
+
+	p := &fakePersister{err: errors.New("disk full")}
+	srv.SetPersister(p)
+	if v := conn.do(t, "SHUTDOWN"); v.Str != "ERR Errors trying to SHUTDOWN. Check logs." {
+		t.Fatalf("SHUTDOWN with failing save: %+v", v)
+	}
+	// NOSAVE 不调用 Save
+	conn.send(t, "SHUTDOWN", "NOSAVE", "NOW")
+	waitServed(t, served)
+	if n := p.saves.Load(); n != 1 {
+		t.Errorf("Save called %d times, want 1", n)
+	}
+}
+
+func TestShutdownForce(t *testing.T) {
+	srv, served := startShutdownServer(t)
+	conn := dialTest(t, srv.Addr())
+
+	p := &fakePersister{err: errors.New("disk full")}
+	srv.SetPersister(p)
+	conn.send(t, "SHUTDOWN", "FORCE")
+	waitServed(t, served)
+	if n := p.saves.Load(); n != 1 {
+		t.Errorf("Save called %d times, want 1", n)
+	}
+}
+
+func TestShutdownSyntax(t *testing.T) {
+	srv, _ := startShutdownServer(t)
+	conn := dialTest(t, srv.Addr())
+
+	for _, args := range [][]string{
+		{"SHUTDOWN", "SAVE", "NOSAVE"},
+		{"SHUTDOWN", "ABORT", "NOW"},
+		{"SHUTDOWN", "LATER"},
+	} {
+		if v := conn.do(t, args...); v.Str != "ERR syntax error" {
+			t.Errorf("%v: %+v", args, v)
+		}
+	}
+
+}
+
+func TestShutdownInMulti(t *testing.T) {
+	srv, _ := startShutdownServer(t)
+	conn := dialTest(t, srv.Addr())
+
+	// 与其他 no_multi 命令一样在入队时拒绝，EXEC 放弃整个事务，服务器继续运行
+	conn.do(t, "MULTI")
+	conn.do(t, "SET", "k", "v")
+	for _, name := range []string{"SHUTDOWN", "MONITOR", "SUBSCRIBE"} {
+		args := []string{name}
+		if name == "SUBSCRIBE" {
+			args = append(args, "ch")
+		}
+		if v := conn.do(t, args...); v.Str != "ERR Command not allowed inside a transaction" {
+			t.Errorf("%s in MULTI: %+v", name, v)
+		}
+	}
+	if v := conn.do(t, "EXEC"); v.Type != protocol.ErrorType || !strings.HasPrefix(v.Str, "EXECABORT") {
+		t.Fatalf("EXEC: %+v", v)
+	}
+	if v := conn.do(t, "PING"); v.Str != "PONG" {
+		t.Fatalf("PING after EXEC: %+v", v)
+	}
+	if _, ok := srv.db.Get("k"); ok {
+		t.Error("aborted transaction was executed")
+	}
+}
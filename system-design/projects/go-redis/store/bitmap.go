@@ -0,0 +1,303 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// 位图
+//
+// 位图不是单独的类型，而是把字符串值看作位数组：第 0 个字节的最高位是第 0 位。
+// 写入超出字符串长度的位置时用 0 字节补齐，与 Redis 一样偏移量最大为 2^32-1（字符串最长 512MB）
+
+// MaxBitOffset 是位偏移量的上限（不含）
+const MaxBitOffset = 1 << 32
+
+var (
+	// ErrBitOffset 表示位偏移量不是整数或超出范围
+	ErrBitOffset = errors.New("ERR bit offset is not an integer or out of range")
+	// ErrBitFieldType 表示 BITFIELD 的类型不合法
+	ErrBitFieldType = errors.New("ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+)
+
+// BitRange 是 BITCOUNT/BITPOS 的范围，Start 和 End 都包含在内，可以为负数（从末尾倒数）
+type BitRange struct {
+	Start  int64
+	End    int64
+	HasEnd bool // 为 false 时 End 表示到字符串末尾
+	Bit    bool // 为 true 时按位计算范围，否则按字节
+}
+
+// BitOperation 是 BITOP 的运算
+type BitOperation int
+
+const (
+	BitOpAnd BitOperation = iota
+	BitOpOr
+	BitOpXor
+	BitOpNot
+)
+
+// BitFieldType 是 BITFIELD 的整数类型，例如 i8、u16
+type BitFieldType struct {
+	Signed bool
+	Bits   uint // 有符号 1..64，无符号 1..63
+}
+
+// ParseBitFieldType 解析 "i<bits>" 或 "u<bits>"
+func ParseBitFieldType(s string) (BitFieldType, error) {
+	if len(s) < 2 || (s[0] != 'i' && s[0] != 'I' && s[0] != 'u' && s[0] != 'U') {
+		return BitFieldType{}, ErrBitFieldType
+	}
+	n := 0
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' || n > 64 {
+			return BitFieldType{}, ErrBitFieldType
+		}
+		n = n*10 + int(c-'0')
+	}
+
+	t := BitFieldType{Signed: s[0] == 'i' || s[0] == 'I', Bits: uint(n)}
+	if n < 1 || (t.Signed && n > 64) || (!t.Signed && n > 63) {
+		return BitFieldType{}, ErrBitFieldType
+	}
+	return t, nil
+}
+
+// BitOverflow 是 BITFIELD SET/INCRBY 溢出时的处理方式
+type BitOverflow int
+
+const (
+	OverflowWrap BitOverflow = iota // 回绕
+	OverflowSat                     // 饱和到最大或最小值
+	OverflowFail                    // 不修改，返回 nil
+)
+
+// BitFieldOpKind 是 BITFIELD 子命令的种类
+type BitFieldOpKind int
+
+const (
+	BitFieldGet BitFieldOpKind = iota
+	BitFieldSet
+	BitFieldIncrBy
+)
+
+// BitFieldOp 是 BITFIELD 的一个子命令，Value 是 SET 的值或 INCRBY 的增量
+type BitFieldOp struct {
+	Kind     BitFieldOpKind
+	Type     BitFieldType
+	Offset   int64
+	Value    int64
+	Overflow BitOverflow
+}
+
+// getBit 返回 b 中第 offset 位的值，超出长度时为 0
+func getBit[T ~string | ~[]byte](b T, offset int64) int {
+	i := offset >> 3
+	if i >= int64(len(b)) {
+		return 0
+	}
+	return int(b[i]>>(7-uint(offset&7))) & 1
+}
+
+// setBit 设置 b 中第 offset 位，b 的长度必须足够
+func setBit(b []byte, offset int64, on bool) {
+	mask := byte(1) << (7 - uint(offset&7))
+	if on {
+		b[offset>>3] |= mask
+	} else {
+		b[offset>>3] &^= mask
+	}
+}
+
+// normalizeRange 把可以为负数的 [start, end] 转换为 [0, length) 内的范围，范围为空时 ok 为 false
+func normalizeRange(start, end, length int64) (int64, int64, bool) {
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	start = max(start, 0)
+	end = min(max(end, 0), length-1)
+	return start, end, start <= end
+}
+
+// bitRange 把 BitRange 转换为位的范围 [first, last]
+func bitRange(b string, r *BitRange) (first, last int64, ok bool) {
+	length := int64(len(b))
+	if r == nil {
+		return 0, length*8 - 1, length > 0
+	}
+
+	unit := int64(8)
+	if r.Bit {
+		unit = 1
+	}
+	end := r.End
+	if !r.HasEnd {
+		end = -1
+	}
+	start, end, ok := normalizeRange(r.Start, end, length*8/unit)
+	if !ok {
+		return 0, 0, false
+	}
+	return start * unit, end*unit + unit - 1, true
+}
+
+// bitCount 统计 b 在位范围 [first, last] 内 1 的个数
+func bitCount(b string, first, last int64) int64 {
+	firstByte, lastByte := first>>3, last>>3
+	var n int
+	for i := firstByte; i <= lastByte; i++ {
+		c := b[i]
+		if i == firstByte {
+			c &= 0xff >> uint(first&7)
+		}
+		if i == lastByte {
+			c &= 0xff << (7 - uint(last&7))
+		}
+		n += bits.OnesCount8(c)
+	}
+	return int64(n)
+}
+
+// bitPos 返回 b 在位范围 [first, last] 内第一个值为 bit 的位置，没有时返回 -1
+func bitPos(b string, bit int, first, last int64) int64 {
+	// 整个字节都不可能匹配时跳过这个字节
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+	for pos := first; pos <= last; {
+		if pos&7 == 0 && pos+7 <= last && b[pos>>3] == skip {
+			pos += 8
+			continue
+		}
+		if getBit(b, pos) == bit {
+			return pos
+		}
+		pos++
+	}
+	return -1
+}
+
+// bitOp 对 srcs 按字节执行运算，较短的值视为用 0 补齐，结果长度为最长的值的长度
+func bitOp(op BitOperation, srcs []string) []byte {
+	length := 0
+	for _, src := range srcs {
+		length = max(length, len(src))
+	}
+	result := make([]byte, length)
+	if length == 0 {
+		return result
+	}
+
+	if op == BitOpNot {
+		for i := range result {
+			result[i] = ^srcs[0][i]
+		}
+		return result
+	}
+
+	copy(result, srcs[0])
+	for _, src := range srcs[1:] {
+		for i := range result {
+			var c byte
+			if i < len(src) {
+				c = src[i]
+			}
+			switch op {
+			case BitOpAnd:
+				result[i] &= c
+			case BitOpOr:
+				result[i] |= c
+			case BitOpXor:
+				result[i] ^= c
+			}
+		}
+	}
+	return result
+}
+
+// getBits 读取 b 中从 offset 开始的 n 位，作为无符号整数返回
+func getBits[T ~string | ~[]byte](b T, offset int64, n uint) uint64 {
+	var v uint64
+	for i := uint(0); i < n; i++ {
+		v = v<<1 | uint64(getBit(b, offset+int64(i)))
+	}
+	return v
+}
+
+// setBits 把 v 的低 n 位写入 b 中从 offset 开始的位置，b 的长度必须足够
+func setBits(b []byte, offset int64, n uint, v uint64) {
+	for i := uint(0); i < n; i++ {
+		setBit(b, offset+int64(i), v>>(n-1-i)&1 == 1)
+	}
+}
+
+// get 把读出的位解释为该类型的整数
+func (t BitFieldType) get(raw uint64) int64 {
+	if t.Signed && t.Bits < 64 && raw&(1<<(t.Bits-1)) != 0 {
+		// 符号扩展
+		return int64(raw | ^uint64(0)<<t.Bits)
+	}
+	return int64(raw)
+}
+
+// mask 返回该类型所占位的掩码
+func (t BitFieldType) mask() uint64 {
+	if t.Bits == 64 {
+		return math.MaxUint64
+	}
+	return 1<<t.Bits - 1
+}
+
+// bounds 返回该类型能表示的范围
+func (t BitFieldType) bounds() (lo, hi int64) {
+	if t.Signed {
+		return -1 << (t.Bits - 1), 1<<(t.Bits-1) - 1
+	}
+	return 0, 1<<t.Bits - 1
+}
+
+// wrap 把 v 截断为该类型（回绕）
+func (t BitFieldType) wrap(v uint64) int64 {
+	return t.get(v & t.mask())
+}
+
+// fit 按 overflow 把计算结果转换为该类型能表示的值，ok 为 false 表示 FAIL
+// raw 是结果的低 64 位，用于 WRAP；up/down 表示结果超出了上界/下界（结果本身可能超出 int64）
+func (t BitFieldType) fit(raw uint64, up, down bool, overflow BitOverflow) (int64, bool) {
+	if !up && !down {
+		return t.wrap(raw), true
+	}
+	lo, hi := t.bounds()
+	switch overflow {
+	case OverflowSat:
+		if up {
+			return hi, true
+		}
+		return lo, true
+	case OverflowFail:
+		return 0, false
+	default:
+		return t.wrap(raw), true
+	}
+}
+
+// setValue 计算 SET value 之后的值
+func (t BitFieldType) setValue(v int64, overflow BitOverflow) (int64, bool) {
+	lo, hi := t.bounds()
+	return t.fit(uint64(v), v > hi, v < lo, overflow)
+}
+
+// incrBy 计算 old + incr 之后的值，old 在该类型的范围内
+func (t BitFieldType) incrBy(old, incr int64, overflow BitOverflow) (int64, bool) {
+	lo, hi := t.bounds()
+	// 用无符号数比较增量和到边界的距离，避免计算本身溢出
+	up := incr > 0 && uint64(incr) > uint64(hi)-uint64(old)
+	down := incr < 0 && uint64(-incr) > uint64(old)-uint64(lo)
+	return t.fit(uint64(old)+uint64(incr), up, down, overflow)
+}
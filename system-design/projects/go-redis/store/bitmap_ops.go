@@ -0,0 +1,237 @@
+package store
+
+import (
+	"strconv"
+
+	"go-redis/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stringValue 返回 key 上的字符串值，INCR 保存的整数转换为十进制字符串，调用前需持有锁
+// 键不存在时 exists 为 false；键存在但不是字符串时返回 ErrWrongType
+func (s *Store) stringValue(key string) (value string, exists bool, err error) {
+	v, exists := s.lookup(key)
+	if !exists {
+		return "", false, nil
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true, nil
+	case int64:
+		return strconv.FormatInt(v, 10), true, nil
+	default:
+		return "", true, ErrWrongType
+	}
+}
+
+// growString 返回 value 的可修改副本，长度至少为 n 字节，不足的部分用 0 补齐
+func growString(value string, n int64) []byte {
+	b := make([]byte, max(int64(len(value)), n))
+	copy(b, value)
+	return b
+}
+
+// storeString 保存修改后的字符串，保留原有的过期时间，调用前需持有写锁
+func (s *Store) storeString(key string, b []byte, existed bool) {
+	if !existed {
+		s.Notify(NotifyNew, "new", key)
+	}
+	s.setValue(key, string(b))
+	s.keyModified(key)
+}
+
+// GetBit 返回 key 上字符串第 offset 位的值，键不存在或超出长度时为 0
+func (s *Store) GetBit(key string, offset int64) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, _, err := s.stringValue(key)
+	if err != nil {
+		return 0, err
+	}
+	return getBit(value, offset), nil
+}
+
+// SetBit 设置 key 上字符串第 offset 位，返回原来的值
+// 键不存在时创建，字符串长度不够时用 0 字节补齐
+func (s *Store) SetBit(key string, offset int64, on bool) (int, error) {
+	logger.WithFields(logrus.Fields{
+		"operation": "SETBIT",
+		"key":       key,
+		"offset":    offset,
+	}).Debug("执行 SetBit 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	value, exists, err := s.stringValue(key)
+	if err != nil {
+		return 0, err
+	}
+
+	old := getBit(value, offset)
+	b := growString(value, offset>>3+1)
+	setBit(b, offset, on)
+	s.storeString(key, b, exists)
+	return old, nil
+}
+
+// BitCount 统计 key 上字符串在范围 r 内值为 1 的位数，r 为 nil 表示整个字符串
+func (s *Store) BitCount(key string, r *BitRange) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, _, err := s.stringValue(key)
+	if err != nil {
+		return 0, err
+	}
+
+	first, last, ok := bitRange(value, r)
+	if !ok {
+		return 0, nil
+	}
+	return bitCount(value, first, last), nil
+}
+
+// BitPos 返回 key 上字符串在范围 r 内第一个值为 bit 的位置，r 为 nil 表示整个字符串
+// 与 Redis 相同：查找 0 且没有指定范围终点时，字符串全为 1 则返回字符串末尾之后的第一位；
+// 键不存在时查找 1 返回 -1，查找 0 返回 0
+func (s *Store) BitPos(key string, bit int, r *BitRange) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, exists, err := s.stringValue(key)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		if bit == 1 {
+			return -1, nil
+		}
+		return 0, nil
+	}
+
+	first, last, ok := bitRange(value, r)
+	if !ok {
+		return -1, nil
+	}
+	pos := bitPos(value, bit, first, last)
+	if pos == -1 && bit == 0 && (r == nil || !r.HasEnd) {
+		return int64(len(value)) * 8, nil
+	}
+	return pos, nil
+}
+
+// BitOp 对 keys 上的字符串执行位运算并把结果保存到 dest，返回结果的长度
+// 不存在的键视为空字符串；结果为空时删除 dest。deleted 表示 dest 原来存在且被删除
+func (s *Store) BitOp(op BitOperation, dest string, keys []string) (length int64, deleted bool, err error) {
+	logger.WithFields(logrus.Fields{
+		"operation": "BITOP",
+		"dest":      dest,
+		"keys":      keys,
+	}).Debug("执行 BitOp 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	srcs := make([]string, len(keys))
+	for i, key := range keys {
+		s.expireIfNeeded(key)
+		if srcs[i], _, err = s.stringValue(key); err != nil {
+			return 0, false, err
+		}
+	}
+
+	result := bitOp(op, srcs)
+	s.expireIfNeeded(dest)
+	_, exists := s.data[dest]
+	if len(result) == 0 {
+		if exists {
+			s.removeKey(dest)
+		}
+		return 0, exists, nil
+	}
+
+	if !exists {
+		s.Notify(NotifyNew, "new", dest)
+	}
+	// 与 SET 一样覆盖原有的值和过期时间
+	s.setValue(dest, string(result))
+	delete(s.expires, dest)
+	s.keyModified(dest)
+	return int64(len(result)), false, nil
+}
+
+// BitField 依次执行 BITFIELD 的子命令，返回每个子命令的结果，OVERFLOW FAIL 未执行的为 nil
+// changed 表示是否修改了键。只有 GET 时不会创建键
+func (s *Store) BitField(key string, ops []BitFieldOp) (results []*int64, changed bool, err error) {
+	write := false
+	var end int64 // 写操作涉及的最大字节数
+	for _, op := range ops {
+		if op.Kind != BitFieldGet {
+			write = true
+			end = max(end, (op.Offset+int64(op.Type.Bits)+7)>>3)
+		}
+	}
+
+	if !write {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	} else {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.expireIfNeeded(key)
+	}
+
+	value, exists, err := s.stringValue(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !write {
+		results = make([]*int64, len(ops))
+		for i, op := range ops {
+			v := op.Type.get(getBits(value, op.Offset, op.Type.Bits))
+			results[i] = &v
+		}
+		return results, false, nil
+	}
+
+	// 与 Redis 一样，有写操作时先把字符串扩展到所需的长度，即使所有写操作都因溢出失败
+	b := growString(value, end)
+	results = make([]*int64, len(ops))
+	for i, op := range ops {
+		old := op.Type.get(getBits(b, op.Offset, op.Type.Bits))
+		if op.Kind == BitFieldGet {
+			results[i] = &old
+			continue
+		}
+
+		var v int64
+		var ok bool
+		if op.Kind == BitFieldSet {
+			v, ok = op.Type.setValue(op.Value, op.Overflow)
+		} else {
+			v, ok = op.Type.incrBy(old, op.Value, op.Overflow)
+		}
+		if !ok {
+			continue
+		}
+
+		setBits(b, op.Offset, op.Type.Bits, uint64(v))
+		changed = true
+		if op.Kind == BitFieldSet {
+			results[i] = &old
+		} else {
+			results[i] = &v
+		}
+	}
+
+	if changed || len(b) > len(value) {
+		s.storeString(key, b, exists)
+	}
+	return results, changed, nil
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSetBitExtends(t *testing.T) {
+	s := NewStore()
+
+	if old, err := s.SetBit("b", 7, true); err != nil || old != 0 {
+		t.Fatalf("SetBit: %d %v", old, err)
+	}
+	if old, _ := s.SetBit("b", 7, true); old != 1 {
+		t.Errorf("old bit = %d, want 1", old)
+	}
+	s.SetBit("b", 100, true)
+	v, _ := s.Get("b")
+	if str := v.(string); len(str) != 13 || str[0] != 0x01 || str[12] != 0x08 {
+		t.Errorf("value = %q", str)
+	}
+	if bit, _ := s.GetBit("b", 100); bit != 1 {
+		t.Errorf("GetBit(100) = %d", bit)
+	}
+	if bit, _ := s.GetBit("b", 1<<20); bit != 0 {
+		t.Errorf("GetBit beyond length = %d", bit)
+	}
+
+	// INCR 保存的整数按十进制字符串处理
+	s.Incr("n")
+	if bit, _ := s.GetBit("n", 7); bit != 1 { // "1" = 0x31
+		t.Errorf("GetBit on integer = %d", bit)
+	}
+
+	s.XAdd("stream", "*", []string{"a", "1"}, false, nil)
+	if _, err := s.SetBit("stream", 0, true); err != ErrWrongType {
+		t.Errorf("SetBit on stream: %v", err)
+	}
+}
+
+func TestBitCountAndPos(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "\xff\xf0\x00")
+
+	tests := []struct {
+		r    *BitRange
+		want int64
+	}{
+		{nil, 12},
+		{&BitRange{Start: 1, End: 1, HasEnd: true}, 4},
+		{&BitRange{Start: -2, End: -1, HasEnd: true}, 4},
+		{&BitRange{Start: 5, End: 10, HasEnd: true, Bit: true}, 6},
+		{&BitRange{Start: 2, End: 1, HasEnd: true}, 0},
+		{&BitRange{Start: 100, End: 200, HasEnd: true}, 0},
+	}
+	for _, tt := range tests {
+		if got, _ := s.BitCount("k", tt.r); got != tt.want {
+			t.Errorf("BitCount(%+v) = %d, want %d", tt.r, got, tt.want)
+		}
+	}
+
+	posTests := []struct {
+		bit  int
+		r    *BitRange
+		want int64
+	}{
+		{0, nil, 12},
+		{1, nil, 0},
+		{1, &BitRange{Start: 2}, -1},
+		{1, &BitRange{Start: 9, End: -1, HasEnd: true, Bit: true}, 9},
+		{0, &BitRange{Start: 0, End: 0, HasEnd: true}, -1},
+	}
+	for _, tt := range posTests {
+		if got, _ := s.BitPos("k", tt.bit, tt.r); got != tt.want {
+			t.Errorf("BitPos(%d, %+v) = %d, want %d", tt.bit, tt.r, got, tt.want)
+		}
+	}
+
+	// 全为 1 且没有指定终点时，查找 0 返回字符串之后的第一位
+	s.Set("ones", "\xff\xff")
+	if got, _ := s.BitPos("ones", 0, nil); got != 16 {
+		t.Errorf("BitPos(ones, 0) = %d, want 16", got)
+	}
+	if got, _ := s.BitPos("ones", 0, &BitRange{Start: 0, End: -1, HasEnd: true}); got != -1 {
+		t.Errorf("BitPos(ones, 0, with end) = %d, want -1", got)
+	}
+	if got, _ := s.BitPos("missing", 0, nil); got != 0 {
+		t.Errorf("BitPos(missing, 0) = %d", got)
+	}
+	if got, _ := s.BitPos("missing", 1, nil); got != -1 {
+		t.Errorf("BitPos(missing, 1) = %d", got)
+	}
+}
+
+func TestBitOp(t *testing.T) {
+	s := NewStore()
+	s.Set("a", "\xf0\x0f")
+	s.Set("b", "\xff")
+
+	for _, tt := range []struct {
+		op   BitOperation
+		keys []string
+		want string
+	}{
+		{BitOpAnd, []string{"a", "b"}, "\xf0\x00"},
+		{BitOpOr, []string{"a", "b"}, "\xff\x0f"},
+		{BitOpXor, []string{"a", "b"}, "\x0f\x0f"},
+		{BitOpNot, []string{"a"}, "\x0f\xf0"},
+		{BitOpOr, []string{"a", "missing"}, "\xf0\x0f"},
+	} {
+		n, _, err := s.BitOp(tt.op, "dest", tt.keys)
+		v, _ := s.Get("dest")
+		if err != nil || n != int64(len(tt.want)) || v != tt.want {
+			t.Errorf("BitOp(%d, %v) = %d %q %v, want %q", tt.op, tt.keys, n, v, err, tt.want)
+		}
+	}
+
+	if n, deleted, _ := s.BitOp(BitOpAnd, "dest", []string{"missing"}); n != 0 || !deleted || s.Exists("dest") {
+		t.Errorf("empty result should delete dest: n=%d deleted=%v", n, deleted)
+	}
+}
+
+func TestBitField(t *testing.T) {
+	s := NewStore()
+
+	i := func(v int64) *int64 { return &v }
+	u8 := BitFieldType{Bits: 8}
+	i8 := BitFieldType{Signed: true, Bits: 8}
+	i64 := BitFieldType{Signed: true, Bits: 64}
+
+	// 只有 GET 时不创建键
+	res, _, _ := s.BitField("bf", []BitFieldOp{{Kind: BitFieldGet, Type: u8}})
+	if *res[0] != 0 || s.Exists("bf") {
+		t.Fatalf("GET on missing key: %v exists=%v", *res[0], s.Exists("bf"))
+	}
+
+	tests := []struct {
+		op   BitFieldOp
+		want *int64
+	}{
+		{BitFieldOp{Kind: BitFieldSet, Type: u8, Offset: 0, Value: 200}, i(0)},
+		{BitFieldOp{Kind: BitFieldGet, Type: i8, Offset: 0}, i(-56)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: u8, Offset: 0, Value: 100}, i(44)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: u8, Offset: 0, Value: 300, Overflow: OverflowSat}, i(255)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: u8, Offset: 0, Value: 1, Overflow: OverflowFail}, nil},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: i8, Offset: 8, Value: -200, Overflow: OverflowSat}, i(-128)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: i8, Offset: 8, Value: -1}, i(127)},
+		{BitFieldOp{Kind: BitFieldSet, Type: i8, Offset: 16, Value: 1000, Overflow: OverflowFail}, nil},
+		{BitFieldOp{Kind: BitFieldSet, Type: BitFieldType{Bits: 4}, Offset: 4, Value: 0x1f}, i(15)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: i64, Offset: 64, Value: math.MaxInt64}, i(math.MaxInt64)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: i64, Offset: 64, Value: 1}, i(math.MinInt64)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: i64, Offset: 64, Value: math.MinInt64, Overflow: OverflowSat}, i(math.MinInt64)},
+		{BitFieldOp{Kind: BitFieldIncrBy, Type: BitFieldType{Bits: 63}, Offset: 128, Value: math.MinInt64, Overflow: OverflowSat}, i(0)},
+	}
+	for _, tt := range tests {
+		res, _, err := s.BitField("bf", []BitFieldOp{tt.op})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := res[0]
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.op, deref(got), deref(tt.want))
+		}
+	}
+
+	v, _ := s.Get("bf")
+	if str := v.(string); str[0] != 0xff {
+		t.Errorf("first byte = %#x, want 0xff", str[0])
+	}
+}
+
+func deref(p *int64) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestParseBitFieldType(t *testing.T) {
+	for _, s := range []string{"i1", "i64", "u1", "u63", "I8", "U16"} {
+		if _, err := ParseBitFieldType(s); err != nil {
+			t.Errorf("ParseBitFieldType(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "i", "i0", "i65", "u64", "x8", "i8a", "i999999999999999999999"} {
+		if _, err := ParseBitFieldType(s); err != ErrBitFieldType {
+			t.Errorf("ParseBitFieldType(%q): expected error", s)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+// 阻塞命令（如 XREAD BLOCK）的等待机制：
+// 命令先通过 WatchKeys 登记关心的键，再尝试读取；
+// 读不到数据时等待返回的通道，键上有写入时会收到通知后重试。
+// 先登记再读取可以保证两步之间发生的写入不会被错过。
+
+// WatchKeys 为一组键登记等待者，返回的通道在任一键被写入时收到通知
+func (s *Store) WatchKeys(keys []string) chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		set, ok := s.waiters[key]
+		if !ok {
+			set = make(map[chan struct{}]struct{})
+			s.waiters[key] = set
+		}
+		set[ch] = struct{}{}
+	}
+
+	return ch
+}
+
+// UnwatchKeys 取消 WatchKeys 登记的等待者
+func (s *Store) UnwatchKeys(keys []string, ch chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		set, ok := s.waiters[key]
+		if !ok {
+			continue
+		}
+		delete(set, ch)
+		if len(set) == 0 {
+			delete(s.waiters, key)
+		}
+	}
+}
+
+// Done 返回在 Store 关闭时被关闭的通道，阻塞中的命令据此提前返回
+func (s *Store) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close 关闭 Store，停止后台过期清理并唤醒所有阻塞中的命令
+func (s *Store) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// signalKey 通知等待 key 的阻塞命令，调用前需持有写锁
+func (s *Store) signalKey(key string) {
+	for ch := range s.waiters[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc64"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-redis/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DUMP/RESTORE 的序列化格式
+//
+// 与 Redis 相同：值的 RDB 编码，后面是 2 字节小端序的 RDB 版本号和 8 字节小端序的 CRC-64/Jones 校验和
+// （覆盖前面的所有字节）。
+//
+//	+------+-------------+---------+----------+
+//	| type | value ...   | version | CRC64    |
+//	+------+-------------+---------+----------+
+//
+// 字符串使用 RDB 的字符串类型（0），与 Redis 互通：这里 DUMP 的字符串可以 RESTORE 到 Redis，
+// Redis DUMP 的字符串（包括整数编码和 LZF 压缩的）也可以 RESTORE 到这里。
+// stream 使用本项目私有的类型 dumpTypeStream，Redis 的 stream 编码基于 listpack 和 rax，这里没有实现。
+
+const (
+	// DumpVersion 是写入的 RDB 版本号，RESTORE 接受不高于它的版本（与 Redis 7.2 相同）
+	DumpVersion = 11
+
+	dumpTypeString = 0
+	dumpTypeStream = 200 // 私有类型，不在 Redis 的 RDB 类型范围内
+
+	rdb6BitLen  = 0
+	rdb14BitLen = 1
+	rdb32BitLen = 0x80
+	rdb64BitLen = 0x81
+	rdbEncVal   = 3
+
+	rdbEncInt8  = 0
+	rdbEncInt16 = 1
+	rdbEncInt32 = 2
+	rdbEncLZF   = 3
+)
+
+var (
+	ErrDumpPayload    = errors.New("ERR DUMP payload version or checksum are wrong")
+	ErrBadDumpFormat  = errors.New("ERR Bad data format")
+	crc64JonesTable   = crc64.MakeTable(0x95ac9329ac4bc9b5) // 0xad93d23594c935a9 的反转形式
+	errDumpTruncation = errors.New("truncated")
+)
+
+// crc64Jones 计算 Redis 使用的 CRC-64/Jones（初值 0，结果不取反）
+// hash/crc64 的初值和结果都会取反，传入全 1 再把结果取反即可抵消
+func crc64Jones(data []byte) uint64 {
+	return ^crc64.Update(math.MaxUint64, crc64JonesTable, data)
+}
+
+// dumpWriter 按 RDB 编码写入值
+type dumpWriter struct {
+	buf []byte
+}
+
+func (w *dumpWriter) writeLen(n uint64) {
+	switch {
+	case n < 1<<6:
+		w.buf = append(w.buf, byte(n))
+	case n < 1<<14:
+		w.buf = append(w.buf, byte(n>>8)|rdb14BitLen<<6, byte(n))
+	case n <= math.MaxUint32:
+		w.buf = append(w.buf, rdb32BitLen)
+		w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(n))
+	default:
+		w.buf = append(w.buf, rdb64BitLen)
+		w.buf = binary.BigEndian.AppendUint64(w.buf, n)
+	}
+}
+
+// writeString 写入字符串，可以表示为 32 位整数的短字符串使用整数编码（与 Redis 相同）
+func (w *dumpWriter) writeString(s string) {
+	if len(s) <= 11 {
+		if n, err := strconv.ParseInt(s, 10, 32); err == nil && strconv.FormatInt(n, 10) == s {
+			w.writeInt(n)
+			return
+		}
+	}
+	w.writeLen(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *dumpWriter) writeInt(n int64) {
+	switch {
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		w.buf = append(w.buf, rdbEncVal<<6|rdbEncInt8, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		w.buf = append(w.buf, rdbEncVal<<6|rdbEncInt16)
+		w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(n))
+	default:
+		w.buf = append(w.buf, rdbEncVal<<6|rdbEncInt32)
+		w.buf = binary.LittleEndian.AppendUint32(w.buf, uint32(n))
+	}
+}
+
+func (w *dumpWriter) writeMillis(t time.Time) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, uint64(t.UnixMilli()))
+}
+
+func (w *dumpWriter) writeID(id StreamID) {
+	w.writeLen(id.Ms)
+	w.writeLen(id.Seq)
+}
+
+// dumpValue 序列化一个值，返回完整的 DUMP 结果
+func dumpValue(v interface{}) []byte {
+	w := &dumpWriter{}
+
+	switch v := v.(type) {
+	case string:
+		w.buf = append(w.buf, dumpTypeString)
+		w.writeString(v)
+	case int64:
+		w.buf = append(w.buf, dumpTypeString)
+		w.writeString(strconv.FormatInt(v, 10))
+	case *Stream:
+		w.buf = append(w.buf, dumpTypeStream)
+		w.writeStream(v)
+	}
+
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, DumpVersion)
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, crc64Jones(w.buf))
+	return w.buf
+}
+
+// writeStream 写入 stream：条目、最后 ID，以及每个消费组的消费者和待确认列表
+func (w *dumpWriter) writeStream(st *Stream) {
+	w.writeLen(uint64(len(st.entries)))
+	for _, e := range st.entries {
+		w.writeID(e.ID)
+		w.writeLen(uint64(len(e.Fields)))
+		for _, f := range e.Fields {
+			w.writeString(f)
+		}
+	}
+	w.writeID(st.lastID)
+
+	w.writeLen(uint64(len(st.groups)))
+	for _, g := range sortedGroups(st.groups) {
+		w.writeString(g.Name)
+		w.writeID(g.LastID)
+
+		consumers := sortedConsumers(g.consumers)
+		w.writeLen(uint64(len(consumers)))
+		for _, c := range consumers {
+			w.writeString(c.Name)
+			w.writeMillis(c.SeenTime)
+		}
+
+		pending := sortedPending(g.pending)
+		w.writeLen(uint64(len(pending)))
+		for _, pe := range pending {
+			w.writeID(pe.ID)
+			w.writeString(pe.Consumer)
+			w.writeMillis(pe.DeliveryTime)
+			w.writeLen(uint64(pe.DeliveryCount))
+		}
+	}
+}
+
+// dumpReader 按 RDB 编码读取值，数据不完整时 err 为 errDumpTruncation
+type dumpReader struct {
+	buf []byte
+	err error
+}
+
+func (r *dumpReader) next(n int) []byte {
+	if r.err != nil || len(r.buf) < n {
+		r.err = errDumpTruncation
+		return make([]byte, n)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *dumpReader) readByte() byte {
+	return r.next(1)[0]
+}
+
+// readLen 读取长度，encoded 为 true 时 n 是特殊编码的类型（整数或 LZF）
+func (r *dumpReader) readLen() (n uint64, encoded bool) {
+	b := r.readByte()
+	switch b >> 6 {
+	case rdb6BitLen:
+		return uint64(b & 0x3f), false
+	case rdb14BitLen:
+		return uint64(b&0x3f)<<8 | uint64(r.readByte()), false
+	case rdbEncVal:
+		return uint64(b & 0x3f), true
+	}
+	switch b {
+	case rdb32BitLen:
+		return uint64(binary.BigEndian.Uint32(r.next(4))), false
+	case rdb64BitLen:
+		return binary.BigEndian.Uint64(r.next(8)), false
+	}
+	r.err = ErrBadDumpFormat
+	return 0, false
+}
+
+// readCount 读取元素个数，不允许超过剩余的字节数，避免按损坏的长度分配内存
+func (r *dumpReader) readCount() int {
+	n, encoded := r.readLen()
+	if encoded || n > uint64(len(r.buf)) {
+		r.err = ErrBadDumpFormat
+		return 0
+	}
+	return int(n)
+}
+
+func (r *dumpReader) readString() string {
+	n, encoded := r.readLen()
+	if !encoded {
+		if n > uint64(len(r.buf)) {
+			r.err = errDumpTruncation
+			return ""
+		}
+		return string(r.next(int(n)))
+	}
+
+	switch n {
+	case rdbEncInt8:
+		return strconv.FormatInt(int64(int8(r.readByte())), 10)
+	case rdbEncInt16:
+		return strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(r.next(2)))), 10)
+	case rdbEncInt32:
+		return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(r.next(4)))), 10)
+	case rdbEncLZF:
+		clen, _ := r.readLen()
+		ulen, _ := r.readLen()
+		if clen > uint64(len(r.buf)) || ulen > 512*1024*1024 {
+			r.err = ErrBadDumpFormat
+			return ""
+		}
+		out, ok := lzfDecompress(r.next(int(clen)), int(ulen))
+		if !ok {
+			r.err = ErrBadDumpFormat
+		}
+		return string(out)
+	}
+	r.err = ErrBadDumpFormat
+	return ""
+}
+
+func (r *dumpReader) readMillis() time.Time {
+	return time.UnixMilli(int64(binary.LittleEndian.Uint64(r.next(8))))
+}
+
+func (r *dumpReader) readID() StreamID {
+	ms, e1 := r.readLen()
+	seq, e2 := r.readLen()
+	if e1 || e2 {
+		r.err = ErrBadDumpFormat
+	}
+	return StreamID{Ms: ms, Seq: seq}
+}
+
+func (r *dumpReader) readStream() *Stream {
+	st := newStream()
+
+	n := r.readCount()
+	for i := 0; i < n && r.err == nil; i++ {
+		id := r.readID()
+		if i > 0 && !st.entries[i-1].ID.Less(id) {
+			r.err = ErrBadDumpFormat
+			break
+		}
+		nf := r.readCount()
+		if nf%2 != 0 {
+			r.err = ErrBadDumpFormat
+			break
+		}
+		fields := make([]string, nf)
+		for j := range fields {
+			fields[j] = r.readString()
+		}
+		st.entries = append(st.entries, StreamEntry{ID: id, Fields: fields})
+	}
+	st.lastID = r.readID()
+	if len(st.entries) > 0 && st.lastID.Less(st.entries[len(st.entries)-1].ID) {
+		r.err = ErrBadDumpFormat
+	}
+
+	ng := r.readCount()
+	for i := 0; i < ng && r.err == nil; i++ {
+		g := &ConsumerGroup{
+			Name:      r.readString(),
+			LastID:    r.readID(),
+			pending:   make(map[StreamID]*PendingEntry),
+			consumers: make(map[string]*StreamConsumer),
+		}
+
+		nc := r.readCount()
+		for j := 0; j < nc && r.err == nil; j++ {
+			c := &StreamConsumer{
+				Name:     r.readString(),
+				SeenTime: r.readMillis(),
+				pending:  make(map[StreamID]*PendingEntry),
+			}
+			g.consumers[c.Name] = c
+		}
+
+		np := r.readCount()
+		for j := 0; j < np && r.err == nil; j++ {
+			pe := &PendingEntry{ID: r.readID(), Consumer: r.readString(), DeliveryTime: r.readMillis()}
+			count, _ := r.readLen()
+			pe.DeliveryCount = int64(count)
+
+			c, ok := g.consumers[pe.Consumer]
+			if !ok {
+				r.err = ErrBadDumpFormat
+				break
+			}
+			g.pending[pe.ID] = pe
+			c.pending[pe.ID] = pe
+		}
+		st.groups[g.Name] = g
+	}
+	return st
+}
+
+// verifyDump 检查版本号和校验和，返回去掉尾部的 RDB 编码
+func verifyDump(payload []byte) ([]byte, error) {
+	if len(payload) < 10 {
+		return nil, ErrDumpPayload
+	}
+	footer := payload[len(payload)-10:]
+	if binary.LittleEndian.Uint16(footer) > DumpVersion {
+		return nil, ErrDumpPayload
+	}
+	if crc64Jones(payload[:len(payload)-8]) != binary.LittleEndian.Uint64(footer[2:]) {
+		return nil, ErrDumpPayload
+	}
+	return payload[:len(payload)-10], nil
+}
+
+// loadValue 反序列化 DUMP 的结果
+func loadValue(payload []byte) (interface{}, error) {
+	data, err := verifyDump(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &dumpReader{buf: data}
+	var v interface{}
+	switch r.readByte() {
+	case dumpTypeString:
+		v = r.readString()
+	case dumpTypeStream:
+		v = r.readStream()
+	default:
+		return nil, ErrBadDumpFormat
+	}
+	if r.err != nil || len(r.buf) != 0 {
+		return nil, ErrBadDumpFormat
+	}
+	return v, nil
+}
+
+// lzfDecompress 解压 LZF 数据（Redis 用它压缩 RDB 中较长的字符串）
+func lzfDecompress(in []byte, outLen int) ([]byte, bool) {
+	out := make([]byte, 0, outLen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		if ctrl < 1<<5 {
+			// 字面量：后面的 ctrl+1 个字节原样复制
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > outLen {
+				return nil, false
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		// 回溯引用：长度在高 3 位（7 表示还有一个长度字节），偏移跨越低 5 位和下一个字节
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, false
+			}
+			n += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, false
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		n += 2
+		if ref < 0 || len(out)+n > outLen {
+			return nil, false
+		}
+		// 引用可能与正在写入的部分重叠，逐字节复制
+		for j := 0; j < n; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	return out, len(out) == outLen
+}
+
+// sortedGroups 返回按名字排序的消费组
+func sortedGroups(m map[string]*ConsumerGroup) []*ConsumerGroup {
+	res := make([]*ConsumerGroup, 0, len(m))
+	for _, g := range m {
+		res = append(res, g)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Dump 序列化 key 上的值，键不存在时 ok 为 false
+func (s *Store) Dump(key string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, exists := s.lookup(key)
+	if !exists {
+		return nil, false
+	}
+	return dumpValue(v), true
+}
+
+// RestoreOptions 是 RESTORE 的选项
+type RestoreOptions struct {
+	ExpireAt time.Time     // 过期时间点，零值表示不过期
+	Replace  bool          // 键已存在时覆盖
+	IdleTime time.Duration // 设置键的空闲时间，小于 0 表示不设置
+	Freq     int           // 设置键的 LFU 计数器，小于 0 表示不设置
+}
+
+// Restore 把 DUMP 的结果反序列化后保存到 key
+// 过期时间点已经过去时不创建键（Replace 时删除原有的键），created 为 false
+func (s *Store) Restore(key string, payload []byte, opts RestoreOptions) (created bool, err error) {
+	logger.WithFields(logrus.Fields{
+		"operation": "RESTORE",
+		"key":       key,
+		"size":      len(payload),
+	}).Debug("执行 Restore 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	_, exists := s.data[key]
+	if exists && !opts.Replace {
+		return false, ErrBusyKey
+	}
+
+	v, err := loadValue(payload)
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		s.removeKey(key)
+	}
+	if !opts.ExpireAt.IsZero() && !opts.ExpireAt.After(time.Now()) {
+		return false, nil
+	}
+
+	s.Notify(NotifyNew, "new", key)
+	s.setValue(key, v)
+	if !opts.ExpireAt.IsZero() {
+		s.expires[key] = opts.ExpireAt
+	}
+	s.keyModified(key)
+	s.setObjectMeta(key, opts.IdleTime, opts.Freq)
+	return true, nil
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCRC64Jones(t *testing.T) {
+	// Redis crc64.c 自带的测试向量
+	if got := crc64Jones([]byte("123456789")); got != 0xe9c6d914c4b8d9ca {
+		t.Errorf("crc64Jones = %#x", got)
+	}
+}
+
+func TestRestoreRedisPayload(t *testing.T) {
+	s := NewStore()
+
+	// Redis 文档中 SET mykey 10 之后 DUMP mykey 的结果（RDB 版本 10）
+	payload := "\x00\xc0\n\n\x00n\x9fWE\x0e\xaec\xbb"
+	if _, err := s.Restore("mykey", []byte(payload), RestoreOptions{IdleTime: -1, Freq: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.Get("mykey"); v != "10" {
+		t.Errorf("restored value = %v", v)
+	}
+
+	// 与 Redis 相同的编码：DUMP 得到的结果只有版本号不同
+	dump, _ := s.Dump("mykey")
+	if got := string(dump[:3]); got != "\x00\xc0\n" {
+		t.Errorf("dump = %q", dump)
+	}
+}
+
+func TestDumpRestoreRoundTrip(t *testing.T) {
+	s := NewStore()
+	long := strings.Repeat("x", 20000)
+	s.Set("short", "hello")
+	s.Set("number", "-70000")
+	s.Set("long", long)
+	s.Set("bin", "\x00\xff")
+	s.Incr("counter")
+
+	s.XAdd("stream", "1-1", []string{"f", "v"}, false, nil)
+	s.XAdd("stream", "2-1", []string{"a", "1", "b", "2"}, false, nil)
+	s.XDel("stream", []StreamID{{Ms: 2, Seq: 1}})
+	s.XAdd("stream", "3-0", []string{"c", "3"}, false, nil)
+	s.XGroupCreate("stream", "g", "0", false)
+	s.XReadGroup("g", "alice", []string{"stream"}, []string{">"}, 1, false)
+
+	for _, key := range []string{"short", "number", "long", "bin", "counter", "stream"} {
+		dump, ok := s.Dump(key)
+		if !ok {
+			t.Fatalf("Dump(%s) missing", key)
+		}
+		if _, err := s.Restore(key+":copy", dump, RestoreOptions{IdleTime: -1, Freq: -1}); err != nil {
+			t.Fatalf("Restore(%s): %v", key, err)
+		}
+		if _, err := s.Restore(key+":copy", dump, RestoreOptions{IdleTime: -1, Freq: -1}); err != ErrBusyKey {
+			t.Errorf("Restore(%s) on existing key: %v", key, err)
+		}
+	}
+
+	for key, want := range map[string]string{"short": "hello", "number": "-70000", "long": long, "bin": "\x00\xff", "counter": "1"} {
+		if v, _ := s.Get(key + ":copy"); v != want {
+			t.Errorf("%s = %.20q, want %.20q", key, v, want)
+		}
+	}
+
+	entries, _ := s.XRange("stream:copy", StreamID{}, MaxStreamID, 0, false)
+	if len(entries) != 2 || entries[1].ID != (StreamID{Ms: 3}) || entries[1].Fields[0] != "c" {
+		t.Errorf("restored entries = %+v", entries)
+	}
+	if id, _ := s.StreamLastID("stream:copy"); id != (StreamID{Ms: 3}) {
+		t.Errorf("restored last ID = %v", id)
+	}
+	summary, err := s.XPendingSummary("stream:copy", "g")
+	if err != nil || summary.Count != 1 || summary.Consumers[0].Name != "alice" {
+		t.Errorf("restored pending = %+v %v", summary, err)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "value")
+	dump, _ := s.Dump("k")
+	opts := RestoreOptions{IdleTime: -1, Freq: -1}
+
+	bad := append([]byte(nil), dump...)
+	bad[2] ^= 0xff
+	newer := append([]byte(nil), dump...)
+	newer[len(newer)-10] = DumpVersion + 1
+
+	for name, payload := range map[string][]byte{
+		"checksum": bad,
+		"version":  newer,
+		"short":    []byte("\x00\x01"),
+	} {
+		if _, err := s.Restore("r", payload, opts); err != ErrDumpPayload {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// 校验和正确但内容不完整
+	if _, err := loadValue(withFooter([]byte{dumpTypeString, 10, 'a'})); err != ErrBadDumpFormat {
+		t.Errorf("truncated value: %v", err)
+	}
+	if _, err := loadValue(withFooter([]byte{42})); err != ErrBadDumpFormat {
+		t.Errorf("unknown type: %v", err)
+	}
+
+	// REPLACE 覆盖原有的值，过期时间点已经过去时只删除原有的键
+	opts.Replace = true
+	opts.ExpireAt = time.Now().Add(-time.Second)
+	if created, err := s.Restore("k", dump, opts); err != nil || created || s.Exists("k") {
+		t.Errorf("Restore with past expire: created=%v err=%v exists=%v", created, err, s.Exists("k"))
+	}
+}
+
+// withFooter 给 RDB 编码加上版本号和校验和
+func withFooter(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(append([]byte(nil), b...), DumpVersion)
+	return binary.LittleEndian.AppendUint64(b, crc64Jones(b))
+}
+
+func TestLZFDecompress(t *testing.T) {
+	// 字面量 "abc" 加上一个回溯 3 字节、长度 6 的引用
+	out, ok := lzfDecompress([]byte{0x02, 'a', 'b', 'c', 0x80, 0x02}, 9)
+	if !ok || string(out) != "abcabcabc" {
+		t.Errorf("lzfDecompress = %q %v", out, ok)
+	}
+	if _, ok := lzfDecompress([]byte{0x80, 0x05}, 8); ok {
+		t.Error("reference before start should fail")
+	}
+	if _, ok := lzfDecompress([]byte{0x02, 'a', 'b', 'c'}, 4); ok {
+		t.Error("length mismatch should fail")
+	}
+
+	// Redis 压缩过的字符串（RDB_ENC_LZF）
+	lzf := []byte{dumpTypeString, rdbEncVal<<6 | rdbEncLZF, 6, 9, 0x02, 'a', 'b', 'c', 0x80, 0x02}
+	if v, err := loadValue(withFooter(lzf)); err != nil || v != "abcabcabc" {
+		t.Errorf("LZF string = %v %v", v, err)
+	}
+}
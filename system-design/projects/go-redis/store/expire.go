@@ -0,0 +1,200 @@
+package store
+
+import (
+	"time"
+
+	"go-redis/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 过期策略（与 Redis 相同，详见 docs/phase6-expiration.md）：
+// - 懒删除：写路径在操作键之前调用 expireIfNeeded 删除已过期的键；
+//   读路径只持有读锁，通过 lookup 把过期键视为不存在，留给定期删除清理
+// - 定期删除：后台 goroutine 每 100ms 随机抽查一批带过期时间的键
+
+const (
+	activeExpireInterval  = 100 * time.Millisecond
+	activeExpireSamples   = 20
+	activeExpireThreshold = activeExpireSamples / 4 // 过期比例超过 25% 时继续下一轮
+)
+
+// isExpired 检查键是否已过期，调用前需持有锁
+func (s *Store) isExpired(key string, now time.Time) bool {
+	at, ok := s.expires[key]
+	return ok && !now.Before(at)
+}
+
+// lookup 读取键的值并记录一次访问，已过期的键视为不存在，调用前需持有锁
+func (s *Store) lookup(key string) (interface{}, bool) {
+	value, exists := s.peek(key)
+	if exists {
+		s.touchKey(key)
+	}
+	return value, exists
+}
+
+// peek 与 lookup 相同，但不记录访问（OBJECT、TYPE 等命令不应改变键的空闲时间）
+func (s *Store) peek(key string) (interface{}, bool) {
+	value, exists := s.data[key]
+	if !exists {
+		return nil, false
+	}
+	if len(s.expires) > 0 && s.isExpired(key, time.Now()) {
+		return nil, false
+	}
+	return value, true
+}
+
+// expireIfNeeded 删除已过期的键并发布 expired 事件，调用前需持有写锁
+// 返回键是否因过期被删除
+func (s *Store) expireIfNeeded(key string) bool {
+	if len(s.expires) == 0 || !s.isExpired(key, time.Now()) {
+		return false
+	}
+
+	s.removeKey(key)
+	s.expiredKeys++
+	s.Notify(NotifyExpired, "expired", key)
+
+	return true
+}
+
+// setValue 写入键的值，新的键同时加入 SCAN 使用的 keyTable，调用前需持有写锁
+func (s *Store) setValue(key string, v interface{}) {
+	if _, exists := s.data[key]; !exists {
+		s.keys.add(key)
+	}
+	s.data[key] = v
+}
+
+// removeKey 删除键及其过期时间，调用前需持有写锁
+func (s *Store) removeKey(key string) {
+	if _, exists := s.data[key]; exists {
+		s.keys.remove(key)
+	}
+	delete(s.data, key)
+	delete(s.expires, key)
+	s.keyModified(key)
+}
+
+// ExpireAt 为已存在的键设置过期时间点，返回键是否存在
+// 时间点已经过去时直接删除该键
+func (s *Store) ExpireAt(key string, at time.Time) bool {
+	logger.WithFields(logrus.Fields{
+		"operation": "EXPIRE",
+		"key":       key,
+		"at":        at,
+	}).Debug("执行 ExpireAt 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	if _, exists := s.data[key]; !exists {
+		return false
+	}
+
+	if !at.After(time.Now()) {
+		s.removeKey(key)
+		return true
+	}
+
+	s.expires[key] = at
+	s.keyModified(key)
+	return true
+}
+
+// PTTL 返回键的剩余生存时间（毫秒）
+// 键不存在返回 -2，键没有过期时间返回 -1
+func (s *Store) PTTL(key string) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.lookup(key); !exists {
+		return -2
+	}
+
+	at, ok := s.expires[key]
+	if !ok {
+		return -1
+	}
+
+	ttl := time.Until(at).Milliseconds()
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl
+}
+
+// Persist 移除键的过期时间，返回是否移除成功
+func (s *Store) Persist(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+	if _, ok := s.expires[key]; !ok {
+		return false
+	}
+
+	delete(s.expires, key)
+	s.keyModified(key)
+	return true
+}
+
+// ExpiredKeys 返回因过期被删除的键总数
+func (s *Store) ExpiredKeys() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.expiredKeys
+}
+
+// KeyspaceStats 返回键的总数和设置了过期时间的键数（INFO keyspace）
+// 尚未被删除的过期键也会计算在内，与 Redis 一致
+func (s *Store) KeyspaceStats() (keys, expires int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.data), len(s.expires)
+}
+
+// activeExpireLoop 定期删除过期键，直到 Store 关闭
+func (s *Store) activeExpireLoop() {
+	ticker := time.NewTicker(activeExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			for s.activeExpireCycle() > activeExpireThreshold {
+			}
+		}
+	}
+}
+
+// activeExpireCycle 随机抽查一批带过期时间的键，删除其中已过期的，返回删除数量
+func (s *Store) activeExpireCycle() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sampled, expired := 0, 0
+
+	// map 的遍历顺序是随机的，相当于随机抽样
+	for key, at := range s.expires {
+		if sampled >= activeExpireSamples {
+			break
+		}
+		sampled++
+
+		if !now.Before(at) {
+			s.removeKey(key)
+			s.expiredKeys++
+			s.Notify(NotifyExpired, "expired", key)
+			expired++
+		}
+	}
+
+	return expired
+}
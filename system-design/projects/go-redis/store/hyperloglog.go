@@ -0,0 +1,516 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog
+//
+// 与 Redis 的 hyperloglog.c 字节兼容：HLL 保存为普通字符串，GET 得到的内容可以直接 SET 到 Redis，反之亦然。
+//
+// 字符串布局：
+//
+//	+------+---+-----+----------+----------------------+
+//	| HYLL | E | N/U | Cardin.  | registers ...        |
+//	+------+---+-----+----------+----------------------+
+//
+// 前 4 字节是魔数 "HYLL"，E 是编码（0 dense，1 sparse），接着 3 个未使用的字节，
+// 然后是 8 字节小端序的基数缓存，最高字节的最高位为 1 表示缓存失效。
+//
+// dense 编码把 16384 个 6 位寄存器依次排列，寄存器从字节的低位开始存放。
+// sparse 编码用三种操作码对寄存器做游程编码：
+//
+//	ZERO  00xxxxxx           连续 xxxxxx+1 个（1-64）寄存器为 0
+//	XZERO 01xxxxxx yyyyyyyy  连续 xxxxxxyyyyyyyy+1 个（1-16384）寄存器为 0
+//	VAL   1vvvvvxx           连续 xx+1 个（1-4）寄存器的值为 vvvvv+1（1-32）
+//
+// 新建的 HLL 使用 sparse 编码；寄存器的值超过 32，或长度超过 hll-sparse-max-bytes 时转换为 dense 编码
+
+const (
+	hllP                 = 14 // 用于选择寄存器的哈希位数
+	hllQ                 = 64 - hllP
+	hllRegisters         = 1 << hllP
+	hllPMask             = hllRegisters - 1
+	hllBits              = 6
+	hllRegisterMax       = 1<<hllBits - 1
+	hllHdrSize           = 16
+	hllDenseSize         = hllHdrSize + (hllRegisters*hllBits+7)/8
+	hllDense             = 0
+	hllSparse            = 1
+	hllMaxEncoding       = 1
+	hllHashSeed          = 0xadc83b19
+	hllAlphaInf          = 0.721347520444481703680 // 0.5/ln(2)
+	hllSparseXZero       = 0x40
+	hllSparseVal         = 0x80
+	hllSparseValMaxValue = 32 // VAL 能表示的最大值
+	hllSparseValMaxLen   = 4  // VAL 能表示的最大游程
+	hllSparseZeroMaxLen  = 64 // ZERO 能表示的最大游程
+
+	// DefaultHLLSparseMaxBytes 是 hll-sparse-max-bytes 的默认值
+	DefaultHLLSparseMaxBytes = 3000
+)
+
+var (
+	// ErrNotHLL 表示字符串不是合法的 HyperLogLog
+	ErrNotHLL = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	// ErrHLLCorrupted 表示 sparse 编码的内容损坏
+	ErrHLLCorrupted = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// murmurHash64A 是 Redis 使用的 MurmurHash2 64 位版本，按小端序读取，与平台无关
+func murmurHash64A[T ~string | ~[]byte](data T, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	n := len(data)
+	h := seed ^ (uint64(n) * m)
+
+	i := 0
+	for ; i+8 <= n; i += 8 {
+		var k uint64
+		for j := 7; j >= 0; j-- {
+			k = k<<8 | uint64(data[i+j])
+		}
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+
+	if rest := n & 7; rest > 0 {
+		for j := rest - 1; j >= 0; j-- {
+			h ^= uint64(data[i+j]) << (8 * j)
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllPatLen 返回元素对应的寄存器和值：值为哈希剩余位中从低位开始第一个 1 的位置（从 1 开始）
+func hllPatLen[T ~string | ~[]byte](ele T) (int, uint8) {
+	hash := murmurHash64A(ele, hllHashSeed)
+	index := int(hash & hllPMask)
+	hash >>= hllP
+	hash |= 1 << hllQ // 保证循环能结束，值最大为 Q+1
+	return index, uint8(bits.TrailingZeros64(hash) + 1)
+}
+
+// isHLL 检查字符串是否具有合法的 HLL 头部
+func isHLL(v string) bool {
+	if len(v) < hllHdrSize || v[:4] != "HYLL" || v[4] > hllMaxEncoding {
+		return false
+	}
+	return v[4] != hllDense || len(v) == hllDenseSize
+}
+
+// newHLL 创建一个空的 sparse HLL：一个覆盖全部寄存器的 XZERO，基数缓存为 0
+func newHLL() []byte {
+	h := make([]byte, hllHdrSize+2)
+	copy(h, "HYLL")
+	h[4] = hllSparse
+	hllSparseXZeroSet(h[hllHdrSize:], hllRegisters)
+	return h
+}
+
+func hllInvalidateCache(h []byte) {
+	h[15] |= 1 << 7
+}
+
+func hllValidCache(h []byte) bool {
+	return h[15]&(1<<7) == 0
+}
+
+func hllCachedCard(h []byte) uint64 {
+	return binary.LittleEndian.Uint64(h[8:16])
+}
+
+func hllSetCachedCard(h []byte, card uint64) {
+	binary.LittleEndian.PutUint64(h[8:16], card)
+}
+
+// hllDenseGet 读取 dense 编码的第 i 个寄存器
+func hllDenseGet(regs []byte, i int) uint8 {
+	pos := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	b0 := uint(regs[pos])
+	var b1 uint
+	if pos+1 < len(regs) {
+		b1 = uint(regs[pos+1])
+	}
+	return uint8((b0>>fb | b1<<(8-fb)) & hllRegisterMax)
+}
+
+// hllDenseSet 设置 dense 编码的第 i 个寄存器
+func hllDenseSet(regs []byte, i int, val uint8) {
+	pos := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	v := uint(val)
+	regs[pos] &^= byte(uint(hllRegisterMax) << fb)
+	regs[pos] |= byte(v << fb)
+	if pos+1 < len(regs) {
+		regs[pos+1] &^= byte(uint(hllRegisterMax) >> (8 - fb))
+		regs[pos+1] |= byte(v >> (8 - fb))
+	}
+}
+
+// hllDenseUpdate 在 count 大于寄存器原来的值时更新它，返回是否修改
+func hllDenseUpdate(regs []byte, index int, count uint8) bool {
+	if count > hllDenseGet(regs, index) {
+		hllDenseSet(regs, index, count)
+		return true
+	}
+	return false
+}
+
+func hllSparseIsZero(op byte) bool  { return op&0xc0 == 0 }
+func hllSparseIsXZero(op byte) bool { return op&0xc0 == hllSparseXZero }
+func hllSparseZeroLen(op byte) int  { return int(op&0x3f) + 1 }
+func hllSparseValValue(op byte) int { return int(op>>2&0x1f) + 1 }
+func hllSparseValLen(op byte) int   { return int(op&0x3) + 1 }
+
+func hllSparseXZeroLen(op, next byte) int {
+	return (int(op&0x3f)<<8 | int(next)) + 1
+}
+
+func hllSparseValSet(p []byte, val, n int) {
+	p[0] = byte((val-1)<<2|(n-1)) | hllSparseVal
+}
+
+func hllSparseZeroSet(p []byte, n int) {
+	p[0] = byte(n - 1)
+}
+
+func hllSparseXZeroSet(p []byte, n int) {
+	p[0] = byte((n-1)>>8) | hllSparseXZero
+	p[1] = byte((n - 1) & 0xff)
+}
+
+// hllSparseToDense 把 sparse 编码转换为 dense 编码，已经是 dense 时原样返回
+func hllSparseToDense(h []byte) ([]byte, error) {
+	if h[4] == hllDense {
+		return h, nil
+	}
+
+	dense := make([]byte, hllDenseSize)
+	copy(dense, h[:hllHdrSize]) // 魔数和基数缓存
+	dense[4] = hllDense
+	regs := dense[hllHdrSize:]
+
+	idx := 0
+	for p := hllHdrSize; p < len(h); {
+		switch op := h[p]; {
+		case hllSparseIsZero(op):
+			idx += hllSparseZeroLen(op)
+			p++
+		case hllSparseIsXZero(op):
+			if p+1 >= len(h) {
+				return nil, ErrHLLCorrupted
+			}
+			idx += hllSparseXZeroLen(op, h[p+1])
+			p += 2
+		default:
+			runlen, val := hllSparseValLen(op), hllSparseValValue(op)
+			if idx+runlen > hllRegisters {
+				return nil, ErrHLLCorrupted
+			}
+			for ; runlen > 0; runlen-- {
+				hllDenseSet(regs, idx, uint8(val))
+				idx++
+			}
+			p++
+		}
+	}
+
+	// 游程之和必须正好覆盖所有寄存器
+	if idx != hllRegisters {
+		return nil, ErrHLLCorrupted
+	}
+	return dense, nil
+}
+
+// hllSparseSet 在 count 大于第 index 个寄存器的值时更新它，返回新的 HLL 和是否修改
+// 值超过 VAL 的上限或长度超过 maxBytes 时转换为 dense 编码
+func hllSparseSet(h []byte, index int, count uint8, maxBytes int64) ([]byte, bool, error) {
+	if count > hllSparseValMaxValue {
+		return hllPromote(h, index, count)
+	}
+
+	// 第一步：找到覆盖 index 的操作码 p，first 是它覆盖的第一个寄存器，prev 是前一个操作码
+	p, end := hllHdrSize, len(h)
+	prev := -1
+	first, span, oplen := 0, 0, 1
+	for p < end {
+		oplen = 1
+		switch op := h[p]; {
+		case hllSparseIsZero(op):
+			span = hllSparseZeroLen(op)
+		case hllSparseIsXZero(op):
+			if p+1 >= end {
+				return nil, false, ErrHLLCorrupted
+			}
+			span = hllSparseXZeroLen(op, h[p+1])
+			oplen = 2
+		default:
+			span = hllSparseValLen(op)
+		}
+		if index <= first+span-1 {
+			break
+		}
+		prev = p
+		p += oplen
+		first += span
+	}
+	if span == 0 || p >= end {
+		return nil, false, ErrHLLCorrupted
+	}
+
+	op := h[p]
+	isVal := !hllSparseIsZero(op) && !hllSparseIsXZero(op)
+
+	// 第二步：不需要拆分操作码的情况
+	if isVal {
+		if hllSparseValValue(op) >= int(count) {
+			return h, false, nil
+		}
+		if span == 1 {
+			hllSparseValSet(h[p:], int(count), 1)
+			return hllSparseMerge(h, prev), true, nil
+		}
+	} else if hllSparseIsZero(op) && span == 1 {
+		hllSparseValSet(h[p:], int(count), 1)
+		return hllSparseMerge(h, prev), true, nil
+	}
+
+	// 第三步：把操作码拆分为最多 3 个（最长 5 字节）：index 之前、index 本身、index 之后
+	var seq [5]byte
+	n := 0
+	last := first + span - 1
+	zeros := func(length int) {
+		if length > hllSparseZeroMaxLen {
+			hllSparseXZeroSet(seq[n:], length)
+			n += 2
+		} else {
+			hllSparseZeroSet(seq[n:], length)
+			n++
+		}
+	}
+	if !isVal {
+		if index != first {
+			zeros(index - first)
+		}
+		hllSparseValSet(seq[n:], int(count), 1)
+		n++
+		if index != last {
+			zeros(last - index)
+		}
+	} else {
+		curval := hllSparseValValue(op)
+		if index != first {
+			hllSparseValSet(seq[n:], curval, index-first)
+			n++
+		}
+		hllSparseValSet(seq[n:], int(count), 1)
+		n++
+		if index != last {
+			hllSparseValSet(seq[n:], curval, last-index)
+			n++
+		}
+	}
+
+	if delta := n - oplen; delta > 0 && int64(len(h)+delta) > maxBytes {
+		return hllPromote(h, index, count)
+	}
+
+	out := make([]byte, 0, len(h)+n-oplen)
+	out = append(out, h[:p]...)
+	out = append(out, seq[:n]...)
+	out = append(out, h[p+oplen:]...)
+	return hllSparseMerge(out, prev), true, nil
+}
+
+// hllSparseMerge 从 prev（为 -1 时从头）开始检查最多 5 个操作码，合并值相同的相邻 VAL，并使缓存失效
+func hllSparseMerge(h []byte, prev int) []byte {
+	p := prev
+	if p < 0 {
+		p = hllHdrSize
+	}
+	for scan := 5; p < len(h) && scan > 0; scan-- {
+		op := h[p]
+		if hllSparseIsXZero(op) {
+			p += 2
+			continue
+		}
+		if hllSparseIsZero(op) {
+			p++
+			continue
+		}
+		if p+1 < len(h) && !hllSparseIsZero(h[p+1]) && !hllSparseIsXZero(h[p+1]) {
+			v := hllSparseValValue(op)
+			n := hllSparseValLen(op) + hllSparseValLen(h[p+1])
+			if v == hllSparseValValue(h[p+1]) && n <= hllSparseValMaxLen {
+				hllSparseValSet(h[p+1:], v, n)
+				h = append(h[:p], h[p+1:]...)
+				// 合并后不移动 p，继续尝试与右边的操作码合并
+				continue
+			}
+		}
+		p++
+	}
+	hllInvalidateCache(h)
+	return h
+}
+
+// hllPromote 把 HLL 转换为 dense 编码后设置寄存器
+func hllPromote(h []byte, index int, count uint8) ([]byte, bool, error) {
+	dense, err := hllSparseToDense(h)
+	if err != nil {
+		return nil, false, err
+	}
+	hllDenseUpdate(dense[hllHdrSize:], index, count)
+	return dense, true, nil
+}
+
+// hllAdd 把元素加入 HLL，返回新的 HLL 和寄存器是否发生变化
+func hllAdd(h []byte, ele string, maxBytes int64) ([]byte, bool, error) {
+	index, count := hllPatLen(ele)
+	if h[4] == hllDense {
+		return h, hllDenseUpdate(h[hllHdrSize:], index, count), nil
+	}
+	return hllSparseSet(h, index, count, maxBytes)
+}
+
+// hllRegHisto 统计各个寄存器值出现的次数
+func hllRegHisto[T ~string | ~[]byte](h T, histo *[64]int) error {
+	if h[4] == hllDense {
+		regs := []byte(h[hllHdrSize:])
+		for i := 0; i < hllRegisters; i++ {
+			histo[hllDenseGet(regs, i)]++
+		}
+		return nil
+	}
+
+	idx := 0
+	for p := hllHdrSize; p < len(h); {
+		switch op := h[p]; {
+		case hllSparseIsZero(op):
+			runlen := hllSparseZeroLen(op)
+			idx += runlen
+			histo[0] += runlen
+			p++
+		case hllSparseIsXZero(op):
+			if p+1 >= len(h) {
+				return ErrHLLCorrupted
+			}
+			runlen := hllSparseXZeroLen(op, h[p+1])
+			idx += runlen
+			histo[0] += runlen
+			p += 2
+		default:
+			runlen := hllSparseValLen(op)
+			idx += runlen
+			histo[hllSparseValValue(op)] += runlen
+			p++
+		}
+	}
+	if idx != hllRegisters {
+		return ErrHLLCorrupted
+	}
+	return nil
+}
+
+// hllMerge 把 HLL 的寄存器合并到 max 中，每个寄存器取较大值
+func hllMerge[T ~string | ~[]byte](max *[hllRegisters]uint8, h T) error {
+	if h[4] == hllDense {
+		regs := []byte(h[hllHdrSize:])
+		for i := range max {
+			if v := hllDenseGet(regs, i); v > max[i] {
+				max[i] = v
+			}
+		}
+		return nil
+	}
+
+	idx := 0
+	for p := hllHdrSize; p < len(h); {
+		switch op := h[p]; {
+		case hllSparseIsZero(op):
+			idx += hllSparseZeroLen(op)
+			p++
+		case hllSparseIsXZero(op):
+			if p+1 >= len(h) {
+				return ErrHLLCorrupted
+			}
+			idx += hllSparseXZeroLen(op, h[p+1])
+			p += 2
+		default:
+			runlen, val := hllSparseValLen(op), uint8(hllSparseValValue(op))
+			if idx+runlen > hllRegisters {
+				return ErrHLLCorrupted
+			}
+			for ; runlen > 0; runlen-- {
+				if val > max[idx] {
+					max[idx] = val
+				}
+				idx++
+			}
+			p++
+		}
+	}
+	if idx != hllRegisters {
+		return ErrHLLCorrupted
+	}
+	return nil
+}
+
+// hllCount 根据寄存器值的直方图估算基数
+// 使用 Otmar Ertl 提出的改进估计方法（与 Redis 5 之后的实现相同），小基数时不需要额外修正
+func hllCount(histo *[64]int) uint64 {
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histo[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histo[0])/m)
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"go-redis/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetHLLSparseMaxBytes 设置 sparse 编码的最大字节数（包含头部），超过后转换为 dense 编码
+func (s *Store) SetHLLSparseMaxBytes(n int64) {
+	s.hllSparseMaxBytes.Store(n)
+}
+
+// HLLSparseMaxBytes 返回 sparse 编码的最大字节数
+func (s *Store) HLLSparseMaxBytes() int64 {
+	return s.hllSparseMaxBytes.Load()
+}
+
+// hllValue 返回 key 上的 HLL，调用前需持有锁
+// 键不是字符串时返回 ErrWrongType，字符串不是合法的 HLL 时返回 ErrNotHLL
+func (s *Store) hllValue(key string) (string, bool, error) {
+	v, exists := s.lookup(key)
+	if !exists {
+		return "", false, nil
+	}
+	switch v := v.(type) {
+	case string:
+		if !isHLL(v) {
+			return "", true, ErrNotHLL
+		}
+		return v, true, nil
+	case int64:
+		return "", true, ErrNotHLL
+	default:
+		return "", true, ErrWrongType
+	}
+}
+
+// PFAdd 把元素加入 key 上的 HLL，键不存在时创建
+// 返回 true 表示键被创建或者有寄存器发生了变化（基数估计值可能改变）
+func (s *Store) PFAdd(key string, elements []string) (bool, error) {
+	logger.WithFields(logrus.Fields{
+		"operation": "PFADD",
+		"key":       key,
+		"count":     len(elements),
+	}).Debug("执行 PFAdd 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	v, exists, err := s.hllValue(key)
+	if err != nil {
+		return false, err
+	}
+
+	var h []byte
+	updated := !exists
+	if exists {
+		h = []byte(v)
+	} else {
+		h = newHLL()
+	}
+
+	maxBytes := s.hllSparseMaxBytes.Load()
+	for _, ele := range elements {
+		var changed bool
+		if h, changed, err = hllAdd(h, ele, maxBytes); err != nil {
+			return false, err
+		}
+		updated = updated || changed
+	}
+
+	if updated {
+		hllInvalidateCache(h)
+		s.storeString(key, h, exists)
+	}
+	return updated, nil
+}
+
+// PFCount 返回 keys 上 HLL 并集的基数估计值，不存在的键视为空集
+// 只有一个键时使用并更新头部的基数缓存
+func (s *Store) PFCount(keys ...string) (uint64, error) {
+	if len(keys) == 1 {
+		return s.pfCountOne(keys[0])
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var max [hllRegisters]uint8
+	for _, key := range keys {
+		v, exists, err := s.hllValue(key)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			continue
+		}
+		if err := hllMerge(&max, v); err != nil {
+			return 0, err
+		}
+	}
+
+	var histo [64]int
+	for _, v := range max {
+		histo[v]++
+	}
+	return hllCount(&histo), nil
+}
+
+func (s *Store) pfCountOne(key string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	v, exists, err := s.hllValue(key)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	h := []byte(v[:hllHdrSize])
+	if hllValidCache(h) {
+		return hllCachedCard(h), nil
+	}
+
+	var histo [64]int
+	if err := hllRegHisto(v, &histo); err != nil {
+		return 0, err
+	}
+	card := hllCount(&histo)
+
+	// 与 Redis 一样把结果写回头部的缓存，下次 PFCOUNT 不需要重新计算
+	h = []byte(v)
+	hllSetCachedCard(h, card)
+	s.storeString(key, h, true)
+	return card, nil
+}
+
+// PFMerge 把 dest 和 keys 上的 HLL 合并后保存到 dest，dest 不存在时创建
+// 有任何一个输入是 dense 编码时结果使用 dense 编码
+func (s *Store) PFMerge(dest string, keys []string) error {
+	logger.WithFields(logrus.Fields{
+		"operation": "PFMERGE",
+		"dest":      dest,
+		"keys":      keys,
+	}).Debug("执行 PFMerge 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var max [hllRegisters]uint8
+	dense := false
+	for _, key := range append([]string{dest}, keys...) {
+		s.expireIfNeeded(key)
+		v, exists, err := s.hllValue(key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		if v[4] == hllDense {
+			dense = true
+		}
+		if err := hllMerge(&max, v); err != nil {
+			return err
+		}
+	}
+
+	v, exists, _ := s.hllValue(dest)
+	h := newHLL()
+	if exists {
+		h = []byte(v)
+	}
+
+	if dense {
+		var err error
+		if h, err = hllSparseToDense(h); err != nil {
+			return err
+		}
+		regs := h[hllHdrSize:]
+		for i, val := range max {
+			hllDenseSet(regs, i, val)
+		}
+	} else {
+		maxBytes := s.hllSparseMaxBytes.Load()
+		for i, val := range max {
+			if val == 0 {
+				continue
+			}
+			var err error
+			if h[4] == hllDense {
+				hllDenseUpdate(h[hllHdrSize:], i, val)
+			} else if h, _, err = hllSparseSet(h, i, val, maxBytes); err != nil {
+				return err
+			}
+		}
+	}
+
+	hllInvalidateCache(h)
+	s.storeString(dest, h, exists)
+	return nil
+}
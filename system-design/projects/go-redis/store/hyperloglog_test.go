@@ -0,0 +1,221 @@
+package store
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestHLLEmptyEncoding(t *testing.T) {
+	s := NewStore()
+
+	// 与 Redis 新建的 HLL 完全一致：sparse 编码，一个覆盖 16384 个寄存器的 XZERO
+	want := "HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff"
+	if got := string(newHLL()); got != want {
+		t.Fatalf("newHLL = %q, want %q", got, want)
+	}
+
+	// PFADD 创建键时即使没有元素也会使缓存失效
+	if updated, _ := s.PFAdd("h", nil); !updated {
+		t.Error("PFAdd on missing key should report update")
+	}
+	v, _ := s.Get("h")
+	if str := v.(string); str[15] != 0x80 || str[16:] != "\x7f\xff" {
+		t.Errorf("value = %q", str)
+	}
+	if n, _ := s.PFCount("h"); n != 0 {
+		t.Errorf("PFCount(empty) = %d", n)
+	}
+	// PFCOUNT 把结果写回缓存
+	v, _ = s.Get("h")
+	if str := v.(string); str[15] != 0 {
+		t.Errorf("cache not updated: %q", str)
+	}
+}
+
+func TestHLLSparseOpcodes(t *testing.T) {
+	h := newHLL()
+	var err error
+	set := func(index int, count uint8) {
+		t.Helper()
+		if h, _, err = hllSparseSet(h, index, count, DefaultHLLSparseMaxBytes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 拆分 XZERO：XZERO(100) VAL(3,1) XZERO(16283)
+	set(100, 3)
+	if got, want := string(h[hllHdrSize:]), "\x40\x63\x88\x7f\x9a"; got != want {
+		t.Fatalf("opcodes = %q, want %q", got, want)
+	}
+	// 相邻的相同值合并为一个 VAL：XZERO(100) VAL(3,2) XZERO(16282)
+	set(101, 3)
+	if got, want := string(h[hllHdrSize:]), "\x40\x63\x89\x7f\x99"; got != want {
+		t.Fatalf("opcodes = %q, want %q", got, want)
+	}
+	// 较小的值不修改寄存器
+	if _, changed, _ := hllSparseSet(h, 101, 2, DefaultHLLSparseMaxBytes); changed {
+		t.Error("smaller count should not change the register")
+	}
+	// 拆分 VAL：ZERO(5) ... 前面短的零游程使用 ZERO
+	set(5, 1)
+	if got, want := string(h[hllHdrSize:]), "\x04\x80\x40\x5d\x89\x7f\x99"; got != want {
+		t.Fatalf("opcodes = %q, want %q", got, want)
+	}
+
+	dense, err := hllSparseToDense(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regs := dense[hllHdrSize:]
+	for i, want := range map[int]uint8{0: 0, 5: 1, 100: 3, 101: 3, 102: 0, hllRegisters - 1: 0} {
+		if got := hllDenseGet(regs, i); got != want {
+			t.Errorf("register %d = %d, want %d", i, got, want)
+		}
+	}
+
+	// 游程之和不等于寄存器数量的内容视为损坏
+	bad := append(newHLL()[:hllHdrSize], 0x7f, 0xfe)
+	if _, err := hllSparseToDense(bad); err != ErrHLLCorrupted {
+		t.Errorf("corrupted sparse: %v", err)
+	}
+}
+
+func TestHLLDenseRegisters(t *testing.T) {
+	regs := make([]byte, hllDenseSize-hllHdrSize)
+	for i := 0; i < hllRegisters; i++ {
+		hllDenseSet(regs, i, uint8(i%64))
+	}
+	for i := 0; i < hllRegisters; i++ {
+		if got := hllDenseGet(regs, i); got != uint8(i%64) {
+			t.Fatalf("register %d = %d, want %d", i, got, i%64)
+		}
+	}
+}
+
+func TestHLLErrorBound(t *testing.T) {
+	s := NewStore()
+
+	// 标准误差为 1.04/sqrt(16384) ≈ 0.81%，这里允许 3 倍标准误差
+	const tolerance = 0.0243
+	added := 0
+	for _, n := range []int{10, 100, 1000, 10000, 100000} {
+		batch := make([]string, 0, n-added)
+		for ; added < n; added++ {
+			batch = append(batch, "element:"+strconv.Itoa(added))
+		}
+		s.PFAdd("h", batch)
+
+		got, err := s.PFCount("h")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := math.Abs(float64(got)-float64(n)) / float64(n); e > tolerance {
+			t.Errorf("PFCount = %d for %d elements, error %.2f%%", got, n, e*100)
+		}
+	}
+
+	// 大量元素之后已经转换为 dense 编码
+	v, _ := s.Get("h")
+	if str := v.(string); str[4] != hllDense || len(str) != hllDenseSize {
+		t.Errorf("encoding = %d, len = %d", str[4], len(str))
+	}
+}
+
+func TestHLLPromotion(t *testing.T) {
+	s := NewStore()
+	s.SetHLLSparseMaxBytes(100)
+
+	var elements []string
+	for i := 0; i < 200; i++ {
+		elements = append(elements, strconv.Itoa(i))
+	}
+
+	// sparse 和 dense 编码对同一组元素的估计值相同
+	s.PFAdd("dense", elements)
+	s.SetHLLSparseMaxBytes(DefaultHLLSparseMaxBytes)
+	s.PFAdd("sparse", elements)
+
+	dv, _ := s.Get("dense")
+	sv, _ := s.Get("sparse")
+	if dv.(string)[4] != hllDense || sv.(string)[4] != hllSparse {
+		t.Fatalf("encodings = %d %d", dv.(string)[4], sv.(string)[4])
+	}
+	dn, _ := s.PFCount("dense")
+	sn, _ := s.PFCount("sparse")
+	if dn != sn || dn < 195 || dn > 205 {
+		t.Errorf("PFCount dense = %d, sparse = %d", dn, sn)
+	}
+
+	// 寄存器值超过 32 时也会转换为 dense 编码
+	h, _, _ := hllSparseSet(newHLL(), 0, 33, DefaultHLLSparseMaxBytes)
+	if h[4] != hllDense || hllDenseGet(h[hllHdrSize:], 0) != 33 {
+		t.Errorf("value > 32 should promote to dense")
+	}
+}
+
+func TestPFMerge(t *testing.T) {
+	s := NewStore()
+
+	var a, b []string
+	for i := 0; i < 5000; i++ {
+		a = append(a, "a"+strconv.Itoa(i))
+		b = append(b, "b"+strconv.Itoa(i))
+	}
+	s.PFAdd("a", a)
+	s.PFAdd("b", b[:50])
+	s.PFAdd("c", b)
+
+	if err := s.PFMerge("ab", []string{"a", "b", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	merged, _ := s.PFCount("ab")
+	union, _ := s.PFCount("a", "b")
+	if merged != union {
+		t.Errorf("PFCount(merged) = %d, PFCount(a, b) = %d", merged, union)
+	}
+
+	// 合并到已经存在的 sparse 目标键
+	if err := s.PFMerge("b", []string{"c"}); err != nil {
+		t.Fatal(err)
+	}
+	bn, _ := s.PFCount("b")
+	cn, _ := s.PFCount("c")
+	if bn != cn {
+		t.Errorf("PFCount(b) = %d after merge, want %d", bn, cn)
+	}
+
+	// 只有 sparse 输入时结果保持 sparse
+	s.PFAdd("s1", []string{"x"})
+	s.PFAdd("s2", []string{"y"})
+	s.PFMerge("s", []string{"s1", "s2"})
+	if v, _ := s.Get("s"); v.(string)[4] != hllSparse {
+		t.Error("merge of sparse inputs should stay sparse")
+	}
+	if n, _ := s.PFCount("s"); n != 2 {
+		t.Errorf("PFCount(s) = %d", n)
+	}
+}
+
+func TestHLLWrongType(t *testing.T) {
+	s := NewStore()
+	s.Set("str", "not a hll")
+	s.Incr("int")
+	s.XAdd("stream", "*", []string{"a", "1"}, false, nil)
+	s.Set("short", "HYLL\x00")
+
+	for _, key := range []string{"str", "int", "short"} {
+		if _, err := s.PFAdd(key, []string{"x"}); err != ErrNotHLL {
+			t.Errorf("PFAdd(%s): %v", key, err)
+		}
+		if _, err := s.PFCount("missing", key); err != ErrNotHLL {
+			t.Errorf("PFCount(%s): %v", key, err)
+		}
+	}
+	if _, err := s.PFAdd("stream", nil); err != ErrWrongType {
+		t.Errorf("PFAdd(stream): %v", err)
+	}
+	if err := s.PFMerge("dest", []string{"str"}); err != ErrNotHLL || s.Exists("dest") {
+		t.Errorf("PFMerge with invalid source: %v", err)
+	}
+}
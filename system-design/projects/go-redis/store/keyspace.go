@@ -0,0 +1,235 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"go-redis/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNoSuchKey  = errors.New("ERR no such key")
+	ErrSameObject = errors.New("ERR source and destination objects are the same")
+	ErrBusyKey    = errors.New("BUSYKEY Target key name already exists.")
+)
+
+// LazyfreeThreshold 是后台释放的阈值：释放代价（元素个数）超过它的值交给后台 goroutine 处理
+const LazyfreeThreshold = 64
+
+// Rename 把 src 重命名为 dst，dst 已存在时被覆盖，过期时间和访问信息随键一起移动
+// nx 为 true 时 dst 已存在则不做任何操作。返回是否执行了重命名
+func (s *Store) Rename(src, dst string, nx bool) (bool, error) {
+	logger.WithFields(logrus.Fields{
+		"operation": "RENAME",
+		"src":       src,
+		"dst":       dst,
+	}).Debug("执行 Rename 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(src)
+	s.expireIfNeeded(dst)
+
+	v, exists := s.data[src]
+	if !exists {
+		return false, ErrNoSuchKey
+	}
+	// 与 Redis 一样，重命名为自身时 RENAME 返回 OK，RENAMENX 返回 0
+	if src == dst {
+		return !nx, nil
+	}
+	_, dstExists := s.data[dst]
+	if dstExists && nx {
+		return false, nil
+	}
+
+	at, hasTTL := s.expires[src]
+	meta := s.meta[src]
+	s.removeKey(src)
+
+	if !dstExists {
+		s.Notify(NotifyNew, "new", dst)
+	}
+	s.setValue(dst, v)
+	if hasTTL {
+		s.expires[dst] = at
+	} else {
+		delete(s.expires, dst)
+	}
+	if meta != nil {
+		s.meta[dst] = meta
+	}
+	s.keyModified(dst)
+	return true, nil
+}
+
+// Copy 把 src 的值（包括过期时间）复制到 dst，返回是否复制
+// dst 已存在且 replace 为 false 时不复制
+func (s *Store) Copy(src, dst string, replace bool) (bool, error) {
+	logger.WithFields(logrus.Fields{
+		"operation": "COPY",
+		"src":       src,
+		"dst":       dst,
+	}).Debug("执行 Copy 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if src == dst {
+		return false, ErrSameObject
+	}
+
+	s.expireIfNeeded(src)
+	s.expireIfNeeded(dst)
+
+	v, exists := s.data[src]
+	if !exists {
+		return false, nil
+	}
+	_, dstExists := s.data[dst]
+	if dstExists && !replace {
+		return false, nil
+	}
+
+	if dstExists {
+		s.removeKey(dst)
+	}
+	s.Notify(NotifyNew, "new", dst)
+	s.setValue(dst, cloneValue(v))
+	if at, ok := s.expires[src]; ok {
+		s.expires[dst] = at
+	}
+	s.keyModified(dst)
+	return true, nil
+}
+
+// cloneValue 深拷贝一个值，字符串和整数本身不可变，可以直接共享
+func cloneValue(v interface{}) interface{} {
+	if st, ok := v.(*Stream); ok {
+		return st.clone()
+	}
+	return v
+}
+
+// RandomKey 随机返回一个未过期的键，遇到的过期键会被删除
+func (s *Store) RandomKey() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// map 的遍历顺序是随机的
+	for key := range s.data {
+		if s.expireIfNeeded(key) {
+			continue
+		}
+		return key, true
+	}
+	return "", false
+}
+
+// Touch 更新键的访问时间，返回其中存在的键的个数
+func (s *Store) Touch(keys []string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, key := range keys {
+		if _, exists := s.lookup(key); exists {
+			n++
+		}
+	}
+	return n
+}
+
+// Unlink 与 Delete 相同，但只在持有锁时把键从键空间中摘除，大对象的释放交给后台完成
+func (s *Store) Unlink(key string) bool {
+	logger.WithFields(logrus.Fields{
+		"operation": "UNLINK",
+		"key":       key,
+	}).Debug("执行 Unlink 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expireIfNeeded(key) {
+		return false
+	}
+	v, exists := s.data[key]
+	if !exists {
+		return false
+	}
+
+	s.removeKey(key)
+	s.freeAsync(v)
+	return true
+}
+
+// LazyfreeStats 返回等待后台释放和已经由后台释放的对象数量（INFO）
+func (s *Store) LazyfreeStats() (pending, freed int64) {
+	return s.lazyfreePending.Load(), s.lazyfreed.Load()
+}
+
+// freeEffort 估算释放一个值的代价，即需要逐个断开引用的元素个数
+func freeEffort(v interface{}) int {
+	st, ok := v.(*Stream)
+	if !ok {
+		return 1
+	}
+	n := len(st.entries)
+	for _, g := range st.groups {
+		n += len(g.pending) + len(g.consumers)
+	}
+	return n
+}
+
+// freeAsync 释放已经从键空间摘除的值
+// Go 的内存由 GC 回收，这里做的是 Redis 后台释放线程的对应工作：在锁外逐个断开大对象内部的引用，
+// 让遍历大对象的开销不落在持有锁的命令上，GC 也可以更早回收其中的元素
+func (s *Store) freeAsync(v interface{}) {
+	if freeEffort(v) <= LazyfreeThreshold {
+		return
+	}
+
+	s.lazyfreePending.Add(1)
+	go func() {
+		releaseValue(v)
+		s.lazyfreePending.Add(-1)
+		s.lazyfreed.Add(1)
+	}()
+}
+
+// releaseValue 断开值内部的引用，值必须已经不可被其他命令访问
+func releaseValue(v interface{}) {
+	st, ok := v.(*Stream)
+	if !ok {
+		return
+	}
+	for i := range st.entries {
+		st.entries[i].Fields = nil
+	}
+	st.entries = nil
+	for _, g := range st.groups {
+		for _, c := range g.consumers {
+			clear(c.pending)
+		}
+		clear(g.pending)
+		clear(g.consumers)
+	}
+	st.groups = nil
+}
+
+// setObjectMeta 按 RESTORE 的 IDLETIME/FREQ 设置键的访问信息，调用前需持有写锁
+func (s *Store) setObjectMeta(key string, idle time.Duration, freq int) {
+	m, ok := s.meta[key]
+	if !ok {
+		return
+	}
+	if idle >= 0 {
+		m.atime.Store(time.Now().Add(-idle).UnixMilli())
+	}
+	if freq >= 0 {
+		m.counter.Store(int32(freq))
+	}
+}
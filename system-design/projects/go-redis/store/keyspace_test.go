@@ -0,0 +1,256 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRename(t *testing.T) {
+	s := NewStore()
+	s.Set("a", "1")
+	s.ExpireAt("a", time.Now().Add(time.Hour))
+	s.Set("b", "2")
+
+	if _, err := s.Rename("missing", "x", false); err != ErrNoSuchKey {
+		t.Errorf("Rename(missing): %v", err)
+	}
+	if ok, _ := s.Rename("a", "b", true); ok {
+		t.Error("RENAMENX should not overwrite an existing key")
+	}
+	if ok, _ := s.Rename("a", "a", true); ok {
+		t.Error("RENAMENX to itself should return false")
+	}
+	if ok, _ := s.Rename("a", "a", false); !ok || !s.Exists("a") {
+		t.Error("RENAME to itself should succeed and keep the key")
+	}
+
+	if ok, err := s.Rename("a", "b", false); !ok || err != nil {
+		t.Fatalf("Rename: %v %v", ok, err)
+	}
+	if s.Exists("a") {
+		t.Error("source still exists")
+	}
+	if v, _ := s.Get("b"); v != "1" {
+		t.Errorf("b = %v", v)
+	}
+	if ttl := s.PTTL("b"); ttl <= 0 {
+		t.Errorf("TTL should move with the key, PTTL = %d", ttl)
+	}
+	if ttl := s.PTTL("a"); ttl != -2 {
+		t.Errorf("PTTL(a) = %d", ttl)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	s := NewStore()
+	s.XAdd("src", "1-0", []string{"f", "v"}, false, nil)
+	s.XGroupCreate("src", "g", "0", false)
+	s.XReadGroup("g", "alice", []string{"src"}, []string{">"}, 10, false)
+	s.Set("other", "x")
+
+	if _, err := s.Copy("src", "src", false); err != ErrSameObject {
+		t.Errorf("Copy to itself: %v", err)
+	}
+	if ok, _ := s.Copy("src", "other", false); ok {
+		t.Error("Copy should not overwrite without REPLACE")
+	}
+	if ok, _ := s.Copy("missing", "dst", false); ok {
+		t.Error("Copy of a missing key should return false")
+	}
+	if ok, _ := s.Copy("src", "other", true); !ok || s.Type("other") != "stream" {
+		t.Fatalf("Copy with REPLACE failed: type %s", s.Type("other"))
+	}
+
+	// 修改副本不影响原来的 stream
+	s.XAdd("other", "2-0", []string{"f", "v"}, false, nil)
+	s.XAck("other", "g", []StreamID{{Ms: 1}})
+	if n, _ := s.XLen("src"); n != 1 {
+		t.Errorf("XLen(src) = %d after modifying the copy", n)
+	}
+	if summary, _ := s.XPendingSummary("src", "g"); summary.Count != 1 {
+		t.Errorf("src pending = %d after acking the copy", summary.Count)
+	}
+}
+
+func TestTypeAndObject(t *testing.T) {
+	s := NewStore()
+	s.Set("int", "12345")
+	s.Set("str", "hello")
+	s.Set("raw", string(make([]byte, 45)))
+	s.Incr("counter")
+	s.XAdd("stream", "*", []string{"a", "1"}, false, nil)
+
+	for key, want := range map[string][2]string{
+		"int":     {"string", "int"},
+		"str":     {"string", "embstr"},
+		"raw":     {"string", "raw"},
+		"counter": {"string", "int"},
+		"stream":  {"stream", "stream"},
+	} {
+		info, ok := s.Object(key)
+		if typ := s.Type(key); typ != want[0] || !ok || info.Encoding != want[1] {
+			t.Errorf("%s: type %s encoding %s, want %v", key, typ, info.Encoding, want)
+		}
+	}
+	if typ := s.Type("missing"); typ != "none" {
+		t.Errorf("Type(missing) = %s", typ)
+	}
+	if _, ok := s.Object("missing"); ok {
+		t.Error("Object(missing) should not exist")
+	}
+
+	// 新键的计数器从 LFUInitVal 开始，访问次数多了才会增长
+	if info, _ := s.Object("str"); info.Freq != LFUInitVal || info.IdleTime > time.Second {
+		t.Errorf("new key: %+v", info)
+	}
+	for i := 0; i < 1000; i++ {
+		s.Get("str")
+	}
+	if info, _ := s.Object("str"); info.Freq <= LFUInitVal {
+		t.Errorf("freq after 1000 reads = %d", info.Freq)
+	}
+
+	// IDLETIME 按最近一次访问计算，OBJECT 和 TYPE 本身不算访问
+	s.setObjectMetaLocked("str", 10*time.Second, -1)
+	s.Type("str")
+	if info, _ := s.Object("str"); info.IdleTime < 10*time.Second {
+		t.Errorf("idle time = %v, want >= 10s", info.IdleTime)
+	}
+	if n := s.Touch([]string{"str", "missing"}); n != 1 {
+		t.Errorf("Touch = %d", n)
+	}
+	if info, _ := s.Object("str"); info.IdleTime >= time.Second {
+		t.Errorf("idle time after TOUCH = %v", info.IdleTime)
+	}
+}
+
+// setObjectMetaLocked 在测试中加锁调用 setObjectMeta
+func (s *Store) setObjectMetaLocked(key string, idle time.Duration, freq int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setObjectMeta(key, idle, freq)
+}
+
+func TestRandomKey(t *testing.T) {
+	s := NewStore()
+	if _, ok := s.RandomKey(); ok {
+		t.Error("RandomKey on empty store")
+	}
+
+	s.Set("expired", "x")
+	s.ExpireAt("expired", time.Now().Add(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	if key, ok := s.RandomKey(); ok {
+		t.Errorf("RandomKey returned expired key %q", key)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		s.Set(strconv.Itoa(i), "v")
+	}
+	for i := 0; i < 200; i++ {
+		key, _ := s.RandomKey()
+		seen[key] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("RandomKey returned %v", seen)
+	}
+}
+
+func TestUnlinkLazyfree(t *testing.T) {
+	s := NewStore()
+
+	for i := 0; i < LazyfreeThreshold*2; i++ {
+		s.XAdd("big", "*", []string{"f", "v"}, false, nil)
+	}
+	s.XAdd("small", "*", []string{"f", "v"}, false, nil)
+	s.Set("str", "v")
+
+	for _, key := range []string{"big", "small", "str"} {
+		if !s.Unlink(key) || s.Exists(key) {
+			t.Errorf("Unlink(%s) failed", key)
+		}
+	}
+	if s.Unlink("big") {
+		t.Error("Unlink of a missing key should return false")
+	}
+
+	// 只有大对象交给后台释放
+	deadline := time.Now().Add(time.Second)
+	for {
+		pending, freed := s.LazyfreeStats()
+		if pending == 0 && freed == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("lazyfree stats: pending=%d freed=%d", pending, freed)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScan(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 500; i++ {
+		s.Set("key:"+strconv.Itoa(i), "v")
+	}
+
+	// 遍历中途桶数先翻倍再减半，一直存在的键仍然都要返回
+	seen := make(map[string]bool)
+	cursor, rounds := uint64(0), 0
+	for {
+		next, keys := s.Scan(cursor, ScanOptions{Count: 20})
+		for _, key := range keys {
+			seen[key] = true
+		}
+		rounds++
+		switch rounds {
+		case 5:
+			for i := 0; i < 2000; i++ {
+				s.Set("tmp:"+strconv.Itoa(i), "v")
+			}
+		case 15:
+			for i := 0; i < 2000; i++ {
+				s.Delete("tmp:" + strconv.Itoa(i))
+			}
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	for i := 0; i < 500; i++ {
+		if key := "key:" + strconv.Itoa(i); !seen[key] {
+			t.Fatalf("%s was not returned by SCAN", key)
+		}
+	}
+	if rounds < 15 {
+		t.Errorf("scan finished after %d rounds, want the resizes to happen mid-scan", rounds)
+	}
+
+	s.XAdd("stream", "*", []string{"a", "1"}, false, nil)
+	s.Set("expired", "v")
+	s.ExpireAt("expired", time.Now().Add(-time.Second))
+	collect := func(opts ScanOptions) map[string]bool {
+		found := make(map[string]bool)
+		cursor := uint64(0)
+		for {
+			next, keys := s.Scan(cursor, opts)
+			for _, key := range keys {
+				found[key] = true
+			}
+			if cursor = next; cursor == 0 {
+				return found
+			}
+		}
+	}
+	if found := collect(ScanOptions{Match: "key:1?"}); len(found) != 10 || !found["key:15"] {
+		t.Errorf("MATCH key:1? found %v", found)
+	}
+	if found := collect(ScanOptions{Type: "stream"}); len(found) != 1 || !found["stream"] {
+		t.Errorf("TYPE stream found %v", found)
+	}
+	if found := collect(ScanOptions{Count: 1000}); len(found) != 501 || found["expired"] {
+		t.Errorf("full scan found %d keys, expired included: %v", len(found), found["expired"])
+	}
+}
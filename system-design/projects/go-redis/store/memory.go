@@ -0,0 +1,117 @@
+package store
+
+// 内存估算（MEMORY USAGE / MEMORY STATS）
+//
+// Go 不提供单个对象占用的内存，这里按值的内部结构估算：字符串头、切片头、map 的条目和实际数据，
+// 数据按 8 字节对齐（接近 Go 小对象分配器的最小粒度）。与 Redis 一样，MEMORY USAGE 包括键名和键空间中
+// 条目本身的开销，聚合类型（stream）只抽样前 samples 个元素再按元素个数推算，samples 为 0 时计算全部元素。
+
+const (
+	// DefaultMemorySamples 是 MEMORY USAGE 默认抽样的元素个数
+	DefaultMemorySamples = 5
+
+	pointerSize      = 8
+	stringHeaderSize = 16 // 指针 + 长度
+	sliceHeaderSize  = 24 // 指针 + 长度 + 容量
+	ifaceSize        = 16 // 类型指针 + 数据指针
+	timeSize         = 24
+	streamIDSize     = 16
+	mapEntryOverhead = 8 // 每个条目在桶中的 tophash 和溢出桶的摊销
+
+	// 键空间中每个键在 data 表中的条目：键的字符串头 + 接口值
+	dataEntrySize = stringHeaderSize + ifaceSize + mapEntryOverhead
+	// expires 表中的条目：键的字符串头 + time.Time
+	expireEntrySize = stringHeaderSize + timeSize + mapEntryOverhead
+	// meta 表中的条目：键的字符串头 + 指针 + objectMeta
+	metaEntrySize = stringHeaderSize + pointerSize + mapEntryOverhead + 16
+)
+
+// allocSize 返回分配 n 字节时实际占用的字节数
+func allocSize(n int) int64 {
+	return int64((n + 7) &^ 7)
+}
+
+func stringSize(s string) int64 {
+	return stringHeaderSize + allocSize(len(s))
+}
+
+// MemoryUsage 估算键和值占用的字节数，键不存在时返回 false，不会更新键的访问信息
+func (s *Store) MemoryUsage(key string, samples int) (int64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, exists := s.peek(key)
+	if !exists {
+		return 0, false
+	}
+
+	size := allocSize(len(key)) + dataEntrySize + valueSize(v, samples)
+	if _, ok := s.expires[key]; ok {
+		size += expireEntrySize
+	}
+	if _, ok := s.meta[key]; ok {
+		size += metaEntrySize
+	}
+	return size, true
+}
+
+// valueSize 估算值本身占用的字节数，不包括键空间中的条目
+func valueSize(v interface{}, samples int) int64 {
+	switch v := v.(type) {
+	case int64:
+		return 8
+	case string:
+		return stringSize(v)
+	case *Stream:
+		return v.memoryUsage(samples)
+	default:
+		return 0
+	}
+}
+
+// memoryUsage 估算 stream 占用的字节数：条目的字段按前 samples 个条目的平均大小推算，
+// 消费组、消费者和待确认列表按个数计算
+func (st *Stream) memoryUsage(samples int) int64 {
+	size := int64(sliceHeaderSize + streamIDSize + pointerSize)
+
+	const entrySize = streamIDSize + sliceHeaderSize
+	size += int64(cap(st.entries)) * entrySize
+
+	n := len(st.entries)
+	if samples <= 0 || samples > n {
+		samples = n
+	}
+	if samples > 0 {
+		var sampled int64
+		for _, e := range st.entries[:samples] {
+			sampled += int64(cap(e.Fields)) * stringHeaderSize
+			for _, f := range e.Fields {
+				sampled += allocSize(len(f))
+			}
+		}
+		size += sampled * int64(n) / int64(samples)
+	}
+
+	// 待确认条目同时出现在消费组和消费者的 pending 表中，条目本身只有一份
+	const pendingSize = streamIDSize + stringHeaderSize + timeSize + 8
+	const pendingRefSize = streamIDSize + pointerSize + mapEntryOverhead
+	for name, g := range st.groups {
+		size += stringHeaderSize + pointerSize + mapEntryOverhead + stringSize(name)
+		size += stringHeaderSize + streamIDSize + 2*pointerSize
+		size += int64(len(g.pending)) * (pendingSize + pendingRefSize)
+		for cname, c := range g.consumers {
+			size += stringHeaderSize + pointerSize + mapEntryOverhead + allocSize(len(cname))
+			size += stringSize(c.Name) + timeSize + pointerSize
+			size += int64(len(c.pending)) * pendingRefSize
+		}
+	}
+	return size
+}
+
+// MemoryOverhead 返回键空间的 data 表和 expires 表本身（不包括键名和值）占用的字节数（MEMORY STATS）
+func (s *Store) MemoryOverhead() (main, expires int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.data))*dataEntrySize + int64(len(s.meta))*metaEntrySize,
+		int64(len(s.expires)) * expireEntrySize
+}
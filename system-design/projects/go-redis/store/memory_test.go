@@ -0,0 +1,54 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	if _, ok := s.MemoryUsage("missing", DefaultMemorySamples); ok {
+		t.Error("MemoryUsage(missing) should report a missing key")
+	}
+
+	s.Set("small", "v")
+	s.Set("large", strings.Repeat("x", 1000))
+	small, _ := s.MemoryUsage("small", DefaultMemorySamples)
+	large, _ := s.MemoryUsage("large", DefaultMemorySamples)
+	if small <= 0 || large-small < 990 {
+		t.Errorf("usage small=%d large=%d", small, large)
+	}
+
+	// 过期时间占用 expires 表中的一个条目
+	s.ExpireAt("small", time.Now().Add(time.Hour))
+	if withTTL, _ := s.MemoryUsage("small", DefaultMemorySamples); withTTL <= small {
+		t.Errorf("usage with TTL = %d, want more than %d", withTTL, small)
+	}
+}
+
+func TestMemoryUsageStreamSamples(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	for i := 0; i < 100; i++ {
+		if _, _, _, err := s.XAdd("s", "*", []string{"field", "value"}, false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 条目大小相同时，抽样推算的结果与计算全部元素相同
+	sampled, _ := s.MemoryUsage("s", DefaultMemorySamples)
+	all, _ := s.MemoryUsage("s", 0)
+	if sampled != all {
+		t.Errorf("sampled usage = %d, full usage = %d", sampled, all)
+	}
+
+	before := all
+	s.XAdd("s", "*", []string{"field", strings.Repeat("v", 1000)}, false, nil)
+	if all, _ := s.MemoryUsage("s", 0); all-before < 1000 {
+		t.Errorf("usage after a large entry = %d, before = %d", all, before)
+	}
+}
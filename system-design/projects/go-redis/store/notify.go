@@ -0,0 +1,143 @@
+package store
+
+import (
+	"errors"
+	"strings"
+)
+
+// 键空间通知的事件类别，与 notify-keyspace-events 配置中的字符一一对应
+const (
+	NotifyKeyspace = 1 << iota // K：发布到 __keyspace@<db>__:<key>
+	NotifyKeyevent             // E：发布到 __keyevent@<db>__:<event>
+	NotifyGeneric              // g：DEL、EXPIRE、RENAME 等通用命令
+	NotifyString               // $：字符串命令
+	NotifyList                 // l：列表命令
+	NotifySet                  // s：集合命令
+	NotifyHash                 // h：哈希命令
+	NotifyZSet                 // z：有序集合命令
+	NotifyExpired              // x：键过期
+	NotifyEvicted              // e：键因 maxmemory 被淘汰
+	NotifyStream               // t：stream 命令
+	NotifyKeyMiss              // m：访问不存在的键
+	NotifyNew                  // n：新建键
+
+	// NotifyAll 对应 "A"，是 g$lshzxet 的别名（不包含 m 和 n）
+	NotifyAll = NotifyGeneric | NotifyString | NotifyList | NotifySet | NotifyHash |
+		NotifyZSet | NotifyExpired | NotifyEvicted | NotifyStream
+)
+
+var notifyFlagChars = []struct {
+	char byte
+	flag int
+}{
+	{'g', NotifyGeneric},
+	{'$', NotifyString},
+	{'l', NotifyList},
+	{'s', NotifySet},
+	{'h', NotifyHash},
+	{'z', NotifyZSet},
+	{'x', NotifyExpired},
+	{'e', NotifyEvicted},
+	{'t', NotifyStream},
+	{'K', NotifyKeyspace},
+	{'E', NotifyKeyevent},
+	{'m', NotifyKeyMiss},
+	{'n', NotifyNew},
+}
+
+// ErrInvalidNotifyFlags 表示 notify-keyspace-events 中包含未知字符
+var ErrInvalidNotifyFlags = errors.New("ERR Invalid event class character. Use 'Ag$lshzxeKEtmn'.")
+
+// ParseNotifyFlags 把 "KEA" 这样的配置字符串解析为类别位图
+func ParseNotifyFlags(s string) (int, error) {
+	flags := 0
+
+outer:
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'A' {
+			flags |= NotifyAll
+			continue
+		}
+		for _, fc := range notifyFlagChars {
+			if fc.char == s[i] {
+				flags |= fc.flag
+				continue outer
+			}
+		}
+		return 0, ErrInvalidNotifyFlags
+	}
+
+	return flags, nil
+}
+
+// NotifyFlagsString 把类别位图还原为配置字符串，包含全部类别时使用 "A" 缩写
+func NotifyFlagsString(flags int) string {
+	var b strings.Builder
+
+	if flags&NotifyAll == NotifyAll {
+		b.WriteByte('A')
+	}
+	for _, fc := range notifyFlagChars {
+		if fc.flag&NotifyAll != 0 && flags&NotifyAll == NotifyAll {
+			continue
+		}
+		if flags&fc.flag != 0 {
+			b.WriteByte(fc.char)
+		}
+	}
+
+	return b.String()
+}
+
+// Publisher 是键空间通知的投递目标，通常由 pubsub.Hub 实现
+// Publish 可能在持有 Store 锁时被调用，实现方不能阻塞，也不能回调 Store
+type Publisher interface {
+	Publish(channel, message string) int64
+}
+
+// publisherHolder 让 atomic.Value 始终存放同一具体类型
+type publisherHolder struct {
+	p Publisher
+}
+
+// SetPublisher 设置键空间通知的投递目标
+func (s *Store) SetPublisher(p Publisher) {
+	s.publisher.Store(publisherHolder{p: p})
+}
+
+// SetNotifyFlags 设置启用的事件类别，0 表示关闭通知
+func (s *Store) SetNotifyFlags(flags int) {
+	s.notifyFlags.Store(int64(flags))
+}
+
+// NotifyFlags 返回当前启用的事件类别
+func (s *Store) NotifyFlags() int {
+	return int(s.notifyFlags.Load())
+}
+
+// notifyEnabled 判断某类事件是否需要发布
+// 关闭通知时只有一次原子读，不会给热路径带来额外开销
+func (s *Store) notifyEnabled(class int) bool {
+	return int(s.notifyFlags.Load())&class != 0
+}
+
+// Notify 发布一条键空间通知
+// class 是事件类别，event 是事件名（如 "set"、"del"、"expired"）
+func (s *Store) Notify(class int, event, key string) {
+	flags := int(s.notifyFlags.Load())
+	if flags&class == 0 || flags&(NotifyKeyspace|NotifyKeyevent) == 0 {
+		return
+	}
+
+	holder, _ := s.publisher.Load().(publisherHolder)
+	if holder.p == nil {
+		return
+	}
+
+	if flags&NotifyKeyspace != 0 {
+		holder.p.Publish("__keyspace@0__:"+key, event)
+	}
+	if flags&NotifyKeyevent != 0 {
+		holder.p.Publish("__keyevent@0__:"+event, key)
+	}
+}
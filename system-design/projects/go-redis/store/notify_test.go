@@ -0,0 +1,219 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordPublisher 记录发布的消息，用于断言键空间通知
+type recordPublisher struct {
+	mu       sync.Mutex
+	messages [][2]string
+}
+
+func (p *recordPublisher) Publish(channel, message string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, [2]string{channel, message})
+	return 1
+}
+
+func (p *recordPublisher) snapshot() [][2]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][2]string(nil), p.messages...)
+}
+
+func TestParseNotifyFlags(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"KEA", "AKE", false},
+		{"Kx", "xK", false},
+		{"E$g", "g$E", false},
+		{"AKEmn", "AKEmn", false},
+		{"Kq", "", true},
+	}
+
+	for _, tt := range tests {
+		flags, err := ParseNotifyFlags(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseNotifyFlags(%q): expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseNotifyFlags(%q): unexpected error %v", tt.input, err)
+			continue
+		}
+		if got := NotifyFlagsString(flags); got != tt.want {
+			t.Errorf("NotifyFlagsString(ParseNotifyFlags(%q)) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNotifyChannels(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	pub := &recordPublisher{}
+	s.SetPublisher(pub)
+
+	// 未开启时不发布
+	s.Notify(NotifyString, "set", "foo")
+	if len(pub.snapshot()) != 0 {
+		t.Fatal("Expected no messages while notifications are disabled")
+	}
+
+	// 只开启 E 不开启类别时也不发布
+	s.SetNotifyFlags(NotifyKeyevent)
+	s.Notify(NotifyString, "set", "foo")
+	if len(pub.snapshot()) != 0 {
+		t.Fatal("Expected no messages without an event class")
+	}
+
+	s.SetNotifyFlags(NotifyKeyspace | NotifyKeyevent | NotifyString)
+	s.Notify(NotifyString, "set", "foo")
+	s.Notify(NotifyGeneric, "del", "foo")
+
+	got := pub.snapshot()
+	want := [][2]string{
+		{"__keyspace@0__:foo", "set"},
+		{"__keyevent@0__:set", "foo"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Message %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNotifyNewAndKeyMiss(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	pub := &recordPublisher{}
+	s.SetPublisher(pub)
+	s.SetNotifyFlags(NotifyKeyevent | NotifyNew | NotifyKeyMiss)
+
+	s.Set("a", "1")
+	s.Set("a", "2")
+	s.Get("missing")
+
+	got := pub.snapshot()
+	want := [][2]string{
+		{"__keyevent@0__:new", "a"},
+		{"__keyevent@0__:keymiss", "missing"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	if s.ExpireAt("missing", time.Now().Add(time.Second)) {
+		t.Error("Expected ExpireAt on a missing key to fail")
+	}
+	if s.PTTL("missing") != -2 {
+		t.Error("Expected PTTL -2 for a missing key")
+	}
+
+	s.Set("k", "v")
+	if s.PTTL("k") != -1 {
+		t.Error("Expected PTTL -1 for a key without TTL")
+	}
+
+	s.ExpireAt("k", time.Now().Add(time.Hour))
+	if ttl := s.PTTL("k"); ttl <= 0 || ttl > time.Hour.Milliseconds() {
+		t.Errorf("Unexpected PTTL %d", ttl)
+	}
+
+	if !s.Persist("k") || s.PTTL("k") != -1 {
+		t.Error("Expected PERSIST to remove the TTL")
+	}
+
+	// SET 覆盖时清除过期时间
+	s.ExpireAt("k", time.Now().Add(time.Hour))
+	s.Set("k", "v2")
+	if s.PTTL("k") != -1 {
+		t.Error("Expected SET to clear the TTL")
+	}
+
+	// 过去的时间点直接删除
+	s.ExpireAt("k", time.Now().Add(-time.Second))
+	if s.Exists("k") {
+		t.Error("Expected key to be deleted by an expiry in the past")
+	}
+}
+
+func TestLazyExpireHidesKey(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	s.Set("k", "v")
+	s.ExpireAt("k", time.Now().Add(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := s.Get("k"); ok {
+		t.Error("Expected expired key to be invisible to Get")
+	}
+	for _, key := range s.Keys() {
+		if key == "k" {
+			t.Error("Expected expired key to be absent from Keys")
+		}
+	}
+	if s.Delete("k") {
+		t.Error("Expected Delete on an expired key to return false")
+	}
+}
+
+func TestActiveExpireNotifies(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	pub := &recordPublisher{}
+	s.SetPublisher(pub)
+	s.SetNotifyFlags(NotifyKeyspace | NotifyExpired)
+
+	s.Set("session", "v")
+	s.ExpireAt("session", time.Now().Add(10*time.Millisecond))
+
+	// 不访问该键，等待后台清理发布 expired 事件
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if msgs := pub.snapshot(); len(msgs) > 0 {
+			if msgs[0] != [2]string{"__keyspace@0__:session", "expired"} {
+				t.Errorf("Unexpected message %v", msgs[0])
+			}
+			if s.ExpiredKeys() != 1 {
+				t.Errorf("Expected 1 expired key, got %d", s.ExpiredKeys())
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("Expected an expired event from the active expire cycle")
+}
+
+// BenchmarkSetNotifyDisabled 衡量关闭通知时 Set 的开销
+func BenchmarkSetNotifyDisabled(b *testing.B) {
+	s := NewStore()
+	defer s.Close()
+	s.SetPublisher(&recordPublisher{})
+
+	for i := 0; i < b.N; i++ {
+		s.Set("key", "value")
+	}
+}
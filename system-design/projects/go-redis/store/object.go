@@ -0,0 +1,149 @@
+package store
+
+import (
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// 键的访问信息，供 OBJECT IDLETIME/FREQ 使用
+//
+// 与 Redis 一样记录最近访问时间和对数形式的访问频率（LFU 计数器）：
+// 计数器每次访问以 1/((counter-LFUInitVal)*lfuLogFactor+1) 的概率加 1，
+// 每经过 lfuDecayTime 没有访问减 1，所以 255 大约对应百万次访问。
+// 本实现没有 maxmemory 淘汰，两种信息都会记录。
+
+const (
+	// LFUInitVal 是新建键的 LFU 计数器初值，避免新键立刻被当作冷数据
+	LFUInitVal   = 5
+	lfuLogFactor = 10
+	lfuDecayTime = time.Minute
+)
+
+// objectMeta 是键的访问信息
+// meta 表只在持有写锁时增删，字段用原子操作读写，读路径持有读锁即可更新
+type objectMeta struct {
+	atime   atomic.Int64 // 最近访问时间（Unix 毫秒）
+	counter atomic.Int32 // LFU 计数器，0-255
+}
+
+func newObjectMeta(now time.Time) *objectMeta {
+	m := &objectMeta{}
+	m.atime.Store(now.UnixMilli())
+	m.counter.Store(LFUInitVal)
+	return m
+}
+
+// decayedCounter 返回按空闲时间衰减后的计数器
+func (m *objectMeta) decayedCounter(now time.Time) int32 {
+	periods := now.Sub(time.UnixMilli(m.atime.Load())) / lfuDecayTime
+	return max(m.counter.Load()-int32(min(periods, 255)), 0)
+}
+
+// touch 记录一次访问
+func (m *objectMeta) touch(now time.Time) {
+	counter := m.decayedCounter(now)
+	if counter < 255 {
+		base := max(float64(counter-LFUInitVal), 0)
+		if rand.Float64() < 1/(base*lfuLogFactor+1) {
+			counter++
+		}
+	}
+	m.counter.Store(counter)
+	m.atime.Store(now.UnixMilli())
+}
+
+// touchKey 更新键的访问信息，调用前需持有锁
+func (s *Store) touchKey(key string) {
+	if m, ok := s.meta[key]; ok {
+		m.touch(time.Now())
+	}
+}
+
+// updateMeta 在键被修改后维护 meta 表：键存在时记录一次访问，键被删除时移除，调用前需持有写锁
+func (s *Store) updateMeta(key string) {
+	if _, exists := s.data[key]; !exists {
+		delete(s.meta, key)
+		return
+	}
+	now := time.Now()
+	if m, ok := s.meta[key]; ok {
+		m.touch(now)
+	} else {
+		s.meta[key] = newObjectMeta(now)
+	}
+}
+
+// objectEncoding 返回值的内部编码名，与 Redis 的 OBJECT ENCODING 保持一致
+func objectEncoding(v interface{}) string {
+	switch v := v.(type) {
+	case int64:
+		return "int"
+	case string:
+		// Redis 把可以表示为 long long 的短字符串保存为整数
+		if len(v) <= 20 {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && strconv.FormatInt(n, 10) == v {
+				return "int"
+			}
+		}
+		if len(v) <= 44 {
+			return "embstr"
+		}
+		return "raw"
+	case *Stream:
+		return "stream"
+	default:
+		return "unknown"
+	}
+}
+
+// typeName 返回 TYPE 命令使用的类型名
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case string, int64:
+		return "string"
+	case *Stream:
+		return "stream"
+	default:
+		return "none"
+	}
+}
+
+// ObjectInfo 是 OBJECT 命令返回的键信息
+type ObjectInfo struct {
+	Encoding string
+	IdleTime time.Duration
+	Freq     int
+}
+
+// Object 返回键的编码、空闲时间和访问频率，不会更新键的访问信息
+func (s *Store) Object(key string) (ObjectInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, exists := s.peek(key)
+	if !exists {
+		return ObjectInfo{}, false
+	}
+
+	info := ObjectInfo{Encoding: objectEncoding(v), Freq: LFUInitVal}
+	if m, ok := s.meta[key]; ok {
+		now := time.Now()
+		info.IdleTime = now.Sub(time.UnixMilli(m.atime.Load()))
+		info.Freq = int(m.decayedCounter(now))
+	}
+	return info, true
+}
+
+// Type 返回键的值类型，键不存在时返回 "none"
+func (s *Store) Type(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, exists := s.peek(key)
+	if !exists {
+		return "none"
+	}
+	return typeName(v)
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"time"
+
+	"go-redis/glob"
+)
+
+// SCAN
+//
+// Go 的 map 没有可以跨调用保持的遍历位置，因此 Store 另外用 keyTable 按哈希把键分到 2 的幂个桶中，
+// SCAN 的游标是桶的编号。与 Redis 的 dictScan 相同，游标按反转的二进制位递增（先增加最高位），
+// 两次调用之间桶的个数翻倍或减半时，已经遍历过的桶对应的仍然是新表中已经遍历过的桶，
+// 因此整个遍历期间一直存在的键至少返回一次，但可能重复；遍历期间增删的键可能返回也可能不返回
+
+const (
+	minScanBuckets   = 16
+	defaultScanCount = 10
+)
+
+// keyTable 记录所有键所在的桶，只被 Store 在持有锁时使用
+type keyTable struct {
+	seed    maphash.Seed
+	buckets [][]string
+	count   int
+}
+
+func newKeyTable() *keyTable {
+	return &keyTable{
+		seed:    maphash.MakeSeed(),
+		buckets: make([][]string, minScanBuckets),
+	}
+}
+
+func (t *keyTable) index(key string, size int) int {
+	return int(maphash.String(t.seed, key) & uint64(size-1))
+}
+
+// add 加入一个新键，调用方保证键不在表中；键数超过桶数时桶数翻倍
+func (t *keyTable) add(key string) {
+	i := t.index(key, len(t.buckets))
+	t.buckets[i] = append(t.buckets[i], key)
+	t.count++
+	if t.count > len(t.buckets) {
+		t.resize(len(t.buckets) * 2)
+	}
+}
+
+// remove 删除一个键；键数少于桶数的 1/8 时桶数减半
+func (t *keyTable) remove(key string) {
+	i := t.index(key, len(t.buckets))
+	b := t.buckets[i]
+	for j, k := range b {
+		if k == key {
+			b[j] = b[len(b)-1]
+			b[len(b)-1] = ""
+			t.buckets[i] = b[:len(b)-1]
+			t.count--
+			break
+		}
+	}
+	if len(t.buckets) > minScanBuckets && t.count < len(t.buckets)/8 {
+		t.resize(len(t.buckets) / 2)
+	}
+}
+
+func (t *keyTable) resize(size int) {
+	buckets := make([][]string, size)
+	for _, b := range t.buckets {
+		for _, key := range b {
+			i := t.index(key, size)
+			buckets[i] = append(buckets[i], key)
+		}
+	}
+	t.buckets = buckets
+}
+
+// scan 从游标 cursor 对应的桶开始依次把整个桶交给 fn，直到交出至少 count 个键、
+// 连续遇到 count*10 个空桶或遍历结束，返回下一次的游标，0 表示遍历结束
+func (t *keyTable) scan(cursor uint64, count int, fn func(key string)) uint64 {
+	mask := uint64(len(t.buckets) - 1)
+	emitted, empty := 0, 0
+	for {
+		b := t.buckets[cursor&mask]
+		for _, key := range b {
+			fn(key)
+		}
+		emitted += len(b)
+		if len(b) == 0 {
+			empty++
+		}
+
+		// 反转的二进制加一：把高于 mask 的位置一后反转、加一、再反转
+		cursor |= ^mask
+		cursor = bits.Reverse64(bits.Reverse64(cursor) + 1)
+
+		if cursor == 0 || emitted >= count || empty >= count*10 {
+			return cursor
+		}
+	}
+}
+
+// ScanOptions 是 SCAN 的可选参数
+type ScanOptions struct {
+	Count int    // 每次大约遍历的键数，不大于 0 时为 10
+	Match string // glob 模式，空字符串表示不过滤
+	Type  string // 值的类型（与 TYPE 的结果相同），空字符串表示不过滤
+}
+
+// Scan 从游标 cursor 开始遍历一部分键，返回其中未过期且符合条件的键和下一次的游标，游标为 0 时遍历结束
+// 与 Redis 一样，过滤在遍历之后进行，返回的键可能少于 Count，甚至为空
+func (s *Store) Scan(cursor uint64, opts ScanOptions) (uint64, []string) {
+	count := opts.Count
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var keys []string
+	next := s.keys.scan(cursor, count, func(key string) {
+		if s.isExpired(key, now) {
+			return
+		}
+		if opts.Match != "" && !glob.Match(opts.Match, key) {
+			return
+		}
+		if opts.Type != "" && typeName(s.data[key]) != opts.Type {
+			return
+		}
+		keys = append(keys, key)
+	})
+	return next, keys
+}
@@ -4,6 +4,8 @@ import (
 	"go-redis/logger"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,16 +14,45 @@ import (
 // 它使用读写锁（RWMutex）来保证并发访问的安全性。
 // 支持任意类型的值（interface{}）。
 type Store struct {
-	mu   sync.RWMutex           // 读写锁
-	data map[string]interface{} // 数据存储
+	mu      sync.RWMutex           // 读写锁
+	data    map[string]interface{} // 数据存储
+	expires map[string]time.Time   // 键的过期时间点
+	meta    map[string]*objectMeta // 键的访问信息（OBJECT IDLETIME/FREQ）
+	keys    *keyTable              // 键所在的桶，供 SCAN 按游标遍历
+
+	expiredKeys int64 // 因过期被删除的键总数
+
+	hllSparseMaxBytes atomic.Int64 // HyperLogLog sparse 编码的最大字节数
+
+	lazyfreePending atomic.Int64 // 等待后台释放的对象数（UNLINK）
+	lazyfreed       atomic.Int64 // 已经由后台释放的对象数
+
+	notifyFlags atomic.Int64 // 启用的键空间通知类别
+	publisher   atomic.Value // 键空间通知的投递目标（publisherHolder）
+	tracker     atomic.Value // 键修改的通知目标（trackerHolder）
+
+	waiters   map[string]map[chan struct{}]struct{} // 阻塞命令的等待者
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-// NewStore 创建一个新的 Store 实例
+// NewStore 创建一个新的 Store 实例，并启动后台过期清理
+// 不再使用时调用 Close 停止后台任务
 func NewStore() *Store {
 	logger.Debug("创建新的 Store 实例")
-	return &Store{
-		data: make(map[string]interface{}),
+	s := &Store{
+		data:    make(map[string]interface{}),
+		expires: make(map[string]time.Time),
+		meta:    make(map[string]*objectMeta),
+		keys:    newKeyTable(),
+		waiters: make(map[string]map[chan struct{}]struct{}),
+		done:    make(chan struct{}),
 	}
+	s.hllSparseMaxBytes.Store(DefaultHLLSparseMaxBytes)
+
+	go s.activeExpireLoop()
+
+	return s
 }
 
 func (s *Store) Incr(key string) bool {
@@ -38,9 +69,13 @@ func (s *Store) IncrBy(key string, cnt int64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.expireIfNeeded(key)
+
 	value, exists := s.data[key]
 	if !exists {
-		s.data[key] = cnt
+		s.setValue(key, cnt)
+		s.Notify(NotifyNew, "new", key)
+		s.keyModified(key)
 		return true
 	}
 
@@ -48,21 +83,22 @@ func (s *Store) IncrBy(key string, cnt int64) bool {
 
 	switch value := value.(type) {
 	case int64:
-		s.data[key] = value + cnt
+		s.setValue(key, value+cnt)
 	case string:
 		val, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			return false
 		}
-		s.data[key] = val + cnt
+		s.setValue(key, val+cnt)
 	default:
 		return false
 	}
 
+	s.keyModified(key)
 	return true
 }
 
-// Set 设置键值对
+// Set 设置键值对，同时清除键上原有的过期时间
 func (s *Store) Set(key string, value interface{}) {
 	logger.WithFields(logrus.Fields{
 		"operation": "SET",
@@ -71,7 +107,17 @@ func (s *Store) Set(key string, value interface{}) {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data[key] = value
+
+	s.expireIfNeeded(key)
+	if s.notifyEnabled(NotifyNew) {
+		if _, exists := s.data[key]; !exists {
+			s.Notify(NotifyNew, "new", key)
+		}
+	}
+
+	s.setValue(key, value)
+	delete(s.expires, key)
+	s.keyModified(key)
 
 	logger.WithField("key", key).Debug("Set 操作完成")
 }
@@ -87,7 +133,10 @@ func (s *Store) Get(key string) (interface{}, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	value, exists := s.data[key]
+	value, exists := s.lookup(key)
+	if !exists {
+		s.Notify(NotifyKeyMiss, "keymiss", key)
+	}
 
 	logger.WithFields(logrus.Fields{
 		"key":    key,
@@ -108,10 +157,15 @@ func (s *Store) Delete(key string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// 已过期的键视为不存在
+	if s.expireIfNeeded(key) {
+		return false
+	}
+
 	// 检查键是否存在
 	_, exists := s.data[key]
 	if exists {
-		delete(s.data, key)
+		s.removeKey(key)
 		logger.WithField("key", key).Debug("Delete 操作完成 - 键已删除")
 		return true
 	}
@@ -130,7 +184,7 @@ func (s *Store) Exists(key string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	_, exists := s.data[key]
+	_, exists := s.lookup(key)
 
 	logger.WithFields(logrus.Fields{
 		"key":    key,
@@ -147,8 +201,12 @@ func (s *Store) Keys() []string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now()
 	keys := make([]string, 0, len(s.data))
 	for key := range s.data {
+		if s.isExpired(key, now) {
+			continue
+		}
 		keys = append(keys, key)
 	}
 
@@ -168,6 +226,13 @@ func (s *Store) Clear() {
 
 	oldCount := len(s.data)
 	s.data = make(map[string]interface{})
+	s.expires = make(map[string]time.Time)
+	s.meta = make(map[string]*objectMeta)
+	s.keys = newKeyTable()
+
+	if holder, _ := s.tracker.Load().(trackerHolder); holder.t != nil {
+		holder.t.FlushAll()
+	}
 
 	logger.WithFields(logrus.Fields{
 		"cleared_count": oldCount,
@@ -0,0 +1,468 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWrongType 表示对键执行了与其值类型不匹配的操作
+	ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+	ErrInvalidStreamID  = errors.New("ERR Invalid stream ID specified as stream command argument")
+	ErrStreamIDTooSmall = errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	ErrStreamIDZero     = errors.New("ERR The ID specified in XADD must be greater than 0-0")
+	ErrStreamExhausted  = errors.New("ERR The stream has exhausted the last possible ID, unable to add more items")
+	ErrBusyGroup        = errors.New("BUSYGROUP Consumer Group name already exists")
+	ErrNoGroupKey       = errors.New("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+)
+
+// StreamID 是 stream 条目的 ID，由毫秒时间戳和同一毫秒内的序号组成
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// MaxStreamID 是可表示的最大 ID，对应区间查询中的 "+"
+var MaxStreamID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// Less 判断 id 是否严格小于 other
+func (id StreamID) Less(other StreamID) bool {
+	if id.Ms != other.Ms {
+		return id.Ms < other.Ms
+	}
+	return id.Seq < other.Seq
+}
+
+// IsZero 判断是否为 0-0
+func (id StreamID) IsZero() bool {
+	return id.Ms == 0 && id.Seq == 0
+}
+
+// Next 返回紧跟在 id 之后的 ID，已是最大值时 ok 为 false
+func (id StreamID) Next() (StreamID, bool) {
+	if id.Seq < math.MaxUint64 {
+		return StreamID{Ms: id.Ms, Seq: id.Seq + 1}, true
+	}
+	if id.Ms < math.MaxUint64 {
+		return StreamID{Ms: id.Ms + 1}, true
+	}
+	return id, false
+}
+
+// Prev 返回紧挨在 id 之前的 ID，已是 0-0 时 ok 为 false
+func (id StreamID) Prev() (StreamID, bool) {
+	if id.Seq > 0 {
+		return StreamID{Ms: id.Ms, Seq: id.Seq - 1}, true
+	}
+	if id.Ms > 0 {
+		return StreamID{Ms: id.Ms - 1, Seq: math.MaxUint64}, true
+	}
+	return id, false
+}
+
+// ParseStreamID 解析 "ms-seq" 或 "ms" 形式的 ID
+// 只给出 ms 时，序号取 missingSeq
+func ParseStreamID(s string, missingSeq uint64) (StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamID{}, ErrInvalidStreamID
+	}
+
+	if !hasSeq {
+		return StreamID{Ms: ms, Seq: missingSeq}, nil
+	}
+
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return StreamID{}, ErrInvalidStreamID
+	}
+
+	return StreamID{Ms: ms, Seq: seq}, nil
+}
+
+// ParseStreamRange 解析 XRANGE/XPENDING 等命令的区间边界
+// 支持 "-"、"+"、不完整 ID 以及 "(" 前缀的开区间
+func ParseStreamRange(s string, isStart bool) (StreamID, error) {
+	switch s {
+	case "-":
+		return StreamID{}, nil
+	case "+":
+		return MaxStreamID, nil
+	}
+
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+
+	missingSeq := uint64(0)
+	if !isStart {
+		missingSeq = math.MaxUint64
+	}
+
+	id, err := ParseStreamID(s, missingSeq)
+	if err != nil {
+		return StreamID{}, err
+	}
+
+	if !exclusive {
+		return id, nil
+	}
+
+	var ok bool
+	if isStart {
+		id, ok = id.Next()
+		if !ok {
+			return StreamID{}, errors.New("ERR invalid start ID for the interval")
+		}
+	} else {
+		id, ok = id.Prev()
+		if !ok {
+			return StreamID{}, errors.New("ERR invalid end ID for the interval")
+		}
+	}
+
+	return id, nil
+}
+
+// StreamEntry 是 stream 中的一条消息
+// Fields 按 field1, value1, field2, value2... 的顺序平铺存放
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string
+}
+
+// StreamTrim 描述 XADD/XTRIM 的裁剪策略
+type StreamTrim struct {
+	Strategy string // "MAXLEN" 或 "MINID"
+	MaxLen   int64
+	MinID    StreamID
+	Approx   bool  // "~" 近似裁剪，本实现按精确裁剪处理
+	Limit    int64 // 单次最多删除的条目数，0 表示不限制
+}
+
+// PendingEntry 是消费组待确认列表（PEL）中的一项
+type PendingEntry struct {
+	ID            StreamID
+	Consumer      string
+	DeliveryTime  time.Time
+	DeliveryCount int64
+}
+
+// StreamConsumer 是消费组中的一个消费者
+type StreamConsumer struct {
+	Name     string
+	SeenTime time.Time
+	pending  map[StreamID]*PendingEntry
+}
+
+// ConsumerGroup 是 stream 上的消费组
+type ConsumerGroup struct {
+	Name      string
+	LastID    StreamID
+	pending   map[StreamID]*PendingEntry
+	consumers map[string]*StreamConsumer
+}
+
+// Stream 是追加写的消息日志
+// 条目按 ID 递增存放在切片中，追加为均摊 O(1)，区间查询通过二分查找定位
+type Stream struct {
+	entries []StreamEntry
+	lastID  StreamID
+	groups  map[string]*ConsumerGroup
+}
+
+func newStream() *Stream {
+	return &Stream{
+		groups: make(map[string]*ConsumerGroup),
+	}
+}
+
+// Len 返回条目数量
+func (st *Stream) Len() int {
+	return len(st.entries)
+}
+
+// LastID 返回曾经写入过的最大 ID（条目被删除后依然保留）
+func (st *Stream) LastID() StreamID {
+	return st.lastID
+}
+
+// search 返回第一个 ID >= id 的条目下标
+func (st *Stream) search(id StreamID) int {
+	return sort.Search(len(st.entries), func(i int) bool {
+		return !st.entries[i].ID.Less(id)
+	})
+}
+
+// lookup 按 ID 精确查找条目
+func (st *Stream) lookup(id StreamID) (StreamEntry, bool) {
+	i := st.search(id)
+	if i < len(st.entries) && st.entries[i].ID == id {
+		return st.entries[i], true
+	}
+	return StreamEntry{}, false
+}
+
+// nextID 根据 XADD 的 ID 参数（"*"、"ms-*" 或完整 ID）生成新 ID
+func (st *Stream) nextID(spec string, now time.Time) (StreamID, error) {
+	last := st.lastID
+
+	if spec == "*" {
+		ms := uint64(now.UnixMilli())
+		if ms > last.Ms {
+			return StreamID{Ms: ms}, nil
+		}
+		id, ok := last.Next()
+		if !ok {
+			return StreamID{}, ErrStreamExhausted
+		}
+		return id, nil
+	}
+
+	if msPart, ok := strings.CutSuffix(spec, "-*"); ok {
+		ms, err := strconv.ParseUint(msPart, 10, 64)
+		if err != nil {
+			return StreamID{}, ErrInvalidStreamID
+		}
+		switch {
+		case ms > last.Ms:
+			return StreamID{Ms: ms}, nil
+		case ms == last.Ms:
+			if last.Seq == math.MaxUint64 {
+				return StreamID{}, ErrStreamIDTooSmall
+			}
+			return StreamID{Ms: ms, Seq: last.Seq + 1}, nil
+		default:
+			return StreamID{}, ErrStreamIDTooSmall
+		}
+	}
+
+	id, err := ParseStreamID(spec, 0)
+	if err != nil {
+		return StreamID{}, err
+	}
+	if id.IsZero() {
+		return StreamID{}, ErrStreamIDZero
+	}
+	if !last.Less(id) {
+		return StreamID{}, ErrStreamIDTooSmall
+	}
+
+	return id, nil
+}
+
+// add 追加一条消息
+func (st *Stream) add(id StreamID, fields []string) {
+	st.entries = append(st.entries, StreamEntry{ID: id, Fields: fields})
+	st.lastID = id
+}
+
+// rangeOf 返回 [start, end] 区间内最多 count 条消息，count <= 0 表示不限制
+// 区间的两端都用二分查找定位，代价是 O(log N + count)，与区间内的消息总数无关
+func (st *Stream) rangeOf(start, end StreamID, count int, rev bool) []StreamEntry {
+	if end.Less(start) {
+		return []StreamEntry{}
+	}
+
+	lo := st.search(start)
+	// hi 是第一个 ID > end 的条目
+	hi := sort.Search(len(st.entries), func(i int) bool {
+		return end.Less(st.entries[i].ID)
+	})
+	if count > 0 && count < hi-lo {
+		if rev {
+			lo = hi - count
+		} else {
+			hi = lo + count
+		}
+	}
+
+	res := make([]StreamEntry, 0, hi-lo)
+	if rev {
+		for i := hi - 1; i >= lo; i-- {
+			res = append(res, st.entries[i])
+		}
+	} else {
+		res = append(res, st.entries[lo:hi]...)
+	}
+
+	return res
+}
+
+// after 返回 ID 严格大于 id 的最多 count 条消息
+func (st *Stream) after(id StreamID, count int) []StreamEntry {
+	start, ok := id.Next()
+	if !ok {
+		return []StreamEntry{}
+	}
+	return st.rangeOf(start, MaxStreamID, count, false)
+}
+
+// delete 删除指定 ID 的条目，返回实际删除的数量
+func (st *Stream) delete(ids []StreamID) int {
+	deleted := 0
+	for _, id := range ids {
+		i := st.search(id)
+		if i < len(st.entries) && st.entries[i].ID == id {
+			st.entries = append(st.entries[:i], st.entries[i+1:]...)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// trim 按策略从头部裁剪，返回删除的条目数
+func (st *Stream) trim(t *StreamTrim) int {
+	var n int
+	switch t.Strategy {
+	case "MAXLEN":
+		if int64(len(st.entries)) > t.MaxLen {
+			n = len(st.entries) - int(t.MaxLen)
+		}
+	case "MINID":
+		n = st.search(t.MinID)
+	}
+
+	if t.Limit > 0 && int64(n) > t.Limit {
+		n = int(t.Limit)
+	}
+	if n == 0 {
+		return 0
+	}
+
+	// 头部被裁掉的空间过多时重新分配，避免底层数组只增不减
+	rest := st.entries[n:]
+	if cap(rest) > 2*len(rest)+64 {
+		compacted := make([]StreamEntry, len(rest))
+		copy(compacted, rest)
+		rest = compacted
+	}
+	st.entries = rest
+
+	return n
+}
+
+// clone 深拷贝 stream，包括消费组、消费者和待确认列表（COPY）
+func (st *Stream) clone() *Stream {
+	c := &Stream{
+		entries: make([]StreamEntry, len(st.entries)),
+		lastID:  st.lastID,
+		groups:  make(map[string]*ConsumerGroup, len(st.groups)),
+	}
+	for i, e := range st.entries {
+		c.entries[i] = StreamEntry{ID: e.ID, Fields: slices.Clone(e.Fields)}
+	}
+
+	for name, g := range st.groups {
+		ng := &ConsumerGroup{
+			Name:      g.Name,
+			LastID:    g.LastID,
+			pending:   make(map[StreamID]*PendingEntry, len(g.pending)),
+			consumers: make(map[string]*StreamConsumer, len(g.consumers)),
+		}
+		for cname, consumer := range g.consumers {
+			ng.consumers[cname] = &StreamConsumer{
+				Name:     consumer.Name,
+				SeenTime: consumer.SeenTime,
+				pending:  make(map[StreamID]*PendingEntry, len(consumer.pending)),
+			}
+		}
+		// 组和消费者的 PEL 共享同一个 PendingEntry
+		for id, pe := range g.pending {
+			npe := *pe
+			ng.pending[id] = &npe
+			if consumer, ok := ng.consumers[pe.Consumer]; ok {
+				consumer.pending[id] = &npe
+			}
+		}
+		c.groups[name] = ng
+	}
+	return c
+}
+
+// group 返回指定名字的消费组
+func (st *Stream) group(name string) (*ConsumerGroup, bool) {
+	g, ok := st.groups[name]
+	return g, ok
+}
+
+// consumer 返回消费者，不存在时创建
+func (g *ConsumerGroup) consumer(name string, now time.Time) *StreamConsumer {
+	c, ok := g.consumers[name]
+	if !ok {
+		c = &StreamConsumer{
+			Name:    name,
+			pending: make(map[StreamID]*PendingEntry),
+		}
+		g.consumers[name] = c
+	}
+	c.SeenTime = now
+	return c
+}
+
+// ack 从 PEL 中移除一条消息
+func (g *ConsumerGroup) ack(id StreamID) bool {
+	pe, ok := g.pending[id]
+	if !ok {
+		return false
+	}
+
+	delete(g.pending, id)
+	if c, ok := g.consumers[pe.Consumer]; ok {
+		delete(c.pending, id)
+	}
+	return true
+}
+
+// assign 把一条消息记入 consumer 名下的 PEL，已在其他消费者名下时转移归属
+func (g *ConsumerGroup) assign(id StreamID, c *StreamConsumer, now time.Time) *PendingEntry {
+	pe, ok := g.pending[id]
+	if !ok {
+		pe = &PendingEntry{ID: id}
+		g.pending[id] = pe
+	} else if old, ok := g.consumers[pe.Consumer]; ok && old != c {
+		delete(old.pending, id)
+	}
+
+	pe.Consumer = c.Name
+	pe.DeliveryTime = now
+	c.pending[id] = pe
+	return pe
+}
+
+// sortedPending 返回按 ID 排序的 PEL 项
+func sortedPending(m map[StreamID]*PendingEntry) []*PendingEntry {
+	res := make([]*PendingEntry, 0, len(m))
+	for _, pe := range m {
+		res = append(res, pe)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID.Less(res[j].ID)
+	})
+	return res
+}
+
+// sortedConsumers 返回按名字排序的消费者
+func sortedConsumers(m map[string]*StreamConsumer) []*StreamConsumer {
+	res := make([]*StreamConsumer, 0, len(m))
+	for _, c := range m {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
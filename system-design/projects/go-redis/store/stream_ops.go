@@ -0,0 +1,611 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"go-redis/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StreamReadResult 是 XREAD/XREADGROUP 对单个键的读取结果
+type StreamReadResult struct {
+	Key     string
+	Entries []StreamEntry
+}
+
+// StreamPendingSummary 是 XPENDING 简要形式的返回内容
+type StreamPendingSummary struct {
+	Count     int64
+	MinID     StreamID
+	MaxID     StreamID
+	Consumers []StreamConsumerPending
+}
+
+// StreamConsumerPending 记录一个消费者名下的待确认消息数
+type StreamConsumerPending struct {
+	Name  string
+	Count int64
+}
+
+// StreamClaimOptions 是 XCLAIM 的可选参数
+type StreamClaimOptions struct {
+	DeliveryTime time.Time // IDLE/TIME 指定的投递时间，零值表示当前时间
+	RetryCount   int64     // RETRYCOUNT 指定的投递次数，-1 表示未指定
+	Force        bool
+	JustID       bool
+	LastID       *StreamID
+}
+
+func noGroupError(key, group string) error {
+	return fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s'", key, group)
+}
+
+// getStream 获取 key 上的 stream，调用前需持有锁（create 为 true 时需持有写锁）
+// 键不存在时按 create 决定是否创建；键存在但不是 stream 时返回 ErrWrongType
+func (s *Store) getStream(key string, create bool) (*Stream, error) {
+	value, exists := s.lookup(key)
+	if !exists {
+		if !create {
+			return nil, nil
+		}
+		st := newStream()
+		s.setValue(key, st)
+		s.Notify(NotifyNew, "new", key)
+		return st, nil
+	}
+
+	st, ok := value.(*Stream)
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return st, nil
+}
+
+// getGroup 获取 key 上 stream 的消费组，调用前需持有锁
+func (s *Store) getGroup(key, group string) (*Stream, *ConsumerGroup, error) {
+	st, err := s.getStream(key, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if st == nil {
+		return nil, nil, noGroupError(key, group)
+	}
+
+	g, ok := st.group(group)
+	if !ok {
+		return nil, nil, noGroupError(key, group)
+	}
+
+	return st, g, nil
+}
+
+// resolveGroupID 解析 XGROUP CREATE/SETID 的 ID 参数，"$" 表示 stream 当前的最后 ID
+func resolveGroupID(st *Stream, spec string) (StreamID, error) {
+	if spec == "$" {
+		return st.LastID(), nil
+	}
+	return ParseStreamID(spec, 0)
+}
+
+// XAdd 向 stream 追加一条消息，返回新消息的 ID 和裁剪掉的条目数
+// noMkStream 为 true 且键不存在时不创建 stream，此时 ok 为 false
+func (s *Store) XAdd(key, idSpec string, fields []string, noMkStream bool, trim *StreamTrim) (id StreamID, trimmed int64, ok bool, err error) {
+	logger.WithFields(logrus.Fields{
+		"operation": "XADD",
+		"key":       key,
+	}).Debug("执行 XAdd 操作")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	st, err := s.getStream(key, false)
+	if err != nil {
+		return StreamID{}, 0, false, err
+	}
+
+	created := st == nil
+	if created {
+		if noMkStream {
+			return StreamID{}, 0, false, nil
+		}
+		st = newStream()
+	}
+
+	id, err = st.nextID(idSpec, time.Now())
+	if err != nil {
+		return StreamID{}, 0, false, err
+	}
+
+	if created {
+		s.setValue(key, st)
+		s.Notify(NotifyNew, "new", key)
+	}
+
+	st.add(id, fields)
+	if trim != nil {
+		trimmed = int64(st.trim(trim))
+	}
+
+	s.keyModified(key)
+
+	return id, trimmed, true, nil
+}
+
+// XLen 返回 stream 的条目数
+func (s *Store) XLen(key string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, err := s.getStream(key, false)
+	if err != nil || st == nil {
+		return 0, err
+	}
+
+	return int64(st.Len()), nil
+}
+
+// XRange 返回 [start, end] 区间内的消息，rev 为 true 时按 ID 从大到小返回
+func (s *Store) XRange(key string, start, end StreamID, count int, rev bool) ([]StreamEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, err := s.getStream(key, false)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return []StreamEntry{}, nil
+	}
+
+	return st.rangeOf(start, end, count, rev), nil
+}
+
+// XDel 删除指定 ID 的消息，返回实际删除的数量
+func (s *Store) XDel(key string, ids []StreamID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	st, err := s.getStream(key, false)
+	if err != nil || st == nil {
+		return 0, err
+	}
+
+	n := st.delete(ids)
+	if n > 0 {
+		s.keyModified(key)
+	}
+	return int64(n), nil
+}
+
+// XTrim 按策略裁剪 stream，返回删除的条目数
+func (s *Store) XTrim(key string, trim *StreamTrim) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	st, err := s.getStream(key, false)
+	if err != nil || st == nil {
+		return 0, err
+	}
+
+	n := st.trim(trim)
+	if n > 0 {
+		s.keyModified(key)
+	}
+	return int64(n), nil
+}
+
+// StreamLastID 返回 stream 的最后 ID，键不存在时返回 0-0
+// 供 XREAD 在阻塞前解析 "$"
+func (s *Store) StreamLastID(key string) (StreamID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, err := s.getStream(key, false)
+	if err != nil || st == nil {
+		return StreamID{}, err
+	}
+
+	return st.LastID(), nil
+}
+
+// XRead 读取每个键上 ID 大于对应 ids[i] 的消息，没有新消息的键不出现在结果中
+func (s *Store) XRead(keys []string, ids []StreamID, count int) ([]StreamReadResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := make([]StreamReadResult, 0)
+	for i, key := range keys {
+		st, err := s.getStream(key, false)
+		if err != nil {
+			return nil, err
+		}
+		if st == nil {
+			continue
+		}
+
+		entries := st.after(ids[i], count)
+		if len(entries) > 0 {
+			res = append(res, StreamReadResult{Key: key, Entries: entries})
+		}
+	}
+
+	return res, nil
+}
+
+// XGroupCreate 创建消费组
+func (s *Store) XGroupCreate(key, group, idSpec string, mkStream bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	st, err := s.getStream(key, mkStream)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return ErrNoGroupKey
+	}
+
+	id, err := resolveGroupID(st, idSpec)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := st.group(group); exists {
+		return ErrBusyGroup
+	}
+
+	st.groups[group] = &ConsumerGroup{
+		Name:      group,
+		LastID:    id,
+		pending:   make(map[StreamID]*PendingEntry),
+		consumers: make(map[string]*StreamConsumer),
+	}
+	s.keyModified(key)
+
+	return nil
+}
+
+// XGroupSetID 修改消费组的最后投递 ID
+func (s *Store) XGroupSetID(key, group, idSpec string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	st, err := s.getStream(key, false)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return ErrNoGroupKey
+	}
+
+	g, ok := st.group(group)
+	if !ok {
+		return fmt.Errorf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+	}
+
+	id, err := resolveGroupID(st, idSpec)
+	if err != nil {
+		return err
+	}
+
+	g.LastID = id
+	s.keyModified(key)
+	return nil
+}
+
+// XGroupDestroy 删除消费组，返回是否存在并被删除
+func (s *Store) XGroupDestroy(key, group string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	st, err := s.getStream(key, false)
+	if err != nil {
+		return false, err
+	}
+	if st == nil {
+		return false, ErrNoGroupKey
+	}
+
+	if _, ok := st.group(group); !ok {
+		return false, nil
+	}
+
+	delete(st.groups, group)
+	// 唤醒阻塞在该组上的 XREADGROUP，让它们返回 NOGROUP 错误
+	s.keyModified(key)
+
+	return true, nil
+}
+
+// XGroupCreateConsumer 显式创建消费者，返回是否新建
+func (s *Store) XGroupCreateConsumer(key, group, consumer string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	_, g, err := s.getGroup(key, group)
+	if err != nil {
+		return false, err
+	}
+
+	if _, exists := g.consumers[consumer]; exists {
+		return false, nil
+	}
+
+	g.consumer(consumer, time.Now())
+	return true, nil
+}
+
+// XGroupDelConsumer 删除消费者，返回它名下被丢弃的待确认消息数
+func (s *Store) XGroupDelConsumer(key, group, consumer string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	_, g, err := s.getGroup(key, group)
+	if err != nil {
+		return 0, err
+	}
+
+	c, ok := g.consumers[consumer]
+	if !ok {
+		return 0, nil
+	}
+
+	pending := int64(len(c.pending))
+	for id := range c.pending {
+		delete(g.pending, id)
+	}
+	delete(g.consumers, consumer)
+
+	return pending, nil
+}
+
+// XReadGroup 以消费组中某个消费者的身份读取消息
+// ids[i] 为 ">" 时读取从未投递给组内任何消费者的新消息并记入 PEL；
+// 否则返回该消费者 PEL 中 ID 大于 ids[i] 的历史消息，已被删除的消息以 Fields 为 nil 表示
+func (s *Store) XReadGroup(group, consumer string, keys, ids []string, count int, noAck bool) ([]StreamReadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	streams := make([]*Stream, len(keys))
+	groups := make([]*ConsumerGroup, len(keys))
+	for i, key := range keys {
+		s.expireIfNeeded(key)
+		st, g, err := s.getGroup(key, group)
+		if err != nil {
+			if err == ErrWrongType {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%v in XREADGROUP with GROUP option", err)
+		}
+		streams[i], groups[i] = st, g
+	}
+
+	now := time.Now()
+	res := make([]StreamReadResult, 0)
+
+	for i, key := range keys {
+		st, g := streams[i], groups[i]
+		c := g.consumer(consumer, now)
+
+		if ids[i] == ">" {
+			entries := st.after(g.LastID, count)
+			for _, e := range entries {
+				g.LastID = e.ID
+				if !noAck {
+					pe := g.assign(e.ID, c, now)
+					pe.DeliveryCount = 1
+				}
+			}
+			if len(entries) > 0 {
+				res = append(res, StreamReadResult{Key: key, Entries: entries})
+			}
+			continue
+		}
+
+		start, err := ParseStreamID(ids[i], 0)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]StreamEntry, 0)
+		for _, pe := range sortedPending(c.pending) {
+			if count > 0 && len(entries) >= count {
+				break
+			}
+			if pe.ID.Less(start) || pe.ID == start {
+				continue
+			}
+
+			entry, ok := st.lookup(pe.ID)
+			if !ok {
+				entry = StreamEntry{ID: pe.ID}
+			}
+			pe.DeliveryCount++
+			pe.DeliveryTime = now
+			entries = append(entries, entry)
+		}
+		res = append(res, StreamReadResult{Key: key, Entries: entries})
+	}
+
+	return res, nil
+}
+
+// XAck 确认消息，返回成功从 PEL 中移除的数量
+func (s *Store) XAck(key, group string, ids []StreamID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	_, g, err := s.getGroup(key, group)
+	if err != nil {
+		if err == ErrWrongType {
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	var acked int64
+	for _, id := range ids {
+		if g.ack(id) {
+			acked++
+		}
+	}
+
+	return acked, nil
+}
+
+// XPendingSummary 返回消费组 PEL 的概要信息
+func (s *Store) XPendingSummary(key, group string) (*StreamPendingSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, g, err := s.getGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &StreamPendingSummary{
+		Count:     int64(len(g.pending)),
+		Consumers: make([]StreamConsumerPending, 0),
+	}
+
+	pending := sortedPending(g.pending)
+	if len(pending) == 0 {
+		return summary, nil
+	}
+
+	summary.MinID = pending[0].ID
+	summary.MaxID = pending[len(pending)-1].ID
+
+	counts := make(map[string]int64)
+	for _, pe := range pending {
+		counts[pe.Consumer]++
+	}
+	for _, c := range sortedConsumers(g.consumers) {
+		if n := counts[c.Name]; n > 0 {
+			summary.Consumers = append(summary.Consumers, StreamConsumerPending{Name: c.Name, Count: n})
+		}
+	}
+
+	return summary, nil
+}
+
+// XPendingRange 返回 [start, end] 区间内至多 count 条 PEL 项
+// consumer 非空时只返回该消费者的消息，minIdle 过滤掉空闲时间不足的消息
+func (s *Store) XPendingRange(key, group string, start, end StreamID, count int, consumer string, minIdle time.Duration) ([]PendingEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, g, err := s.getGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	source := g.pending
+	if consumer != "" {
+		c, ok := g.consumers[consumer]
+		if !ok {
+			return []PendingEntry{}, nil
+		}
+		source = c.pending
+	}
+
+	now := time.Now()
+	res := make([]PendingEntry, 0)
+	for _, pe := range sortedPending(source) {
+		if len(res) >= count {
+			break
+		}
+		if pe.ID.Less(start) || end.Less(pe.ID) {
+			continue
+		}
+		if minIdle > 0 && now.Sub(pe.DeliveryTime) < minIdle {
+			continue
+		}
+		res = append(res, *pe)
+	}
+
+	return res, nil
+}
+
+// XClaim 把空闲时间不少于 minIdle 的待确认消息转移给 consumer
+// 返回被认领的消息；JustID 时只填充 ID
+func (s *Store) XClaim(key, group, consumer string, minIdle time.Duration, ids []StreamID, opts StreamClaimOptions) ([]StreamEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireIfNeeded(key)
+
+	st, g, err := s.getGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.LastID != nil && g.LastID.Less(*opts.LastID) {
+		g.LastID = *opts.LastID
+	}
+
+	now := time.Now()
+	deliveryTime := opts.DeliveryTime
+	if deliveryTime.IsZero() {
+		deliveryTime = now
+	}
+
+	c := g.consumer(consumer, now)
+	res := make([]StreamEntry, 0)
+
+	for _, id := range ids {
+		entry, inStream := st.lookup(id)
+
+		pe, pending := g.pending[id]
+		if !pending {
+			if !opts.Force || !inStream {
+				continue
+			}
+			pe = g.assign(id, c, deliveryTime)
+		} else {
+			// 消息已从 stream 中删除，顺便清理 PEL
+			if !inStream {
+				g.ack(id)
+				continue
+			}
+			if minIdle > 0 && now.Sub(pe.DeliveryTime) < minIdle {
+				continue
+			}
+		}
+
+		g.assign(id, c, deliveryTime)
+		if opts.RetryCount >= 0 {
+			pe.DeliveryCount = opts.RetryCount
+		} else if !opts.JustID {
+			pe.DeliveryCount++
+		}
+
+		if opts.JustID {
+			entry = StreamEntry{ID: id}
+		}
+		res = append(res, entry)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,325 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// TestXAddAutoID 测试自动生成的 ID 单调递增
+func TestXAddAutoID(t *testing.T) {
+	s := NewStore()
+
+	var last StreamID
+	for i := 0; i < 100; i++ {
+		id, _, ok, err := s.XAdd("events", "*", []string{"n", "1"}, false, nil)
+		if err != nil || !ok {
+			t.Fatalf("XAdd failed: ok=%v err=%v", ok, err)
+		}
+		if !last.Less(id) {
+			t.Fatalf("ID not increasing: %s after %s", id, last)
+		}
+		last = id
+	}
+
+	n, err := s.XLen("events")
+	if err != nil || n != 100 {
+		t.Errorf("Expected 100 entries, got %d (err=%v)", n, err)
+	}
+}
+
+// TestXAddExplicitID 测试显式 ID 和 ms-* 形式的 ID
+func TestXAddExplicitID(t *testing.T) {
+	s := NewStore()
+
+	if _, _, _, err := s.XAdd("s", "0-0", []string{"a", "1"}, false, nil); err != ErrStreamIDZero {
+		t.Errorf("Expected ErrStreamIDZero, got %v", err)
+	}
+
+	id, _, _, err := s.XAdd("s", "5-1", []string{"a", "1"}, false, nil)
+	if err != nil || id != (StreamID{Ms: 5, Seq: 1}) {
+		t.Fatalf("Expected 5-1, got %s (err=%v)", id, err)
+	}
+
+	if _, _, _, err := s.XAdd("s", "5-1", []string{"a", "1"}, false, nil); err != ErrStreamIDTooSmall {
+		t.Errorf("Expected ErrStreamIDTooSmall, got %v", err)
+	}
+
+	id, _, _, err = s.XAdd("s", "5-*", []string{"a", "1"}, false, nil)
+	if err != nil || id != (StreamID{Ms: 5, Seq: 2}) {
+		t.Errorf("Expected 5-2, got %s (err=%v)", id, err)
+	}
+
+	id, _, _, err = s.XAdd("s", "7-*", []string{"a", "1"}, false, nil)
+	if err != nil || id != (StreamID{Ms: 7, Seq: 0}) {
+		t.Errorf("Expected 7-0, got %s (err=%v)", id, err)
+	}
+}
+
+// TestXAddNoMkStream 测试 NOMKSTREAM 不会创建新键
+func TestXAddNoMkStream(t *testing.T) {
+	s := NewStore()
+
+	_, _, ok, err := s.XAdd("missing", "*", []string{"a", "1"}, true, nil)
+	if err != nil || ok {
+		t.Errorf("Expected no-op, got ok=%v err=%v", ok, err)
+	}
+	if s.Exists("missing") {
+		t.Error("NOMKSTREAM should not create the key")
+	}
+}
+
+// TestXAddWrongType 测试对非 stream 键执行 stream 命令
+func TestXAddWrongType(t *testing.T) {
+	s := NewStore()
+	s.Set("str", "value")
+
+	if _, _, _, err := s.XAdd("str", "*", []string{"a", "1"}, false, nil); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+// TestXRange 测试区间查询、开区间和 COUNT
+func TestXRange(t *testing.T) {
+	s := NewStore()
+	for i := 1; i <= 5; i++ {
+		id := StreamID{Ms: uint64(i)}.String()
+		if _, _, _, err := s.XAdd("s", id, []string{"i", id}, false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		count    int
+		rev      bool
+		expected []uint64
+	}{
+		{"all", "-", "+", -1, false, []uint64{1, 2, 3, 4, 5}},
+		{"closed", "2", "4", -1, false, []uint64{2, 3, 4}},
+		{"exclusive", "(2-0", "(4-0", -1, false, []uint64{3}},
+		{"count", "-", "+", 2, false, []uint64{1, 2}},
+		{"reverse", "2", "+", 2, true, []uint64{5, 4}},
+		{"reverse closed", "1", "3", 2, true, []uint64{3, 2}},
+		{"count in range", "2", "4", 2, false, []uint64{2, 3}},
+		{"count over range", "2", "3", 10, true, []uint64{3, 2}},
+		{"empty", "4", "2", -1, false, []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, err := ParseStreamRange(tt.start, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			end, err := ParseStreamRange(tt.end, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := s.XRange("s", start, end, tt.count, tt.rev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("Expected %d entries, got %d", len(tt.expected), len(entries))
+			}
+			for i, e := range entries {
+				if e.ID.Ms != tt.expected[i] {
+					t.Errorf("Entry %d: expected ms %d, got %s", i, tt.expected[i], e.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestXTrim 测试 MAXLEN 和 MINID 裁剪
+func TestXTrim(t *testing.T) {
+	s := NewStore()
+	for i := 1; i <= 10; i++ {
+		s.XAdd("s", StreamID{Ms: uint64(i)}.String(), []string{"a", "b"}, false, nil)
+	}
+
+	n, err := s.XTrim("s", &StreamTrim{Strategy: "MAXLEN", MaxLen: 7})
+	if err != nil || n != 3 {
+		t.Errorf("Expected 3 trimmed, got %d (err=%v)", n, err)
+	}
+
+	n, err = s.XTrim("s", &StreamTrim{Strategy: "MINID", MinID: StreamID{Ms: 6}})
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 trimmed, got %d (err=%v)", n, err)
+	}
+
+	n, err = s.XTrim("s", &StreamTrim{Strategy: "MAXLEN", Approx: true, Limit: 1})
+	if err != nil || n != 1 {
+		t.Errorf("Expected LIMIT to cap trimming at 1, got %d (err=%v)", n, err)
+	}
+
+	entries, _ := s.XRange("s", StreamID{}, MaxStreamID, -1, false)
+	if len(entries) != 4 || entries[0].ID.Ms != 7 {
+		t.Errorf("Unexpected entries after trimming: %v", entries)
+	}
+
+	// XADD 时按 MAXLEN 裁剪
+	s.XAdd("s", "*", []string{"a", "b"}, false, &StreamTrim{Strategy: "MAXLEN", MaxLen: 2})
+	if n, _ := s.XLen("s"); n != 2 {
+		t.Errorf("Expected 2 entries after XADD MAXLEN 2, got %d", n)
+	}
+}
+
+// TestXDelKeepsLastID 测试删除条目后 last ID 不回退
+func TestXDelKeepsLastID(t *testing.T) {
+	s := NewStore()
+	s.XAdd("s", "1-1", []string{"a", "b"}, false, nil)
+	s.XAdd("s", "2-1", []string{"a", "b"}, false, nil)
+
+	n, err := s.XDel("s", []StreamID{{Ms: 2, Seq: 1}, {Ms: 9}})
+	if err != nil || n != 1 {
+		t.Errorf("Expected 1 deleted, got %d (err=%v)", n, err)
+	}
+
+	if _, _, _, err := s.XAdd("s", "2-1", []string{"a", "b"}, false, nil); err != ErrStreamIDTooSmall {
+		t.Errorf("Expected ErrStreamIDTooSmall after delete, got %v", err)
+	}
+}
+
+// TestXReadBlockingWakeup 测试阻塞读取在 XADD 后被唤醒
+func TestXReadBlockingWakeup(t *testing.T) {
+	s := NewStore()
+	ch := s.WatchKeys([]string{"s"})
+	defer s.UnwatchKeys([]string{"s"}, ch)
+
+	go s.XAdd("s", "*", []string{"a", "b"}, false, nil)
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("Waiter was not notified by XADD")
+	}
+
+	res, err := s.XRead([]string{"s"}, []StreamID{{}}, -1)
+	if err != nil || len(res) != 1 || len(res[0].Entries) != 1 {
+		t.Errorf("Unexpected XRead result: %v (err=%v)", res, err)
+	}
+}
+
+// TestConsumerGroupLifecycle 测试消费组的读取、确认、PEL 和认领
+func TestConsumerGroupLifecycle(t *testing.T) {
+	s := NewStore()
+
+	if err := s.XGroupCreate("s", "g", "$", false); err != ErrNoGroupKey {
+		t.Errorf("Expected ErrNoGroupKey, got %v", err)
+	}
+	if err := s.XGroupCreate("s", "g", "$", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.XGroupCreate("s", "g", "$", true); err != ErrBusyGroup {
+		t.Errorf("Expected ErrBusyGroup, got %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		s.XAdd("s", StreamID{Ms: uint64(i)}.String(), []string{"n", "v"}, false, nil)
+	}
+
+	// alice 读取两条新消息
+	res, err := s.XReadGroup("g", "alice", []string{"s"}, []string{">"}, 2, false)
+	if err != nil || len(res) != 1 || len(res[0].Entries) != 2 {
+		t.Fatalf("Unexpected XReadGroup result: %v (err=%v)", res, err)
+	}
+
+	// bob 只能读到剩下的一条
+	res, _ = s.XReadGroup("g", "bob", []string{"s"}, []string{">"}, 10, false)
+	if len(res) != 1 || len(res[0].Entries) != 1 || res[0].Entries[0].ID.Ms != 3 {
+		t.Fatalf("Expected bob to get entry 3, got %v", res)
+	}
+
+	// 没有新消息时返回空结果
+	res, _ = s.XReadGroup("g", "bob", []string{"s"}, []string{">"}, 10, false)
+	if len(res) != 0 {
+		t.Errorf("Expected no new entries, got %v", res)
+	}
+
+	summary, err := s.XPendingSummary("s", "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Count != 3 || summary.MinID.Ms != 1 || summary.MaxID.Ms != 3 {
+		t.Errorf("Unexpected pending summary: %+v", summary)
+	}
+	if len(summary.Consumers) != 2 || summary.Consumers[0].Name != "alice" || summary.Consumers[0].Count != 2 {
+		t.Errorf("Unexpected consumers in summary: %+v", summary.Consumers)
+	}
+
+	// alice 确认第一条
+	n, err := s.XAck("s", "g", []StreamID{{Ms: 1}, {Ms: 1}})
+	if err != nil || n != 1 {
+		t.Errorf("Expected 1 acked, got %d (err=%v)", n, err)
+	}
+
+	// alice 重新读取自己的历史消息，投递次数增加
+	res, _ = s.XReadGroup("g", "alice", []string{"s"}, []string{"0"}, 10, false)
+	if len(res) != 1 || len(res[0].Entries) != 1 || res[0].Entries[0].ID.Ms != 2 {
+		t.Fatalf("Expected alice history to contain entry 2, got %v", res)
+	}
+
+	pending, _ := s.XPendingRange("s", "g", StreamID{}, MaxStreamID, 10, "alice", 0)
+	if len(pending) != 1 || pending[0].DeliveryCount != 2 {
+		t.Errorf("Expected delivery count 2, got %+v", pending)
+	}
+
+	// bob 认领 alice 的消息，空闲时间不足时不转移
+	claimed, err := s.XClaim("s", "g", "bob", time.Hour, []StreamID{{Ms: 2}}, StreamClaimOptions{RetryCount: -1})
+	if err != nil || len(claimed) != 0 {
+		t.Errorf("Expected nothing claimed, got %v (err=%v)", claimed, err)
+	}
+
+	claimed, err = s.XClaim("s", "g", "bob", 0, []StreamID{{Ms: 2}}, StreamClaimOptions{RetryCount: -1})
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected 1 claimed, got %v (err=%v)", claimed, err)
+	}
+
+	pending, _ = s.XPendingRange("s", "g", StreamID{}, MaxStreamID, 10, "bob", 0)
+	if len(pending) != 2 {
+		t.Errorf("Expected bob to own 2 pending entries, got %+v", pending)
+	}
+	pending, _ = s.XPendingRange("s", "g", StreamID{}, MaxStreamID, 10, "alice", 0)
+	if len(pending) != 0 {
+		t.Errorf("Expected alice to own no pending entries, got %+v", pending)
+	}
+
+	n, err = s.XGroupDelConsumer("s", "g", "bob")
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 pending dropped with bob, got %d (err=%v)", n, err)
+	}
+}
+
+// TestXClaimDeletedEntry 测试认领已被删除的消息时清理 PEL
+func TestXClaimDeletedEntry(t *testing.T) {
+	s := NewStore()
+	s.XGroupCreate("s", "g", "0", true)
+	s.XAdd("s", "1-0", []string{"a", "b"}, false, nil)
+	s.XReadGroup("g", "alice", []string{"s"}, []string{">"}, 0, false)
+	s.XDel("s", []StreamID{{Ms: 1}})
+
+	claimed, err := s.XClaim("s", "g", "bob", 0, []StreamID{{Ms: 1}}, StreamClaimOptions{RetryCount: -1})
+	if err != nil || len(claimed) != 0 {
+		t.Errorf("Expected nothing claimed, got %v (err=%v)", claimed, err)
+	}
+
+	summary, _ := s.XPendingSummary("s", "g")
+	if summary.Count != 0 {
+		t.Errorf("Expected deleted entry to be removed from PEL, got %+v", summary)
+	}
+}
+
+// TestXReadGroupNoGroup 测试消费组不存在时的错误
+func TestXReadGroupNoGroup(t *testing.T) {
+	s := NewStore()
+	s.XAdd("s", "*", []string{"a", "b"}, false, nil)
+
+	_, err := s.XReadGroup("nope", "c", []string{"s"}, []string{">"}, 0, false)
+	if err == nil || err.Error() != "NOGROUP No such key 's' or consumer group 'nope' in XREADGROUP with GROUP option" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package store
+
+// KeyTracker 接收键被修改的通知，用于客户端缓存失效（CLIENT TRACKING）
+// 方法在持有 Store 写锁时被调用，实现方不能阻塞，也不能回调 Store
+type KeyTracker interface {
+	// KeyModified 在键的值被修改、删除或过期后调用
+	KeyModified(key string)
+	// FlushAll 在所有键被清空后调用
+	FlushAll()
+}
+
+// trackerHolder 让 atomic.Value 始终存放同一具体类型
+type trackerHolder struct {
+	t KeyTracker
+}
+
+// SetKeyTracker 设置键修改的通知目标
+func (s *Store) SetKeyTracker(t KeyTracker) {
+	s.tracker.Store(trackerHolder{t: t})
+}
+
+// keyModified 在键被修改后调用：更新访问信息，唤醒阻塞在该键上的命令，并通知 KeyTracker
+// 调用前需持有写锁
+func (s *Store) keyModified(key string) {
+	s.updateMeta(key)
+	s.signalKey(key)
+
+	if holder, _ := s.tracker.Load().(trackerHolder); holder.t != nil {
+		holder.t.KeyModified(key)
+	}
+}
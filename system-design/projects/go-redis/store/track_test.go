@@ -0,0 +1,52 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordTracker 记录被修改的键，flush 记为空字符串
+type recordTracker struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (t *recordTracker) KeyModified(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = append(t.keys, key)
+}
+
+func (t *recordTracker) FlushAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = append(t.keys, "")
+}
+
+func TestKeyTracker(t *testing.T) {
+	s := NewStore()
+	defer s.Close()
+
+	tracker := &recordTracker{}
+	s.SetKeyTracker(tracker)
+
+	s.Set("a", "1")
+	s.IncrBy("n", 2)
+	s.Get("a") // 读取不算修改
+	s.Delete("a")
+	s.Delete("missing")
+	s.ExpireAt("n", time.Now().Add(-time.Second))
+	s.Clear()
+
+	want := []string{"a", "n", "a", "n", ""}
+	if len(tracker.keys) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, tracker.keys)
+	}
+	for i := range want {
+		if tracker.keys[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, tracker.keys)
+			break
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package tracking
+
+import (
+	"errors"
+	"fmt"
+	"go-redis/protocol"
+	"go-redis/pubsub"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 客户端缓存（CLIENT TRACKING）的失效表
+//
+// 默认模式下记录每个客户端读过的键，键被修改时通知读过它的客户端，并把该键从表中移除，
+// 客户端再次读取时重新登记；广播模式（BCAST）不记录键，按前缀通知所有登记了该前缀的客户端。
+//
+// 这里只支持 RESP2：失效消息以发布订阅消息的形式发给订阅了 InvalidateChannel 的连接，
+// 通常是通过 REDIRECT 指定的另一个连接。
+
+// InvalidateChannel 是接收失效消息的频道
+const InvalidateChannel = "__redis__:invalidate"
+
+var (
+	ErrOptInOptOut   = errors.New("ERR You can't use both OPTIN and OPTOUT")
+	ErrBCastOptInOut = errors.New("ERR OPTIN and OPTOUT are not compatible with BCAST")
+	ErrPrefixNoBCast = errors.New("ERR PREFIX option requires BCAST mode to be enabled")
+	ErrSwitchMode    = errors.New("ERR You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode.")
+	ErrNoRedirect    = errors.New("ERR The client ID you want redirect to does not exist")
+	ErrCaching       = errors.New("ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	ErrCachingYes    = errors.New("ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.")
+	ErrCachingNo     = errors.New("ERR CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode.")
+)
+
+// Options 是 CLIENT TRACKING ON 的选项
+type Options struct {
+	Redirect int64 // 接收失效消息的连接 ID，0 表示发给自己
+	BCast    bool
+	Prefixes []string
+	OptIn    bool // 只记录 CLIENT CACHING YES 之后的下一条命令读取的键
+	OptOut   bool // 不记录 CLIENT CACHING NO 之后的下一条命令读取的键
+	NoLoop   bool // 不接收自己修改键产生的失效消息
+}
+
+// Info 是客户端的跟踪状态（CLIENT TRACKINGINFO）
+type Info struct {
+	Options
+	Caching int // CLIENT CACHING 的设置：1 为 YES，-1 为 NO，0 为未设置
+}
+
+type client struct {
+	sub       pubsub.Subscriber
+	opts      Options
+	caching   int
+	executing int // 正在执行的命令数，用于 NOLOOP
+}
+
+// Table 保存所有客户端的跟踪状态
+type Table struct {
+	hub    *pubsub.Hub
+	lookup func(id int64) pubsub.Subscriber
+
+	n atomic.Int64 // 开启跟踪的客户端数，为 0 时跳过所有处理
+
+	mu       sync.Mutex
+	clients  map[int64]*client
+	keys     map[string]map[int64]struct{} // 默认模式：键 -> 读过它的客户端
+	prefixes map[string]map[int64]struct{} // 广播模式：前缀 -> 登记的客户端
+}
+
+// NewTable 创建失效表，失效消息通过 hub 上的订阅关系投递
+func NewTable(hub *pubsub.Hub) *Table {
+	return &Table{
+		hub:      hub,
+		clients:  make(map[int64]*client),
+		keys:     make(map[string]map[int64]struct{}),
+		prefixes: make(map[string]map[int64]struct{}),
+	}
+}
+
+// SetLookup 设置按 ID 查找连接的函数，用于 REDIRECT，只能在服务器开始处理请求之前调用
+func (t *Table) SetLookup(lookup func(id int64) pubsub.Subscriber) {
+	t.lookup = lookup
+}
+
+// Len 返回开启跟踪的客户端数
+func (t *Table) Len() int64 {
+	return t.n.Load()
+}
+
+// Enable 为客户端开启跟踪；已开启时更新选项并追加前缀
+func (t *Table) Enable(id int64, sub pubsub.Subscriber, opts Options) error {
+	if opts.OptIn && opts.OptOut {
+		return ErrOptInOptOut
+	}
+	if opts.BCast && (opts.OptIn || opts.OptOut) {
+		return ErrBCastOptInOut
+	}
+	if len(opts.Prefixes) > 0 && !opts.BCast {
+		return ErrPrefixNoBCast
+	}
+	if opts.Redirect != 0 && opts.Redirect != id && (t.lookup == nil || t.lookup(opts.Redirect) == nil) {
+		return ErrNoRedirect
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, exists := t.clients[id]
+	if exists && c.opts.BCast != opts.BCast {
+		return ErrSwitchMode
+	}
+
+	var prefixes []string
+	if opts.BCast {
+		if exists {
+			prefixes = c.opts.Prefixes
+		}
+		if len(opts.Prefixes) == 0 && len(prefixes) == 0 {
+			// 不指定前缀时接收所有键的失效消息
+			opts.Prefixes = []string{""}
+		}
+		for _, p := range opts.Prefixes {
+			if err := checkPrefix(prefixes, p); err != nil {
+				return err
+			}
+			prefixes = append(prefixes, p)
+		}
+	}
+	opts.Prefixes = prefixes
+
+	if !exists {
+		c = &client{sub: sub}
+		t.clients[id] = c
+		t.n.Add(1)
+	}
+	c.opts = opts
+	c.caching = 0
+
+	for _, p := range prefixes {
+		ids, ok := t.prefixes[p]
+		if !ok {
+			ids = make(map[int64]struct{})
+			t.prefixes[p] = ids
+		}
+		ids[id] = struct{}{}
+	}
+
+	return nil
+}
+
+// checkPrefix 检查新前缀是否与客户端已有的前缀重叠
+func checkPrefix(prefixes []string, p string) error {
+	for _, existing := range prefixes {
+		if existing == p {
+			continue
+		}
+		if strings.HasPrefix(existing, p) || strings.HasPrefix(p, existing) {
+			return fmt.Errorf("ERR Prefix '%s' overlaps with an existing prefix '%s'. Prefixes for a single client must not overlap.", p, existing)
+		}
+	}
+	return nil
+}
+
+// Disable 关闭客户端的跟踪，连接断开时也需要调用
+// 默认模式下键表中残留的 ID 在键被修改时惰性清理
+func (t *Table) Disable(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[id]
+	if !ok {
+		return
+	}
+
+	for _, p := range c.opts.Prefixes {
+		delete(t.prefixes[p], id)
+		if len(t.prefixes[p]) == 0 {
+			delete(t.prefixes, p)
+		}
+	}
+	delete(t.clients, id)
+	t.n.Add(-1)
+}
+
+// Tracking 判断客户端是否开启了跟踪
+func (t *Table) Tracking(id int64) bool {
+	if t.n.Load() == 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.clients[id]
+	return ok
+}
+
+// Info 返回客户端的跟踪状态
+func (t *Table) Info(id int64) (Info, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[id]
+	if !ok {
+		return Info{}, false
+	}
+	return Info{Options: c.opts, Caching: c.caching}, true
+}
+
+// Caching 处理 CLIENT CACHING YES|NO，只对客户端的下一条命令生效
+func (t *Table) Caching(id int64, yes bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[id]
+	if !ok || !(c.opts.OptIn || c.opts.OptOut) {
+		return ErrCaching
+	}
+	if yes && !c.opts.OptIn {
+		return ErrCachingYes
+	}
+	if !yes && !c.opts.OptOut {
+		return ErrCachingNo
+	}
+
+	if yes {
+		c.caching = 1
+	} else {
+		c.caching = -1
+	}
+	return nil
+}
+
+// BeginCommand 在客户端执行命令之前调用，readKeys 是只读命令将要读取的键
+// 在读取之前登记，读取与登记之间发生的修改也会产生失效消息
+func (t *Table) BeginCommand(id int64, readKeys []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[id]
+	if !ok {
+		return
+	}
+
+	caching := c.caching
+	c.caching = 0
+	if c.opts.NoLoop {
+		c.executing++
+	}
+
+	if c.opts.BCast || len(readKeys) == 0 {
+		return
+	}
+	if (c.opts.OptIn && caching != 1) || (c.opts.OptOut && caching == -1) {
+		return
+	}
+
+	for _, key := range readKeys {
+		ids, ok := t.keys[key]
+		if !ok {
+			ids = make(map[int64]struct{})
+			t.keys[key] = ids
+		}
+		ids[id] = struct{}{}
+	}
+}
+
+// EndCommand 在客户端的命令执行完之后调用
+func (t *Table) EndCommand(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c, ok := t.clients[id]; ok && c.executing > 0 {
+		c.executing--
+	}
+}
+
+// KeyModified 向读过该键或登记了匹配前缀的客户端发送失效消息（实现 store.KeyTracker）
+//
+// NOLOOP 客户端在执行命令期间不接收任何失效消息：
+// 修改来自哪个连接只有 Router 知道，Store 并不知道，因此以执行窗口近似
+func (t *Table) KeyModified(key string) {
+	if t.n.Load() == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := []string{key}
+
+	if ids, ok := t.keys[key]; ok {
+		delete(t.keys, key)
+		for id := range ids {
+			if c, ok := t.clients[id]; ok && !c.opts.BCast {
+				t.deliver(c, keys)
+			}
+		}
+	}
+
+	for p, ids := range t.prefixes {
+		if !strings.HasPrefix(key, p) {
+			continue
+		}
+		for id := range ids {
+			t.deliver(t.clients[id], keys)
+		}
+	}
+}
+
+// FlushAll 通知所有客户端丢弃全部缓存（实现 store.KeyTracker）
+func (t *Table) FlushAll() {
+	if t.n.Load() == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.keys = make(map[string]map[int64]struct{})
+	for _, c := range t.clients {
+		t.deliver(c, nil)
+	}
+}
+
+// deliver 发送一条失效消息，keys 为 nil 表示全部失效，调用前需持有 mu
+func (t *Table) deliver(c *client, keys []string) {
+	if c.opts.NoLoop && c.executing > 0 {
+		return
+	}
+
+	target := c.sub
+	if c.opts.Redirect != 0 {
+		if t.lookup == nil {
+			return
+		}
+		if target = t.lookup(c.opts.Redirect); target == nil {
+			return
+		}
+	}
+
+	if !t.hub.IsSubscribed(target, InvalidateChannel) {
+		return
+	}
+
+	payload := protocol.NullArray()
+	if keys != nil {
+		values := make([]protocol.Value, len(keys))
+		for i, key := range keys {
+			values[i] = *protocol.BulkString(key)
+		}
+		payload = protocol.Array(values)
+	}
+
+	target.Send(protocol.Array([]protocol.Value{
+		*protocol.BulkString("message"),
+		*protocol.BulkString(InvalidateChannel),
+		*payload,
+	}))
+}
@@ -0,0 +1,187 @@
+package tracking
+
+import (
+	"go-redis/protocol"
+	"go-redis/pubsub"
+	"testing"
+)
+
+type recordSubscriber struct {
+	messages []*protocol.Value
+}
+
+func (s *recordSubscriber) Send(msg *protocol.Value) {
+	s.messages = append(s.messages, msg)
+}
+
+// invalidated 返回收到的失效消息中的键，nil 表示收到了全部失效
+func (s *recordSubscriber) invalidated(t *testing.T) [][]string {
+	t.Helper()
+	var res [][]string
+	for _, msg := range s.messages {
+		if len(msg.Array) != 3 || msg.Array[1].Str != InvalidateChannel {
+			t.Fatalf("Unexpected message: %+v", msg)
+		}
+		if msg.Array[2].IsNull {
+			res = append(res, nil)
+			continue
+		}
+		var keys []string
+		for _, k := range msg.Array[2].Array {
+			keys = append(keys, k.Str)
+		}
+		res = append(res, keys)
+	}
+	return res
+}
+
+// newTestTable 创建失效表和一个订阅了失效频道的重定向目标（ID 100）
+func newTestTable() (*Table, *recordSubscriber) {
+	hub := pubsub.NewHub()
+	target := &recordSubscriber{}
+	hub.Subscribe(target, InvalidateChannel)
+
+	t := NewTable(hub)
+	t.SetLookup(func(id int64) pubsub.Subscriber {
+		if id == 100 {
+			return target
+		}
+		return nil
+	})
+	return t, target
+}
+
+func TestDefaultModeInvalidatesReadKeys(t *testing.T) {
+	table, target := newTestTable()
+
+	if err := table.Enable(1, &recordSubscriber{}, Options{Redirect: 100}); err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+
+	table.BeginCommand(1, []string{"a"})
+	table.EndCommand(1)
+
+	table.KeyModified("b") // 没读过，不通知
+	table.KeyModified("a")
+	table.KeyModified("a") // 已经通知过，需要重新读取才会再次登记
+
+	got := target.invalidated(t)
+	if len(got) != 1 || len(got[0]) != 1 || got[0][0] != "a" {
+		t.Errorf("Unexpected invalidations: %v", got)
+	}
+}
+
+func TestBroadcastMode(t *testing.T) {
+	table, target := newTestTable()
+
+	err := table.Enable(1, &recordSubscriber{}, Options{Redirect: 100, BCast: true, Prefixes: []string{"user:"}})
+	if err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+
+	table.KeyModified("user:1")
+	table.KeyModified("order:1")
+	table.KeyModified("user:1")
+
+	if got := target.invalidated(t); len(got) != 2 {
+		t.Errorf("Expected 2 invalidations, got %v", got)
+	}
+
+	err = table.Enable(1, &recordSubscriber{}, Options{Redirect: 100, BCast: true, Prefixes: []string{"user:1"}})
+	if err == nil {
+		t.Error("Expected overlapping prefix error")
+	}
+	if err := table.Enable(1, &recordSubscriber{}, Options{Redirect: 100}); err != ErrSwitchMode {
+		t.Errorf("Expected ErrSwitchMode, got %v", err)
+	}
+}
+
+func TestOptInAndOptOut(t *testing.T) {
+	table, target := newTestTable()
+
+	table.Enable(1, &recordSubscriber{}, Options{Redirect: 100, OptIn: true})
+	table.BeginCommand(1, []string{"a"}) // 没有 CACHING YES，不记录
+	table.EndCommand(1)
+	if err := table.Caching(1, true); err != nil {
+		t.Fatalf("Caching failed: %v", err)
+	}
+	table.BeginCommand(1, []string{"b"})
+	table.EndCommand(1)
+
+	table.Enable(2, &recordSubscriber{}, Options{Redirect: 100, OptOut: true})
+	table.Caching(2, false)
+	table.BeginCommand(2, []string{"c"}) // CACHING NO，不记录
+	table.EndCommand(2)
+	table.BeginCommand(2, []string{"d"})
+	table.EndCommand(2)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		table.KeyModified(key)
+	}
+
+	got := target.invalidated(t)
+	if len(got) != 2 || got[0][0] != "b" || got[1][0] != "d" {
+		t.Errorf("Unexpected invalidations: %v", got)
+	}
+
+	if err := table.Caching(2, true); err != ErrCachingYes {
+		t.Errorf("Expected ErrCachingYes, got %v", err)
+	}
+}
+
+func TestNoLoop(t *testing.T) {
+	table, target := newTestTable()
+
+	table.Enable(1, &recordSubscriber{}, Options{Redirect: 100, BCast: true, NoLoop: true})
+
+	table.BeginCommand(1, nil)
+	table.KeyModified("k") // 自己的修改
+	table.EndCommand(1)
+	table.KeyModified("k") // 其他连接的修改
+
+	if got := target.invalidated(t); len(got) != 1 {
+		t.Errorf("Expected 1 invalidation, got %v", got)
+	}
+}
+
+func TestEnableErrors(t *testing.T) {
+	table, _ := newTestTable()
+	sub := &recordSubscriber{}
+
+	tests := []struct {
+		opts Options
+		err  error
+	}{
+		{Options{OptIn: true, OptOut: true}, ErrOptInOptOut},
+		{Options{BCast: true, OptIn: true}, ErrBCastOptInOut},
+		{Options{Prefixes: []string{"a"}}, ErrPrefixNoBCast},
+		{Options{Redirect: 42}, ErrNoRedirect},
+	}
+	for _, tt := range tests {
+		if err := table.Enable(1, sub, tt.opts); err != tt.err {
+			t.Errorf("Enable(%+v) = %v, expected %v", tt.opts, err, tt.err)
+		}
+	}
+	if table.Len() != 0 {
+		t.Errorf("Expected no tracking clients, got %d", table.Len())
+	}
+}
+
+func TestFlushAllAndDisable(t *testing.T) {
+	table, target := newTestTable()
+
+	table.Enable(1, &recordSubscriber{}, Options{Redirect: 100})
+	table.BeginCommand(1, []string{"a"})
+	table.EndCommand(1)
+
+	table.FlushAll()
+	got := target.invalidated(t)
+	if len(got) != 1 || got[0] != nil {
+		t.Fatalf("Expected a flush invalidation, got %v", got)
+	}
+
+	table.Disable(1)
+	if table.Tracking(1) || table.Len() != 0 {
+		t.Error("Expected tracking to be disabled")
+	}
+}
@@ -5,3 +5,48 @@ import "go-redis/protocol"
 type Handler interface {
 	Handle(args []protocol.Value) *protocol.Value
 }
+
+// ClientInfo 描述发起命令的连接，供慢日志等功能记录调用方
+type ClientInfo interface {
+	ID() int64
+	Addr() string
+	Name() string
+}
+
+// Conn 是发起命令的连接的上下文，需要读写连接自身状态的命令（SELECT 等）通过它访问连接
+type Conn interface {
+	ClientInfo
+	// DB 返回连接当前选择的数据库编号
+	DB() int
+	SetDB(index int)
+	// User 返回连接认证的用户名，未认证时是 default
+	User() string
+	// ProtocolVersion 返回连接使用的 RESP 版本
+	ProtocolVersion() int
+	// InTransaction 判断命令是否在事务中执行（EXEC 执行排队的命令时）
+	InTransaction() bool
+	// Push 向连接推送一条不属于当前命令回复的消息，不阻塞调用方，与命令回复的先后顺序不确定
+	Push(msg *protocol.Value)
+}
+
+// ContextHandler 是需要连接上下文的处理器，conn 不为 nil，没有连接时是一个不关联连接的默认上下文
+type ContextHandler interface {
+	HandleContext(conn Conn, args []protocol.Value) *protocol.Value
+}
+
+// HandlerAdapter 让只实现 Handler 的处理器作为 ContextHandler 使用，连接上下文被忽略
+type HandlerAdapter struct {
+	Handler
+}
+
+func (a HandlerAdapter) HandleContext(_ Conn, args []protocol.Value) *protocol.Value {
+	return a.Handle(args)
+}
+
+// AsContextHandler 返回 h 对应的 ContextHandler，h 本身实现了 ContextHandler 时直接使用它
+func AsContextHandler(h Handler) ContextHandler {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch
+	}
+	return HandlerAdapter{h}
+}